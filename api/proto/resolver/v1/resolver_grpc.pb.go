@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
-// - protoc-gen-go-grpc v1.4.0
+// - protoc-gen-go-grpc v1.5.1
 // - protoc             v5.28.3
 // source: resolver.proto
 
@@ -15,12 +15,13 @@ import (
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the grpc package it is being compiled against.
-// Requires gRPC-Go v1.62.0 or later.
-const _ = grpc.SupportPackageIsVersion8
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
 
 const (
 	ResolverService_Resolve_FullMethodName     = "/nexus.resolver.v1.ResolverService/Resolve"
 	ResolverService_ResolveMany_FullMethodName = "/nexus.resolver.v1.ResolverService/ResolveMany"
+	ResolverService_Watch_FullMethodName       = "/nexus.resolver.v1.ResolverService/Watch"
 )
 
 // ResolverServiceClient is the client API for ResolverService service.
@@ -33,6 +34,11 @@ type ResolverServiceClient interface {
 	Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*ResolveResponse, error)
 	// ResolveMany resolves a batch of agent URIs in a single request.
 	ResolveMany(ctx context.Context, in *ResolveManyRequest, opts ...grpc.CallOption) (*ResolveManyResponse, error)
+	// Watch streams the current resolution of an agent URI and then pushes a new
+	// event whenever its endpoint, status or cert serial changes. Over the HTTP
+	// gateway the stream is served as Server-Sent Events when the client sends
+	// Accept: text/event-stream, and as newline-delimited JSON otherwise.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error)
 }
 
 type resolverServiceClient struct {
@@ -63,9 +69,28 @@ func (c *resolverServiceClient) ResolveMany(ctx context.Context, in *ResolveMany
 	return out, nil
 }
 
+func (c *resolverServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ResolverService_ServiceDesc.Streams[0], ResolverService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ResolverService_WatchClient = grpc.ServerStreamingClient[WatchEvent]
+
 // ResolverServiceServer is the server API for ResolverService service.
 // All implementations must embed UnimplementedResolverServiceServer
-// for forward compatibility
+// for forward compatibility.
 //
 // ResolverService translates agent:// URIs into transport endpoints.
 type ResolverServiceServer interface {
@@ -73,12 +98,20 @@ type ResolverServiceServer interface {
 	Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error)
 	// ResolveMany resolves a batch of agent URIs in a single request.
 	ResolveMany(context.Context, *ResolveManyRequest) (*ResolveManyResponse, error)
+	// Watch streams the current resolution of an agent URI and then pushes a new
+	// event whenever its endpoint, status or cert serial changes. Over the HTTP
+	// gateway the stream is served as Server-Sent Events when the client sends
+	// Accept: text/event-stream, and as newline-delimited JSON otherwise.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error
 	mustEmbedUnimplementedResolverServiceServer()
 }
 
-// UnimplementedResolverServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedResolverServiceServer struct {
-}
+// UnimplementedResolverServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedResolverServiceServer struct{}
 
 func (UnimplementedResolverServiceServer) Resolve(context.Context, *ResolveRequest) (*ResolveResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Resolve not implemented")
@@ -86,7 +119,11 @@ func (UnimplementedResolverServiceServer) Resolve(context.Context, *ResolveReque
 func (UnimplementedResolverServiceServer) ResolveMany(context.Context, *ResolveManyRequest) (*ResolveManyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ResolveMany not implemented")
 }
+func (UnimplementedResolverServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedResolverServiceServer) mustEmbedUnimplementedResolverServiceServer() {}
+func (UnimplementedResolverServiceServer) testEmbeddedByValue()                         {}
 
 // UnsafeResolverServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to ResolverServiceServer will
@@ -96,6 +133,13 @@ type UnsafeResolverServiceServer interface {
 }
 
 func RegisterResolverServiceServer(s grpc.ServiceRegistrar, srv ResolverServiceServer) {
+	// If the following call pancis, it indicates UnimplementedResolverServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
 	s.RegisterService(&ResolverService_ServiceDesc, srv)
 }
 
@@ -135,6 +179,17 @@ func _ResolverService_ResolveMany_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ResolverService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ResolverServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ResolverService_WatchServer = grpc.ServerStreamingServer[WatchEvent]
+
 // ResolverService_ServiceDesc is the grpc.ServiceDesc for ResolverService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -151,6 +206,12 @@ var ResolverService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ResolverService_ResolveMany_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _ResolverService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "resolver.proto",
 }
@@ -0,0 +1,60 @@
+// cmd/kms-standin — a minimal signing service speaking the registry's KMS
+// key-store protocol (identity.keystore.backend: kms). It keeps keys in a
+// directory of PEM files, optionally passphrase-encrypted, and only ever
+// returns public keys and signatures, so the registry process never holds the
+// CA or JWT private key. Use it for development and testing, or as a template
+// for fronting a real KMS or HSM.
+//
+// Usage:
+//
+//	KMS_PASSPHRASE=... KMS_TOKEN=... go run ./cmd/kms-standin -dir data/kms -listen 127.0.0.1:9443
+//
+// and point the registry at it:
+//
+//	IDENTITY_KEYSTORE_BACKEND=kms IDENTITY_KEYSTORE_KMS_URL=http://127.0.0.1:9443 IDENTITY_KEYSTORE_KMS_TOKEN=...
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "kms-standin: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	dir := flag.String("dir", "data/kms", "directory holding the key files")
+	listen := flag.String("listen", "127.0.0.1:9443", "address to listen on")
+	flag.Parse()
+
+	passphrase := os.Getenv("KMS_PASSPHRASE")
+	token := os.Getenv("KMS_TOKEN")
+	if token == "" {
+		log.Printf("warning: KMS_TOKEN is not set; any client that can reach %s can sign", *listen)
+	}
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		return fmt.Errorf("create key dir: %w", err)
+	}
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           identity.NewKMSHandler(identity.NewFileKeyStore(*dir, passphrase), token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("serving keys from %s on %s (encrypted at rest: %t)", *dir, *listen, passphrase != "")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -6,12 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
 	"github.com/jmerrifield20/NexusAgentProtocol/pkg/agentcard"
 	"github.com/jmerrifield20/NexusAgentProtocol/pkg/client"
 	"github.com/jmerrifield20/NexusAgentProtocol/pkg/uri"
@@ -69,8 +71,10 @@ func init() {
 	rootCmd.AddCommand(registerCmd)
 	rootCmd.AddCommand(claimCmd)
 	rootCmd.AddCommand(revokeCmd)
+	rootCmd.AddCommand(certCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(dnsChallengeCmd)
+	rootCmd.AddCommand(ledgerCmd)
 }
 
 // ── resolve ──────────────────────────────────────────────────────────────────
@@ -297,6 +301,7 @@ var (
 	claimOutputDir   string
 	claimTimeoutMin  int
 	claimInsecure    bool
+	claimKeyAlg      string
 )
 
 // agentSpec collects the registration fields for a single agent.
@@ -314,7 +319,9 @@ var claimCmd = &cobra.Command{
 	Long: `claim guides you through the complete DNS-01 → register → activate flow.
 
 It optionally reads agent-card.json from your domain to pre-populate fields.
-On success the agent X.509 cert bundle is written to ~/.nap/certs/<domain>/.`,
+The agent's private key is generated locally and only a CSR is sent to the
+registry. On success the agent X.509 cert bundle is written to
+~/.nap/certs/<domain>/.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runClaim,
 }
@@ -328,6 +335,7 @@ func init() {
 	claimCmd.Flags().StringVar(&claimOutputDir, "output", "", "Certificate output directory (default ~/.nap/certs/<domain>/)")
 	claimCmd.Flags().IntVar(&claimTimeoutMin, "timeout", 10, "DNS polling timeout in minutes")
 	claimCmd.Flags().BoolVar(&claimInsecure, "insecure", false, "Skip TLS certificate verification (development only)")
+	claimCmd.Flags().StringVar(&claimKeyAlg, "key-alg", "rsa", "Agent key algorithm: rsa, ecdsa-p256 or ed25519")
 }
 
 func runClaim(cmd *cobra.Command, args []string) error {
@@ -384,7 +392,7 @@ func runClaim(cmd *cobra.Command, args []string) error {
 	}
 
 	// Build client.
-	opts := []client.Option{}
+	opts := []client.Option{client.WithKeyAlgorithm(client.KeyAlgorithm(claimKeyAlg))}
 	if claimInsecure {
 		opts = append(opts, client.WithInsecureSkipVerify())
 	}
@@ -468,7 +476,7 @@ func runClaim(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("activate agent %s: %w", agentResult.ID, err)
 		}
-		fmt.Println("✓ Agent activated")
+		fmt.Println("✓ Agent activated (private key generated locally)")
 
 		// Determine output directory.
 		outputDir := claimOutputDir
@@ -627,7 +635,10 @@ func runRevoke(cmd *cobra.Command, args []string) error {
 	}
 
 	// Revoke with Bearer token.
-	authC, err := client.New(registryURL, append(lookupOpts, client.WithBearerToken(token))...)
+	authC, err := client.New(registryURL, append(lookupOpts,
+		client.WithBearerToken(token),
+		client.WithKeyAlgorithm(client.KeyAlgorithm(renewKeyAlg)),
+	)...)
 	if err != nil {
 		return err
 	}
@@ -639,6 +650,124 @@ func runRevoke(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// ── cert ─────────────────────────────────────────────────────────────────────
+
+var (
+	renewCert     string
+	renewKey      string
+	renewCA       string
+	renewToken    string
+	renewOutput   string
+	renewInsecure bool
+	renewKeyAlg   string
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage agent certificates",
+}
+
+var certRenewCmd = &cobra.Command{
+	Use:   "renew <agent-uuid | agent://...>",
+	Short: "Re-issue an agent's certificate, keeping its URI",
+	Long: `renew generates a new private key locally and asks the registry to
+certify it, for an active, deprecated or expired agent. Only a CSR is sent;
+the key never leaves this machine. The agent URI does not change; an expired
+agent becomes active again.
+
+Authentication works as for 'nap revoke': a JWT token (--token), or the
+agent's current mTLS certificate (--cert/--key, or auto-discovered from
+~/.nap/certs/<owner-domain>/). Once the certificate has expired it can no
+longer be used, so pass a user token for the owning account instead.
+
+The new bundle is written to ~/.nap/certs/<owner-domain>/ (or --output),
+replacing the previous cert.pem and key.pem.
+
+Examples:
+
+  # Renew using the certificate saved by 'nap claim'
+  nap cert renew agent://acme.com/finance/agent_abc
+
+  # Renew an expired agent with a user token
+  nap cert renew --token eyJhbG... 550e8400-e29b-41d4-a716-446655440000`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCertRenew,
+}
+
+func init() {
+	certRenewCmd.Flags().StringVar(&renewCert, "cert", "", "Current client certificate PEM file (for mTLS → token exchange)")
+	certRenewCmd.Flags().StringVar(&renewKey, "key", "", "Current client private key PEM file")
+	certRenewCmd.Flags().StringVar(&renewCA, "ca", "", "CA certificate PEM file (validates registry TLS)")
+	certRenewCmd.Flags().StringVar(&renewToken, "token", "", "JWT Bearer token (skips mTLS token exchange)")
+	certRenewCmd.Flags().StringVar(&renewOutput, "output", "", "Certificate output directory (default ~/.nap/certs/<owner-domain>/)")
+	certRenewCmd.Flags().BoolVar(&renewInsecure, "insecure", false, "Skip TLS certificate verification (development only)")
+	certRenewCmd.Flags().StringVar(&renewKeyAlg, "key-alg", "rsa", "New key algorithm: rsa, ecdsa-p256 or ed25519")
+	certCmd.AddCommand(certRenewCmd)
+}
+
+func runCertRenew(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	var lookupOpts []client.Option
+	if renewInsecure {
+		lookupOpts = append(lookupOpts, client.WithInsecureSkipVerify())
+	}
+	c, err := client.New(registryURL, lookupOpts...)
+	if err != nil {
+		return err
+	}
+	agentUUID, agent, err := resolveAgentArg(ctx, c, args[0])
+	if err != nil {
+		return err
+	}
+
+	token := renewToken
+	if token == "" {
+		certPEM, keyPEM, caPEM, loadErr := loadOrDiscoverCerts(renewCert, renewKey, renewCA, agent.OwnerDomain)
+		if loadErr != nil {
+			return fmt.Errorf("no credentials for renewal: %w\n\nUse --token, or --cert/--key", loadErr)
+		}
+		authClient, err := client.New(registryURL, client.WithMTLS(certPEM, keyPEM, caPEM))
+		if err != nil {
+			return fmt.Errorf("build mTLS client: %w", err)
+		}
+		token, err = authClient.FetchToken(ctx)
+		if err != nil {
+			return fmt.Errorf("fetch auth token via mTLS: %w\n\nIf the certificate has expired, renew with --token <user token>", err)
+		}
+	}
+
+	authC, err := client.New(registryURL, append(lookupOpts,
+		client.WithBearerToken(token),
+		client.WithKeyAlgorithm(client.KeyAlgorithm(renewKeyAlg)),
+	)...)
+	if err != nil {
+		return err
+	}
+	result, err := authC.RenewCert(ctx, agentUUID)
+	if err != nil {
+		return fmt.Errorf("renew failed: %w", err)
+	}
+
+	outputDir := renewOutput
+	if outputDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("get home dir: %w", err)
+		}
+		outputDir = filepath.Join(home, ".nap", "certs", agent.OwnerDomain)
+	}
+	if err := saveCertBundle(outputDir, result); err != nil {
+		return fmt.Errorf("save certs: %w", err)
+	}
+
+	fmt.Printf("✓ Certificate renewed: %s\n\n", result.URI)
+	fmt.Printf("  Serial:  %s\n", result.CertSerial)
+	fmt.Printf("  Expires: %s\n", result.CertExpiresAt.Format(time.RFC3339))
+	fmt.Printf("  Certs:   %s\n", outputDir)
+	return nil
+}
+
 // resolveAgentArg accepts either an agent:// URI or a UUID string and returns
 // the agent's UUID and full AgentDetail from the registry.
 func resolveAgentArg(ctx context.Context, c *client.Client, arg string) (string, *client.AgentDetail, error) {
@@ -825,3 +954,97 @@ func init() {
 	dnsChallengeCmd.AddCommand(dnsVerifyCmd)
 	dnsChallengeCmd.AddCommand(dnsStatusCmd)
 }
+
+// ── ledger ────────────────────────────────────────────────────────────────────
+
+var (
+	ledgerFile     string
+	ledgerSave     string
+	ledgerInsecure bool
+)
+
+var ledgerCmd = &cobra.Command{
+	Use:   "ledger",
+	Short: "Inspect and independently verify the Trust Ledger",
+}
+
+var ledgerVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify a Trust Ledger export without trusting the registry",
+	Long: `verify recomputes every entry hash in a Trust Ledger export, checks the chain
+back to the well-known genesis hash, checks each payload against its data_hash,
+and reports the first broken index.
+
+By default the export is downloaded from the registry's /api/v1/ledger/export
+endpoint. Use --file to verify a previously saved export (or "-" for stdin).`,
+	Args: cobra.NoArgs,
+	RunE: runLedgerVerify,
+}
+
+func init() {
+	ledgerVerifyCmd.Flags().StringVar(&ledgerFile, "file", "", `Read a JSONL export from this file ("-" for stdin) instead of downloading`)
+	ledgerVerifyCmd.Flags().StringVar(&ledgerSave, "save", "", "Also write the downloaded export to this file")
+	ledgerVerifyCmd.Flags().BoolVar(&ledgerInsecure, "insecure", false, "Skip TLS certificate verification (development only)")
+	ledgerCmd.AddCommand(ledgerVerifyCmd)
+}
+
+func runLedgerVerify(cmd *cobra.Command, args []string) error {
+	var src io.Reader
+	expected := -1
+
+	switch ledgerFile {
+	case "":
+		var opts []client.Option
+		if ledgerInsecure {
+			opts = append(opts, client.WithInsecureSkipVerify())
+		}
+		c, err := client.New(registryURL, opts...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Downloading ledger export from %s...\n", registryURL)
+		export, err := c.ExportLedger(context.Background())
+		if err != nil {
+			return fmt.Errorf("download ledger export: %w", err)
+		}
+		defer export.Body.Close()
+		expected = export.Entries
+		src = export.Body
+
+		if ledgerSave != "" {
+			f, err := os.Create(ledgerSave)
+			if err != nil {
+				return fmt.Errorf("create %s: %w", ledgerSave, err)
+			}
+			defer f.Close()
+			src = io.TeeReader(src, f)
+		}
+	case "-":
+		src = os.Stdin
+	default:
+		f, err := os.Open(ledgerFile)
+		if err != nil {
+			return fmt.Errorf("open export: %w", err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	v, err := trustledger.VerifyJSONL(src)
+	if err != nil {
+		var broken *trustledger.BrokenChainError
+		if errors.As(err, &broken) {
+			fmt.Printf("✗ Ledger broken at index %d: %s\n", broken.Index, broken.Reason)
+			return fmt.Errorf("ledger verification failed at index %d", broken.Index)
+		}
+		return fmt.Errorf("verify export: %w", err)
+	}
+	if expected >= 0 && v.Count() < expected {
+		fmt.Printf("✗ Export truncated: verified %d of %d advertised entries\n", v.Count(), expected)
+		return fmt.Errorf("ledger export truncated at index %d", v.Count())
+	}
+
+	fmt.Printf("✓ Ledger verified: %d entries\n", v.Count())
+	fmt.Printf("  Chain tip: %s (index %d)\n", v.Last().Hash, v.Last().Index)
+	return nil
+}
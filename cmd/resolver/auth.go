@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/spf13/viper"
+)
+
+// serverTLSConfig builds the TLS config shared by the gRPC and HTTP listeners
+// from resolver.tls.*. It returns nil when TLS is not configured. With
+// resolver.auth.client_ca_file set, client certificates signed by that CA
+// (normally the registry CA) are verified during the handshake; whether one
+// is required is decided per call by resolver.Authenticator.
+func serverTLSConfig() (*tls.Config, error) {
+	certFile := viper.GetString("resolver.tls.cert_file")
+	keyFile := viper.GetString("resolver.tls.key_file")
+	clientCA := viper.GetString("resolver.auth.client_ca_file")
+	if certFile == "" && keyFile == "" {
+		if clientCA != "" {
+			return nil, errors.New("resolver.auth.client_ca_file requires resolver.tls.cert_file and key_file")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load resolver TLS certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCA != "" {
+		pool, err := loadCertPool(clientCA, false)
+		if err != nil {
+			return nil, fmt.Errorf("resolver.auth.client_ca_file: %w", err)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+// registryTLSConfig builds the TLS config for requests to registries from
+// resolver.registry_tls.*. It returns nil when nothing is configured.
+func registryTLSConfig() (*tls.Config, error) {
+	caFile := viper.GetString("resolver.registry_tls.ca_file")
+	certFile := viper.GetString("resolver.registry_tls.cert_file")
+	keyFile := viper.GetString("resolver.registry_tls.key_file")
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		// Keep the system roots so federated registries with public
+		// certificates still verify.
+		pool, err := loadCertPool(caFile, true)
+		if err != nil {
+			return nil, fmt.Errorf("resolver.registry_tls.ca_file: %w", err)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load registry client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// tokenVerifier returns a verifier for registry-issued Task Tokens, or nil
+// when bearer auth is disabled. Keys come from resolver.auth.token_jwks_url,
+// which follows key rotations, or from resolver.auth.token_key_file: the
+// registry's jwt.pub bundle, or its CA certificate when the registry signs
+// tokens with the CA key. registryTLS, if set, is used to fetch the JWKS.
+func tokenVerifier(registryTLS *tls.Config) (*identity.TaskTokenVerifier, error) {
+	jwksURL := viper.GetString("resolver.auth.token_jwks_url")
+	keyFile := viper.GetString("resolver.auth.token_key_file")
+	if jwksURL == "" && keyFile == "" {
+		return nil, nil
+	}
+	issuer := viper.GetString("resolver.auth.token_issuer")
+	if issuer == "" {
+		return nil, errors.New("resolver.auth.token_key_file and token_jwks_url require resolver.auth.token_issuer")
+	}
+
+	if jwksURL != "" {
+		jwks := identity.NewRemoteJWKS(jwksURL, time.Duration(viper.GetInt("resolver.auth.token_jwks_refresh_seconds"))*time.Second)
+		if registryTLS != nil {
+			jwks.SetHTTPClient(&http.Client{
+				Timeout:   10 * time.Second,
+				Transport: &http.Transport{TLSClientConfig: registryTLS},
+			})
+		}
+		return identity.NewTaskTokenVerifierWithKeySet(jwks, issuer), nil
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read token key: %w", err)
+	}
+	// jwt.pub lists every published key, so tokens signed before a rotation
+	// keep verifying until the file is next reloaded.
+	var keys identity.StaticKeySet
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		pub, err := identity.PublicKeyFromPEM(string(pem.EncodeToMemory(block)))
+		if err != nil {
+			return nil, fmt.Errorf("token key in %s: %w", keyFile, err)
+		}
+		if _, err := identity.KeyAlgorithmOf(pub); err != nil {
+			return nil, fmt.Errorf("token key in %s: %w", keyFile, err)
+		}
+		keys = append(keys, pub)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM keys found in %s", keyFile)
+	}
+	return identity.NewTaskTokenVerifierWithKeySet(keys, issuer), nil
+}
+
+func loadCertPool(path string, withSystem bool) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if withSystem {
+		if sys, err := x509.SystemCertPool(); err == nil {
+			pool = sys
+		}
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
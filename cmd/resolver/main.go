@@ -8,15 +8,23 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/federation"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/invalidation"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/resolver"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
@@ -34,12 +42,20 @@ func main() {
 }
 
 func run(logger *zap.Logger) error {
+	pflag.Bool("offline", false, "serve only from the cache snapshot and never contact the registry")
+	pflag.Parse()
+
 	// ── Configuration ─────────────────────────────────────────────────────────
 	viper.SetConfigName("resolver")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("configs")
 	viper.AddConfigPath(".")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
+	// --offline overrides RESOLVER_OFFLINE and resolver.offline in the config.
+	if err := viper.BindPFlag("resolver.offline", pflag.Lookup("offline")); err != nil {
+		return fmt.Errorf("bind --offline: %w", err)
+	}
 
 	viper.SetDefault("resolver.grpc_port", 9090)
 	viper.SetDefault("resolver.http_port", 9091)    // grpc-gateway REST port
@@ -47,6 +63,33 @@ func run(logger *zap.Logger) error {
 	viper.SetDefault("resolver.cache_ttl_seconds", 60)
 	viper.SetDefault("resolver.http_timeout_seconds", 5)
 	viper.SetDefault("resolver.eviction_interval_seconds", 60)
+	viper.SetDefault("resolver.negative_cache_ttl_seconds", 5)
+	viper.SetDefault("resolver.stale_ttl_seconds", 300)
+	viper.SetDefault("resolver.watch_interval_seconds", 5)
+	viper.SetDefault("resolver.invalidation_secret", "")
+	viper.SetDefault("resolver.snapshot_path", "")
+	viper.SetDefault("resolver.snapshot_interval_seconds", 60)
+	viper.SetDefault("resolver.offline", false)
+	viper.SetDefault("resolver.tls.cert_file", "")
+	viper.SetDefault("resolver.tls.key_file", "")
+	viper.SetDefault("resolver.auth.client_ca_file", "")
+	viper.SetDefault("resolver.auth.token_key_file", "")
+	viper.SetDefault("resolver.auth.token_issuer", "")
+	viper.SetDefault("resolver.auth.token_jwks_url", "")
+	viper.SetDefault("resolver.auth.token_jwks_refresh_seconds", 600)
+	viper.SetDefault("resolver.auth.token_audience", "") // default: token_issuer
+	viper.SetDefault("resolver.auth.allow_unaudienced_tokens", false)
+	viper.SetDefault("resolver.registry_tls.ca_file", "")
+	viper.SetDefault("resolver.registry_tls.cert_file", "")
+	viper.SetDefault("resolver.registry_tls.key_file", "")
+	viper.SetDefault("resolver.dns.enabled", false)
+	viper.SetDefault("resolver.dns.addr", ":5353")
+	viper.SetDefault("resolver.dns.zone", "nap.internal")
+	viper.SetDefault("resolver.dns.ttl_seconds", 30)
+	viper.SetDefault("resolver.dns.allow_unauthenticated", false)
+	viper.SetDefault("resolver.federation.enabled", false)
+	viper.SetDefault("resolver.federation.dns_discovery_enabled", true)
+	viper.SetDefault("resolver.federation.registry_cache_ttl_seconds", 300)
 
 	if err := viper.ReadInConfig(); err != nil {
 		var cfgNotFound viper.ConfigFileNotFoundError
@@ -62,20 +105,122 @@ func run(logger *zap.Logger) error {
 	cacheTTL := time.Duration(viper.GetInt("resolver.cache_ttl_seconds")) * time.Second
 	httpTimeout := time.Duration(viper.GetInt("resolver.http_timeout_seconds")) * time.Second
 	evictionInterval := time.Duration(viper.GetInt("resolver.eviction_interval_seconds")) * time.Second
+	negativeTTL := time.Duration(viper.GetInt("resolver.negative_cache_ttl_seconds")) * time.Second
+	staleTTL := time.Duration(viper.GetInt("resolver.stale_ttl_seconds")) * time.Second
+	watchInterval := time.Duration(viper.GetInt("resolver.watch_interval_seconds")) * time.Second
+	registryCacheTTL := time.Duration(viper.GetInt("resolver.federation.registry_cache_ttl_seconds")) * time.Second
+	snapshotPath := viper.GetString("resolver.snapshot_path")
+	snapshotInterval := time.Duration(viper.GetInt("resolver.snapshot_interval_seconds")) * time.Second
+	offline := viper.GetBool("resolver.offline")
+	if offline && snapshotPath == "" {
+		return errors.New("offline mode requires resolver.snapshot_path")
+	}
+
+	// ── TLS and authentication ────────────────────────────────────────────────
+	serverTLS, err := serverTLSConfig()
+	if err != nil {
+		return err
+	}
+	registryTLS, err := registryTLSConfig()
+	if err != nil {
+		return err
+	}
+	tokens, err := tokenVerifier(registryTLS)
+	if err != nil {
+		return err
+	}
+
+	auth := resolver.NewAuthenticator(logger)
+	auth.SetRequireClientCert(serverTLS != nil && serverTLS.ClientCAs != nil)
+	// The REST gateway reaches gRPC over loopback; its callers' certificates and
+	// token bindings are checked on the HTTP listener, so it only needs the
+	// token check.
+	gatewayAuth := resolver.NewAuthenticator(logger)
+	gatewayAuth.SetTokenBinding(nil)
+	if tokens != nil {
+		audience := viper.GetString("resolver.auth.token_audience")
+		if audience == "" {
+			audience = viper.GetString("resolver.auth.token_issuer")
+		}
+		allowNoAudience := viper.GetBool("resolver.auth.allow_unaudienced_tokens")
+		for _, a := range []*resolver.Authenticator{auth, gatewayAuth} {
+			a.SetTokenVerifier(tokens)
+			a.SetTokenAudience(audience, allowNoAudience)
+		}
+	}
+	logger.Info("resolver authentication",
+		zap.Bool("tls", serverTLS != nil),
+		zap.Bool("mtls", serverTLS != nil && serverTLS.ClientCAs != nil),
+		zap.Bool("bearer_tokens", tokens != nil),
+		zap.Bool("registry_tls", registryTLS != nil),
+	)
 
 	// ── Resolver service ──────────────────────────────────────────────────────
 	cfg := resolver.Config{
 		RegistryAddr: registryAddr,
 		CacheTTL:     cacheTTL,
 		HTTPTimeout:  httpTimeout,
+
+		NegativeTTL:   negativeTTL,
+		StaleTTL:      staleTTL,
+		WatchInterval: watchInterval,
+
+		RegistryCacheTTL: registryCacheTTL,
+		Offline:          offline,
+		RegistryTLS:      registryTLS,
 	}
 	svc := resolver.New(cfg, logger)
 
+	// Warm the cache from the last snapshot so lookups work even if the
+	// registry is unreachable at startup.
+	if snapshotPath != "" {
+		if _, err := svc.LoadSnapshot(snapshotPath); err != nil {
+			if offline || !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("load cache snapshot: %w", err)
+			}
+			logger.Info("no cache snapshot yet", zap.String("path", snapshotPath))
+		}
+	}
+	if offline {
+		logger.Warn("resolver is offline: answering from the cache snapshot only",
+			zap.String("snapshot", snapshotPath),
+		)
+	}
+
+	// Federated resolution: find each trust root's registry via the static
+	// table, then DNS (_nap-registry TXT), falling back to registry_addr.
+	if viper.GetBool("resolver.federation.enabled") {
+		var static []struct {
+			TrustRoot string `mapstructure:"trust_root"`
+			URL       string `mapstructure:"url"`
+		}
+		if err := viper.UnmarshalKey("resolver.federation.registries", &static); err != nil {
+			return fmt.Errorf("resolver.federation.registries: %w", err)
+		}
+		table := make(map[string]string, len(static))
+		for _, r := range static {
+			table[r.TrustRoot] = r.URL
+		}
+
+		// The resolver adds the scheme to a scheme-less registry_addr.
+		locator := federation.NewRemoteResolver(nil, registryAddr,
+			viper.GetBool("resolver.federation.dns_discovery_enabled"), httpTimeout, logger)
+		locator.SetStaticRegistries(table)
+		svc.SetRegistryLocator(locator)
+		logger.Info("federated resolution enabled",
+			zap.Int("static_registries", len(table)),
+			zap.Duration("registry_cache_ttl", registryCacheTTL),
+		)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Start background cache eviction
 	svc.StartCacheEviction(ctx, evictionInterval)
+	if snapshotPath != "" {
+		svc.StartSnapshotting(ctx, snapshotPath, snapshotInterval)
+	}
 
 	// ── gRPC server ───────────────────────────────────────────────────────────
 	grpcLis, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
@@ -83,15 +228,21 @@ func run(logger *zap.Logger) error {
 		return fmt.Errorf("gRPC listen on :%d: %w", grpcPort, err)
 	}
 
-	grpcServer := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(loggingInterceptor(logger)),
-	)
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(loggingInterceptor(logger), auth.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(streamLoggingInterceptor(logger), auth.StreamInterceptor()),
+	}
+	if serverTLS != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(serverTLS)))
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
 
 	resolverv1.RegisterResolverServiceServer(grpcServer, svc)
 
-	// Standard gRPC health service
+	// Standard gRPC health service (exempt from authentication)
 	healthSvc := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthSvc)
+	healthSvc.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
 	healthSvc.SetServingStatus(
 		resolverv1.ResolverService_ServiceDesc.ServiceName,
 		grpc_health_v1.HealthCheckResponse_SERVING,
@@ -101,36 +252,101 @@ func run(logger *zap.Logger) error {
 	reflection.Register(grpcServer)
 
 	// ── grpc-gateway HTTP/JSON reverse proxy ──────────────────────────────────
+	jsonMarshaler := &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			UseProtoNames:   true,
+			EmitUnpopulated: false,
+		},
+	}
 	gwMux := runtime.NewServeMux(
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
-			MarshalOptions: protojson.MarshalOptions{
-				UseProtoNames:   true,
-				EmitUnpopulated: false,
-			},
-		}),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, jsonMarshaler),
+		// GET /v1/watch with Accept: text/event-stream is served as SSE.
+		runtime.WithMarshalerOption(resolver.EventStreamMIME, &resolver.EventStreamMarshaler{Marshaler: jsonMarshaler}),
 	)
 
-	// Register the gateway against the local gRPC server
-	grpcAddr := fmt.Sprintf("localhost:%d", grpcPort)
-	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-	if err := resolverv1.RegisterResolverServiceHandlerFromEndpoint(ctx, gwMux, grpcAddr, dialOpts); err != nil {
+	// Register the gateway against a second gRPC server on a loopback port,
+	// so it works whatever TLS and client-certificate settings the public
+	// port uses. Client certificates are checked on the HTTP listener; the
+	// loopback server still enforces bearer tokens.
+	gatewayLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("gateway listen on loopback: %w", err)
+	}
+	gatewayServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(gatewayAuth.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(gatewayAuth.StreamInterceptor()),
+	)
+	resolverv1.RegisterResolverServiceServer(gatewayServer, svc)
+	go gatewayServer.Serve(gatewayLis) //nolint:errcheck
+
+	gatewayConn, err := grpc.NewClient(gatewayLis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return fmt.Errorf("dial gateway gRPC: %w", err)
+	}
+	defer gatewayConn.Close()
+	if err := resolverv1.RegisterResolverServiceHandler(ctx, gwMux, gatewayConn); err != nil {
 		return fmt.Errorf("register grpc-gateway: %w", err)
 	}
 
 	httpMux := http.NewServeMux()
 	httpMux.Handle("/", gwMux)
+	// Registry-pushed cache invalidation (see internal/invalidation).
+	if secret := viper.GetString("resolver.invalidation_secret"); secret != "" {
+		httpMux.Handle("/v1/invalidate", invalidation.NewHandler(secret, func(ev invalidation.Event) {
+			svc.InvalidateAgent(ev.TrustRoot, ev.AgentID)
+		}, logger))
+	} else {
+		logger.Warn("resolver.invalidation_secret not set; cached resolutions expire only by TTL")
+	}
 	httpMux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprint(w, `{"status":"ok","service":"resolver"}`)
 	})
-	httpMux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"cache_entries":%d}`, svc.CacheStats())
-	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "nap_resolver_cache_entries",
+		Help: "Entries in the resolver cache, including stale and negative entries.",
+	}, func() float64 { return float64(svc.CacheStats()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "nap_resolver_registry_cache_entries",
+		Help: "Trust roots with a cached registry location (federated resolution).",
+	}, func() float64 { return float64(svc.RegistryCacheStats()) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "nap_resolver_watches",
+		Help: "Open Watch streams.",
+	}, func() float64 { return float64(svc.WatchCount()) })
+	httpMux.Handle("/metrics", promhttp.Handler())
+
+	// ── DNS front-end (optional) ──────────────────────────────────────────────
+	// DNS carries neither client certificates nor tokens, so the listener
+	// would answer anyone the gRPC and REST APIs turn away.
+	if viper.GetBool("resolver.dns.enabled") {
+		authEnabled := tokens != nil || (serverTLS != nil && serverTLS.ClientCAs != nil)
+		if authEnabled && !viper.GetBool("resolver.dns.allow_unauthenticated") {
+			return errors.New("resolver.dns.enabled serves resolutions without authentication; " +
+				"set resolver.dns.allow_unauthenticated to run it alongside resolver.auth")
+		}
+		dnsAddr := viper.GetString("resolver.dns.addr")
+		dnsSrv := resolver.NewDNSServer(svc, viper.GetString("resolver.dns.zone"),
+			time.Duration(viper.GetInt("resolver.dns.ttl_seconds"))*time.Second, logger)
+		go func() {
+			logger.Info("resolver DNS listening",
+				zap.String("addr", dnsAddr),
+				zap.String("zone", viper.GetString("resolver.dns.zone")),
+			)
+			if err := dnsSrv.ListenAndServe(ctx, dnsAddr); err != nil {
+				logger.Fatal("DNS serve error", zap.Error(err))
+			}
+		}()
+	}
 
 	httpSrv := &http.Server{
-		Addr:              fmt.Sprintf(":%d", httpPort),
-		Handler:           httpMux,
+		Addr: fmt.Sprintf(":%d", httpPort),
+		// Health, metrics and the HMAC-signed invalidation hook stay
+		// reachable without a client certificate.
+		Handler:           auth.RequireClientCertHTTP(auth.TokenBindingHTTP(httpMux), "/healthz", "/metrics", "/v1/invalidate"),
+		TLSConfig:         serverTLS,
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
@@ -150,8 +366,14 @@ func run(logger *zap.Logger) error {
 	}()
 
 	go func() {
-		logger.Info("resolver HTTP/JSON gateway listening", zap.Int("port", httpPort))
-		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Info("resolver HTTP/JSON gateway listening", zap.Int("port", httpPort), zap.Bool("tls", serverTLS != nil))
+		var err error
+		if serverTLS != nil {
+			err = httpSrv.ListenAndServeTLS("", "")
+		} else {
+			err = httpSrv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Fatal("HTTP serve error", zap.Error(err))
 		}
 	}()
@@ -159,20 +381,43 @@ func run(logger *zap.Logger) error {
 	// ── Graceful shutdown ──────────────────────────────────────────────────────
 	<-quit
 	logger.Info("shutting down resolver...")
-	cancel() // stop cache eviction
+	cancel() // stop cache eviction, snapshotting and the DNS listener
 
-	grpcServer.GracefulStop()
+	healthSvc.Shutdown() // report NOT_SERVING while draining
+	// Watch streams never end on their own, so draining is bounded.
+	gracefulStop(grpcServer, 10*time.Second)
 
 	shutCtx, shutCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutCancel()
 	if err := httpSrv.Shutdown(shutCtx); err != nil {
 		logger.Error("HTTP gateway shutdown", zap.Error(err))
 	}
+	gatewayServer.Stop()
+
+	if snapshotPath != "" && !offline {
+		if err := svc.SaveSnapshot(snapshotPath); err != nil {
+			logger.Error("save cache snapshot", zap.Error(err))
+		}
+	}
 
 	logger.Info("resolver stopped")
 	return nil
 }
 
+// gracefulStop stops srv gracefully, forcing it closed after timeout.
+func gracefulStop(srv *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		srv.Stop()
+	}
+}
+
 // loggingInterceptor returns a gRPC unary server interceptor that logs each call.
 func loggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
@@ -190,3 +435,22 @@ func loggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+// streamLoggingInterceptor returns a gRPC stream server interceptor that logs
+// each stream when it ends.
+func streamLoggingInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		code := "OK"
+		if err != nil {
+			code = grpc.Code(err).String() //nolint:staticcheck
+		}
+		logger.Info("grpc stream",
+			zap.String("method", info.FullMethod),
+			zap.String("code", code),
+			zap.Duration("duration", time.Since(start)),
+		)
+		return err
+	}
+}
@@ -8,18 +8,21 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/model"
 )
 
 // remoteAgentResponse is the subset of the agent API response needed for resolution.
 type remoteAgentResponse struct {
-	AgentID        string `json:"agent_id"`
-	TrustRoot      string `json:"trust_root"`
-	CapabilityNode string `json:"capability_node"`
-	DisplayName    string `json:"display_name"`
-	Description    string `json:"description"`
-	Endpoint       string `json:"endpoint"`
-	Status         string `json:"status"`
-	Version        string `json:"version"`
+	AgentID        string                `json:"agent_id"`
+	TrustRoot      string                `json:"trust_root"`
+	CapabilityNode string                `json:"capability_node"`
+	DisplayName    string                `json:"display_name"`
+	Description    string                `json:"description"`
+	Endpoint       string                `json:"endpoint"`
+	Endpoints      []model.AgentEndpoint `json:"endpoints"`
+	Status         string                `json:"status"`
+	Version        string                `json:"version"`
 }
 
 // RegistryClient is a lightweight HTTP client for querying a remote NAP registry.
@@ -47,7 +50,7 @@ func (c *RegistryClient) Resolve(ctx context.Context, trustRoot, capNode, agentI
 	}
 	q := u.Query()
 	q.Set("trust_root", trustRoot)
-	q.Set("cap_node", capNode)
+	q.Set("capability_node", capNode)
 	q.Set("agent_id", agentID)
 	u.RawQuery = q.Encode()
 
@@ -14,11 +14,12 @@ import (
 
 // RemoteResolver implements service.RemoteResolver by querying federated registries.
 // Discovery priority:
-//  1. Federation table lookup (fedSvc.GetByTrustRoot)
+//  1. Federation table lookup (fedSvc.GetByTrustRoot, then static registries)
 //  2. DNS TXT record: _nap-registry.{trustRoot} → "v=nap1 url=https://..."
 //  3. Configured rootRegistryURL fallback
 type RemoteResolver struct {
 	fedSvc          *FederationService
+	static          map[string]string
 	rootRegistryURL string
 	dnsEnabled      bool
 	timeout         time.Duration
@@ -26,9 +27,15 @@ type RemoteResolver struct {
 
 	// dnsDiscoverFn is the DNS discovery function. Defaults to dnsDiscover.
 	// Tests can override this to avoid real DNS lookups.
-	dnsDiscoverFn func(trustRoot string) (string, bool)
+	dnsDiscoverFn func(trustRoot string) (string, error)
 }
 
+// ErrNoRegistry is returned by DiscoverEndpoint when every discovery path
+// answered and none knows a registry for the trust root. Other errors mean
+// discovery could not complete (database or DNS failure) and may succeed on
+// retry.
+var ErrNoRegistry = errors.New("no registry endpoint found")
+
 // NewRemoteResolver creates a RemoteResolver.
 // fedSvc and rootRegistryURL may be zero-valued to skip those discovery paths.
 func NewRemoteResolver(
@@ -52,6 +59,19 @@ func NewRemoteResolver(
 	return rr
 }
 
+// SetStaticRegistries configures a trust root → registry URL table that is
+// consulted after the federation table. It lets processes without a database
+// (e.g. the standalone resolver) pin registries for known trust roots.
+func (r *RemoteResolver) SetStaticRegistries(registries map[string]string) {
+	r.static = registries
+}
+
+// DiscoverEndpoint returns the HTTP base URL of the registry responsible for
+// trustRoot, using the same discovery order as Resolve.
+func (r *RemoteResolver) DiscoverEndpoint(ctx context.Context, trustRoot string) (string, error) {
+	return r.discoverEndpoint(ctx, trustRoot)
+}
+
 // Resolve attempts to find an agent on a remote registry.
 // It returns *model.Agent populated from the remote response, or an error if
 // the agent could not be found through any discovery path.
@@ -78,6 +98,7 @@ func (r *RemoteResolver) Resolve(ctx context.Context, trustRoot, capNode, agentI
 		DisplayName:    remote.DisplayName,
 		Description:    remote.Description,
 		Endpoint:       remote.Endpoint,
+		Endpoints:      remote.Endpoints,
 		Status:         model.AgentStatus(remote.Status),
 		Version:        remote.Version,
 	}
@@ -92,22 +113,38 @@ func (r *RemoteResolver) Resolve(ctx context.Context, trustRoot, capNode, agentI
 
 // discoverEndpoint finds the HTTP base URL of the registry responsible for trustRoot.
 func (r *RemoteResolver) discoverEndpoint(ctx context.Context, trustRoot string) (string, error) {
+	// lookupErr is the last transient failure. If no path finds a registry,
+	// it is returned instead of ErrNoRegistry: the answer is not authoritative.
+	var lookupErr error
+
 	// 1. Federation table lookup.
 	if r.fedSvc != nil {
 		reg, err := r.fedSvc.GetByTrustRoot(ctx, trustRoot)
-		if err == nil && reg.Status == StatusActive {
+		switch {
+		case err == nil && reg.Status == StatusActive:
 			return reg.EndpointURL, nil
+		case err != nil && !errors.Is(err, ErrNotFound):
+			lookupErr = fmt.Errorf("federation table: %w", err)
 		}
 	}
+	if url, ok := r.static[trustRoot]; ok {
+		return url, nil
+	}
 
 	// 2. DNS TXT discovery.
 	if r.dnsEnabled {
-		if url, ok := r.dnsDiscoverFn(trustRoot); ok {
+		url, err := r.dnsDiscoverFn(trustRoot)
+		if err != nil {
+			lookupErr = fmt.Errorf("dns discovery: %w", err)
+		} else if url != "" {
 			// When fedSvc is available (root mode), require the trust root
 			// to be approved in the federation table before accepting DNS results.
 			if r.fedSvc != nil {
 				reg, err := r.fedSvc.GetByTrustRoot(ctx, trustRoot)
 				if err != nil || reg.Status != StatusActive {
+					if err != nil && !errors.Is(err, ErrNotFound) {
+						lookupErr = fmt.Errorf("federation table: %w", err)
+					}
 					r.logger.Warn("DNS discovery rejected: trust root not approved",
 						zap.String("trust_root", trustRoot),
 						zap.String("dns_url", url),
@@ -127,16 +164,24 @@ func (r *RemoteResolver) discoverEndpoint(ctx context.Context, trustRoot string)
 		return r.rootRegistryURL, nil
 	}
 
-	return "", fmt.Errorf("no registry endpoint found for trust_root %q", trustRoot)
+	if lookupErr != nil {
+		return "", fmt.Errorf("discover registry for trust_root %q: %w", trustRoot, lookupErr)
+	}
+	return "", fmt.Errorf("%w for trust_root %q", ErrNoRegistry, trustRoot)
 }
 
 // dnsDiscover looks up _nap-registry.{trustRoot} TXT records.
 // Expected format: "v=nap1 url=https://registry.example.com"
-func (r *RemoteResolver) dnsDiscover(trustRoot string) (string, bool) {
+// It returns "" and no error when the name has no such record.
+func (r *RemoteResolver) dnsDiscover(trustRoot string) (string, error) {
 	host := "_nap-registry." + trustRoot
 	txts, err := net.LookupTXT(host)
 	if err != nil {
-		return "", false
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return "", nil
+		}
+		return "", err
 	}
 	for _, txt := range txts {
 		if !strings.HasPrefix(txt, "v=nap1 ") {
@@ -144,9 +189,9 @@ func (r *RemoteResolver) dnsDiscover(trustRoot string) (string, bool) {
 		}
 		for _, part := range strings.Fields(txt) {
 			if strings.HasPrefix(part, "url=") {
-				return strings.TrimPrefix(part, "url="), true
+				return strings.TrimPrefix(part, "url="), nil
 			}
 		}
 	}
-	return "", false
+	return "", nil
 }
@@ -2,7 +2,9 @@ package federation
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"testing"
 
 	"github.com/google/uuid"
@@ -18,7 +20,7 @@ func (s *stubFedRepo) GetByTrustRoot(_ context.Context, trustRoot string) (*Regi
 	if reg, ok := s.byTrustRoot[trustRoot]; ok {
 		return reg, nil
 	}
-	return nil, fmt.Errorf("not found")
+	return nil, ErrNotFound
 }
 
 func (s *stubFedRepo) Create(context.Context, *RegisteredRegistry) error              { return nil }
@@ -43,8 +45,8 @@ func TestDiscoverEndpoint_DNSRejectedWithoutApproval(t *testing.T) {
 	fedSvc := newTestFedSvc(repo)
 
 	rr := NewRemoteResolver(fedSvc, "https://root.example.com", true, 0, zap.NewNop())
-	rr.dnsDiscoverFn = func(trustRoot string) (string, bool) {
-		return "https://rogue.example.com", true
+	rr.dnsDiscoverFn = func(trustRoot string) (string, error) {
+		return "https://rogue.example.com", nil
 	}
 
 	url, err := rr.discoverEndpoint(context.Background(), "rogue.com")
@@ -71,8 +73,8 @@ func TestDiscoverEndpoint_DNSAcceptedWithApproval(t *testing.T) {
 	}
 	fedSvc := newTestFedSvc(countingRepo)
 	rr := NewRemoteResolver(fedSvc, "https://root.example.com", true, 0, zap.NewNop())
-	rr.dnsDiscoverFn = func(trustRoot string) (string, bool) {
-		return "https://dns.acme.com", true
+	rr.dnsDiscoverFn = func(trustRoot string) (string, error) {
+		return "https://dns.acme.com", nil
 	}
 
 	url, err := rr.discoverEndpoint(context.Background(), "acme.com")
@@ -90,8 +92,8 @@ func TestDiscoverEndpoint_DNSAcceptedWithApproval(t *testing.T) {
 // (federated mode), DNS discovery works without any cross-reference check.
 func TestDiscoverEndpoint_DNSWorksWithoutFedSvc(t *testing.T) {
 	rr := NewRemoteResolver(nil, "https://root.example.com", true, 0, zap.NewNop())
-	rr.dnsDiscoverFn = func(trustRoot string) (string, bool) {
-		return "https://dns.example.com", true
+	rr.dnsDiscoverFn = func(trustRoot string) (string, error) {
+		return "https://dns.example.com", nil
 	}
 
 	url, err := rr.discoverEndpoint(context.Background(), "example.com")
@@ -118,9 +120,9 @@ func TestDiscoverEndpoint_TableTakesPrecedence(t *testing.T) {
 
 	dnsCalled := false
 	rr := NewRemoteResolver(fedSvc, "https://root.example.com", true, 0, zap.NewNop())
-	rr.dnsDiscoverFn = func(trustRoot string) (string, bool) {
+	rr.dnsDiscoverFn = func(trustRoot string) (string, error) {
 		dnsCalled = true
-		return "https://dns.acme.com", true
+		return "https://dns.acme.com", nil
 	}
 
 	url, err := rr.discoverEndpoint(context.Background(), "acme.com")
@@ -159,3 +161,54 @@ func (c *countingFedRepo) List(context.Context, RegistryStatus, int, int) ([]*Re
 func (c *countingFedRepo) UpdateStatus(context.Context, uuid.UUID, RegistryStatus) error { return nil }
 func (c *countingFedRepo) SetIntermediateCA(context.Context, uuid.UUID, string) error     { return nil }
 func (c *countingFedRepo) UpdateMaxPathLen(context.Context, uuid.UUID, int) error         { return nil }
+
+// TestDiscoverEndpoint_StaticRegistries verifies that a static table entry is
+// used before DNS when there is no federation service.
+func TestDiscoverEndpoint_StaticRegistries(t *testing.T) {
+	rr := NewRemoteResolver(nil, "https://root.example.com", true, 0, zap.NewNop())
+	rr.SetStaticRegistries(map[string]string{"acme.com": "https://static.acme.com"})
+	rr.dnsDiscoverFn = func(trustRoot string) (string, error) {
+		return "https://dns.example.com", nil
+	}
+
+	url, err := rr.DiscoverEndpoint(context.Background(), "acme.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://static.acme.com" {
+		t.Errorf("expected static URL, got %q", url)
+	}
+
+	url, err = rr.DiscoverEndpoint(context.Background(), "other.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://dns.example.com" {
+		t.Errorf("expected DNS URL for unlisted trust root, got %q", url)
+	}
+}
+
+// TestDiscoverEndpoint_NoRegistry verifies that ErrNoRegistry is returned only
+// when every discovery path answered, and transient failures are not.
+func TestDiscoverEndpoint_NoRegistry(t *testing.T) {
+	rr := NewRemoteResolver(newTestFedSvc(&stubFedRepo{}), "", true, 0, zap.NewNop())
+	rr.dnsDiscoverFn = func(trustRoot string) (string, error) { return "", nil }
+
+	if _, err := rr.DiscoverEndpoint(context.Background(), "unknown.com"); !errors.Is(err, ErrNoRegistry) {
+		t.Errorf("no record anywhere: err = %v, want ErrNoRegistry", err)
+	}
+
+	rr.dnsDiscoverFn = func(trustRoot string) (string, error) {
+		return "", &net.DNSError{Err: "i/o timeout", Name: "_nap-registry." + trustRoot, IsTimeout: true}
+	}
+	_, err := rr.DiscoverEndpoint(context.Background(), "unknown.com")
+	if err == nil || errors.Is(err, ErrNoRegistry) {
+		t.Errorf("DNS timeout: err = %v, want a transient error", err)
+	}
+
+	rr = NewRemoteResolver(newTestFedSvc(&countingFedRepo{}), "", false, 0, zap.NewNop())
+	_, err = rr.DiscoverEndpoint(context.Background(), "unknown.com")
+	if err == nil || errors.Is(err, ErrNoRegistry) {
+		t.Errorf("federation table error: err = %v, want a transient error", err)
+	}
+}
@@ -3,7 +3,6 @@ package health
 import (
 	"context"
 	"net/http"
-	"os"
 	"sync"
 	"time"
 
@@ -86,18 +85,18 @@ func (h *HealthChecker) SetMetricsRecord(fn MetricsRecordFunc) {
 	h.onMetrics = fn
 }
 
-// Start runs the health check loop until quit is signalled.
-func (h *HealthChecker) Start(quit <-chan os.Signal) {
+// Start runs the health check loop until ctx is done.
+func (h *HealthChecker) Start(ctx context.Context) {
 	ticker := time.NewTicker(h.cfg.CheckInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			ctx, cancel := context.WithTimeout(context.Background(), h.cfg.CheckInterval-time.Second)
+			ctx, cancel := context.WithTimeout(ctx, h.cfg.CheckInterval-time.Second)
 			h.CheckAll(ctx)
 			cancel()
-		case <-quit:
+		case <-ctx.Done():
 			return
 		}
 	}
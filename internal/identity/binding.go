@@ -0,0 +1,326 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Sender-constraint errors.
+var (
+	// ErrTokenBinding is returned when a bound token is presented without
+	// proof of the key it is bound to, or an unbound token is presented
+	// where binding is required.
+	ErrTokenBinding = errors.New("token is not presented with the key it is bound to")
+	// ErrInvalidDPoPProof is returned when a DPoP header is malformed,
+	// badly signed, stale, replayed or made for another request.
+	ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+)
+
+// DPoP proof freshness: a proof is accepted DPoPProofMaxAge after its "iat"
+// and dpopClockSkew before it.
+const (
+	DPoPProofMaxAge = 5 * time.Minute
+	dpopClockSkew   = time.Minute
+)
+
+// DefaultMaxDPoPProofs is how many accepted DPoP proofs a BindingVerifier
+// remembers at most. When it is full, the proofs closest to expiry are
+// forgotten to make room.
+const DefaultMaxDPoPProofs = 100_000
+
+// proofBucketWidth is the span of proof expiry times kept in one replay
+// bucket; a bucket is dropped whole once all of its proofs have expired.
+const proofBucketWidth = time.Minute
+
+// Confirmation is the RFC 7800 "cnf" claim of a sender-constrained Task
+// Token. Exactly one member is set: the SHA-256 thumbprint of the mTLS client
+// certificate the token was issued to (RFC 8705), or the JWK thumbprint of
+// the caller's DPoP key (RFC 9449).
+type Confirmation struct {
+	X5TS256 string `json:"x5t#S256,omitempty"`
+	JKT     string `json:"jkt,omitempty"`
+}
+
+// CertConfirmation binds a token to cert.
+func CertConfirmation(cert *x509.Certificate) *Confirmation {
+	return &Confirmation{X5TS256: CertThumbprint(cert)}
+}
+
+// CertThumbprint returns the RFC 8705 "x5t#S256" of cert: the unpadded
+// base64url SHA-256 of its DER encoding.
+func CertThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWKThumbprint returns the RFC 7638 SHA-256 thumbprint of pub, the "jkt" of
+// DPoP-bound tokens.
+func JWKThumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, err := PublicKeyToJWK(pub, "")
+	if err != nil {
+		return "", err
+	}
+	// Only the required members, in lexicographic order, without whitespace.
+	// encoding/json keeps struct field order.
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AccessTokenHash returns the DPoP "ath" of an access token: the unpadded
+// base64url SHA-256 of its ASCII form.
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// CheckCertBinding reports whether a certificate-bound token is presented
+// over mTLS with the certificate it was issued to. It is nil for tokens that
+// are not certificate-bound.
+func (c *TaskTokenClaims) CheckCertBinding(cert *x509.Certificate) error {
+	if c.Cnf == nil || c.Cnf.X5TS256 == "" {
+		return nil
+	}
+	if cert == nil {
+		return fmt.Errorf("%w: no client certificate", ErrTokenBinding)
+	}
+	if subtle.ConstantTimeCompare([]byte(CertThumbprint(cert)), []byte(c.Cnf.X5TS256)) != 1 {
+		return fmt.Errorf("%w: client certificate does not match", ErrTokenBinding)
+	}
+	return nil
+}
+
+// dpopClaims are the claims of a DPoP proof JWT (RFC 9449 §4.2).
+type dpopClaims struct {
+	jwt.RegisteredClaims
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+}
+
+// BindingVerifier checks that sender-constrained Task Tokens are presented
+// by their holder: over mTLS with the bound certificate, or with a DPoP proof
+// signed by the bound key. It remembers the proofs it has accepted so that
+// each is used once.
+type BindingVerifier struct {
+	publicURL string
+	maxProofs int
+
+	mu      sync.Mutex
+	buckets map[int64]map[string]struct{} // expiry bucket → proof keys
+	proofs  int                           // proofs across all buckets
+}
+
+// NewBindingVerifier creates a BindingVerifier. publicURL is the scheme and
+// host callers use to reach the service (e.g. the registry's issuer URL),
+// which DPoP proofs must name; when empty it is taken from each request.
+func NewBindingVerifier(publicURL string) *BindingVerifier {
+	return &BindingVerifier{
+		publicURL: strings.TrimRight(publicURL, "/"),
+		maxProofs: DefaultMaxDPoPProofs,
+		buckets:   make(map[int64]map[string]struct{}),
+	}
+}
+
+// SetMaxProofs sets how many accepted DPoP proofs are remembered for replay
+// detection (default DefaultMaxDPoPProofs). Once that many unexpired proofs
+// are remembered, the bucket closest to expiry is dropped for each new one,
+// so a flood of proofs shortens the replay window instead of locking out
+// every caller.
+func (b *BindingVerifier) SetMaxProofs(n int) {
+	b.maxProofs = n
+}
+
+// Verify checks the binding of claims, a verified token presented as
+// accessToken on r. Unbound tokens fail with ErrTokenBinding; callers that
+// still accept them check claims.Cnf first.
+func (b *BindingVerifier) Verify(claims *TaskTokenClaims, r *http.Request, accessToken string) error {
+	switch {
+	case claims.Cnf == nil:
+		return fmt.Errorf("%w: token is not sender-constrained", ErrTokenBinding)
+	case claims.Cnf.X5TS256 != "":
+		return claims.CheckCertBinding(peerCert(r))
+	case claims.Cnf.JKT != "":
+		jkt, err := b.VerifyDPoP(r, accessToken)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(jkt), []byte(claims.Cnf.JKT)) != 1 {
+			return fmt.Errorf("%w: DPoP key does not match", ErrTokenBinding)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: empty cnf claim", ErrTokenBinding)
+	}
+}
+
+// VerifyDPoP validates the DPoP header of r and returns the JWK thumbprint
+// of the key that signed it. When accessToken is non-empty the proof must
+// carry its hash ("ath"), as required on resource requests; the token
+// endpoint passes "".
+func (b *BindingVerifier) VerifyDPoP(r *http.Request, accessToken string) (string, error) {
+	values := r.Header.Values("DPoP")
+	if len(values) != 1 {
+		return "", fmt.Errorf("%w: exactly one DPoP header is required", ErrInvalidDPoPProof)
+	}
+
+	var jkt string
+	claims := &dpopClaims{}
+	_, err := jwt.ParseWithClaims(values[0], claims, func(t *jwt.Token) (any, error) {
+		if typ, _ := t.Header["typ"].(string); typ != "dpop+jwt" {
+			return nil, errors.New(`typ must be "dpop+jwt"`)
+		}
+		raw, err := json.Marshal(t.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		var jwk JWK
+		if err := json.Unmarshal(raw, &jwk); err != nil || jwk.Kty == "" {
+			return nil, errors.New("missing jwk header")
+		}
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if JWTAlgorithm(pub) != t.Method.Alg() {
+			return nil, fmt.Errorf("alg %s does not match the jwk", t.Method.Alg())
+		}
+		if jkt, err = JWKThumbprint(pub); err != nil {
+			return nil, err
+		}
+		return pub, nil
+	}, jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	now := time.Now()
+	switch {
+	case claims.ID == "":
+		return "", fmt.Errorf("%w: missing jti", ErrInvalidDPoPProof)
+	case claims.IssuedAt == nil || claims.IssuedAt.Before(now.Add(-DPoPProofMaxAge)) || claims.IssuedAt.After(now.Add(dpopClockSkew)):
+		return "", fmt.Errorf("%w: iat is missing or not recent", ErrInvalidDPoPProof)
+	case claims.HTM != r.Method:
+		return "", fmt.Errorf("%w: htm does not match the request method", ErrInvalidDPoPProof)
+	case !sameTargetURI(claims.HTU, b.requestURL(r)):
+		return "", fmt.Errorf("%w: htu does not match the request URL", ErrInvalidDPoPProof)
+	case accessToken != "" && subtle.ConstantTimeCompare([]byte(claims.ATH), []byte(AccessTokenHash(accessToken))) != 1:
+		return "", fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+	}
+	if err := b.remember(jkt+":"+claims.ID, claims.IssuedAt.Add(DPoPProofMaxAge+dpopClockSkew)); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+	return jkt, nil
+}
+
+// remember records a proof until forgetAt, failing if it was already
+// recorded. Proofs are kept in buckets by forgetAt, so a replay is found in
+// the one bucket its (signed) iat maps to and expired proofs are dropped a
+// bucket at a time. When the cache is full the oldest bucket is evicted.
+func (b *BindingVerifier) remember(key string, forgetAt time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for start, bucket := range b.buckets {
+		if !now.Before(time.Unix(start, 0).Add(proofBucketWidth)) {
+			b.proofs -= len(bucket)
+			delete(b.buckets, start)
+		}
+	}
+
+	start := forgetAt.Truncate(proofBucketWidth).Unix()
+	if _, ok := b.buckets[start][key]; ok {
+		return errors.New("proof replayed")
+	}
+	for b.proofs >= b.maxProofs && len(b.buckets) > 0 {
+		oldest := start
+		for s := range b.buckets {
+			oldest = min(oldest, s)
+		}
+		if _, ok := b.buckets[oldest]; !ok {
+			break // the new proof expires first; every other bucket is newer
+		}
+		b.proofs -= len(b.buckets[oldest])
+		delete(b.buckets, oldest)
+	}
+	bucket := b.buckets[start]
+	if bucket == nil {
+		bucket = make(map[string]struct{})
+		b.buckets[start] = bucket
+	}
+	bucket[key] = struct{}{}
+	b.proofs++
+	return nil
+}
+
+// requestURL returns the URL a DPoP proof for r must name: publicURL (or the
+// request's own scheme and host) and the request path, without query.
+func (b *BindingVerifier) requestURL(r *http.Request) string {
+	base := b.publicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + r.URL.Path
+}
+
+// sameTargetURI compares the htu of a proof with the request URL, ignoring
+// query, fragment and the case of scheme and host (RFC 9449 §4.3).
+func sameTargetURI(htu, want string) bool {
+	a, errA := url.Parse(htu)
+	b, errB := url.Parse(want)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host) && a.Path == b.Path
+}
+
+// peerCert returns the client certificate of an mTLS request, or nil.
+func peerCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
@@ -0,0 +1,173 @@
+package identity_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+)
+
+// dpopProof signs a DPoP proof for method and htu with key, covering
+// accessToken when it is non-empty.
+func dpopProof(t *testing.T, key ed25519.PrivateKey, method, htu, accessToken string, iat time.Time) string {
+	t.Helper()
+	jwk, err := identity.PublicKeyToJWK(key.Public(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"jti": uuid.New().String(), "htm": method, "htu": htu, "iat": iat.Unix()}
+	if accessToken != "" {
+		claims["ath"] = identity.AccessTokenHash(accessToken)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	tok.Header["typ"] = "dpop+jwt"
+	tok.Header["jwk"] = map[string]string{"kty": jwk.Kty, "crv": jwk.Crv, "x": jwk.X}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWKThumbprint_RFC7638Example(t *testing.T) {
+	n, _ := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	got, err := identity.JWKThumbprint(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("JWKThumbprint = %q, want %q", got, want)
+	}
+}
+
+func TestRequireToken_certificateBinding(t *testing.T) {
+	ca := newTestCA(t)
+	issuer := identity.NewIssuer(ca)
+	tokens := identity.NewTokenIssuer(ca.Key(), testRegistry, time.Hour)
+	holder, _ := issuer.IssueAgentCert("agent://nexusagentprotocol.com/edge/agent_a", "owner", time.Hour, "")
+	other, _ := issuer.IssueAgentCert("agent://nexusagentprotocol.com/edge/agent_b", "owner", time.Hour, "")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/bound", identity.RequireBoundToken(tokens, identity.NewBindingVerifier("")), ok)
+	r.GET("/any", identity.RequireToken(tokens, identity.NewBindingVerifier("")), ok)
+
+	bound, _ := tokens.IssueBound("agent://nexusagentprotocol.com/edge/agent_a", nil, testRegistry, identity.CertConfirmation(holder.Cert))
+	bearer, _ := tokens.IssueForAudience("agent://nexusagentprotocol.com/edge/agent_a", nil, testRegistry)
+	for _, tc := range []struct {
+		name      string
+		token     string
+		cert      *x509.Certificate
+		wantBound int // RequireBoundToken
+		wantAny   int // RequireToken
+	}{
+		{"holder", bound, holder.Cert, http.StatusOK, http.StatusOK},
+		{"other certificate", bound, other.Cert, http.StatusUnauthorized, http.StatusUnauthorized},
+		{"no certificate", bound, nil, http.StatusUnauthorized, http.StatusUnauthorized},
+		{"unbound token", bearer, holder.Cert, http.StatusUnauthorized, http.StatusOK},
+	} {
+		for path, want := range map[string]int{"/bound": tc.wantBound, "/any": tc.wantAny} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			if tc.cert != nil {
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tc.cert}}
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%s %s: got %d, want %d", path, tc.name, w.Code, want)
+			}
+		}
+	}
+}
+
+func TestBindingVerifier_DPoP(t *testing.T) {
+	ca := newTestCA(t)
+	tokens := identity.NewTokenIssuer(ca.Key(), testRegistry, time.Hour)
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	jkt, err := identity.JWKThumbprint(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, _ := tokens.IssueBound("agent://nexusagentprotocol.com/edge/agent_a", nil, testRegistry, &identity.Confirmation{JKT: jkt})
+	claims, err := tokens.Verify(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binding := identity.NewBindingVerifier("https://registry.example")
+
+	request := func(proof string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "http://internal:8080/api/v1/agents?x=1", nil)
+		req.Header.Set("Authorization", "DPoP "+tok)
+		req.Header.Set("DPoP", proof)
+		return req
+	}
+	now := time.Now()
+	good := dpopProof(t, key, http.MethodPost, "https://registry.example/api/v1/agents", tok, now)
+	if err := binding.Verify(claims, request(good), tok); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if err := binding.Verify(claims, request(good), tok); !errors.Is(err, identity.ErrInvalidDPoPProof) {
+		t.Errorf("replayed proof: err = %v, want ErrInvalidDPoPProof", err)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	for name, proof := range map[string]string{
+		"other method": dpopProof(t, key, http.MethodGet, "https://registry.example/api/v1/agents", tok, now),
+		"other URL":    dpopProof(t, key, http.MethodPost, "https://evil.example/api/v1/agents", tok, now),
+		"other token":  dpopProof(t, key, http.MethodPost, "https://registry.example/api/v1/agents", "stolen", now),
+		"stale":        dpopProof(t, key, http.MethodPost, "https://registry.example/api/v1/agents", tok, now.Add(-time.Hour)),
+		"other key":    dpopProof(t, otherKey, http.MethodPost, "https://registry.example/api/v1/agents", tok, now),
+	} {
+		if err := binding.Verify(claims, request(proof), tok); !errors.Is(err, identity.ErrInvalidDPoPProof) && !errors.Is(err, identity.ErrTokenBinding) {
+			t.Errorf("%s: err = %v, want a binding error", name, err)
+		}
+	}
+}
+
+func TestBindingVerifier_maxProofs(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	binding := identity.NewBindingVerifier("https://registry.example")
+	binding.SetMaxProofs(2)
+
+	now := time.Now()
+	verify := func(proof string) error {
+		req := httptest.NewRequest(http.MethodPost, "https://registry.example/api/v1/token", nil)
+		req.Header.Set("DPoP", proof)
+		_, err := binding.VerifyDPoP(req, "")
+		return err
+	}
+	var proofs []string
+	for i := range 3 {
+		// Proofs made over several minutes land in different buckets, oldest
+		// first. A full cache makes room rather than rejecting anyone.
+		proof := dpopProof(t, key, http.MethodPost, "https://registry.example/api/v1/token", "", now.Add(time.Duration(i-2)*time.Minute))
+		if err := verify(proof); err != nil {
+			t.Errorf("proof %d: %v", i, err)
+		}
+		proofs = append(proofs, proof)
+	}
+
+	if err := verify(proofs[2]); !errors.Is(err, identity.ErrInvalidDPoPProof) {
+		t.Errorf("replay of the newest proof: err = %v, want ErrInvalidDPoPProof", err)
+	}
+	// The oldest bucket was evicted to make room, so its proof is forgotten.
+	if err := verify(proofs[0]); err != nil {
+		t.Errorf("replay of an evicted proof: %v", err)
+	}
+}
@@ -2,11 +2,13 @@ package identity
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"io"
@@ -14,27 +16,53 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
-const (
-	caCertFile = "ca.crt"
-	caKeyFile  = "ca.key"
-	caKeyBits  = 4096
-)
+const caKeyBits = 4096
 
 // CAManager manages the Nexus Certificate Authority lifecycle.
-// It creates and persists a root CA to disk on first run, then reloads it on
+// It creates and persists a root CA on first run, then reloads it on
 // subsequent starts. All agent and server certificates are signed by this CA.
+// The certificate lives in dir; the private key lives in a KeyStore under
+// KeyNameCA, by default an unencrypted file store in the same dir ("ca.key").
+//
+// Rotate adds a CA generation ("ca-2.crt" and key "ca-2", and so on),
+// recorded in the key manifest. The active generation signs; the others stay
+// trusted until they retire, so certificates they issued keep verifying.
 type CAManager struct {
 	dir  string
-	cert *x509.Certificate
-	key  *rsa.PrivateKey
+	alg  KeyAlgorithm
+	keys KeyStore
+
+	mu          sync.RWMutex
+	versions    []KeyVersion   // every generation, retired ones included
+	generations []caGeneration // published generations, oldest first
+}
+
+// caGeneration is one CA certificate and its key.
+type caGeneration struct {
+	version KeyVersion
+	cert    *x509.Certificate
+	key     crypto.Signer
 }
 
 // NewCAManager returns a CAManager that stores the CA files in dir.
 func NewCAManager(dir string) *CAManager {
-	return &CAManager{dir: dir}
+	return &CAManager{dir: dir, alg: KeyAlgorithmRSA, keys: NewFileKeyStore(dir, "")}
+}
+
+// SetKeyStore sets where the CA private key is kept, e.g. a passphrase-
+// encrypted FileKeyStore or a KMSKeyStore.
+func (m *CAManager) SetKeyStore(ks KeyStore) {
+	m.keys = ks
+}
+
+// SetKeyAlgorithm sets the key algorithm used when Create generates a new CA.
+// An existing CA is loaded with whatever key type it was created with.
+func (m *CAManager) SetKeyAlgorithm(alg KeyAlgorithm) {
+	m.alg = alg
 }
 
 // LoadOrCreate loads the CA from disk if it exists; creates a new one otherwise.
@@ -45,39 +73,111 @@ func (m *CAManager) LoadOrCreate() error {
 	return m.Create()
 }
 
-// Load reads an existing CA cert and key from the configured directory.
+// Load reads the CA certificates from the configured directory and their
+// keys from the key store: every generation in the key manifest that has not
+// retired, or just ca.crt before the first rotation. Calling it again picks
+// up a rotation made by another registry process.
 func (m *CAManager) Load() error {
-	certPEM, err := os.ReadFile(filepath.Join(m.dir, caCertFile))
+	manifest, err := readKeyManifest(m.dir)
 	if err != nil {
-		return fmt.Errorf("read CA cert: %w", err)
+		return err
 	}
-	keyPEM, err := os.ReadFile(filepath.Join(m.dir, caKeyFile))
+	versions := manifest.CA
+	if len(versions) == 0 {
+		versions = []KeyVersion{{Name: KeyNameCA}}
+	}
+
+	m.mu.RLock()
+	loaded := make(map[string]caGeneration, len(m.generations))
+	for _, g := range m.generations {
+		loaded[g.version.Name] = g
+	}
+	m.mu.RUnlock()
+
+	now := time.Now()
+	var gens []caGeneration
+	for i, v := range versions {
+		if !v.Published(now) {
+			continue
+		}
+		g, ok := loaded[v.Name]
+		if ok {
+			g.version = describeCA(v, g.cert, g.key)
+		} else if g, err = m.loadGeneration(v); err != nil {
+			return err
+		}
+		versions[i] = g.version
+		gens = append(gens, g)
+	}
+	if len(gens) == 0 {
+		return fmt.Errorf("no unretired CA in %s", keyManifestFile)
+	}
+
+	m.mu.Lock()
+	m.versions, m.generations = versions, gens
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *CAManager) loadGeneration(v KeyVersion) (caGeneration, error) {
+	certFile := v.Name + ".crt"
+	certPEM, err := os.ReadFile(filepath.Join(m.dir, certFile))
+	if err != nil {
+		return caGeneration{}, fmt.Errorf("read CA cert: %w", err)
+	}
+	cert, err := decodeCert(certPEM)
 	if err != nil {
-		return fmt.Errorf("read CA key: %w", err)
+		return caGeneration{}, err
+	}
+	key, err := m.keys.Signer(context.Background(), v.Name)
+	if err != nil {
+		return caGeneration{}, fmt.Errorf("load CA key: %w", err)
+	}
+	if !PublicKeysEqual(cert.PublicKey, key.Public()) {
+		return caGeneration{}, fmt.Errorf("CA key does not match %s", certFile)
+	}
+	return caGeneration{version: describeCA(v, cert, key), cert: cert, key: key}, nil
+}
+
+// describeCA fills in the version of a CA that predates the key manifest.
+func describeCA(v KeyVersion, cert *x509.Certificate, key crypto.Signer) KeyVersion {
+	if v.KID == "" {
+		v.KID = caKeyID(cert)
+		v.Algorithm, _ = KeyAlgorithmOf(key.Public())
+		v.CreatedAt = cert.NotBefore
 	}
-	cert, key, err := decodeCertAndKey(certPEM, keyPEM)
+	return v
+}
+
+// Create generates a new CA — 4096-bit RSA unless SetKeyAlgorithm chose
+// otherwise — saves it, and activates it. The key is generated by the key
+// store, so a KMS-backed CA key is never exported.
+func (m *CAManager) Create() error {
+	g, err := m.createGeneration(KeyNameCA, m.alg)
 	if err != nil {
 		return err
 	}
-	m.cert = cert
-	m.key = key
+	m.mu.Lock()
+	m.versions, m.generations = []KeyVersion{g.version}, []caGeneration{g}
+	m.mu.Unlock()
 	return nil
 }
 
-// Create generates a new 4096-bit RSA CA, saves it to disk, and activates it.
-func (m *CAManager) Create() error {
+// createGeneration generates the named CA key and a self-signed certificate
+// for it, written to "<name>.crt".
+func (m *CAManager) createGeneration(name string, alg KeyAlgorithm) (caGeneration, error) {
 	if err := os.MkdirAll(m.dir, 0o700); err != nil {
-		return fmt.Errorf("create cert dir %q: %w", m.dir, err)
+		return caGeneration{}, fmt.Errorf("create cert dir %q: %w", m.dir, err)
 	}
 
-	key, err := rsa.GenerateKey(rand.Reader, caKeyBits)
+	key, err := m.keys.Generate(context.Background(), name, alg)
 	if err != nil {
-		return fmt.Errorf("generate CA key: %w", err)
+		return caGeneration{}, fmt.Errorf("generate CA key: %w", err)
 	}
 
 	serial, err := randomSerial()
 	if err != nil {
-		return err
+		return caGeneration{}, err
 	}
 
 	template := &x509.Certificate{
@@ -95,45 +195,132 @@ func (m *CAManager) Create() error {
 		MaxPathLen:            0,
 	}
 
-	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
 	if err != nil {
-		return fmt.Errorf("create CA certificate: %w", err)
+		return caGeneration{}, fmt.Errorf("create CA certificate: %w", err)
 	}
 	cert, err := x509.ParseCertificate(certDER)
 	if err != nil {
-		return fmt.Errorf("parse CA certificate: %w", err)
+		return caGeneration{}, fmt.Errorf("parse CA certificate: %w", err)
 	}
 
 	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
-	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(filepath.Join(m.dir, name+".crt"), certPEM, 0o644); err != nil {
+		return caGeneration{}, fmt.Errorf("write CA cert: %w", err)
+	}
 
-	if err := os.WriteFile(filepath.Join(m.dir, caCertFile), certPEM, 0o644); err != nil {
-		return fmt.Errorf("write CA cert: %w", err)
+	keyAlg, _ := KeyAlgorithmOf(key.Public())
+	return caGeneration{
+		version: KeyVersion{Name: name, KID: caKeyID(cert), Algorithm: keyAlg, CreatedAt: cert.NotBefore},
+		cert:    cert,
+		key:     key,
+	}, nil
+}
+
+// Rotate creates a new CA generation and records it in the key manifest. The
+// new CA is trusted at once and signs from now+ActivateAfter; the CAs it
+// replaces stay trusted until GracePeriod after that, which should cover the
+// certificates they issued.
+func (m *CAManager) Rotate(opts RotateOptions) (KeyVersion, error) {
+	if err := m.Load(); err != nil {
+		return KeyVersion{}, err
 	}
-	if err := os.WriteFile(filepath.Join(m.dir, caKeyFile), keyPEM, 0o600); err != nil {
-		return fmt.Errorf("write CA key: %w", err)
+	manifest, err := readKeyManifest(m.dir)
+	if err != nil {
+		return KeyVersion{}, err
+	}
+	versions := manifest.CA
+	if len(versions) == 0 {
+		// First rotation: record the original CA as generation 1.
+		versions = []KeyVersion{m.Versions()[0]}
 	}
 
-	m.cert = cert
-	m.key = key
-	return nil
+	now := time.Now().UTC()
+	alg := opts.Algorithm
+	if alg == "" {
+		alg = versions[activeIndex(versions, now)].Algorithm
+	}
+	g, err := m.createGeneration(fmt.Sprintf("%s-%d", KeyNameCA, len(versions)+1), alg)
+	if err != nil {
+		return KeyVersion{}, err
+	}
+	g.version.CreatedAt = now
+	g.version.ActivateAt = now.Add(opts.ActivateAfter)
+
+	manifest.CA = addVersion(versions, g.version, opts.GracePeriod)
+	if err := writeKeyManifest(m.dir, manifest); err != nil {
+		return KeyVersion{}, err
+	}
+	if err := m.Load(); err != nil {
+		return KeyVersion{}, err
+	}
+	return g.version, nil
 }
 
-// Cert returns the loaded CA certificate.
-func (m *CAManager) Cert() *x509.Certificate { return m.cert }
+// Versions returns every CA generation, retired ones included, oldest first.
+func (m *CAManager) Versions() []KeyVersion {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]KeyVersion(nil), m.versions...)
+}
 
-// Key returns the loaded CA private key.
-func (m *CAManager) Key() *rsa.PrivateKey { return m.key }
+// active returns the generation that signs now.
+func (m *CAManager) active() caGeneration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.generations) == 0 {
+		return caGeneration{}
+	}
+	versions := make([]KeyVersion, len(m.generations))
+	for i, g := range m.generations {
+		versions[i] = g.version
+	}
+	return m.generations[activeIndex(versions, time.Now())]
+}
 
-// CertPEM returns the CA certificate encoded as PEM.
+// published returns the published generations, the active one first.
+func (m *CAManager) published() []caGeneration {
+	active := m.active()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := []caGeneration{active}
+	for i := len(m.generations) - 1; i >= 0; i-- {
+		if m.generations[i].version.Name != active.version.Name {
+			out = append(out, m.generations[i])
+		}
+	}
+	return out
+}
+
+// Cert returns the active CA certificate.
+func (m *CAManager) Cert() *x509.Certificate { return m.active().cert }
+
+// Key returns the active CA signing key. It may be an opaque signer whose
+// private half stays in the key store.
+func (m *CAManager) Key() crypto.Signer { return m.active().key }
+
+// CertPEM returns the active CA certificate encoded as PEM.
 func (m *CAManager) CertPEM() []byte {
-	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: m.cert.Raw})
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: m.Cert().Raw})
+}
+
+// BundlePEM returns every trusted CA certificate as PEM, the active one
+// first. Clients that trust the bundle keep accepting certificates from a CA
+// that is being rotated out.
+func (m *CAManager) BundlePEM() []byte {
+	var out []byte
+	for _, g := range m.published() {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: g.cert.Raw})...)
+	}
+	return out
 }
 
-// CertPool returns an x509.CertPool containing only this CA certificate.
+// CertPool returns an x509.CertPool of the trusted CA certificates.
 func (m *CAManager) CertPool() *x509.CertPool {
 	pool := x509.NewCertPool()
-	pool.AddCert(m.cert)
+	for _, g := range m.published() {
+		pool.AddCert(g.cert)
+	}
 	return pool
 }
 
@@ -149,32 +336,46 @@ func (m *CAManager) TLSConfig(serverCert tls.Certificate) *tls.Config {
 	}
 }
 
-// decodeCertAndKey parses PEM-encoded certificate and RSA private key bytes.
-func decodeCertAndKey(certPEM, keyPEM []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+// decodeCert parses a PEM-encoded certificate.
+func decodeCert(certPEM []byte) (*x509.Certificate, error) {
 	block, _ := pem.Decode(certPEM)
 	if block == nil {
-		return nil, nil, fmt.Errorf("failed to decode certificate PEM")
+		return nil, fmt.Errorf("failed to decode certificate PEM")
 	}
 	cert, err := x509.ParseCertificate(block.Bytes)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parse certificate: %w", err)
+		return nil, fmt.Errorf("parse certificate: %w", err)
 	}
+	return cert, nil
+}
 
-	block, _ = pem.Decode(keyPEM)
-	if block == nil {
-		return nil, nil, fmt.Errorf("failed to decode private key PEM")
+// decodeCertAndKey parses a PEM-encoded certificate and private key, which may
+// be encrypted with passphrase, and checks that they belong together.
+func decodeCertAndKey(certPEM, keyPEM, passphrase []byte) (*x509.Certificate, crypto.Signer, error) {
+	cert, err := decodeCert(certPEM)
+	if err != nil {
+		return nil, nil, err
 	}
-	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	key, err := ParsePrivateKeyPEMWithPassphrase(keyPEM, passphrase)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parse private key: %w", err)
+		return nil, nil, err
+	}
+	if !PublicKeysEqual(cert.PublicKey, key.Public()) {
+		return nil, nil, fmt.Errorf("private key does not match certificate")
 	}
 	return cert, key, nil
 }
 
-// LoadCertAndKey parses PEM-encoded cert + RSA key bytes.
+// LoadCertAndKey parses a PEM-encoded cert and its RSA, ECDSA or Ed25519 key.
 // Exposes the internal decodeCertAndKey for use by main.go federated startup.
-func LoadCertAndKey(certPEM, keyPEM []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
-	return decodeCertAndKey(certPEM, keyPEM)
+func LoadCertAndKey(certPEM, keyPEM []byte) (*x509.Certificate, crypto.Signer, error) {
+	return decodeCertAndKey(certPEM, keyPEM, nil)
+}
+
+// LoadCertAndEncryptedKey is LoadCertAndKey for a key that may be a
+// passphrase-encrypted PKCS#8 "ENCRYPTED PRIVATE KEY".
+func LoadCertAndEncryptedKey(certPEM, keyPEM []byte, passphrase string) (*x509.Certificate, crypto.Signer, error) {
+	return decodeCertAndKey(certPEM, keyPEM, []byte(passphrase))
 }
 
 // FetchRootCAPool downloads a CA cert PEM from a URL and returns a CertPool.
@@ -207,6 +408,15 @@ func FetchRootCAPool(ctx context.Context, caURL string, timeout time.Duration) (
 	return pool, nil
 }
 
+// caKeyID identifies a CA certificate by its subject key ID, hex-encoded.
+func caKeyID(cert *x509.Certificate) string {
+	if len(cert.SubjectKeyId) > 0 {
+		return hex.EncodeToString(cert.SubjectKeyId)
+	}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:20])
+}
+
 // randomSerial generates a cryptographically random 128-bit certificate serial.
 func randomSerial() (*big.Int, error) {
 	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
@@ -74,3 +74,29 @@ func TestCAManager_CertPEM(t *testing.T) {
 		t.Errorf("CertPEM() does not start with PEM header: %q", string(pem[:27]))
 	}
 }
+
+func TestCAManager_keyAlgorithms(t *testing.T) {
+	for _, alg := range []identity.KeyAlgorithm{identity.KeyAlgorithmECDSAP256, identity.KeyAlgorithmEd25519} {
+		t.Run(string(alg), func(t *testing.T) {
+			dir := t.TempDir()
+			ca := identity.NewCAManager(dir)
+			ca.SetKeyAlgorithm(alg)
+			if err := ca.Create(); err != nil {
+				t.Fatalf("Create() error: %v", err)
+			}
+
+			// Reload from disk; the algorithm comes from the key file.
+			loaded := identity.NewCAManager(dir)
+			if err := loaded.Load(); err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			got, err := identity.KeyAlgorithmOf(loaded.Key().Public())
+			if err != nil || got != alg {
+				t.Errorf("loaded key algorithm = %q (%v), want %q", got, err, alg)
+			}
+			if _, err := loaded.Cert().Verify(x509.VerifyOptions{Roots: loaded.CertPool()}); err != nil {
+				t.Errorf("CA cert does not verify against itself: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrTokenRevoked is returned when a token, or every token of its subject,
+// has been revoked before it expired.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevokedToken is a single revoked token, listed until it would have expired.
+type RevokedToken struct {
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// RevokedSubject revokes every token issued to a subject (an agent URI or a
+// user ID) at or before Before. A zero Before revokes all of its tokens,
+// including ones issued later, until the subject is lifted. A zero ExpiresAt
+// keeps the entry forever.
+type RevokedSubject struct {
+	Subject   string
+	Before    time.Time
+	ExpiresAt time.Time
+}
+
+// DenylistStore persists a Denylist so that revocations survive restarts and
+// reach every registry replica.
+type DenylistStore interface {
+	// AddToken records a revoked token. Adding a jti twice is a no-op.
+	AddToken(ctx context.Context, t RevokedToken) error
+	// PutSubject records a subject entry, replacing any existing one.
+	PutSubject(ctx context.Context, s RevokedSubject) error
+	// List returns the entries that have not expired at now.
+	List(ctx context.Context, now time.Time) ([]RevokedToken, []RevokedSubject, error)
+	// DeleteExpired removes entries that expired before now.
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// Denylist is the registry's record of tokens revoked before they expire:
+// single tokens by jti (logout, RFC 7009 revocation) and every token of a
+// subject (suspension, agent revocation, password reset). Token and user
+// token issuers with a Denylist reject listed tokens in Verify.
+//
+// Checks are served from memory. Revocations made through this Denylist apply
+// at once; those made by other replicas sharing the store apply after the
+// next Refresh.
+type Denylist struct {
+	store  DenylistStore // nil = in-memory only
+	maxTTL time.Duration
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti → token expiry
+	subjects map[string]RevokedSubject
+	// writes counts local subject writes; written records the count at each
+	// subject's last one, so Refresh keeps entries newer than its snapshot.
+	writes  uint64
+	written map[string]uint64
+}
+
+// NewDenylist creates a Denylist backed by store, which may be nil. maxTTL is
+// the longest lifetime of any token the registry issues: a subject's cut-off
+// is kept that long, after which every token it covers has expired anyway.
+func NewDenylist(store DenylistStore, maxTTL time.Duration) *Denylist {
+	return &Denylist{
+		store:    store,
+		maxTTL:   maxTTL,
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]RevokedSubject),
+		written:  make(map[string]uint64),
+	}
+}
+
+// RevokeToken revokes the token with the given jti until it expires.
+func (d *Denylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return fmt.Errorf("revoke token: token has no jti")
+	}
+	if d.store != nil {
+		if err := d.store.AddToken(ctx, RevokedToken{JTI: jti, ExpiresAt: expiresAt}); err != nil {
+			return fmt.Errorf("revoke token: %w", err)
+		}
+	}
+	d.mu.Lock()
+	d.tokens[jti] = expiresAt
+	d.mu.Unlock()
+	return nil
+}
+
+// RevokeSubject revokes every token issued to subject so far. Tokens issued
+// afterwards are valid. A subject blocked with BlockSubject stays blocked.
+func (d *Denylist) RevokeSubject(ctx context.Context, subject string) error {
+	d.mu.RLock()
+	cur, ok := d.subjects[subject]
+	d.mu.RUnlock()
+	if ok && cur.Before.IsZero() {
+		return nil
+	}
+	now := time.Now().UTC()
+	return d.putSubject(ctx, RevokedSubject{Subject: subject, Before: now, ExpiresAt: now.Add(d.maxTTL)})
+}
+
+// BlockSubject revokes every token of subject, including tokens issued from
+// now on, until UnblockSubject is called. Use it for suspended and revoked
+// agents, which still hold a certificate the token endpoint accepts.
+func (d *Denylist) BlockSubject(ctx context.Context, subject string) error {
+	return d.putSubject(ctx, RevokedSubject{Subject: subject})
+}
+
+// UnblockSubject lifts BlockSubject. Tokens issued while the subject was
+// blocked stay revoked.
+func (d *Denylist) UnblockSubject(ctx context.Context, subject string) error {
+	now := time.Now().UTC()
+	return d.putSubject(ctx, RevokedSubject{Subject: subject, Before: now, ExpiresAt: now.Add(d.maxTTL)})
+}
+
+func (d *Denylist) putSubject(ctx context.Context, s RevokedSubject) error {
+	if d.store != nil {
+		if err := d.store.PutSubject(ctx, s); err != nil {
+			return fmt.Errorf("revoke tokens of %s: %w", s.Subject, err)
+		}
+	}
+	d.mu.Lock()
+	d.subjects[s.Subject] = s
+	if d.store != nil {
+		d.writes++
+		d.written[s.Subject] = d.writes
+	}
+	d.mu.Unlock()
+	return nil
+}
+
+// Check returns ErrTokenRevoked if the token identified by claims has been
+// revoked, directly or through any of subjects.
+func (d *Denylist) Check(claims *jwt.RegisteredClaims, subjects ...string) error {
+	now := time.Now()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if exp, ok := d.tokens[claims.ID]; ok && claims.ID != "" && now.Before(exp) {
+		return ErrTokenRevoked
+	}
+	for _, sub := range subjects {
+		s, ok := d.subjects[sub]
+		if !ok || (!s.ExpiresAt.IsZero() && now.After(s.ExpiresAt)) {
+			continue
+		}
+		// NumericDate truncates iat to the second, so a token issued in the
+		// same second as the cut-off counts as issued before it.
+		if s.Before.IsZero() || claims.IssuedAt == nil || !claims.IssuedAt.After(s.Before) {
+			return ErrTokenRevoked
+		}
+	}
+	return nil
+}
+
+// Refresh merges the entries in the store into the Denylist, picking up
+// revocations made by other replicas, and prunes expired entries from both.
+// Revocations made through this Denylist while Refresh runs are kept.
+func (d *Denylist) Refresh(ctx context.Context) error {
+	now := time.Now().UTC()
+	if d.store == nil {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.pruneLocked(now)
+		return nil
+	}
+
+	if err := d.store.DeleteExpired(ctx, now); err != nil {
+		return fmt.Errorf("prune denylist: %w", err)
+	}
+	d.mu.RLock()
+	listedAt := d.writes
+	d.mu.RUnlock()
+	revokedTokens, revokedSubjects, err := d.store.List(ctx, now)
+	if err != nil {
+		return fmt.Errorf("load denylist: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, t := range revokedTokens {
+		d.tokens[t.JTI] = t.ExpiresAt
+	}
+	for _, s := range revokedSubjects {
+		// A subject written here after List started may be newer than the
+		// store's answer.
+		if d.written[s.Subject] > listedAt {
+			continue
+		}
+		d.subjects[s.Subject] = s
+	}
+	for sub, n := range d.written {
+		if n <= listedAt {
+			delete(d.written, sub)
+		}
+	}
+	d.pruneLocked(now)
+	return nil
+}
+
+// pruneLocked drops entries that expired before now. Called with d.mu held.
+func (d *Denylist) pruneLocked(now time.Time) {
+	for jti, exp := range d.tokens {
+		if now.After(exp) {
+			delete(d.tokens, jti)
+		}
+	}
+	for sub, s := range d.subjects {
+		if !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt) {
+			delete(d.subjects, sub)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package identity_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+)
+
+// memDenylistStore is an in-memory identity.DenylistStore shared by replicas.
+type memDenylistStore struct {
+	mu       sync.Mutex
+	tokens   map[string]identity.RevokedToken
+	subjects map[string]identity.RevokedSubject
+}
+
+func newMemDenylistStore() *memDenylistStore {
+	return &memDenylistStore{tokens: map[string]identity.RevokedToken{}, subjects: map[string]identity.RevokedSubject{}}
+}
+
+func (m *memDenylistStore) AddToken(_ context.Context, t identity.RevokedToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[t.JTI] = t
+	return nil
+}
+
+func (m *memDenylistStore) PutSubject(_ context.Context, s identity.RevokedSubject) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subjects[s.Subject] = s
+	return nil
+}
+
+func (m *memDenylistStore) List(_ context.Context, now time.Time) ([]identity.RevokedToken, []identity.RevokedSubject, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var tokens []identity.RevokedToken
+	for _, t := range m.tokens {
+		if t.ExpiresAt.After(now) {
+			tokens = append(tokens, t)
+		}
+	}
+	var subjects []identity.RevokedSubject
+	for _, s := range m.subjects {
+		if s.ExpiresAt.IsZero() || s.ExpiresAt.After(now) {
+			subjects = append(subjects, s)
+		}
+	}
+	return tokens, subjects, nil
+}
+
+func (m *memDenylistStore) DeleteExpired(_ context.Context, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for jti, t := range m.tokens {
+		if !t.ExpiresAt.After(now) {
+			delete(m.tokens, jti)
+		}
+	}
+	return nil
+}
+
+func TestTokenIssuer_denylist(t *testing.T) {
+	ti := newTestTokenIssuer(t)
+	denylist := identity.NewDenylist(nil, time.Hour)
+	ti.SetDenylist(denylist)
+	ctx := context.Background()
+	caller := "agent://acme.com/assistant/agent_u"
+	agentA := "agent://acme.com/finance/agent_a"
+	agentB := "agent://acme.com/finance/agent_b"
+
+	// A single token, by jti.
+	tok, _ := ti.IssueForAudience(caller, []string{"agent:call"}, agentA)
+	other, _ := ti.IssueForAudience(caller, []string{"agent:call"}, agentA)
+	claims, err := ti.Verify(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := denylist.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.Verify(tok); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("revoked token: err = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := ti.Verify(other); err != nil {
+		t.Errorf("other token of the same agent: %v", err)
+	}
+
+	// Blocking an actor revokes the delegated tokens it acts in.
+	subject, _ := ti.Verify(other)
+	delegated, _, err := ti.Exchange(subject, agentA, agentB, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := denylist.BlockSubject(ctx, agentA); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.Verify(delegated); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("delegated token of a blocked actor: err = %v", err)
+	}
+	if _, err := ti.Verify(other); err != nil {
+		t.Errorf("the caller's own token: %v", err)
+	}
+	fresh, _ := ti.IssueForAudience(agentA, nil, agentB)
+	if _, err := ti.Verify(fresh); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token issued while blocked: err = %v", err)
+	}
+
+	// Unblocking keeps tokens issued so far revoked; later ones are valid.
+	if err := denylist.UnblockSubject(ctx, agentA); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.Verify(fresh); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token issued while blocked, after unblocking: err = %v", err)
+	}
+	later := jwt.RegisteredClaims{ID: "later", IssuedAt: jwt.NewNumericDate(time.Now().Add(2 * time.Second))}
+	if err := denylist.Check(&later, agentA); err != nil {
+		t.Errorf("token issued after unblocking: %v", err)
+	}
+}
+
+func TestDenylist_revokeSubjectAndRefresh(t *testing.T) {
+	store := newMemDenylistStore()
+	replicaA := identity.NewDenylist(store, time.Hour)
+	replicaB := identity.NewDenylist(store, time.Hour)
+	ctx := context.Background()
+	now := time.Now()
+
+	user := "5f0c2a4e-user"
+	before := jwt.RegisteredClaims{ID: "a", Subject: user, IssuedAt: jwt.NewNumericDate(now.Add(-time.Minute))}
+	after := jwt.RegisteredClaims{ID: "b", Subject: user, IssuedAt: jwt.NewNumericDate(now.Add(2 * time.Second))}
+
+	if err := replicaA.RevokeSubject(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaA.RevokeToken(ctx, "b", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaB.Check(&before, user); err != nil {
+		t.Fatalf("replica B before Refresh: %v", err)
+	}
+	if err := replicaB.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaB.Check(&before, user); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token issued before the cut-off: err = %v", err)
+	}
+	if err := replicaB.Check(&after, user); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token revoked by jti: err = %v", err)
+	}
+	after.ID = "c"
+	if err := replicaB.Check(&after, user); err != nil {
+		t.Errorf("token issued after the cut-off: %v", err)
+	}
+
+	// Entries go once the tokens they cover have expired.
+	if err := replicaA.RevokeToken(ctx, "expired", now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := replicaA.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.tokens["expired"]; ok {
+		t.Error("expired entry was not pruned from the store")
+	}
+}
+
+// listHookStore runs beforeReturn after taking the List snapshot, to
+// simulate revocations made while a Refresh is in flight.
+type listHookStore struct {
+	*memDenylistStore
+	beforeReturn func()
+}
+
+func (s *listHookStore) List(ctx context.Context, now time.Time) ([]identity.RevokedToken, []identity.RevokedSubject, error) {
+	tokens, subjects, err := s.memDenylistStore.List(ctx, now)
+	if s.beforeReturn != nil {
+		s.beforeReturn()
+		s.beforeReturn = nil
+	}
+	return tokens, subjects, err
+}
+
+func TestDenylist_refreshKeepsConcurrentRevocations(t *testing.T) {
+	store := &listHookStore{memDenylistStore: newMemDenylistStore()}
+	d := identity.NewDenylist(store, time.Hour)
+	ctx := context.Background()
+	agent := "agent://acme.com/finance/agent_a"
+	if err := d.BlockSubject(ctx, agent); err != nil {
+		t.Fatal(err)
+	}
+
+	store.beforeReturn = func() {
+		if err := d.RevokeToken(ctx, "late", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.UnblockSubject(ctx, agent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Check(&jwt.RegisteredClaims{ID: "late"}); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token revoked during Refresh: err = %v, want ErrTokenRevoked", err)
+	}
+	later := jwt.RegisteredClaims{ID: "x", IssuedAt: jwt.NewNumericDate(time.Now().Add(2 * time.Second))}
+	if err := d.Check(&later, agent); err != nil {
+		t.Errorf("subject unblocked during Refresh is blocked again: %v", err)
+	}
+
+	// The next Refresh sees the store's current entries.
+	if err := d.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Check(&later, agent); err != nil {
+		t.Errorf("after a second Refresh: %v", err)
+	}
+}
@@ -1,10 +1,14 @@
 // Package identity implements the Nexus Agent Protocol identity layer.
 //
 // It provides:
-//   - CAManager       — creates/loads the Nexus root Certificate Authority
+//   - CAManager       — creates/loads/rotates the Nexus root Certificate Authority
 //   - Issuer          — issues and verifies X.509 agent and server certificates
-//   - TokenIssuer     — issues and verifies RS256 JWT Task Tokens
+//   - TokenIssuer     — issues and verifies RS256, ES256 or EdDSA JWT Task Tokens
+//   - KeyRing         — token signing keys across rotations, with kids
 //   - OIDCProvider    — OIDC discovery and JWKS HTTP endpoints
+//   - RemoteJWKS      — a registry's JWKS fetched by a verifier
 //   - RequireMTLS     — Gin middleware enforcing mutual TLS authentication
 //   - RequireToken    — Gin middleware enforcing Bearer Task Token authentication
+//   - BindingVerifier — checks certificate- and DPoP-bound Task Tokens
+//   - Denylist        — tokens revoked before they expire, by jti or subject
 package identity
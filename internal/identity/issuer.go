@@ -1,6 +1,7 @@
 package identity
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -35,13 +36,26 @@ func (ic *IssuedCert) TLSCertificate() (tls.Certificate, error) {
 type Issuer struct {
 	ca               *CAManager        // non-nil: root mode
 	intermediateCert *x509.Certificate // non-nil: federated mode
-	intermediateKey  *rsa.PrivateKey   // non-nil: federated mode
+	intermediateKey  crypto.Signer     // non-nil: federated mode
 	rootCAPool       *x509.CertPool    // non-nil: federated mode, anchors verification
+	keyAlg           KeyAlgorithm      // algorithm of keys generated for issued certs
+
+	// Revocation locations embedded in issued leaf certs; empty = omitted.
+	crlURL       string
+	ocspURL      string
+	caIssuersURL string
 }
 
 // NewIssuer creates an Issuer backed by the given CAManager (root/standalone mode).
 func NewIssuer(ca *CAManager) *Issuer {
-	return &Issuer{ca: ca}
+	return &Issuer{ca: ca, keyAlg: KeyAlgorithmRSA}
+}
+
+// SetKeyAlgorithm sets the algorithm of the key pairs the issuer generates
+// for agent, server and intermediate certificates. Certificates issued for a
+// caller's own key (IssueAgentCertForKey) keep that key's algorithm.
+func (i *Issuer) SetKeyAlgorithm(alg KeyAlgorithm) {
+	i.keyAlg = alg
 }
 
 // NewIssuerWithIntermediate creates an Issuer that signs leaf certificates with
@@ -49,24 +63,35 @@ func NewIssuer(ca *CAManager) *Issuer {
 // rootCAPool is used to verify peer certificates up to the root trust anchor.
 func NewIssuerWithIntermediate(
 	intermediateCert *x509.Certificate,
-	intermediateKey *rsa.PrivateKey,
+	intermediateKey crypto.Signer,
 	rootCAPool *x509.CertPool,
 ) *Issuer {
 	return &Issuer{
 		intermediateCert: intermediateCert,
 		intermediateKey:  intermediateKey,
 		rootCAPool:       rootCAPool,
+		keyAlg:           KeyAlgorithmRSA,
 	}
 }
 
-// CACertPEM returns the CA certificate in PEM format.
+// CACertPEM returns the CA certificates in PEM format: the active CA first,
+// then any CA generations still trusted after a rotation.
 // In federated mode this returns the intermediate cert PEM so that clients
 // can configure their TLS trust against this registry's issuing CA.
 func (i *Issuer) CACertPEM() string {
 	if i.intermediateCert != nil {
 		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.intermediateCert.Raw}))
 	}
-	return string(i.ca.CertPEM())
+	return string(i.ca.BundlePEM())
+}
+
+// CACertDER returns the issuing CA certificate in DER form, as served at the
+// caIssuers location of the Authority Information Access extension.
+func (i *Issuer) CACertDER() []byte {
+	if i.intermediateCert != nil {
+		return i.intermediateCert.Raw
+	}
+	return i.ca.Cert().Raw
 }
 
 // IssueIntermediateCert signs a subordinate CA certificate.
@@ -81,13 +106,12 @@ func (i *Issuer) IssueIntermediateCert(org string, validFor time.Duration, maxPa
 	// Determine signing parent.
 	var (
 		parentCert *x509.Certificate
-		signerKey  interface{}
+		signerKey  crypto.Signer
 	)
 	switch {
-	case i.ca != nil && i.ca.cert != nil && i.ca.key != nil:
-		// Root mode — sign with root CA.
-		parentCert = i.ca.cert
-		signerKey = i.ca.key
+	case i.ca != nil && i.ca.Cert() != nil && i.ca.Key() != nil:
+		// Root mode — sign with the active root CA.
+		parentCert, signerKey = i.signingPair()
 	case i.intermediateCert != nil && i.intermediateKey != nil && i.intermediateCert.MaxPathLen > 0:
 		// Intermediate mode with sub-delegation authority.
 		if maxPathLen >= i.intermediateCert.MaxPathLen {
@@ -104,7 +128,7 @@ func (i *Issuer) IssueIntermediateCert(org string, validFor time.Duration, maxPa
 		validFor = 5 * 365 * 24 * time.Hour
 	}
 
-	subKey, err := rsa.GenerateKey(rand.Reader, caKeyBits)
+	subKey, err := generateKey(i.keyAlg, caKeyBits)
 	if err != nil {
 		return nil, fmt.Errorf("generate intermediate key: %w", err)
 	}
@@ -130,7 +154,7 @@ func (i *Issuer) IssueIntermediateCert(org string, validFor time.Duration, maxPa
 		MaxPathLenZero:        maxPathLen == 0,
 	}
 
-	certDER, err := x509.CreateCertificate(rand.Reader, template, parentCert, &subKey.PublicKey, signerKey)
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parentCert, subKey.Public(), signerKey)
 	if err != nil {
 		return nil, fmt.Errorf("create intermediate certificate: %w", err)
 	}
@@ -140,7 +164,10 @@ func (i *Issuer) IssueIntermediateCert(org string, validFor time.Duration, maxPa
 	}
 
 	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
-	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(subKey)}))
+	keyPEM, err := MarshalPrivateKeyPEM(subKey)
+	if err != nil {
+		return nil, err
+	}
 
 	return &IssuedCert{
 		CertPEM: certPEM,
@@ -172,6 +199,37 @@ func (i *Issuer) IssueAgentCert(agentURI, ownerCN string, validFor time.Duration
 	if err := i.checkSigning(); err != nil {
 		return nil, err
 	}
+	agentKey, err := generateKey(i.keyAlg, agentKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate agent key: %w", err)
+	}
+	template, err := agentCertTemplate(agentURI, ownerCN, validFor, ownerEmail)
+	if err != nil {
+		return nil, err
+	}
+	return i.sign(template, agentKey.Public(), agentKey)
+}
+
+// IssueAgentCertForKey issues an agent certificate, with the same contents as
+// IssueAgentCert, for a public key the caller generated — normally the key
+// from a CSR checked with ParseCSR. The registry never sees the private key,
+// so the returned KeyPEM is empty.
+func (i *Issuer) IssueAgentCertForKey(pub crypto.PublicKey, agentURI, ownerCN string, validFor time.Duration, ownerEmail string) (*IssuedCert, error) {
+	if err := i.checkSigning(); err != nil {
+		return nil, err
+	}
+	if err := checkLeafKey(pub); err != nil {
+		return nil, err
+	}
+	template, err := agentCertTemplate(agentURI, ownerCN, validFor, ownerEmail)
+	if err != nil {
+		return nil, err
+	}
+	return i.sign(template, pub, nil)
+}
+
+// agentCertTemplate builds the template described on IssueAgentCert.
+func agentCertTemplate(agentURI, ownerCN string, validFor time.Duration, ownerEmail string) (*x509.Certificate, error) {
 	if validFor == 0 {
 		validFor = 365 * 24 * time.Hour
 	}
@@ -181,11 +239,6 @@ func (i *Issuer) IssueAgentCert(agentURI, ownerCN string, validFor time.Duration
 		return nil, fmt.Errorf("parse agent URI %q: %w", agentURI, err)
 	}
 
-	agentKey, err := rsa.GenerateKey(rand.Reader, agentKeyBits)
-	if err != nil {
-		return nil, fmt.Errorf("generate agent key: %w", err)
-	}
-
 	serial, err := randomSerial()
 	if err != nil {
 		return nil, err
@@ -212,8 +265,7 @@ func (i *Issuer) IssueAgentCert(agentURI, ownerCN string, validFor time.Duration
 		// Domain-verified agent: the CN is a DNS hostname the owner controls.
 		template.DNSNames = []string{ownerCN}
 	}
-
-	return i.sign(template, &agentKey.PublicKey, agentKey)
+	return template, nil
 }
 
 // AgentOwnerEmailFromCert extracts the verified owner email address from a
@@ -236,7 +288,7 @@ func (i *Issuer) IssueServerCert(dnsNames []string, ips []net.IP, validFor time.
 		validFor = 365 * 24 * time.Hour
 	}
 
-	serverKey, err := rsa.GenerateKey(rand.Reader, agentKeyBits)
+	serverKey, err := generateKey(i.keyAlg, agentKeyBits)
 	if err != nil {
 		return nil, fmt.Errorf("generate server key: %w", err)
 	}
@@ -261,7 +313,7 @@ func (i *Issuer) IssueServerCert(dnsNames []string, ips []net.IP, validFor time.
 		IPAddresses: ips,
 	}
 
-	return i.sign(template, &serverKey.PublicKey, serverKey)
+	return i.sign(template, serverKey.Public(), serverKey)
 }
 
 // VerifyAgentCert parses and verifies a PEM-encoded agent certificate against the CA.
@@ -327,26 +379,22 @@ func (i *Issuer) checkSigning() error {
 	if i.intermediateCert != nil && i.intermediateKey != nil {
 		return nil
 	}
-	if i.ca != nil && i.ca.cert != nil && i.ca.key != nil {
+	if i.ca != nil && i.ca.Cert() != nil && i.ca.Key() != nil {
 		return nil
 	}
 	return fmt.Errorf("CA not loaded; call LoadOrCreate first or configure intermediate CA")
 }
 
-// sign creates and signs a certificate.
+// sign creates and signs a leaf certificate, adding the CRL and OCSP locations.
 // Uses the intermediate CA when in federated mode, otherwise falls back to the root CAManager.
-func (i *Issuer) sign(template *x509.Certificate, pub *rsa.PublicKey, priv *rsa.PrivateKey) (*IssuedCert, error) {
-	var (
-		parent    *x509.Certificate
-		signerKey interface{}
-	)
-	if i.intermediateCert != nil && i.intermediateKey != nil {
-		parent = i.intermediateCert
-		signerKey = i.intermediateKey
-	} else {
-		parent = i.ca.cert
-		signerKey = i.ca.key
+// priv is nil when the subject generated its own key; KeyPEM is then empty.
+func (i *Issuer) sign(template *x509.Certificate, pub crypto.PublicKey, priv crypto.Signer) (*IssuedCert, error) {
+	if _, isRSA := pub.(*rsa.PublicKey); !isRSA {
+		// Key encipherment is an RSA key-transport usage.
+		template.KeyUsage &^= x509.KeyUsageKeyEncipherment
 	}
+	parent, signerKey := i.signingPair()
+	i.addRevocationInfo(template, parent, signerKey)
 
 	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signerKey)
 	if err != nil {
@@ -358,7 +406,12 @@ func (i *Issuer) sign(template *x509.Certificate, pub *rsa.PublicKey, priv *rsa.
 	}
 
 	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
-	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}))
+	var keyPEM string
+	if priv != nil {
+		if keyPEM, err = MarshalPrivateKeyPEM(priv); err != nil {
+			return nil, err
+		}
+	}
 
 	return &IssuedCert{
 		CertPEM: certPEM,
@@ -1,7 +1,14 @@
 package identity_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"net"
 	"testing"
 	"time"
@@ -307,3 +314,134 @@ func TestSubDelegation_CannotExceedParent(t *testing.T) {
 		t.Error("expected error when child maxPathLen > parent maxPathLen, got nil")
 	}
 }
+
+// newTestCSR returns a PEM CSR for a fresh P-256 key, and the key.
+func newTestCSR(t *testing.T) (string, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "ignored"},
+	}, key)
+	if err != nil {
+		t.Fatalf("create CSR: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})), key
+}
+
+func TestIssuer_IssueAgentCertForKey_fromCSR(t *testing.T) {
+	issuer := identity.NewIssuer(newTestCA(t))
+	csrPEM, key := newTestCSR(t)
+
+	csr, err := identity.ParseCSR(csrPEM)
+	if err != nil {
+		t.Fatalf("ParseCSR() error: %v", err)
+	}
+	agentURI := "agent://nexusagentprotocol.com/finance/taxes/agent_csr"
+	cert, err := issuer.IssueAgentCertForKey(csr.PublicKey, agentURI, "example.com", 24*time.Hour, "")
+	if err != nil {
+		t.Fatalf("IssueAgentCertForKey() error: %v", err)
+	}
+	if cert.KeyPEM != "" {
+		t.Error("KeyPEM should be empty for a CSR-issued certificate")
+	}
+	if !identity.PublicKeysEqual(cert.Cert.PublicKey, key.Public()) {
+		t.Error("certificate is not for the CSR's key")
+	}
+	if cert.Cert.Subject.CommonName != "example.com" {
+		t.Errorf("CN = %q, want the registry-chosen example.com", cert.Cert.Subject.CommonName)
+	}
+	if got, _ := identity.AgentURIFromCert(cert.Cert); got != agentURI {
+		t.Errorf("URI SAN = %q, want %q", got, agentURI)
+	}
+	if _, err := issuer.VerifyAgentCert(cert.CertPEM); err != nil {
+		t.Errorf("VerifyAgentCert() error: %v", err)
+	}
+}
+
+func TestParseCSR_rejectsInvalid(t *testing.T) {
+	csrPEM, _ := newTestCSR(t)
+	block, _ := pem.Decode([]byte(csrPEM))
+	tampered := append([]byte(nil), block.Bytes...)
+	tampered[len(tampered)-1] ^= 0xff // corrupt the signature
+
+	weakKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	weakDER, _ := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, weakKey)
+
+	cases := map[string]string{
+		"not PEM":   "hello",
+		"tampered":  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: tampered})),
+		"weak RSA":  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: weakDER})),
+		"wrong PEM": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: block.Bytes})),
+	}
+	for name, in := range cases {
+		if _, err := identity.ParseCSR(in); err == nil {
+			t.Errorf("%s: ParseCSR() succeeded, want error", name)
+		}
+	}
+}
+
+func TestIssuer_keyAlgorithms_mTLSHandshake(t *testing.T) {
+	for _, alg := range []identity.KeyAlgorithm{identity.KeyAlgorithmECDSAP256, identity.KeyAlgorithmEd25519} {
+		t.Run(string(alg), func(t *testing.T) {
+			ca := identity.NewCAManager(t.TempDir())
+			ca.SetKeyAlgorithm(alg)
+			if err := ca.Create(); err != nil {
+				t.Fatalf("create CA: %v", err)
+			}
+			issuer := identity.NewIssuer(ca)
+			issuer.SetKeyAlgorithm(alg)
+
+			agentCert, err := issuer.IssueAgentCert("agent://nexusagentprotocol.com/finance/taxes/agent_edge", "example.com", time.Hour, "")
+			if err != nil {
+				t.Fatalf("IssueAgentCert() error: %v", err)
+			}
+			if got, _ := identity.KeyAlgorithmOf(agentCert.Cert.PublicKey); got != alg {
+				t.Errorf("leaf key algorithm = %q, want %q", got, alg)
+			}
+			if agentCert.Cert.KeyUsage&x509.KeyUsageKeyEncipherment != 0 {
+				t.Error("non-RSA leaf must not assert key encipherment")
+			}
+			serverCert, err := issuer.IssueServerCert([]string{"localhost"}, []net.IP{net.ParseIP("127.0.0.1")}, time.Hour)
+			if err != nil {
+				t.Fatalf("IssueServerCert() error: %v", err)
+			}
+
+			serverTLS, err := serverCert.TLSCertificate()
+			if err != nil {
+				t.Fatalf("server TLSCertificate() error: %v", err)
+			}
+			clientTLS, err := agentCert.TLSCertificate()
+			if err != nil {
+				t.Fatalf("agent TLSCertificate() error: %v", err)
+			}
+
+			srvConn, cliConn := net.Pipe()
+			defer srvConn.Close()
+			defer cliConn.Close()
+			srvCfg := ca.TLSConfig(serverTLS)
+			srvCfg.ClientAuth = tls.RequireAndVerifyClientCert
+			srv := tls.Server(srvConn, srvCfg)
+			cli := tls.Client(cliConn, &tls.Config{
+				Certificates: []tls.Certificate{clientTLS},
+				RootCAs:      ca.CertPool(),
+				ServerName:   "localhost",
+				MinVersion:   tls.VersionTLS13,
+			})
+			errc := make(chan error, 1)
+			go func() { errc <- srv.Handshake() }()
+			if err := cli.Handshake(); err != nil {
+				t.Fatalf("client handshake: %v", err)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("server handshake: %v", err)
+			}
+			peer := srv.ConnectionState().PeerCertificates[0]
+			if _, err := issuer.VerifyPeerCert(peer); err != nil {
+				t.Errorf("VerifyPeerCert() error: %v", err)
+			}
+		})
+	}
+}
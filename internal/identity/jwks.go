@@ -0,0 +1,135 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// jwksRefetchInterval limits refetches triggered by unknown kids, so tokens
+// with made-up kids cannot turn a verifier into a request amplifier.
+const jwksRefetchInterval = 30 * time.Second
+
+// RemoteJWKS is a KeySet fetched from a registry's /.well-known/jwks.json. The
+// set is refetched every refresh interval, and early when a token names a kid
+// it does not know, so a verifier follows key rotations without a restart.
+//
+// Fetches run outside the lock and concurrent ones share a single request, so
+// a slow registry never blocks verification with keys already cached.
+type RemoteJWKS struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	flight  singleflight.Group
+
+	mu          sync.Mutex
+	keys        []PublishedKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewRemoteJWKS returns a RemoteJWKS for jwksURL. refresh defaults to 10
+// minutes.
+func NewRemoteJWKS(jwksURL string, refresh time.Duration) *RemoteJWKS {
+	if refresh == 0 {
+		refresh = 10 * time.Minute
+	}
+	return &RemoteJWKS{url: jwksURL, refresh: refresh, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// SetHTTPClient sets the client used to fetch the set, e.g. one that trusts
+// the registry's CA.
+func (r *RemoteJWKS) SetHTTPClient(c *http.Client) {
+	r.client = c
+}
+
+// Refresh fetches the key set now. Keys that cannot be decoded are skipped.
+func (r *RemoteJWKS) Refresh(ctx context.Context) error {
+	res := <-r.refetch(ctx)
+	return res.Err
+}
+
+// refetch starts a fetch, or joins the one in flight, and swaps the keys in
+// if it succeeds. attemptedAt is set when it completes, so callers arriving
+// while it runs join it rather than being rate-limited.
+func (r *RemoteJWKS) refetch(ctx context.Context) <-chan singleflight.Result {
+	return r.flight.DoChan("jwks", func() (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		keys, err := r.fetch(ctx)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.attemptedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		r.keys, r.fetchedAt = keys, r.attemptedAt
+		return nil, nil
+	})
+}
+
+// fetch downloads and decodes the key set. It does not touch r's state.
+func (r *RemoteJWKS) fetch(ctx context.Context) ([]PublishedKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build JWKS request: %w", err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS from %s: %w", r.url, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: unexpected status %d from %s", resp.StatusCode, r.url)
+	}
+	var set JWKSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make([]PublishedKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys = append(keys, PublishedKey{KID: jwk.Kid, Public: pub})
+	}
+	return keys, nil
+}
+
+// VerificationKeys implements KeySet. A failed refetch keeps the keys from the
+// last successful one. When the set is merely stale, the cached keys are
+// returned at once and the refetch runs in the background; only a kid with no
+// cached key waits for it.
+func (r *RemoteJWKS) VerificationKeys(kid string) []crypto.PublicKey {
+	r.mu.Lock()
+	now := time.Now()
+	keys := publishedKeysFor(r.keys, kid)
+	stale := now.Sub(r.fetchedAt) > r.refresh
+	due := (stale || len(keys) == 0) && now.Sub(r.attemptedAt) > jwksRefetchInterval
+	r.mu.Unlock()
+	if !due {
+		return keys
+	}
+
+	done := r.refetch(context.Background())
+	if len(keys) > 0 {
+		return keys
+	}
+	<-done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return publishedKeysFor(r.keys, kid)
+}
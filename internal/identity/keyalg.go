@@ -0,0 +1,288 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyAlgorithm selects the key type generated for the CA, intermediates and
+// leaf certificates. Tokens are signed with the algorithm matching the
+// signing key: RS256, ES256 or EdDSA.
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA       KeyAlgorithm = "rsa"        // RSA; 4096 bits for CAs, 2048 for leaves
+	KeyAlgorithmECDSAP256 KeyAlgorithm = "ecdsa-p256" // ECDSA on NIST P-256; ES256 tokens
+	KeyAlgorithmEd25519   KeyAlgorithm = "ed25519"    // Ed25519; EdDSA tokens
+)
+
+// ParseKeyAlgorithm parses a configured algorithm name. An empty string
+// selects RSA, the historical default.
+func ParseKeyAlgorithm(s string) (KeyAlgorithm, error) {
+	switch alg := KeyAlgorithm(strings.ToLower(strings.TrimSpace(s))); alg {
+	case "":
+		return KeyAlgorithmRSA, nil
+	case KeyAlgorithmRSA, KeyAlgorithmECDSAP256, KeyAlgorithmEd25519:
+		return alg, nil
+	case "p256", "p-256", "ecdsa", "es256":
+		return KeyAlgorithmECDSAP256, nil
+	default:
+		return "", fmt.Errorf("unknown key algorithm %q (want rsa, ecdsa-p256 or ed25519)", s)
+	}
+}
+
+// generateKey creates a private key of the given algorithm; rsaBits applies
+// to RSA only.
+func generateKey(alg KeyAlgorithm, rsaBits int) (crypto.Signer, error) {
+	switch alg {
+	case "", KeyAlgorithmRSA:
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	case KeyAlgorithmECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("unknown key algorithm %q", alg)
+	}
+}
+
+// KeyAlgorithmOf returns the algorithm of a supported public key.
+func KeyAlgorithmOf(pub crypto.PublicKey) (KeyAlgorithm, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return KeyAlgorithmRSA, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return KeyAlgorithmECDSAP256, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return KeyAlgorithmEd25519, nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// MarshalPrivateKeyPEM encodes key as PEM: PKCS#1 "RSA PRIVATE KEY" for RSA
+// (as always written), SEC 1 "EC PRIVATE KEY" for ECDSA and PKCS#8
+// "PRIVATE KEY" for Ed25519.
+func MarshalPrivateKeyPEM(key crypto.Signer) (string, error) {
+	var block *pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", fmt.Errorf("marshal EC key: %w", err)
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return "", fmt.Errorf("marshal Ed25519 key: %w", err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", key)
+	}
+	return string(pem.EncodeToMemory(block)), nil
+}
+
+// MarshalEncryptedPrivateKeyPEM encodes key as a passphrase-protected PKCS#8
+// "ENCRYPTED PRIVATE KEY" (PBES2, PBKDF2-HMAC-SHA256, AES-256-CBC). An empty
+// passphrase falls back to the plaintext encoding of MarshalPrivateKeyPEM.
+func MarshalEncryptedPrivateKeyPEM(key crypto.Signer, passphrase []byte) (string, error) {
+	if len(passphrase) == 0 {
+		return MarshalPrivateKeyPEM(key)
+	}
+	if _, err := KeyAlgorithmOf(key.Public()); err != nil {
+		return "", err
+	}
+	out, err := encryptPKCS8(key, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ParsePrivateKeyPEM decodes a PKCS#1, SEC 1 or PKCS#8 PEM private key.
+func ParsePrivateKeyPEM(keyPEM []byte) (crypto.Signer, error) {
+	return ParsePrivateKeyPEMWithPassphrase(keyPEM, nil)
+}
+
+// ParsePrivateKeyPEMWithPassphrase is ParsePrivateKeyPEM that also accepts a
+// PKCS#8 "ENCRYPTED PRIVATE KEY", decrypted with passphrase. Plaintext keys
+// are accepted whatever the passphrase.
+func ParsePrivateKeyPEMWithPassphrase(keyPEM, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode private key PEM")
+	}
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("private key is encrypted and no passphrase was configured")
+		}
+		key, err = decryptPKCS8(block.Bytes, passphrase)
+		if errors.Is(err, ErrIncorrectPassphrase) {
+			return nil, err
+		}
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected private key PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if _, err := KeyAlgorithmOf(signer.Public()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// jwtSigningMethod returns the JWS algorithm for tokens signed by the private
+// half of pub.
+func jwtSigningMethod(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	alg, err := KeyAlgorithmOf(pub)
+	if err != nil {
+		return nil, err
+	}
+	switch alg {
+	case KeyAlgorithmECDSAP256:
+		return jwt.SigningMethodES256, nil
+	case KeyAlgorithmEd25519:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return jwt.SigningMethodRS256, nil
+	}
+}
+
+// JWTAlgorithm returns the JWS "alg" for tokens verified with pub, or "" when
+// the key type is unsupported.
+func JWTAlgorithm(pub crypto.PublicKey) string {
+	m, err := jwtSigningMethod(pub)
+	if err != nil {
+		return ""
+	}
+	return m.Alg()
+}
+
+// signJWT signs claims with key using the algorithm matching its type.
+func signJWT(key crypto.Signer, claims jwt.Claims, kid string) (string, error) {
+	method, err := jwtSigningMethod(key.Public())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(signerMethod{method}, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(key)
+}
+
+// signerMethod signs JWTs with any crypto.Signer. jwt's built-in RS256 and
+// ES256 methods only accept in-memory *rsa.PrivateKey and *ecdsa.PrivateKey,
+// which rules out keys held by a KeyStore backend such as a KMS. Verification
+// is delegated to the wrapped built-in method.
+type signerMethod struct {
+	jwt.SigningMethod
+}
+
+// Sign implements jwt.SigningMethod.
+func (m signerMethod) Sign(signingString string, key any) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, jwt.ErrInvalidKeyType
+	}
+	var (
+		digest = []byte(signingString)
+		opts   crypto.SignerOpts
+	)
+	switch m.Alg() {
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg():
+		sum := sha256.Sum256(digest)
+		digest, opts = sum[:], crypto.SHA256
+	default: // EdDSA signs the message itself
+		opts = crypto.Hash(0)
+	}
+	sig, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	if m.Alg() == jwt.SigningMethodES256.Alg() {
+		return ecdsaRawSignature(sig, 32)
+	}
+	return sig, nil
+}
+
+// ecdsaRawSignature converts an ASN.1 DER ECDSA signature, as returned by
+// crypto.Signer, to the fixed-width r||s form JWS requires (RFC 7518 §3.4).
+func ecdsaRawSignature(der []byte, size int) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil || len(rest) != 0 ||
+		sig.R.BitLen() > 8*size || sig.S.BitLen() > 8*size {
+		return nil, fmt.Errorf("malformed ECDSA signature")
+	}
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out, nil
+}
+
+// parseJWT parses and validates tokenStr into claims, accepting only the
+// algorithm that matches pub.
+func parseJWT(tokenStr string, claims jwt.Claims, pub crypto.PublicKey, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	if _, err := jwtSigningMethod(pub); err != nil {
+		return nil, err
+	}
+	return parseJWTWithKeys(tokenStr, claims, StaticKeySet{pub}, opts...)
+}
+
+// parseJWTWithKeys parses and validates tokenStr into claims against the keys
+// keys returns for its kid. Only keys whose type matches the token's "alg"
+// are tried, so a token cannot pick a different algorithm for a key.
+func parseJWTWithKeys(tokenStr string, claims jwt.Claims, keys KeySet, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	opts = append(opts, jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}))
+	return jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		var set jwt.VerificationKeySet
+		for _, pub := range keys.VerificationKeys(kid) {
+			if JWTAlgorithm(pub) == t.Method.Alg() {
+				set.Keys = append(set.Keys, pub)
+			}
+		}
+		if len(set.Keys) == 0 {
+			return nil, fmt.Errorf("no %s key published for kid %q", t.Method.Alg(), kid)
+		}
+		return set, nil
+	}, opts...)
+}
@@ -0,0 +1,357 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// keyManifestFile records every generation of the registry's rotated keys. It
+// lives in the cert dir next to ca.crt, which replicas already share.
+const keyManifestFile = "keys.json"
+
+// ErrKeyRotationUnsupported is returned when a key cannot be rotated, e.g.
+// tokens signed with the CA key or a federated registry's intermediate CA.
+var ErrKeyRotationUnsupported = errors.New("key rotation is not supported for this key")
+
+// KeyVersion is one generation of a rotated signing key. A version is
+// published from creation until RetireAt; it signs from ActivateAt until the
+// next version activates.
+type KeyVersion struct {
+	Name       string       `json:"name"` // key store name: "jwt", "jwt-2", "ca-2", ...
+	KID        string       `json:"kid"`  // JWKS kid, or hex subject key ID for a CA
+	Algorithm  KeyAlgorithm `json:"algorithm"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ActivateAt time.Time    `json:"activate_at"`
+	RetireAt   *time.Time   `json:"retire_at,omitempty"`
+}
+
+// Published reports whether the version is still published (and accepted
+// for verification) at t.
+func (v KeyVersion) Published(t time.Time) bool {
+	return v.RetireAt == nil || t.Before(*v.RetireAt)
+}
+
+// Key version states reported by KeyStates.
+const (
+	KeyStatePending  = "pending"  // published, not signing yet
+	KeyStateActive   = "active"   // signing
+	KeyStateRetiring = "retiring" // published, no longer signing
+	KeyStateRetired  = "retired"  // no longer published or accepted
+)
+
+// KeyStates returns the state of each version at t.
+func KeyStates(versions []KeyVersion, t time.Time) []string {
+	active := activeIndex(versions, t)
+	states := make([]string, len(versions))
+	for i, v := range versions {
+		switch {
+		case !v.Published(t):
+			states[i] = KeyStateRetired
+		case i == active:
+			states[i] = KeyStateActive
+		case v.ActivateAt.After(t):
+			states[i] = KeyStatePending
+		default:
+			states[i] = KeyStateRetiring
+		}
+	}
+	return states
+}
+
+// RotateOptions controls a key rotation.
+type RotateOptions struct {
+	// Algorithm of the new key; empty keeps the active key's algorithm.
+	Algorithm KeyAlgorithm
+	// ActivateAfter publishes the new key this long before it starts
+	// signing, so verifiers that cache keys pick it up first.
+	ActivateAfter time.Duration
+	// GracePeriod keeps the old key published this long after the new key
+	// activates, so what it signed keeps verifying.
+	GracePeriod time.Duration
+}
+
+// keyManifest is the content of keys.json. Versions are never removed, so
+// version numbers (and kids) are never reused.
+type keyManifest struct {
+	JWT []KeyVersion `json:"jwt,omitempty"`
+	CA  []KeyVersion `json:"ca,omitempty"`
+}
+
+func readKeyManifest(dir string) (*keyManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, keyManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &keyManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read key manifest: %w", err)
+	}
+	var m keyManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", keyManifestFile, err)
+	}
+	return &m, nil
+}
+
+// writeKeyManifest replaces keys.json atomically.
+func writeKeyManifest(dir string, m *keyManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create cert dir %q: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, keyManifestFile+".*")
+	if err != nil {
+		return fmt.Errorf("write key manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close() //nolint:errcheck
+		return fmt.Errorf("write key manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write key manifest: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write key manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, keyManifestFile)); err != nil {
+		return fmt.Errorf("write key manifest: %w", err)
+	}
+	return nil
+}
+
+// activeIndex returns the index of the version that signs at t: the last one
+// whose ActivateAt has passed. Versions are kept in activation order.
+func activeIndex(versions []KeyVersion, t time.Time) int {
+	active := 0
+	for i, v := range versions {
+		if !v.ActivateAt.After(t) {
+			active = i
+		}
+	}
+	return active
+}
+
+// addVersion appends next and schedules every unretired version for
+// retirement GracePeriod after next activates.
+func addVersion(versions []KeyVersion, next KeyVersion, grace time.Duration) []KeyVersion {
+	retireAt := next.ActivateAt.Add(grace)
+	for i := range versions {
+		if versions[i].RetireAt == nil || versions[i].RetireAt.After(retireAt) {
+			versions[i].RetireAt = &retireAt
+		}
+	}
+	return append(versions, next)
+}
+
+// KeyRing holds the registry's token signing keys across rotations. The
+// active key signs; every published key is served in the JWKS and accepted
+// for verification, so tokens and endorsements signed before a rotation keep
+// verifying until the old key retires.
+type KeyRing struct {
+	mu       sync.RWMutex
+	store    KeyStore // nil for a fixed ring that cannot rotate
+	dir      string
+	versions []KeyVersion
+	signers  map[string]crypto.Signer // by version name
+}
+
+// NewKeyRing returns a fixed ring holding only key, published as kid.
+func NewKeyRing(key crypto.Signer, kid string) *KeyRing {
+	alg, _ := KeyAlgorithmOf(key.Public())
+	return &KeyRing{
+		versions: []KeyVersion{{Name: KeyNameJWT, KID: kid, Algorithm: alg}},
+		signers:  map[string]crypto.Signer{KeyNameJWT: key},
+	}
+}
+
+// LoadKeyRing loads the token signing keys recorded in dir's key manifest
+// from ks. Before the first rotation there is a single version: the key
+// named KeyNameJWT (generated with alg if missing), published as
+// SigningKeyID.
+func LoadKeyRing(ctx context.Context, ks KeyStore, dir string, alg KeyAlgorithm) (*KeyRing, error) {
+	r := &KeyRing{store: ks, dir: dir, signers: map[string]crypto.Signer{}}
+	if err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if len(r.versions) > 0 {
+		return r, nil
+	}
+
+	key, err := LoadOrGenerateKey(ctx, ks, KeyNameJWT, alg)
+	if err != nil {
+		return nil, err
+	}
+	keyAlg, _ := KeyAlgorithmOf(key.Public())
+	r.versions = []KeyVersion{{
+		Name:      KeyNameJWT,
+		KID:       SigningKeyID,
+		Algorithm: keyAlg,
+		CreatedAt: time.Now().UTC(),
+	}}
+	r.signers[KeyNameJWT] = key
+	return r, nil
+}
+
+// Reload re-reads the key manifest, picking up rotations made by another
+// registry process sharing the cert dir. It is a no-op for a fixed ring.
+func (r *KeyRing) Reload(ctx context.Context) error {
+	if r.store == nil {
+		return nil
+	}
+	m, err := readKeyManifest(r.dir)
+	if err != nil {
+		return err
+	}
+	if len(m.JWT) == 0 {
+		return nil
+	}
+
+	r.mu.RLock()
+	loaded := make(map[string]crypto.Signer, len(r.signers))
+	for name, s := range r.signers {
+		loaded[name] = s
+	}
+	r.mu.RUnlock()
+
+	now := time.Now()
+	for _, v := range m.JWT {
+		if _, ok := loaded[v.Name]; ok || !v.Published(now) {
+			continue
+		}
+		key, err := r.store.Signer(ctx, v.Name)
+		if err != nil {
+			return fmt.Errorf("load token key %q: %w", v.Name, err)
+		}
+		loaded[v.Name] = key
+	}
+
+	r.mu.Lock()
+	r.versions, r.signers = m.JWT, loaded
+	r.mu.Unlock()
+	return nil
+}
+
+// Rotate generates a new token signing key and records it in the manifest.
+// The new key is published at once and signs from now+ActivateAfter; the
+// keys it replaces retire GracePeriod after that.
+func (r *KeyRing) Rotate(ctx context.Context, opts RotateOptions) (KeyVersion, error) {
+	if r.store == nil {
+		return KeyVersion{}, ErrKeyRotationUnsupported
+	}
+	// Start from the manifest on disk so a rotation made elsewhere is kept.
+	if err := r.Reload(ctx); err != nil {
+		return KeyVersion{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	alg := opts.Algorithm
+	if alg == "" {
+		alg = r.versions[activeIndex(r.versions, now)].Algorithm
+	}
+	n := len(r.versions) + 1
+	next := KeyVersion{
+		Name:       fmt.Sprintf("%s-%d", KeyNameJWT, n),
+		KID:        fmt.Sprintf("nexus-signing-key-%d", n),
+		Algorithm:  alg,
+		CreatedAt:  now,
+		ActivateAt: now.Add(opts.ActivateAfter),
+	}
+	key, err := r.store.Generate(ctx, next.Name, alg)
+	if err != nil {
+		return KeyVersion{}, err
+	}
+
+	m, err := readKeyManifest(r.dir)
+	if err != nil {
+		return KeyVersion{}, err
+	}
+	versions := addVersion(append([]KeyVersion(nil), r.versions...), next, opts.GracePeriod)
+	m.JWT = versions
+	if err := writeKeyManifest(r.dir, m); err != nil {
+		return KeyVersion{}, err
+	}
+	r.versions = versions
+	r.signers[next.Name] = key
+	return next, nil
+}
+
+// Active returns the kid and signer of the key that signs now.
+func (r *KeyRing) Active() (string, crypto.Signer) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v := r.versions[activeIndex(r.versions, time.Now())]
+	return v.KID, r.signers[v.Name]
+}
+
+// Versions returns every recorded version, retired ones included, oldest
+// first.
+func (r *KeyRing) Versions() []KeyVersion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]KeyVersion(nil), r.versions...)
+}
+
+// PublishedKey is a public key served in the JWKS.
+type PublishedKey struct {
+	KID    string
+	Public crypto.PublicKey
+}
+
+// PublishedKeys returns the keys published now, the active key first.
+func (r *KeyRing) PublishedKeys() []PublishedKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	now := time.Now()
+	active := activeIndex(r.versions, now)
+	keys := []PublishedKey{{KID: r.versions[active].KID, Public: r.signers[r.versions[active].Name].Public()}}
+	for i, v := range r.versions {
+		if i == active || !v.Published(now) {
+			continue
+		}
+		if s, ok := r.signers[v.Name]; ok {
+			keys = append(keys, PublishedKey{KID: v.KID, Public: s.Public()})
+		}
+	}
+	return keys
+}
+
+// VerificationKeys implements KeySet.
+func (r *KeyRing) VerificationKeys(kid string) []crypto.PublicKey {
+	return publishedKeysFor(r.PublishedKeys(), kid)
+}
+
+// KeySet supplies the public keys a JWT may be verified with.
+type KeySet interface {
+	// VerificationKeys returns the key published as kid, or every published
+	// key when kid is empty (tokens signed before kids were set).
+	VerificationKeys(kid string) []crypto.PublicKey
+}
+
+// StaticKeySet is a KeySet of fixed keys without kids, such as the PEM keys
+// in a resolver's token_key_file. Every key is tried whatever the token's kid.
+type StaticKeySet []crypto.PublicKey
+
+// VerificationKeys implements KeySet.
+func (s StaticKeySet) VerificationKeys(string) []crypto.PublicKey { return s }
+
+func publishedKeysFor(keys []PublishedKey, kid string) []crypto.PublicKey {
+	var out []crypto.PublicKey
+	for _, k := range keys {
+		if kid == "" || k.KID == kid {
+			out = append(out, k.Public)
+		}
+	}
+	return out
+}
@@ -0,0 +1,299 @@
+package identity_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+)
+
+const testRegistry = "https://registry.nexusagentprotocol.com"
+
+func tokenKID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyRing_rotationKeepsOldTokensVerifying(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ks := identity.NewFileKeyStore(dir, "")
+	ring, err := identity.LoadKeyRing(ctx, ks, dir, identity.KeyAlgorithmEd25519)
+	if err != nil {
+		t.Fatalf("LoadKeyRing() error: %v", err)
+	}
+	ti := identity.NewTokenIssuerWithKeyRing(ring, testRegistry, time.Hour)
+
+	oldToken, err := ti.Issue("agent://nexusagentprotocol.com/edge/agent_a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldEndorsement, err := ti.IssueEndorsement("agent://nexusagentprotocol.com/edge/agent_a", "verified", "", testRegistry, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKID(t, oldToken); kid != identity.SigningKeyID {
+		t.Errorf("first key kid = %q, want %q", kid, identity.SigningKeyID)
+	}
+
+	v, err := ring.Rotate(ctx, identity.RotateOptions{Algorithm: identity.KeyAlgorithmECDSAP256, GracePeriod: time.Hour})
+	if err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	newToken, err := ti.Issue("agent://nexusagentprotocol.com/edge/agent_a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKID(t, newToken); kid != v.KID || kid == identity.SigningKeyID {
+		t.Errorf("new token kid = %q, want %q", kid, v.KID)
+	}
+	if ti.Algorithm() != "ES256" {
+		t.Errorf("Algorithm() = %q after rotating to P-256", ti.Algorithm())
+	}
+	for name, tok := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := ti.Verify(tok); err != nil {
+			t.Errorf("%s token does not verify during the grace period: %v", name, err)
+		}
+	}
+	if len(ring.PublishedKeys()) != 2 {
+		t.Errorf("published %d keys, want 2", len(ring.PublishedKeys()))
+	}
+
+	// Another process sharing the cert dir sees the rotation.
+	replica, err := identity.LoadKeyRing(ctx, ks, dir, identity.KeyAlgorithmEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, _ := replica.Active(); kid != v.KID {
+		t.Errorf("replica active kid = %q, want %q", kid, v.KID)
+	}
+	claims := &identity.NAPEndorsementClaims{}
+	if _, err := jwt.ParseWithClaims(oldEndorsement, claims, func(*jwt.Token) (any, error) {
+		return replica.VerificationKeys(identity.SigningKeyID)[0], nil
+	}); err != nil {
+		t.Errorf("old endorsement does not verify with the replica's published key: %v", err)
+	}
+
+	// Rotating with no grace retires every earlier key at once.
+	if _, err := ring.Rotate(ctx, identity.RotateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.Verify(oldToken); err == nil {
+		t.Error("token signed by a retired key still verifies")
+	}
+	if len(ring.Versions()) != 3 {
+		t.Errorf("Versions() = %d entries, want 3 (retired versions are kept)", len(ring.Versions()))
+	}
+}
+
+func TestKeyRing_prepublishedKeyDoesNotSignYet(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ring, err := identity.LoadKeyRing(ctx, identity.NewFileKeyStore(dir, ""), dir, identity.KeyAlgorithmEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := ring.Rotate(ctx, identity.RotateOptions{ActivateAfter: time.Hour, GracePeriod: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, _ := ring.Active(); kid != identity.SigningKeyID {
+		t.Errorf("active kid = %q before activation, want %q", kid, identity.SigningKeyID)
+	}
+	published := ring.PublishedKeys()
+	if len(published) != 2 || published[0].KID != identity.SigningKeyID || published[1].KID != next.KID {
+		t.Errorf("published keys = %+v, want the active key then %q", published, next.KID)
+	}
+}
+
+func TestKeyRing_fixedRingCannotRotate(t *testing.T) {
+	ring := identity.NewKeyRing(newTestCA(t).Key(), identity.SigningKeyID)
+	if _, err := ring.Rotate(context.Background(), identity.RotateOptions{}); !errors.Is(err, identity.ErrKeyRotationUnsupported) {
+		t.Errorf("err = %v, want ErrKeyRotationUnsupported", err)
+	}
+}
+
+func TestRemoteJWKS_followsRotation(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ring, err := identity.LoadKeyRing(ctx, identity.NewFileKeyStore(dir, ""), dir, identity.KeyAlgorithmECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti := identity.NewTokenIssuerWithKeyRing(ring, testRegistry, time.Hour)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	identity.NewOIDCProvider(testRegistry, ti).RegisterWellKnown(engine)
+	srv := httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+
+	jwks := identity.NewRemoteJWKS(srv.URL+"/.well-known/jwks.json", time.Hour)
+	if err := jwks.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+	verifier := identity.NewTaskTokenVerifierWithKeySet(jwks, testRegistry)
+
+	oldToken, _ := ti.Issue("agent://nexusagentprotocol.com/edge/agent_a", nil)
+	if _, err := verifier.Verify(oldToken); err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+
+	if _, err := ring.Rotate(ctx, identity.RotateOptions{Algorithm: identity.KeyAlgorithmRSA, GracePeriod: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	newToken, _ := ti.Issue("agent://nexusagentprotocol.com/edge/agent_a", nil)
+	if err := jwks.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for name, tok := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := verifier.Verify(tok); err != nil {
+			t.Errorf("%s token: %v", name, err)
+		}
+	}
+
+	// A token whose alg does not match its kid's key is rejected.
+	forged := strings.Join(append([]string{forgeHeader(t, "RS256", identity.SigningKeyID)}, strings.Split(newToken, ".")[1:]...), ".")
+	if _, err := verifier.Verify(forged); err == nil {
+		t.Error("token with a mismatched alg/kid verified")
+	}
+}
+
+func TestRemoteJWKS_concurrentFetchesShareOneRequest(t *testing.T) {
+	ring := identity.NewKeyRing(newTestCA(t).Key(), identity.SigningKeyID)
+	ti := identity.NewTokenIssuerWithKeyRing(ring, testRegistry, time.Hour)
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	identity.NewOIDCProvider(testRegistry, ti).RegisterWellKnown(engine)
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		engine.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	jwks := identity.NewRemoteJWKS(srv.URL+"/.well-known/jwks.json", time.Hour)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if keys := jwks.VerificationKeys(identity.SigningKeyID); len(keys) != 1 {
+				t.Errorf("VerificationKeys returned %d keys, want 1", len(keys))
+			}
+		}()
+	}
+	wg.Wait()
+	if n := hits.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+}
+
+func forgeHeader(t *testing.T, alg, kid string) string {
+	t.Helper()
+	tok := jwt.New(jwt.SigningMethodRS256)
+	tok.Header["alg"], tok.Header["kid"] = alg, kid
+	s, err := tok.SigningString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(s, ".")[0]
+}
+
+func TestJWK_publicKeyRoundTrip(t *testing.T) {
+	for _, alg := range []identity.KeyAlgorithm{identity.KeyAlgorithmRSA, identity.KeyAlgorithmECDSAP256, identity.KeyAlgorithmEd25519} {
+		dir := t.TempDir()
+		key, err := identity.NewFileKeyStore(dir, "").Generate(context.Background(), "jwt", alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jwk, err := identity.PublicKeyToJWK(key.Public(), "k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("%s: PublicKey() error: %v", alg, err)
+		}
+		if !identity.PublicKeysEqual(pub, key.Public()) {
+			t.Errorf("%s: JWK round trip changed the key", alg)
+		}
+	}
+}
+
+func TestCAManager_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	ca := identity.NewCAManager(dir)
+	ca.SetKeyAlgorithm(identity.KeyAlgorithmECDSAP256)
+	if err := ca.Create(); err != nil {
+		t.Fatal(err)
+	}
+	issuer := identity.NewIssuer(ca)
+	oldCA := ca.Cert()
+	oldCert, err := issuer.IssueAgentCert("agent://nexusagentprotocol.com/edge/agent_old", "owner", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := ca.Rotate(identity.RotateOptions{GracePeriod: time.Hour})
+	if err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	if v.Name != "ca-2" || ca.Cert().Equal(oldCA) {
+		t.Fatalf("Rotate() = %+v; active CA unchanged", v)
+	}
+	newCert, err := issuer.IssueAgentCert("agent://nexusagentprotocol.com/edge/agent_new", "owner", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := newCert.Cert.CheckSignatureFrom(ca.Cert()); err != nil {
+		t.Errorf("new cert not signed by the new CA: %v", err)
+	}
+	for name, c := range map[string]*identity.IssuedCert{"old": oldCert, "new": newCert} {
+		if _, err := issuer.VerifyPeerCert(c.Cert); err != nil {
+			t.Errorf("%s cert not trusted during the grace period: %v", name, err)
+		}
+	}
+	if n := strings.Count(issuer.CACertPEM(), "BEGIN CERTIFICATE"); n != 2 {
+		t.Errorf("CA bundle has %d certificates, want 2", n)
+	}
+	block, _ := pem.Decode([]byte(issuer.CACertPEM()))
+	if first, _ := x509.ParseCertificate(block.Bytes); !first.Equal(ca.Cert()) {
+		t.Error("CA bundle does not start with the active CA")
+	}
+
+	// A restarted registry loads both generations.
+	reloaded := identity.NewCAManager(dir)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load() after rotation: %v", err)
+	}
+	if !reloaded.Cert().Equal(ca.Cert()) || len(reloaded.Versions()) != 2 {
+		t.Errorf("reloaded CA: active %v, %d versions", reloaded.Cert().Subject, len(reloaded.Versions()))
+	}
+
+	// Once retired, the old CA is no longer trusted.
+	if _, err := ca.Rotate(identity.RotateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.VerifyPeerCert(oldCert.Cert); err == nil {
+		t.Error("cert from a retired CA is still trusted")
+	}
+}
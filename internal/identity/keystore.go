@@ -0,0 +1,141 @@
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Names of the registry's signing keys in a KeyStore.
+const (
+	KeyNameCA  = "ca"  // signs agent, server and intermediate certificates
+	KeyNameJWT = "jwt" // signs task tokens, user tokens, endorsements and tree heads
+)
+
+// ErrKeyNotFound is returned by a KeyStore when the named key does not exist.
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyStore holds the registry's private keys and hands out crypto.Signers for
+// them. Backends that keep key material outside the process (an HSM over
+// PKCS#11, a cloud KMS) return signers that forward each signature to the
+// backend, so the private key never enters registry memory.
+type KeyStore interface {
+	// Signer returns a signer for the named key, or ErrKeyNotFound.
+	Signer(ctx context.Context, name string) (crypto.Signer, error)
+	// Generate creates the named key, replacing any existing key of that name.
+	Generate(ctx context.Context, name string, alg KeyAlgorithm) (crypto.Signer, error)
+}
+
+// LoadOrGenerateKey returns the named key from ks, generating it with alg if
+// it does not exist yet.
+func LoadOrGenerateKey(ctx context.Context, ks KeyStore, name string, alg KeyAlgorithm) (crypto.Signer, error) {
+	key, err := ks.Signer(ctx, name)
+	if errors.Is(err, ErrKeyNotFound) {
+		return ks.Generate(ctx, name, alg)
+	}
+	return key, err
+}
+
+// validKeyName restricts key names to a safe file-name and URL-path subset.
+var validKeyName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+func checkKeyName(name string) error {
+	if !validKeyName.MatchString(name) {
+		return fmt.Errorf("invalid key name %q", name)
+	}
+	return nil
+}
+
+// FileKeyStore keeps keys as PEM files named "<name>.key" in a directory.
+// With a passphrase, new keys are written as encrypted PKCS#8; existing
+// plaintext keys are still read so a deployment can switch on encryption and
+// re-encrypt its keys at leisure.
+type FileKeyStore struct {
+	dir        string
+	passphrase []byte
+}
+
+// NewFileKeyStore returns a FileKeyStore rooted at dir. An empty passphrase
+// stores keys unencrypted.
+func NewFileKeyStore(dir, passphrase string) *FileKeyStore {
+	return &FileKeyStore{dir: dir, passphrase: []byte(passphrase)}
+}
+
+// Signer implements KeyStore.
+func (s *FileKeyStore) Signer(_ context.Context, name string) (crypto.Signer, error) {
+	if err := checkKeyName(name); err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", s.path(name), ErrKeyNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read key %q: %w", name, err)
+	}
+	key, err := ParsePrivateKeyPEMWithPassphrase(keyPEM, s.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", name, err)
+	}
+	return key, nil
+}
+
+// Generate implements KeyStore. RSA keys are 4096 bits.
+func (s *FileKeyStore) Generate(_ context.Context, name string, alg KeyAlgorithm) (crypto.Signer, error) {
+	if err := checkKeyName(name); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create key dir %q: %w", s.dir, err)
+	}
+	key, err := generateKey(alg, caKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate key %q: %w", name, err)
+	}
+	if err := s.write(name, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Reencrypt rewrites the named key under the store's passphrase (or in
+// plaintext when it has none). Use it after turning on encryption to protect
+// keys written before.
+func (s *FileKeyStore) Reencrypt(ctx context.Context, name string) error {
+	key, err := s.Signer(ctx, name)
+	if err != nil {
+		return err
+	}
+	return s.write(name, key)
+}
+
+func (s *FileKeyStore) write(name string, key crypto.Signer) error {
+	keyPEM, err := MarshalEncryptedPrivateKeyPEM(key, s.passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path(name), []byte(keyPEM), 0o600); err != nil {
+		return fmt.Errorf("write key %q: %w", name, err)
+	}
+	return nil
+}
+
+func (s *FileKeyStore) path(name string) string {
+	return filepath.Join(s.dir, name+".key")
+}
+
+// MarshalPublicKeyPEM encodes pub as a PKIX "PUBLIC KEY" PEM block, the form
+// resolvers accept in resolver.auth.token_key_file.
+func MarshalPublicKeyPEM(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
@@ -0,0 +1,315 @@
+package identity
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The KMS key-store protocol is a small JSON API a signing service exposes so
+// the registry can use keys it never sees:
+//
+//	GET  /v1/keys/{name}       → kmsKeyResponse (404 when the key is absent)
+//	POST /v1/keys/{name}       kmsGenerateRequest → kmsKeyResponse
+//	POST /v1/keys/{name}/sign  kmsSignRequest → kmsSignResponse
+//
+// Requests carry "Authorization: Bearer <token>" when a token is configured.
+// KMSKeyStore is the client; NewKMSHandler serves the protocol from any other
+// KeyStore and is what cmd/kms-standin runs, standing in for a cloud KMS or an
+// HSM front end.
+
+type kmsKeyResponse struct {
+	Name         string       `json:"name"`
+	Algorithm    KeyAlgorithm `json:"algorithm"`
+	PublicKeyPEM string       `json:"public_key_pem"`
+}
+
+type kmsGenerateRequest struct {
+	Algorithm KeyAlgorithm `json:"algorithm"`
+}
+
+type kmsSignRequest struct {
+	Digest        []byte `json:"digest"`                    // pre-hashed digest, or the message for Ed25519
+	Hash          string `json:"hash,omitempty"`            // e.g. "SHA-256"; empty for Ed25519
+	PSSSaltLength *int   `json:"pss_salt_length,omitempty"` // set for RSA-PSS
+}
+
+type kmsSignResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+// KMSKeyStore is a KeyStore backed by a remote signing service speaking the
+// KMS key-store protocol. Private keys stay in the service; each Sign call is
+// a round trip.
+type KMSKeyStore struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewKMSKeyStore returns a KMSKeyStore for the service at baseURL,
+// authenticating with token when it is non-empty.
+func NewKMSKeyStore(baseURL, token string) *KMSKeyStore {
+	return &KMSKeyStore{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Signer implements KeyStore.
+func (s *KMSKeyStore) Signer(ctx context.Context, name string) (crypto.Signer, error) {
+	if err := checkKeyName(name); err != nil {
+		return nil, err
+	}
+	var resp kmsKeyResponse
+	if err := s.do(ctx, http.MethodGet, "/v1/keys/"+name, nil, &resp); err != nil {
+		return nil, err
+	}
+	return s.signer(name, resp)
+}
+
+// Generate implements KeyStore.
+func (s *KMSKeyStore) Generate(ctx context.Context, name string, alg KeyAlgorithm) (crypto.Signer, error) {
+	if err := checkKeyName(name); err != nil {
+		return nil, err
+	}
+	var resp kmsKeyResponse
+	if err := s.do(ctx, http.MethodPost, "/v1/keys/"+name, kmsGenerateRequest{Algorithm: alg}, &resp); err != nil {
+		return nil, err
+	}
+	return s.signer(name, resp)
+}
+
+func (s *KMSKeyStore) signer(name string, resp kmsKeyResponse) (crypto.Signer, error) {
+	pub, err := PublicKeyFromPEM(resp.PublicKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("kms key %q: %w", name, err)
+	}
+	if _, err := KeyAlgorithmOf(pub); err != nil {
+		return nil, fmt.Errorf("kms key %q: %w", name, err)
+	}
+	return &kmsSigner{store: s, name: name, pub: pub}, nil
+}
+
+func (s *KMSKeyStore) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("build kms request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("kms %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("kms %s: %w", path, ErrKeyNotFound)
+	case resp.StatusCode != http.StatusOK:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kms %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("kms %s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// kmsSigner is a crypto.Signer whose private half lives in the KMS.
+type kmsSigner struct {
+	store *KMSKeyStore
+	name  string
+	pub   crypto.PublicKey
+}
+
+func (k *kmsSigner) Public() crypto.PublicKey { return k.pub }
+
+// Sign sends digest to the KMS. The rand argument is ignored; the service
+// supplies its own randomness.
+func (k *kmsSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	req := kmsSignRequest{Digest: digest}
+	if h := opts.HashFunc(); h != 0 {
+		req.Hash = h.String()
+	}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		saltLen := pss.SaltLength
+		req.PSSSaltLength = &saltLen
+	}
+	var resp kmsSignResponse
+	if err := k.store.do(context.Background(), http.MethodPost, "/v1/keys/"+k.name+"/sign", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// kmsHashes are the digests the stand-in accepts, by crypto.Hash name.
+var kmsHashes = map[string]crypto.Hash{
+	crypto.SHA256.String(): crypto.SHA256,
+	crypto.SHA384.String(): crypto.SHA384,
+	crypto.SHA512.String(): crypto.SHA512,
+}
+
+// kmsHandler serves the KMS key-store protocol from a backing KeyStore.
+type kmsHandler struct {
+	keys  KeyStore
+	token string
+
+	mu      sync.Mutex
+	signers map[string]crypto.Signer // decrypted keys, so Sign skips the KDF
+}
+
+// NewKMSHandler returns an http.Handler serving the KMS key-store protocol
+// from keys. When token is non-empty every request must present it as a
+// bearer token.
+func NewKMSHandler(keys KeyStore, token string) http.Handler {
+	h := &kmsHandler{keys: keys, token: token, signers: make(map[string]crypto.Signer)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/keys/{name}", h.getKey)
+	mux.HandleFunc("POST /v1/keys/{name}", h.generateKey)
+	mux.HandleFunc("POST /v1/keys/{name}/sign", h.sign)
+	return h.authenticate(mux)
+}
+
+func (h *kmsHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *kmsHandler) signer(ctx context.Context, name string) (crypto.Signer, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if key, ok := h.signers[name]; ok {
+		return key, nil
+	}
+	key, err := h.keys.Signer(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	h.signers[name] = key
+	return key, nil
+}
+
+func (h *kmsHandler) getKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.signer(r.Context(), r.PathValue("name"))
+	if err != nil {
+		kmsError(w, err)
+		return
+	}
+	writeKMSKey(w, r.PathValue("name"), key)
+}
+
+func (h *kmsHandler) generateKey(w http.ResponseWriter, r *http.Request) {
+	var req kmsGenerateRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	alg, err := ParseKeyAlgorithm(string(req.Algorithm))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := r.PathValue("name")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key, err := h.keys.Generate(r.Context(), name, alg)
+	if err != nil {
+		kmsError(w, err)
+		return
+	}
+	h.signers[name] = key
+	writeKMSKey(w, name, key)
+}
+
+func (h *kmsHandler) sign(w http.ResponseWriter, r *http.Request) {
+	var req kmsSignRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	var opts crypto.SignerOpts = crypto.Hash(0)
+	if req.Hash != "" {
+		hash, ok := kmsHashes[req.Hash]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported hash %q", req.Hash), http.StatusBadRequest)
+			return
+		}
+		if len(req.Digest) != hash.Size() {
+			http.Error(w, "digest length does not match hash", http.StatusBadRequest)
+			return
+		}
+		opts = hash
+		if req.PSSSaltLength != nil {
+			opts = &rsa.PSSOptions{SaltLength: *req.PSSSaltLength, Hash: hash}
+		}
+	}
+
+	key, err := h.signer(r.Context(), r.PathValue("name"))
+	if err != nil {
+		kmsError(w, err)
+		return
+	}
+	sig, err := key.Sign(rand.Reader, req.Digest, opts)
+	if err != nil {
+		http.Error(w, "sign: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeKMSJSON(w, kmsSignResponse{Signature: sig})
+}
+
+func writeKMSKey(w http.ResponseWriter, name string, key crypto.Signer) {
+	pubPEM, err := MarshalPublicKeyPEM(key.Public())
+	if err != nil {
+		kmsError(w, err)
+		return
+	}
+	alg, _ := KeyAlgorithmOf(key.Public())
+	writeKMSJSON(w, kmsKeyResponse{Name: name, Algorithm: alg, PublicKeyPEM: string(pubPEM)})
+}
+
+func writeKMSJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func kmsError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrKeyNotFound) {
+		http.Error(w, "key not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -50,22 +50,43 @@ func RequireMTLS(issuer *Issuer) gin.HandlerFunc {
 	}
 }
 
-// RequireToken returns a Gin middleware that enforces a valid Bearer Task Token.
+// RequireToken returns a Gin middleware that enforces a valid Bearer Task Token
+// issued for the registry. Tokens whose audience names only other services,
+// such as agent-to-agent or delegated tokens, are rejected. A DPoP-bound
+// token may also be sent with the "DPoP" scheme. A sender-constrained token
+// must be presented by its holder, which binding checks; tokens without a
+// "cnf" claim are accepted (see RequireBoundToken). A nil binding checks
+// certificate-bound tokens only against the mTLS client and keeps its own
+// DPoP replay cache.
 //
 // On success it injects the *TaskTokenClaims into the context under the
 // "nexus_token_claims" key.
-func RequireToken(tokens *TokenIssuer) gin.HandlerFunc {
+func RequireToken(tokens *TokenIssuer, binding *BindingVerifier) gin.HandlerFunc {
+	return requireToken(tokens, binding, false)
+}
+
+// RequireBoundToken is RequireToken that also rejects tokens that are not
+// sender-constrained, so every token must be presented by its holder: over
+// mTLS with the certificate it was issued to, or with a DPoP proof from its
+// key.
+func RequireBoundToken(tokens *TokenIssuer, binding *BindingVerifier) gin.HandlerFunc {
+	return requireToken(tokens, binding, true)
+}
+
+func requireToken(tokens *TokenIssuer, binding *BindingVerifier, requireBound bool) gin.HandlerFunc {
+	if binding == nil {
+		binding = NewBindingVerifier("")
+	}
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if !strings.HasPrefix(authHeader, "Bearer ") {
+		tokenStr, ok := taskToken(c)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Bearer token required",
 			})
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := tokens.Verify(tokenStr)
+		claims, err := verifyTaskToken(tokens, binding, requireBound, c.Request, tokenStr)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "invalid token: " + err.Error(),
@@ -78,6 +99,33 @@ func RequireToken(tokens *TokenIssuer) gin.HandlerFunc {
 	}
 }
 
+// verifyTaskToken verifies a Task Token for the registry and, when it is
+// sender-constrained or requireBound is set, that r is sent by its holder.
+func verifyTaskToken(tokens *TokenIssuer, binding *BindingVerifier, requireBound bool, r *http.Request, tokenStr string) (*TaskTokenClaims, error) {
+	claims, err := tokens.VerifyForAudience(tokenStr, tokens.IssuerURL())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Cnf != nil || requireBound {
+		if err := binding.Verify(claims, r, tokenStr); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// taskToken returns the Task Token from the Authorization header, sent with
+// the "Bearer" or "DPoP" scheme.
+func taskToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	for _, scheme := range []string{"Bearer ", "DPoP "} {
+		if strings.HasPrefix(authHeader, scheme) {
+			return strings.TrimPrefix(authHeader, scheme), true
+		}
+	}
+	return "", false
+}
+
 // AgentURIFromCtx retrieves the authenticated agent URI injected by RequireMTLS.
 func AgentURIFromCtx(c *gin.Context) string {
 	v, _ := c.Get(ctxAgentURI)
@@ -94,13 +142,25 @@ func ClaimsFromCtx(c *gin.Context) *TaskTokenClaims {
 
 // OptionalToken returns a Gin middleware that tries to parse a Bearer Task Token.
 // Unlike RequireToken, it never aborts — it silently skips injection when the
-// header is absent or the token fails verification.
-func OptionalToken(tokens *TokenIssuer) gin.HandlerFunc {
+// header is absent or the token fails verification, including the binding
+// check of RequireToken.
+func OptionalToken(tokens *TokenIssuer, binding *BindingVerifier) gin.HandlerFunc {
+	return optionalToken(tokens, binding, false)
+}
+
+// OptionalBoundToken is OptionalToken that also skips tokens which are not
+// sender-constrained, as RequireBoundToken rejects them.
+func OptionalBoundToken(tokens *TokenIssuer, binding *BindingVerifier) gin.HandlerFunc {
+	return optionalToken(tokens, binding, true)
+}
+
+func optionalToken(tokens *TokenIssuer, binding *BindingVerifier, requireBound bool) gin.HandlerFunc {
+	if binding == nil {
+		binding = NewBindingVerifier("")
+	}
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-			if claims, err := tokens.Verify(tokenStr); err == nil {
+		if tokenStr, ok := taskToken(c); ok {
+			if claims, err := verifyTaskToken(tokens, binding, requireBound, c.Request, tokenStr); err == nil {
 				c.Set(ctxTokenClaims, claims)
 			}
 		}
@@ -1,14 +1,26 @@
 package identity
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/binary"
+	"fmt"
+	"math/big"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
+// SigningKeyID is the JWKS "kid" of the registry's first token signing key.
+// Keys added by KeyRing.Rotate are numbered on from it: "nexus-signing-key-2"
+// and so on.
+const SigningKeyID = "nexus-signing-key-1"
+
 // OIDCConfig is the OpenID Connect discovery document served at
 // /.well-known/openid-configuration.
 type OIDCConfig struct {
@@ -26,14 +38,18 @@ type JWKSet struct {
 	Keys []JWK `json:"keys"`
 }
 
-// JWK is a JSON Web Key for an RSA public key.
+// JWK is a JSON Web Key for an RSA ("RSA"), P-256 ("EC") or Ed25519 ("OKP")
+// public key.
 type JWK struct {
 	Kty string `json:"kty"`
 	Use string `json:"use"`
 	Kid string `json:"kid"`
 	Alg string `json:"alg"`
-	N   string `json:"n"`
-	E   string `json:"e"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
 }
 
 // OIDCProvider exposes the OIDC discovery and JWKS endpoints so that
@@ -55,20 +71,107 @@ func (p *OIDCProvider) RegisterWellKnown(engine *gin.Engine) {
 }
 
 func (p *OIDCProvider) discoveryHandler(c *gin.Context) {
+	var algs []string
+	for _, k := range p.tokens.KeyRing().PublishedKeys() {
+		if alg := JWTAlgorithm(k.Public); alg != "" && !slices.Contains(algs, alg) {
+			algs = append(algs, alg)
+		}
+	}
 	c.JSON(http.StatusOK, OIDCConfig{
 		Issuer:                           p.issuerURL,
 		JWKSURI:                          p.issuerURL + "/.well-known/jwks.json",
 		TokenEndpoint:                    p.issuerURL + "/api/v1/token",
 		ResponseTypesSupported:           []string{"token"},
 		SubjectTypesSupported:            []string{"public"},
-		IDTokenSigningAlgValuesSupported: []string{"RS256"},
-		GrantTypesSupported:              []string{"client_credentials"},
+		IDTokenSigningAlgValuesSupported: algs,
+		GrantTypesSupported:              []string{"client_credentials", "urn:ietf:params:oauth:grant-type:token-exchange"},
 	})
 }
 
+// jwksHandler serves every published signing key, the active one first.
+// Verifiers should pick the key by the token's "kid" and refetch the set
+// when they meet a kid they do not know.
 func (p *OIDCProvider) jwksHandler(c *gin.Context) {
-	jwk := rsaPublicKeyToJWK(p.tokens.PublicKey(), "nexus-signing-key-1")
-	c.JSON(http.StatusOK, JWKSet{Keys: []JWK{jwk}})
+	published := p.tokens.KeyRing().PublishedKeys()
+	set := JWKSet{Keys: make([]JWK, 0, len(published))}
+	for _, k := range published {
+		jwk, err := PublicKeyToJWK(k.Public, k.KID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "signing key cannot be published"})
+			return
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
+
+// PublicKeyToJWK encodes an RSA, P-256 or Ed25519 public key as a signing JWK.
+func PublicKeyToJWK(pub crypto.PublicKey, kid string) (JWK, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return rsaPublicKeyToJWK(k, kid), nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return JWK{}, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+		// RFC 7518 §6.2.1: fixed-length, big-endian coordinates.
+		ek, err := k.ECDH()
+		if err != nil {
+			return JWK{}, fmt.Errorf("encode ECDSA key: %w", err)
+		}
+		raw := ek.Bytes() // 0x04 || X || Y
+		size := (len(raw) - 1) / 2
+		return JWK{
+			Kty: "EC", Use: "sig", Kid: kid, Alg: "ES256", Crv: "P-256",
+			X: base64.RawURLEncoding.EncodeToString(raw[1 : 1+size]),
+			Y: base64.RawURLEncoding.EncodeToString(raw[1+size:]),
+		}, nil
+	case ed25519.PublicKey:
+		// RFC 8037 §2.
+		return JWK{
+			Kty: "OKP", Use: "sig", Kid: kid, Alg: "EdDSA", Crv: "Ed25519",
+			X: base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// PublicKey decodes the JWK into an RSA, P-256 or Ed25519 public key.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	b64 := base64.RawURLEncoding.DecodeString
+	switch {
+	case j.Kty == "RSA":
+		n, err := b64(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: bad n: %w", j.Kid, err)
+		}
+		e, err := b64(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("jwk %q: bad e", j.Kid)
+		}
+		exp := 0
+		for _, b := range e {
+			exp = exp<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}, nil
+	case j.Kty == "EC" && j.Crv == "P-256":
+		x, errX := b64(j.X)
+		y, errY := b64(j.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("jwk %q: bad P-256 coordinates", j.Kid)
+		}
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		x, err := b64(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %q: bad Ed25519 key", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("jwk %q: unsupported key type %s %s", j.Kid, j.Kty, j.Crv)
+	}
 }
 
 // rsaPublicKeyToJWK encodes an RSA public key as a JWK (RFC 7518 §6.3).
@@ -0,0 +1,298 @@
+package identity
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// RevocationReason is an RFC 5280 §5.3.1 CRL reason code, also used in OCSP
+// responses.
+type RevocationReason int
+
+const (
+	ReasonUnspecified          RevocationReason = 0
+	ReasonKeyCompromise        RevocationReason = 1
+	ReasonCACompromise         RevocationReason = 2
+	ReasonAffiliationChanged   RevocationReason = 3
+	ReasonSuperseded           RevocationReason = 4
+	ReasonCessationOfOperation RevocationReason = 5
+	ReasonCertificateHold      RevocationReason = 6
+	ReasonPrivilegeWithdrawn   RevocationReason = 9
+	ReasonAACompromise         RevocationReason = 10
+)
+
+var reasonNames = map[RevocationReason]string{
+	ReasonUnspecified:          "unspecified",
+	ReasonKeyCompromise:        "keyCompromise",
+	ReasonCACompromise:         "cACompromise",
+	ReasonAffiliationChanged:   "affiliationChanged",
+	ReasonSuperseded:           "superseded",
+	ReasonCessationOfOperation: "cessationOfOperation",
+	ReasonCertificateHold:      "certificateHold",
+	ReasonPrivilegeWithdrawn:   "privilegeWithdrawn",
+	ReasonAACompromise:         "aACompromise",
+}
+
+// String returns the RFC 5280 name of the reason, e.g. "keyCompromise".
+func (r RevocationReason) String() string {
+	if name, ok := reasonNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("reason(%d)", int(r))
+}
+
+// ParseRevocationReason parses an RFC 5280 reason name. Matching ignores case
+// and underscores, so "keyCompromise" and "key_compromise" are equivalent. An
+// empty string is ReasonUnspecified. removeFromCRL (8) is not accepted: it
+// only has meaning in delta CRLs.
+func ParseRevocationReason(s string) (RevocationReason, error) {
+	norm := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "_", ""))
+	if norm == "" {
+		return ReasonUnspecified, nil
+	}
+	for code, name := range reasonNames {
+		if strings.ToLower(name) == norm {
+			return code, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown revocation reason %q", s)
+}
+
+// RevokedCert is a revoked certificate as listed in a CRL or reported by OCSP.
+type RevokedCert struct {
+	Serial    string // hex, as in IssuedCert.Serial
+	RevokedAt time.Time
+	Reason    RevocationReason
+}
+
+// ErrOCSPMalformedRequest is returned by ParseOCSPRequest for a request that
+// cannot be decoded.
+var ErrOCSPMalformedRequest = errors.New("malformed OCSP request")
+
+// ErrOCSPWrongIssuer is returned by ParseOCSPRequest for a request about a
+// certificate another CA issued.
+var ErrOCSPWrongIssuer = errors.New("OCSP request is for a different issuer")
+
+// ErrUnknownCA is returned for a CA key ID that is not one of the issuer's
+// trusted CA generations.
+var ErrUnknownCA = errors.New("unknown issuing CA")
+
+// ErrOCSPUnsupportedKey is returned when the issuing key cannot sign OCSP
+// responses. OCSP signing supports RSA and ECDSA issuers only.
+var ErrOCSPUnsupportedKey = errors.New("OCSP responses cannot be signed with an Ed25519 issuer key")
+
+// SetRevocationURLs sets the locations embedded in certificates the issuer
+// signs: crlURL in the CRL Distribution Points extension, and ocspURL and
+// caIssuersURL in Authority Information Access. Empty values are omitted; the
+// OCSP URL is also omitted when the issuing key is Ed25519. The CRL URL gets a
+// "ca" query parameter naming the issuing CA, so that after a CA rotation
+// each certificate points at the CRL its own CA signed.
+func (i *Issuer) SetRevocationURLs(crlURL, ocspURL, caIssuersURL string) {
+	i.crlURL, i.ocspURL, i.caIssuersURL = crlURL, ocspURL, caIssuersURL
+}
+
+// addRevocationInfo puts the CDP and AIA locations into a leaf template.
+func (i *Issuer) addRevocationInfo(template, issuerCert *x509.Certificate, issuerKey crypto.Signer) {
+	if i.crlURL != "" {
+		sep := "?"
+		if strings.Contains(i.crlURL, "?") {
+			sep = "&"
+		}
+		template.CRLDistributionPoints = []string{i.crlURL + sep + "ca=" + caKeyID(issuerCert)}
+	}
+	if _, ed := issuerKey.Public().(ed25519.PublicKey); i.ocspURL != "" && !ed {
+		template.OCSPServer = []string{i.ocspURL}
+	}
+	if i.caIssuersURL != "" {
+		template.IssuingCertificateURL = []string{i.caIssuersURL}
+	}
+}
+
+// signingPair returns the certificate and key that sign new certificates.
+func (i *Issuer) signingPair() (*x509.Certificate, crypto.Signer) {
+	if i.intermediateCert != nil && i.intermediateKey != nil {
+		return i.intermediateCert, i.intermediateKey
+	}
+	g := i.ca.active()
+	return g.cert, g.key
+}
+
+// issuingCA returns the trusted CA generation with the given key ID (see
+// caKeyID), or the active one when caKID is empty. Revocation information for
+// a certificate is signed by the CA that issued it.
+func (i *Issuer) issuingCA(caKID string) (*x509.Certificate, crypto.Signer, error) {
+	if err := i.checkSigning(); err != nil {
+		return nil, nil, err
+	}
+	if caKID == "" {
+		cert, key := i.signingPair()
+		return cert, key, nil
+	}
+	if i.intermediateCert != nil && i.intermediateKey != nil {
+		if caKeyID(i.intermediateCert) == caKID {
+			return i.intermediateCert, i.intermediateKey, nil
+		}
+		return nil, nil, ErrUnknownCA
+	}
+	for _, g := range i.ca.published() {
+		if g.version.KID == caKID {
+			return g.cert, g.key, nil
+		}
+	}
+	return nil, nil, ErrUnknownCA
+}
+
+// issuingCAs returns every trusted CA certificate, the active one first.
+func (i *Issuer) issuingCAs() []*x509.Certificate {
+	if i.intermediateCert != nil {
+		return []*x509.Certificate{i.intermediateCert}
+	}
+	var certs []*x509.Certificate
+	for _, g := range i.ca.published() {
+		certs = append(certs, g.cert)
+	}
+	return certs
+}
+
+// CAKeyIDs returns the key IDs of the trusted CA generations, the active one
+// first. They are the values of the "ca" parameter in CRL URLs.
+func (i *Issuer) CAKeyIDs() []string {
+	if i.checkSigning() != nil {
+		return nil
+	}
+	var ids []string
+	for _, cert := range i.issuingCAs() {
+		ids = append(ids, caKeyID(cert))
+	}
+	return ids
+}
+
+// CreateCRL returns a DER-encoded X.509 v2 CRL of revoked, signed by the CA
+// generation caKID (the active CA when empty), or ErrUnknownCA. number must
+// increase with every CRL the issuer publishes.
+func (i *Issuer) CreateCRL(caKID string, revoked []RevokedCert, number *big.Int, thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	cert, key, err := i.issuingCA(caKID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, rc := range revoked {
+		serial, ok := new(big.Int).SetString(rc.Serial, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid certificate serial %q", rc.Serial)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: rc.RevokedAt.UTC(),
+			ReasonCode:     int(rc.Reason),
+		})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    number,
+		ThisUpdate:                thisUpdate.UTC(),
+		NextUpdate:                nextUpdate.UTC(),
+		RevokedCertificateEntries: entries,
+	}, cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("create CRL: %w", err)
+	}
+	return der, nil
+}
+
+// ParseOCSPRequest decodes a DER OCSP request and returns the hex serial it
+// asks about and the key ID of the CA generation that issued it. It returns
+// ErrOCSPMalformedRequest for undecodable input and ErrOCSPWrongIssuer if the
+// request names a CA this issuer does not trust.
+func (i *Issuer) ParseOCSPRequest(der []byte) (serial, caKID string, err error) {
+	req, err := ocsp.ParseRequest(der)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrOCSPMalformedRequest, err)
+	}
+	if err := i.checkSigning(); err != nil {
+		return "", "", err
+	}
+
+	// The CertID names the issuer by hashes of its subject and public key,
+	// computed with the hash the client chose.
+	if !req.HashAlgorithm.Available() {
+		return "", "", fmt.Errorf("%w: unsupported hash %v", ErrOCSPMalformedRequest, req.HashAlgorithm)
+	}
+	for _, cert := range i.issuingCAs() {
+		var spki struct {
+			Algorithm pkix.AlgorithmIdentifier
+			PublicKey asn1.BitString
+		}
+		if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+			return "", "", fmt.Errorf("parse issuer public key: %w", err)
+		}
+		h := req.HashAlgorithm.New()
+		h.Write(cert.RawSubject)
+		nameHash := h.Sum(nil)
+		h.Reset()
+		h.Write(spki.PublicKey.RightAlign())
+		keyHash := h.Sum(nil)
+		if bytes.Equal(nameHash, req.IssuerNameHash) && bytes.Equal(keyHash, req.IssuerKeyHash) {
+			return req.SerialNumber.Text(16), caKeyID(cert), nil
+		}
+	}
+	return "", "", ErrOCSPWrongIssuer
+}
+
+// CreateOCSPResponse returns an OCSP response for serial, signed by the CA
+// generation caKID that issued it. revoked is nil for a good certificate;
+// known=false reports the serial as unknown. The CA signs responses
+// directly, so no delegated responder certificate is used.
+func (i *Issuer) CreateOCSPResponse(caKID, serial string, known bool, revoked *RevokedCert, thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	cert, key, err := i.issuingCA(caKID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ed := key.Public().(ed25519.PublicKey); ed {
+		return nil, ErrOCSPUnsupportedKey
+	}
+	sn, ok := new(big.Int).SetString(serial, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid certificate serial %q", serial)
+	}
+
+	template := ocsp.Response{
+		SerialNumber: sn,
+		Status:       ocsp.Good,
+		ThisUpdate:   thisUpdate.UTC(),
+		NextUpdate:   nextUpdate.UTC(),
+	}
+	switch {
+	case !known:
+		template.Status = ocsp.Unknown
+	case revoked != nil:
+		template.Status = ocsp.Revoked
+		template.RevokedAt = revoked.RevokedAt.UTC()
+		template.RevocationReason = int(revoked.Reason)
+	}
+	resp, err := ocsp.CreateResponse(cert, cert, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("create OCSP response: %w", err)
+	}
+	return resp, nil
+}
+
+// Unsigned OCSP error responses (RFC 6960 §2.3), for requests the responder
+// cannot answer.
+var (
+	OCSPMalformedRequestResponse = ocsp.MalformedRequestErrorResponse
+	OCSPUnauthorizedResponse     = ocsp.UnauthorizedErrorResponse
+	OCSPInternalErrorResponse    = ocsp.InternalErrorErrorResponse
+)
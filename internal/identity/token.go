@@ -1,53 +1,140 @@
 package identity
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/uri"
+)
+
+// MaxDelegationDepth is the longest act chain a token exchange will build.
+const MaxDelegationDepth = 5
+
+// Token exchange errors. Each maps to an RFC 8693 / RFC 6749 error code.
+var (
+	// ErrInvalidTarget is returned when the requested audience is missing or
+	// is not an agent:// URI ("invalid_target").
+	ErrInvalidTarget = errors.New("audience must be an agent:// URI")
+	// ErrInvalidScope is returned when the requested scopes are not a subset
+	// of the subject token's ("invalid_scope").
+	ErrInvalidScope = errors.New("requested scope exceeds the subject token's scope")
+	// ErrExchangeNotAllowed is returned when the actor may not exchange the
+	// subject token: it was not issued for the actor, or the delegation chain
+	// is too long ("invalid_grant").
+	ErrExchangeNotAllowed = errors.New("subject token cannot be exchanged by this actor")
+	// ErrTokenAudience is returned when a token is presented to a service it
+	// was not issued for.
+	ErrTokenAudience = errors.New("token audience does not include this service")
 )
 
 // TaskTokenClaims are the JWT claims for a Nexus Task Token.
 // Task Tokens are short-lived credentials bound to a specific agent identity
 // and a set of scopes, issued after successful mTLS authentication.
+//
+// A token with an "aud" claim is valid only at those services: an agent://
+// URI, or the registry's issuer URL. Tokens without one predate audience
+// restriction and are accepted anywhere. A delegated token from a token
+// exchange has the upstream caller as its subject and the agents acting for
+// it in "act", most recent first.
+//
+// A token with a "cnf" claim is sender-constrained: only the holder of the
+// certificate or DPoP key it names may present it (see BindingVerifier).
 type TaskTokenClaims struct {
 	jwt.RegisteredClaims
-	AgentURI string   `json:"agent_uri"`
-	Scopes   []string `json:"scopes"`
+	AgentURI string        `json:"agent_uri"`
+	Scopes   []string      `json:"scopes"`
+	Act      *ActorClaim   `json:"act,omitempty"`
+	Cnf      *Confirmation `json:"cnf,omitempty"`
+}
+
+// ActorClaim is an RFC 8693 "act" claim: the agent acting for the token's
+// subject, and any actor before it.
+type ActorClaim struct {
+	Subject string      `json:"sub"`
+	Act     *ActorClaim `json:"act,omitempty"`
 }
 
-// TokenIssuer issues and verifies Task Tokens signed with RS256.
-// It reuses the CA's RSA key so that token signatures can be verified
-// using the same JWKS endpoint that serves the CA public key.
+// Actors returns the delegation chain, the current actor first. It is empty
+// for a token used by its own subject.
+func (c *TaskTokenClaims) Actors() []string {
+	var actors []string
+	for a := c.Act; a != nil; a = a.Act {
+		actors = append(actors, a.Subject)
+	}
+	return actors
+}
+
+// HasAudience reports whether the token may be presented to audience. Tokens
+// without an audience are accepted for compatibility.
+func (c *TaskTokenClaims) HasAudience(audience string) bool {
+	return len(c.Audience) == 0 || slices.Contains(c.Audience, audience)
+}
+
+// TokenIssuer issues and verifies Task Tokens signed with RS256, ES256 or
+// EdDSA, matching the type of its key. Tokens are signed by the active key of
+// a KeyRing and carry its kid; any key the ring still publishes verifies, so
+// tokens survive a key rotation until the old key retires.
 type TokenIssuer struct {
-	key    *rsa.PrivateKey
-	pub    *rsa.PublicKey
-	issuer string
-	ttl    time.Duration
+	keys     *KeyRing
+	issuer   string
+	ttl      time.Duration
+	denylist *Denylist // nil = tokens are valid until they expire
 }
 
-// NewTokenIssuer creates a TokenIssuer.
+// NewTokenIssuer creates a TokenIssuer that signs with key alone, published
+// as SigningKeyID.
 //
 //	issuerURL — The "iss" claim value; typically the registry's base URL.
 //	ttl        — Token lifetime (default: 1 hour).
-func NewTokenIssuer(key *rsa.PrivateKey, issuerURL string, ttl time.Duration) *TokenIssuer {
+func NewTokenIssuer(key crypto.Signer, issuerURL string, ttl time.Duration) *TokenIssuer {
+	return NewTokenIssuerWithKeyRing(NewKeyRing(key, SigningKeyID), issuerURL, ttl)
+}
+
+// NewTokenIssuerWithKeyRing creates a TokenIssuer that signs with the active
+// key of a rotating KeyRing.
+func NewTokenIssuerWithKeyRing(keys *KeyRing, issuerURL string, ttl time.Duration) *TokenIssuer {
 	if ttl == 0 {
 		ttl = time.Hour
 	}
 	return &TokenIssuer{
-		key:    key,
-		pub:    &key.PublicKey,
+		keys:   keys,
 		issuer: issuerURL,
 		ttl:    ttl,
 	}
 }
 
+// SetDenylist makes Verify reject revoked tokens: those listed by jti, and
+// those whose subject or any actor in their act chain has been revoked.
+func (t *TokenIssuer) SetDenylist(d *Denylist) {
+	t.denylist = d
+}
+
+// Denylist returns the issuer's Denylist, or nil.
+func (t *TokenIssuer) Denylist() *Denylist { return t.denylist }
+
 // Issue creates a signed Task Token for agentURI with the requested scopes.
+// The token has no audience; prefer IssueForAudience.
 func (t *TokenIssuer) Issue(agentURI string, scopes []string) (string, error) {
+	return t.IssueForAudience(agentURI, scopes, "")
+}
+
+// IssueForAudience creates a signed Task Token for agentURI that is valid
+// only at audience: the agent:// URI of the agent it will be sent to, or the
+// registry's issuer URL. An empty audience issues an unrestricted token.
+func (t *TokenIssuer) IssueForAudience(agentURI string, scopes []string, audience string) (string, error) {
+	return t.IssueBound(agentURI, scopes, audience, nil)
+}
+
+// IssueBound is IssueForAudience for a token bound to cnf: the caller's mTLS
+// certificate or DPoP key. A nil cnf issues a bearer token.
+func (t *TokenIssuer) IssueBound(agentURI string, scopes []string, audience string, cnf *Confirmation) (string, error) {
 	now := time.Now().UTC()
 	claims := TaskTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -59,28 +146,149 @@ func (t *TokenIssuer) Issue(agentURI string, scopes []string) (string, error) {
 		},
 		AgentURI: agentURI,
 		Scopes:   scopes,
+		Cnf:      cnf,
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	signed, err := token.SignedString(t.key)
+	signed, err := t.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("sign token: %w", err)
 	}
 	return signed, nil
 }
 
+// Exchange implements the RFC 8693 token exchange. actorURI, acting for the
+// subject of a verified token it received, gets a delegated token to call
+// audience. The new token keeps the subject, adds actorURI to the front of
+// the act chain, and carries scopes, which must be a subset of the subject
+// token's (nil keeps them all). It expires no later than the subject token,
+// and is bound to cnf, the actor's certificate or DPoP key, when non-nil.
+//
+// The subject token must have been issued for actorURI, so an agent can only
+// pass on authority that was sent to it.
+func (t *TokenIssuer) Exchange(subject *TaskTokenClaims, actorURI, audience string, scopes []string, cnf *Confirmation) (string, *TaskTokenClaims, error) {
+	if _, err := uri.Parse(audience); err != nil {
+		return "", nil, ErrInvalidTarget
+	}
+	if !slices.Contains(subject.Audience, actorURI) {
+		return "", nil, fmt.Errorf("%w: it was not issued for %s", ErrExchangeNotAllowed, actorURI)
+	}
+	if len(subject.Actors()) >= MaxDelegationDepth {
+		return "", nil, fmt.Errorf("%w: delegation chain longer than %d", ErrExchangeNotAllowed, MaxDelegationDepth)
+	}
+	if scopes == nil {
+		scopes = subject.Scopes
+	}
+	for _, s := range scopes {
+		if !slices.Contains(subject.Scopes, s) {
+			return "", nil, fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+	}
+
+	now := time.Now().UTC()
+	exp := now.Add(t.ttl)
+	if subject.ExpiresAt != nil && subject.ExpiresAt.Before(exp) {
+		exp = subject.ExpiresAt.Time
+	}
+	claims := &TaskTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Subject:   subject.Subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+			ID:        uuid.New().String(),
+		},
+		AgentURI: subject.AgentURI,
+		Scopes:   scopes,
+		Act:      &ActorClaim{Subject: actorURI, Act: subject.Act},
+		Cnf:      cnf,
+	}
+
+	signed, err := t.sign(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("sign token: %w", err)
+	}
+	return signed, claims, nil
+}
+
+// sign signs claims with the active key, naming it in the "kid" header.
+func (t *TokenIssuer) sign(claims jwt.Claims) (string, error) {
+	kid, key := t.keys.Active()
+	return signJWT(key, claims, kid)
+}
+
 // Verify parses and validates a Task Token, returning its claims on success.
+// With a Denylist, revoked tokens fail with ErrTokenRevoked.
 func (t *TokenIssuer) Verify(tokenStr string) (*TaskTokenClaims, error) {
-	token, err := jwt.ParseWithClaims(
-		tokenStr,
-		&TaskTokenClaims{},
-		func(tok *jwt.Token) (any, error) {
-			if _, ok := tok.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", tok.Header["alg"])
-			}
-			return t.pub, nil
-		},
-		jwt.WithIssuer(t.issuer),
+	claims, err := VerifyTaskTokenWithKeySet(tokenStr, t.keys, t.issuer)
+	if err != nil {
+		return nil, err
+	}
+	if t.denylist != nil {
+		subjects := append([]string{claims.Subject, claims.AgentURI}, claims.Actors()...)
+		if err := t.denylist.Check(&claims.RegisteredClaims, subjects...); err != nil {
+			return nil, fmt.Errorf("verify token: %w", err)
+		}
+	}
+	return claims, nil
+}
+
+// VerifyForAudience verifies a Task Token and checks that it may be
+// presented to audience (see TaskTokenClaims.HasAudience).
+func (t *TokenIssuer) VerifyForAudience(tokenStr, audience string) (*TaskTokenClaims, error) {
+	claims, err := t.Verify(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.HasAudience(audience) {
+		return nil, ErrTokenAudience
+	}
+	return claims, nil
+}
+
+// TaskTokenVerifier validates Task Tokens with only the registry's public
+// keys, for services (such as the resolver) that accept tokens but never
+// issue them.
+type TaskTokenVerifier struct {
+	keys   KeySet
+	issuer string
+}
+
+// NewTaskTokenVerifier creates a TaskTokenVerifier for tokens signed by pub
+// with the given "iss" claim.
+func NewTaskTokenVerifier(pub crypto.PublicKey, issuer string) *TaskTokenVerifier {
+	return &TaskTokenVerifier{keys: StaticKeySet{pub}, issuer: issuer}
+}
+
+// NewTaskTokenVerifierWithKeySet creates a TaskTokenVerifier for tokens signed
+// by any key in keys, such as a RemoteJWKS that follows the registry's key
+// rotations.
+func NewTaskTokenVerifierWithKeySet(keys KeySet, issuer string) *TaskTokenVerifier {
+	return &TaskTokenVerifier{keys: keys, issuer: issuer}
+}
+
+// Verify parses and validates a Task Token, returning its claims on success.
+func (v *TaskTokenVerifier) Verify(tokenStr string) (*TaskTokenClaims, error) {
+	return VerifyTaskTokenWithKeySet(tokenStr, v.keys, v.issuer)
+}
+
+// VerifyTaskTokenWithKey validates a Task Token against a registry public key
+// and issuer. Only the JWS algorithm matching the key's type is accepted.
+func VerifyTaskTokenWithKey(tokenStr string, pub crypto.PublicKey, issuer string) (*TaskTokenClaims, error) {
+	if _, err := jwtSigningMethod(pub); err != nil {
+		return nil, fmt.Errorf("verify token: %w", err)
+	}
+	return VerifyTaskTokenWithKeySet(tokenStr, StaticKeySet{pub}, issuer)
+}
+
+// VerifyTaskTokenWithKeySet validates a Task Token against the registry keys
+// in keys, choosing by the token's kid.
+func VerifyTaskTokenWithKeySet(tokenStr string, keys KeySet, issuer string) (*TaskTokenClaims, error) {
+	token, err := parseJWTWithKeys(tokenStr, &TaskTokenClaims{}, keys,
+		jwt.WithIssuer(issuer),
 		jwt.WithExpirationRequired(),
 	)
 	if err != nil {
@@ -94,12 +302,21 @@ func (t *TokenIssuer) Verify(tokenStr string) (*TaskTokenClaims, error) {
 	return claims, nil
 }
 
-// PublicKey returns the RSA public key used to verify tokens.
-func (t *TokenIssuer) PublicKey() *rsa.PublicKey { return t.pub }
+// PublicKey returns the public half of the active signing key.
+func (t *TokenIssuer) PublicKey() crypto.PublicKey {
+	_, key := t.keys.Active()
+	return key.Public()
+}
+
+// KeyRing returns the issuer's signing keys.
+func (t *TokenIssuer) KeyRing() *KeyRing { return t.keys }
+
+// Algorithm returns the JWS "alg" of the tokens this issuer signs.
+func (t *TokenIssuer) Algorithm() string { return JWTAlgorithm(t.PublicKey()) }
 
-// PublicKeyPEM returns the RSA public key in PKIX PEM format.
+// PublicKeyPEM returns the active public key in PKIX PEM format.
 func (t *TokenIssuer) PublicKeyPEM() (string, error) {
-	der, err := x509.MarshalPKIXPublicKey(t.pub)
+	der, err := x509.MarshalPKIXPublicKey(t.PublicKey())
 	if err != nil {
 		return "", fmt.Errorf("marshal public key: %w", err)
 	}
@@ -109,6 +326,10 @@ func (t *TokenIssuer) PublicKeyPEM() (string, error) {
 // TTL returns the configured token lifetime.
 func (t *TokenIssuer) TTL() time.Duration { return t.ttl }
 
+// IssuerURL returns the "iss" claim of the tokens this issuer signs. It is
+// also the audience of tokens meant for the registry itself.
+func (t *TokenIssuer) IssuerURL() string { return t.issuer }
+
 // NAPEndorsementClaims are the JWT claims for a NAP agent endorsement.
 // The endorsement is embedded in the agent's A2A card (nap:endorsement field)
 // and can be verified by any party with access to the registry's JWKS endpoint.
@@ -141,10 +362,68 @@ func (t *TokenIssuer) IssueEndorsement(agentURI, trustTier, certSerial, registry
 		CertSerial: certSerial,
 		Registry:   registry,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	signed, err := token.SignedString(t.key)
+	signed, err := t.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("sign endorsement: %w", err)
 	}
 	return signed, nil
 }
+
+// NAPTreeHeadClaims are the JWT claims for a signed trust ledger tree head.
+// A tree head commits the registry to the Merkle root over the first
+// TreeSize ledger entries at Timestamp (Unix milliseconds). It carries no
+// expiry: heads are permanent and are meant to be pinned by auditors.
+type NAPTreeHeadClaims struct {
+	jwt.RegisteredClaims
+	TreeSize  int    `json:"nap:tree_size"`
+	RootHash  string `json:"nap:root_hash"`
+	Timestamp int64  `json:"nap:timestamp"`
+}
+
+// SignTreeHead signs a trust ledger tree head as a compact JWS. The token
+// carries the kid of the registry signing key so that it can be verified
+// against the JWKS endpoint.
+func (t *TokenIssuer) SignTreeHead(treeSize int, rootHash string, timestamp time.Time) (string, error) {
+	claims := NAPTreeHeadClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   t.issuer,
+			IssuedAt: jwt.NewNumericDate(timestamp),
+		},
+		TreeSize:  treeSize,
+		RootHash:  rootHash,
+		Timestamp: timestamp.UnixMilli(),
+	}
+	signed, err := t.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("sign tree head: %w", err)
+	}
+	return signed, nil
+}
+
+// VerifyTreeHead parses and validates a signed tree head produced by
+// SignTreeHead, returning its claims on success.
+func (t *TokenIssuer) VerifyTreeHead(tokenStr string) (*NAPTreeHeadClaims, error) {
+	return verifyTreeHead(tokenStr, t.keys, t.issuer)
+}
+
+// VerifyTreeHeadWithKey validates a signed tree head against a registry
+// public key (typically fetched from /.well-known/jwks.json) and issuer.
+func VerifyTreeHeadWithKey(tokenStr string, pub crypto.PublicKey, issuer string) (*NAPTreeHeadClaims, error) {
+	if _, err := jwtSigningMethod(pub); err != nil {
+		return nil, fmt.Errorf("verify tree head: %w", err)
+	}
+	return verifyTreeHead(tokenStr, StaticKeySet{pub}, issuer)
+}
+
+func verifyTreeHead(tokenStr string, keys KeySet, issuer string) (*NAPTreeHeadClaims, error) {
+	token, err := parseJWTWithKeys(tokenStr, &NAPTreeHeadClaims{}, keys, jwt.WithIssuer(issuer))
+	if err != nil {
+		return nil, fmt.Errorf("verify tree head: %w", err)
+	}
+
+	claims, ok := token.Claims.(*NAPTreeHeadClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid tree head claims")
+	}
+	return claims, nil
+}
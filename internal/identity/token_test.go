@@ -1,6 +1,12 @@
 package identity_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -137,3 +143,228 @@ func TestHasScope(t *testing.T) {
 
 // err is declared at package level to avoid "declared and not used" in tamper test.
 var err error
+
+func TestTokenIssuer_SignTreeHead_roundTrip(t *testing.T) {
+	ti := newTestTokenIssuer(t)
+	ts := time.Now().UTC().Truncate(time.Millisecond)
+
+	signed, err := ti.SignTreeHead(42, "abc123", ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := identity.VerifyTreeHeadWithKey(signed, ti.PublicKey(), "https://registry.nexusagentprotocol.com")
+	if err != nil {
+		t.Fatalf("VerifyTreeHeadWithKey() error: %v", err)
+	}
+	if claims.TreeSize != 42 || claims.RootHash != "abc123" {
+		t.Errorf("claims: got size=%d root=%q", claims.TreeSize, claims.RootHash)
+	}
+	if claims.Timestamp != ts.UnixMilli() {
+		t.Errorf("timestamp: got %d, want %d", claims.Timestamp, ts.UnixMilli())
+	}
+}
+
+func TestTokenIssuer_VerifyTreeHead_wrongKey(t *testing.T) {
+	ti := newTestTokenIssuer(t)
+	other := newTestTokenIssuer(t)
+
+	signed, err := other.SignTreeHead(1, "abc123", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.VerifyTreeHead(signed); err == nil {
+		t.Error("expected tree head signed by another key to be rejected")
+	}
+}
+
+func TestTaskTokenVerifier_publicKeyOnly(t *testing.T) {
+	ti := newTestTokenIssuer(t)
+	token, err := ti.Issue("agent://nexusagentprotocol.com/assistant/agent_xyz", []string{"agent:resolve"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := identity.NewTaskTokenVerifier(ti.PublicKey(), "https://registry.nexusagentprotocol.com")
+	claims, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if claims.AgentURI != "agent://nexusagentprotocol.com/assistant/agent_xyz" {
+		t.Errorf("AgentURI: got %q", claims.AgentURI)
+	}
+
+	other := identity.NewTaskTokenVerifier(newTestTokenIssuer(t).PublicKey(), "https://registry.nexusagentprotocol.com")
+	if _, err := other.Verify(token); err == nil {
+		t.Error("expected token signed by another key to be rejected")
+	}
+}
+
+func TestTokenIssuer_keyAlgorithms(t *testing.T) {
+	cases := map[identity.KeyAlgorithm]string{
+		identity.KeyAlgorithmRSA:       "RS256",
+		identity.KeyAlgorithmECDSAP256: "ES256",
+		identity.KeyAlgorithmEd25519:   "EdDSA",
+	}
+	for alg, jwsAlg := range cases {
+		t.Run(string(alg), func(t *testing.T) {
+			ca := identity.NewCAManager(t.TempDir())
+			ca.SetKeyAlgorithm(alg)
+			if err := ca.Create(); err != nil {
+				t.Fatal(err)
+			}
+			ti := identity.NewTokenIssuer(ca.Key(), "https://registry.nexusagentprotocol.com", time.Hour)
+			if ti.Algorithm() != jwsAlg {
+				t.Errorf("Algorithm() = %q, want %q", ti.Algorithm(), jwsAlg)
+			}
+
+			token, err := ti.Issue("agent://nexusagentprotocol.com/edge/agent_small", []string{"agent:resolve"})
+			if err != nil {
+				t.Fatalf("Issue() error: %v", err)
+			}
+			header, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+			if !strings.Contains(string(header), `"alg":"`+jwsAlg+`"`) {
+				t.Errorf("token header = %s, want alg %s", header, jwsAlg)
+			}
+			if _, err := ti.Verify(token); err != nil {
+				t.Errorf("Verify() error: %v", err)
+			}
+
+			jwk, err := identity.PublicKeyToJWK(ti.PublicKey(), identity.SigningKeyID)
+			if err != nil {
+				t.Fatalf("PublicKeyToJWK() error: %v", err)
+			}
+			if jwk.Alg != jwsAlg {
+				t.Errorf("JWK alg = %q, want %q", jwk.Alg, jwsAlg)
+			}
+		})
+	}
+}
+
+func TestVerifyTaskTokenWithKey_rejectsOtherAlgorithm(t *testing.T) {
+	rsaIssuer := newTestTokenIssuer(t)
+	token, err := rsaIssuer.Issue("agent://nexusagentprotocol.com/edge/agent_small", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := identity.NewCAManager(t.TempDir())
+	ca.SetKeyAlgorithm(identity.KeyAlgorithmECDSAP256)
+	if err := ca.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := identity.VerifyTaskTokenWithKey(token, ca.Key().Public(), "https://registry.nexusagentprotocol.com"); err == nil {
+		t.Error("RS256 token verified against an ECDSA key")
+	}
+}
+
+func TestPublicKeyToJWK_shapes(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwk, err := identity.PublicKeyToJWK(ecKey.Public(), "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+	y, _ := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" || len(x) != 32 || len(y) != 32 || jwk.N != "" {
+		t.Errorf("EC JWK = %+v, want kty EC, crv P-256, 32-byte x and y", jwk)
+	}
+
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	jwk, err = identity.PublicKeyToJWK(edPub, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := base64.RawURLEncoding.DecodeString(jwk.X); jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+		t.Errorf("OKP JWK = %+v, want kty OKP, crv Ed25519, 32-byte x", jwk)
+	}
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if _, err := identity.PublicKeyToJWK(p384.Public(), "k"); err == nil {
+		t.Error("P-384 key should be rejected")
+	}
+}
+
+func TestTokenIssuer_IssueForAudience(t *testing.T) {
+	ti := newTestTokenIssuer(t)
+	agentB := "agent://acme.com/finance/agent_b"
+
+	token, err := ti.IssueForAudience("agent://acme.com/finance/agent_a", []string{"agent:call"}, agentB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.VerifyForAudience(token, agentB); err != nil {
+		t.Errorf("VerifyForAudience(%s) error: %v", agentB, err)
+	}
+	if _, err := ti.VerifyForAudience(token, "agent://acme.com/finance/agent_c"); !errors.Is(err, identity.ErrTokenAudience) {
+		t.Errorf("token for agent B accepted by agent C: err = %v", err)
+	}
+	if _, err := ti.VerifyForAudience(token, ti.IssuerURL()); !errors.Is(err, identity.ErrTokenAudience) {
+		t.Errorf("token for agent B accepted by the registry: err = %v", err)
+	}
+
+	// Tokens without an audience predate restriction and stay valid anywhere.
+	legacy, _ := ti.Issue("agent://acme.com/finance/agent_a", nil)
+	if _, err := ti.VerifyForAudience(legacy, agentB); err != nil {
+		t.Errorf("unrestricted token rejected: %v", err)
+	}
+}
+
+func TestTokenIssuer_Exchange(t *testing.T) {
+	ti := newTestTokenIssuer(t)
+	caller := "agent://acme.com/assistant/agent_u"
+	agentA := "agent://acme.com/finance/agent_a"
+	agentB := "agent://acme.com/finance/agent_b"
+	agentC := "agent://acme.com/finance/agent_c"
+
+	upstream, _ := ti.IssueForAudience(caller, []string{"agent:call", "agent:resolve"}, agentA)
+	subject, err := ti.VerifyForAudience(upstream, agentA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delegated, claims, err := ti.Exchange(subject, agentA, agentB, []string{"agent:call"}, nil)
+	if err != nil {
+		t.Fatalf("Exchange() error: %v", err)
+	}
+	got, err := ti.VerifyForAudience(delegated, agentB)
+	if err != nil {
+		t.Fatalf("delegated token does not verify at agent B: %v", err)
+	}
+	if got.Subject != caller || got.AgentURI != caller {
+		t.Errorf("delegated subject = %q / %q, want the upstream caller", got.Subject, got.AgentURI)
+	}
+	if actors := got.Actors(); len(actors) != 1 || actors[0] != agentA {
+		t.Errorf("actors = %v, want [%s]", actors, agentA)
+	}
+	if len(got.Scopes) != 1 || got.Scopes[0] != "agent:call" {
+		t.Errorf("scopes = %v, want narrowed to [agent:call]", got.Scopes)
+	}
+	if claims.ExpiresAt.After(subject.ExpiresAt.Time) {
+		t.Error("delegated token outlives the subject token")
+	}
+
+	// B passes it on to C; the chain grows with the most recent actor first.
+	_, chained, err := ti.Exchange(got, agentB, agentC, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actors := chained.Actors(); len(actors) != 2 || actors[0] != agentB || actors[1] != agentA {
+		t.Errorf("chained actors = %v, want [%s %s]", actors, agentB, agentA)
+	}
+
+	for name, tc := range map[string]struct {
+		actor, audience string
+		scopes          []string
+		want            error
+	}{
+		"not issued for actor": {agentB, agentC, nil, identity.ErrExchangeNotAllowed},
+		"wider scope":          {agentA, agentB, []string{"nexus:admin"}, identity.ErrInvalidScope},
+		"registry audience":    {agentA, ti.IssuerURL(), nil, identity.ErrInvalidTarget},
+		"no audience":          {agentA, "", nil, identity.ErrInvalidTarget},
+	} {
+		if _, _, err := ti.Exchange(subject, tc.actor, tc.audience, tc.scopes, nil); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package identity
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"fmt"
 	"time"
 
@@ -20,30 +20,54 @@ type UserTokenClaims struct {
 	Role     string `json:"role,omitempty"` // "admin" when set
 }
 
-// UserTokenIssuer issues and verifies user session JWTs using the Nexus CA RSA key.
+// UserTokenIssuer issues and verifies user session JWTs with the registry's
+// token signing keys.
 type UserTokenIssuer struct {
-	key    *rsa.PrivateKey
-	pub    *rsa.PublicKey
-	issuer string
-	ttl    time.Duration
+	keys     *KeyRing
+	issuer   string
+	ttl      time.Duration
+	denylist *Denylist // nil = tokens are valid until they expire
 }
 
-// NewUserTokenIssuer creates a UserTokenIssuer.
+// NewUserTokenIssuer creates a UserTokenIssuer that signs with key alone.
 //
 //	issuerURL — The "iss" claim value; matches the registry's base URL.
 //	ttl        — Token lifetime (default: 24 hours).
-func NewUserTokenIssuer(key *rsa.PrivateKey, issuerURL string, ttl time.Duration) *UserTokenIssuer {
+func NewUserTokenIssuer(key crypto.Signer, issuerURL string, ttl time.Duration) *UserTokenIssuer {
+	return NewUserTokenIssuerWithKeyRing(NewKeyRing(key, SigningKeyID), issuerURL, ttl)
+}
+
+// NewUserTokenIssuerWithKeyRing creates a UserTokenIssuer that signs with the
+// active key of a rotating KeyRing and accepts every key it publishes.
+func NewUserTokenIssuerWithKeyRing(keys *KeyRing, issuerURL string, ttl time.Duration) *UserTokenIssuer {
 	if ttl == 0 {
 		ttl = 24 * time.Hour
 	}
 	return &UserTokenIssuer{
-		key:    key,
-		pub:    &key.PublicKey,
+		keys:   keys,
 		issuer: issuerURL,
 		ttl:    ttl,
 	}
 }
 
+// SetDenylist makes Verify reject revoked session and admin tokens: those
+// listed by jti, and those of a user whose tokens have all been revoked.
+func (u *UserTokenIssuer) SetDenylist(d *Denylist) {
+	u.denylist = d
+}
+
+// Denylist returns the issuer's Denylist, or nil.
+func (u *UserTokenIssuer) Denylist() *Denylist { return u.denylist }
+
+// TTL returns the lifetime of user session tokens.
+func (u *UserTokenIssuer) TTL() time.Duration { return u.ttl }
+
+// sign signs claims with the active key, naming it in the "kid" header.
+func (u *UserTokenIssuer) sign(claims jwt.Claims) (string, error) {
+	kid, key := u.keys.Active()
+	return signJWT(key, claims, kid)
+}
+
 // Issue creates a signed user session token.
 func (u *UserTokenIssuer) Issue(userID, email, username string) (string, error) {
 	now := time.Now().UTC()
@@ -60,8 +84,7 @@ func (u *UserTokenIssuer) Issue(userID, email, username string) (string, error)
 		Username: username,
 		Type:     "user",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	signed, err := token.SignedString(u.key)
+	signed, err := u.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("sign user token: %w", err)
 	}
@@ -69,16 +92,9 @@ func (u *UserTokenIssuer) Issue(userID, email, username string) (string, error)
 }
 
 // Verify parses and validates a user session token, returning its claims.
+// With a Denylist, revoked tokens fail with ErrTokenRevoked.
 func (u *UserTokenIssuer) Verify(tokenStr string) (*UserTokenClaims, error) {
-	token, err := jwt.ParseWithClaims(
-		tokenStr,
-		&UserTokenClaims{},
-		func(tok *jwt.Token) (any, error) {
-			if _, ok := tok.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", tok.Header["alg"])
-			}
-			return u.pub, nil
-		},
+	token, err := parseJWTWithKeys(tokenStr, &UserTokenClaims{}, u.keys,
 		jwt.WithIssuer(u.issuer),
 		jwt.WithExpirationRequired(),
 	)
@@ -92,6 +108,11 @@ func (u *UserTokenIssuer) Verify(tokenStr string) (*UserTokenClaims, error) {
 	if claims.Type != "user" && claims.Type != "admin" {
 		return nil, fmt.Errorf("not a user session token")
 	}
+	if u.denylist != nil {
+		if err := u.denylist.Check(&claims.RegisteredClaims, claims.Subject); err != nil {
+			return nil, fmt.Errorf("verify user token: %w", err)
+		}
+	}
 	return claims, nil
 }
 
@@ -115,8 +136,7 @@ func (u *UserTokenIssuer) IssueAdminToken(ttl time.Duration) (string, error) {
 		Type:   "admin",
 		Role:   "admin",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	signed, err := token.SignedString(u.key)
+	signed, err := u.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("sign admin token: %w", err)
 	}
@@ -138,8 +158,7 @@ func (u *UserTokenIssuer) IssueOAuthState(provider string) (string, error) {
 		UserID: provider, // encode provider in UserID field
 		Type:   "oauth-state",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	signed, err := token.SignedString(u.key)
+	signed, err := u.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("sign oauth state: %w", err)
 	}
@@ -148,15 +167,7 @@ func (u *UserTokenIssuer) IssueOAuthState(provider string) (string, error) {
 
 // VerifyOAuthState validates an OAuth state JWT and returns the embedded provider.
 func (u *UserTokenIssuer) VerifyOAuthState(tokenStr string) (provider string, err error) {
-	token, err := jwt.ParseWithClaims(
-		tokenStr,
-		&UserTokenClaims{},
-		func(tok *jwt.Token) (any, error) {
-			if _, ok := tok.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
-			}
-			return u.pub, nil
-		},
+	token, err := parseJWTWithKeys(tokenStr, &UserTokenClaims{}, u.keys,
 		jwt.WithIssuer(u.issuer),
 		jwt.WithExpirationRequired(),
 	)
@@ -0,0 +1,243 @@
+// Package invalidation pushes agent lifecycle changes from the registry to
+// resolver instances so they can drop cached resolutions immediately instead
+// of serving a revoked or moved agent until the cache TTL expires.
+//
+// The registry POSTs a JSON Event to each configured resolver. Requests are
+// authenticated with an HMAC-SHA256 signature over the timestamp and body,
+// keyed by a secret shared between the registry and its resolvers:
+//
+//	X-NAP-Timestamp: <unix seconds>
+//	X-NAP-Signature: sha256=<hex(HMAC(secret, timestamp + "." + body))>
+//
+// Resolvers reject requests whose timestamp is more than MaxClockSkew away
+// from their own clock. Replaying a request inside that window is harmless:
+// invalidation is idempotent.
+package invalidation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Event types pushed to resolvers. They match the webhook event names where
+// one exists.
+const (
+	EventAgentActivated     = "agent.activated"
+	EventAgentUpdated       = "agent.updated"
+	EventAgentRevoked       = "agent.revoked"
+	EventAgentSuspended     = "agent.suspended"
+	EventAgentRestored      = "agent.restored"
+	EventAgentDeprecated    = "agent.deprecated"
+	EventAgentDeleted       = "agent.deleted"
+	EventAgentExpired       = "agent.expired"
+	EventAgentRenewed       = "agent.renewed"
+	EventAgentHealthChanged = "agent.health_changed"
+)
+
+// Header names used to authenticate pushed events.
+const (
+	TimestampHeader = "X-NAP-Timestamp"
+	SignatureHeader = "X-NAP-Signature"
+)
+
+// MaxClockSkew is how far a pushed event's timestamp may differ from the
+// receiver's clock.
+const MaxClockSkew = 5 * time.Minute
+
+// ErrBadSignature is returned by Verify when a request is not authentic.
+var ErrBadSignature = errors.New("invalid invalidation signature")
+
+// Event describes a change to one agent. Resolvers invalidate every cached
+// resolution for TrustRoot + AgentID, whatever capability path was used to
+// look it up.
+type Event struct {
+	Type      string    `json:"type"`
+	URI       string    `json:"uri"`
+	TrustRoot string    `json:"trust_root"`
+	AgentID   string    `json:"agent_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Sign returns the X-NAP-Signature value for body sent at ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a pushed event.
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, now time.Time) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrBadSignature)
+	}
+	ts := time.Unix(unix, 0)
+	if d := now.Sub(ts); d > MaxClockSkew || d < -MaxClockSkew {
+		return fmt.Errorf("%w: timestamp outside allowed skew", ErrBadSignature)
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signatureHeader)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ── Notifier (registry side) ────────────────────────────────────────────────
+
+// MetricsRecorder is an optional callback for recording push outcomes.
+type MetricsRecorder func(success bool)
+
+// Notifier pushes Events to a fixed set of resolver endpoints.
+type Notifier struct {
+	targets    []string
+	secret     string
+	httpClient *http.Client
+	delays     []time.Duration
+	onMetrics  MetricsRecorder
+	logger     *zap.Logger
+}
+
+// NewNotifier creates a Notifier that POSTs events to each target URL
+// (e.g. "http://resolver-1:9091/v1/invalidate").
+func NewNotifier(targets []string, secret string, logger *zap.Logger) *Notifier {
+	return &Notifier{
+		targets:    targets,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		delays:     []time.Duration{0, 500 * time.Millisecond, 2 * time.Second},
+		logger:     logger,
+	}
+}
+
+// SetMetricsRecorder configures the metrics callback.
+func (n *Notifier) SetMetricsRecorder(fn MetricsRecorder) {
+	n.onMetrics = fn
+}
+
+// SetRetryDelays replaces the delays before each delivery attempt. The number
+// of delays is the number of attempts per target.
+func (n *Notifier) SetRetryDelays(delays []time.Duration) {
+	n.delays = delays
+}
+
+// NotifyAgentChange pushes a change event to every target without blocking
+// the caller. Implements service.ResolverNotifier.
+func (n *Notifier) NotifyAgentChange(ctx context.Context, eventType, uri, trustRoot, agentID string) {
+	ev := Event{
+		Type:      eventType,
+		URI:       uri,
+		TrustRoot: trustRoot,
+		AgentID:   agentID,
+		Timestamp: time.Now().UTC(),
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		n.logger.Error("invalidation: marshal event", zap.Error(err))
+		return
+	}
+
+	// The push must outlive the HTTP request that triggered it.
+	ctx = context.WithoutCancel(ctx)
+	for _, target := range n.targets {
+		go n.deliver(ctx, target, body, ev.Timestamp)
+	}
+}
+
+// deliver sends body to a single target, retrying on failure.
+func (n *Notifier) deliver(ctx context.Context, target string, body []byte, ts time.Time) {
+	sig := Sign(n.secret, ts, body)
+	for attempt, delay := range n.delays {
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		err := n.post(ctx, target, body, ts, sig)
+		if n.onMetrics != nil {
+			n.onMetrics(err == nil)
+		}
+		if err == nil {
+			return
+		}
+		n.logger.Warn("invalidation: push failed",
+			zap.String("target", target),
+			zap.Int("attempt", attempt+1),
+			zap.Error(err),
+		)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, target string, body []byte, ts time.Time, sig string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(SignatureHeader, sig)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1024)) //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ── Handler (resolver side) ─────────────────────────────────────────────────
+
+// NewHandler returns an http.Handler that authenticates pushed events and
+// passes them to apply.
+func NewHandler(secret string, apply func(Event), logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 64*1024))
+		if err != nil {
+			http.Error(w, "read body", http.StatusBadRequest)
+			return
+		}
+		if err := Verify(secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Now()); err != nil {
+			logger.Warn("invalidation: rejected push", zap.String("remote", r.RemoteAddr), zap.Error(err))
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var ev Event
+		if err := json.Unmarshal(body, &ev); err != nil {
+			http.Error(w, "decode event", http.StatusBadRequest)
+			return
+		}
+		if ev.TrustRoot == "" || ev.AgentID == "" {
+			http.Error(w, "trust_root and agent_id are required", http.StatusBadRequest)
+			return
+		}
+
+		apply(ev)
+		logger.Info("invalidation applied",
+			zap.String("type", ev.Type),
+			zap.String("uri", ev.URI),
+		)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,79 @@
+package invalidation_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/invalidation"
+	"go.uber.org/zap"
+)
+
+func TestNotifierToHandler(t *testing.T) {
+	got := make(chan invalidation.Event, 1)
+	srv := httptest.NewServer(invalidation.NewHandler("s3cret", func(ev invalidation.Event) {
+		got <- ev
+	}, zap.NewNop()))
+	defer srv.Close()
+
+	n := invalidation.NewNotifier([]string{srv.URL}, "s3cret", zap.NewNop())
+	results := make(chan bool, 1)
+	n.SetMetricsRecorder(func(ok bool) { results <- ok })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.NotifyAgentChange(ctx, invalidation.EventAgentRevoked, "agent://acme.com/finance/agent_x", "acme.com", "agent_x")
+	cancel() // the push must survive the caller's context ending
+
+	select {
+	case ev := <-got:
+		if ev.Type != invalidation.EventAgentRevoked || ev.TrustRoot != "acme.com" || ev.AgentID != "agent_x" {
+			t.Errorf("event = %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler never received the event")
+	}
+	if ok := <-results; !ok {
+		t.Error("delivery recorded as failed")
+	}
+}
+
+func TestNotifier_retriesOnWrongSecret(t *testing.T) {
+	srv := httptest.NewServer(invalidation.NewHandler("right", func(invalidation.Event) {
+		t.Error("event applied despite bad signature")
+	}, zap.NewNop()))
+	defer srv.Close()
+
+	n := invalidation.NewNotifier([]string{srv.URL}, "wrong", zap.NewNop())
+	n.SetRetryDelays([]time.Duration{0, time.Millisecond})
+	results := make(chan bool, 2)
+	n.SetMetricsRecorder(func(ok bool) { results <- ok })
+
+	n.NotifyAgentChange(context.Background(), invalidation.EventAgentUpdated, "agent://a/b/c", "a", "c")
+	for i := 0; i < 2; i++ {
+		if ok := <-results; ok {
+			t.Errorf("attempt %d succeeded with the wrong secret", i+1)
+		}
+	}
+}
+
+func TestHandler_rejectsStaleTimestamp(t *testing.T) {
+	h := invalidation.NewHandler("s3cret", func(invalidation.Event) {
+		t.Error("stale event applied")
+	}, zap.NewNop())
+
+	body := `{"type":"agent.revoked","trust_root":"a","agent_id":"c"}`
+	ts := time.Now().Add(-2 * invalidation.MaxClockSkew)
+	req := httptest.NewRequest(http.MethodPost, "/v1/invalidate", strings.NewReader(body))
+	req.Header.Set(invalidation.TimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(invalidation.SignatureHeader, invalidation.Sign("s3cret", ts, []byte(body)))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,11 +30,13 @@ type userLookup interface {
 
 // AgentHandler handles HTTP requests for the agent registry.
 type AgentHandler struct {
-	svc        *service.AgentService
-	tokens     *identity.TokenIssuer     // nil = no agent token auth enforcement
-	userTokens *identity.UserTokenIssuer // nil = no user token support
-	ownerSvc   userLookup               // nil = no owner attribution
-	logger     *zap.Logger
+	svc             *service.AgentService
+	tokens          *identity.TokenIssuer     // nil = no agent token auth enforcement
+	binding         *identity.BindingVerifier // checks sender-constrained task tokens
+	bindingRequired bool                      // reject task tokens that are not sender-constrained
+	userTokens      *identity.UserTokenIssuer // nil = no user token support
+	ownerSvc        userLookup                // nil = no owner attribution
+	logger          *zap.Logger
 }
 
 // SetUserLookup configures the user lookup service used to attach owner info to GetAgent responses.
@@ -42,7 +47,7 @@ func (h *AgentHandler) SetUserLookup(ul userLookup) {
 // NewAgentHandler creates a new AgentHandler.
 // tokens and userTokens may be nil to disable JWT auth on protected routes.
 func NewAgentHandler(svc *service.AgentService, tokens *identity.TokenIssuer, logger *zap.Logger) *AgentHandler {
-	return &AgentHandler{svc: svc, tokens: tokens, logger: logger}
+	return &AgentHandler{svc: svc, tokens: tokens, binding: identity.NewBindingVerifier(""), logger: logger}
 }
 
 // SetUserTokenIssuer configures user JWT support for ownership checks.
@@ -50,13 +55,25 @@ func (h *AgentHandler) SetUserTokenIssuer(ut *identity.UserTokenIssuer) {
 	h.userTokens = ut
 }
 
+// SetTokenBinding sets the verifier that checks sender-constrained Task
+// Tokens are presented by their holder, over mTLS with the bound certificate
+// or with a DPoP proof. With required set, tokens that are not
+// sender-constrained are rejected as well.
+func (h *AgentHandler) SetTokenBinding(b *identity.BindingVerifier, required bool) {
+	h.binding = b
+	h.bindingRequired = required
+}
+
 // requireToken returns the RequireToken middleware when agent auth is configured,
 // or a no-op middleware for development/open mode.
 func (h *AgentHandler) requireToken() gin.HandlerFunc {
 	if h.tokens == nil {
 		return func(c *gin.Context) { c.Next() }
 	}
-	return identity.RequireToken(h.tokens)
+	if h.bindingRequired {
+		return identity.RequireBoundToken(h.tokens, h.binding)
+	}
+	return identity.RequireToken(h.tokens, h.binding)
 }
 
 // requireUserToken returns the RequireUserToken middleware when user auth is configured,
@@ -74,7 +91,10 @@ func (h *AgentHandler) optionalAgentToken() gin.HandlerFunc {
 	if h.tokens == nil {
 		return func(c *gin.Context) { c.Next() }
 	}
-	return identity.OptionalToken(h.tokens)
+	if h.bindingRequired {
+		return identity.OptionalBoundToken(h.tokens, h.binding)
+	}
+	return identity.OptionalToken(h.tokens, h.binding)
 }
 
 // optionalUserToken tries to parse a user JWT from the Authorization header
@@ -110,11 +130,16 @@ func (h *AgentHandler) Register(rg *gin.RouterGroup) {
 		agents.PATCH("/:id", h.optionalAgentToken(), h.optionalUserToken(), h.UpdateAgent)
 		agents.DELETE("/:id", h.optionalAgentToken(), h.optionalUserToken(), h.DeleteAgent)
 		agents.POST("/:id/activate", h.optionalAgentToken(), h.optionalUserToken(), h.ActivateAgent)
+		agents.POST("/:id/renew", h.optionalAgentToken(), h.optionalUserToken(), h.RenewAgentCert)
 		agents.POST("/:id/revoke", h.requireToken(), h.optionalUserToken(), h.RevokeAgent)
 		agents.POST("/:id/suspend", h.optionalAgentToken(), h.optionalUserToken(), h.SuspendAgent)
 		agents.POST("/:id/restore", h.optionalAgentToken(), h.optionalUserToken(), h.RestoreAgent)
 		agents.POST("/:id/deprecate", h.optionalAgentToken(), h.optionalUserToken(), h.DeprecateAgent)
 		agents.POST("/:id/report-abuse", h.requireUserToken(), h.ReportAbuseProxy)
+		agents.GET("/:id/revisions", h.optionalAgentToken(), h.optionalUserToken(), h.ListRevisions)
+		agents.GET("/:id/revisions/diff", h.optionalAgentToken(), h.optionalUserToken(), h.DiffRevisions)
+		agents.GET("/:id/revisions/:rev", h.optionalAgentToken(), h.optionalUserToken(), h.GetRevision)
+		agents.POST("/:id/revisions/:rev/rollback", h.optionalAgentToken(), h.optionalUserToken(), h.RollbackAgent)
 	}
 
 	rg.GET("/resolve", h.ResolveAgent)
@@ -122,6 +147,9 @@ func (h *AgentHandler) Register(rg *gin.RouterGroup) {
 	rg.GET("/lookup", h.LookupByDomain)
 	rg.GET("/capabilities", h.GetCapabilities)
 	rg.GET("/crl", h.GetCRL)
+	rg.GET("/crl.der", h.GetCRLDER)
+	rg.POST("/ocsp", h.OCSP)
+	rg.GET("/ocsp/*request", h.OCSP)
 	rg.GET("/users/me/agents", h.requireUserToken(), h.ListMyAgents)
 }
 
@@ -333,12 +361,17 @@ func (h *AgentHandler) UpdateAgent(c *gin.Context) {
 		}
 	}
 
+	req.Actor = callerActor(c)
 	agent, err := h.svc.Update(c.Request.Context(), id, &req)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
 			return
 		}
+		if errors.Is(err, service.ErrInvalidEndpoints) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update agent"})
 		return
 	}
@@ -449,12 +482,21 @@ func (h *AgentHandler) ActivateAgent(c *gin.Context) {
 		return
 	}
 
-	result, err := h.svc.Activate(ctx, id)
+	var req certRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	result, err := h.svc.ActivateWithCSR(ctx, id, req.CSRPEM)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
 			return
 		}
+		if errors.Is(err, service.ErrInvalidCSR) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("activate agent", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to activate agent"})
 		return
@@ -466,14 +508,7 @@ func (h *AgentHandler) ActivateAgent(c *gin.Context) {
 	}
 
 	if result.CertPEM != "" {
-		resp["certificate"] = gin.H{
-			"serial":     result.Serial,
-			"pem":        result.CertPEM,
-			"expires_at": result.ExpiresAt,
-		}
-		resp["private_key_pem"] = result.KeyPEM
-		resp["ca_pem"] = result.CAPEM
-		resp["warning"] = "Store private_key_pem securely. It will not be shown again."
+		addCertBundle(resp, result)
 	}
 
 	if result.AgentCardJSON != "" {
@@ -489,6 +524,87 @@ func (h *AgentHandler) ActivateAgent(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// RenewAgentCert handles POST /agents/:id/renew — issues a new certificate
+// for an active, deprecated or expired agent without changing its URI.
+func (h *AgentHandler) RenewAgentCert(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid agent ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !h.authorizeAgentAction(c, ctx, id, "renew") {
+		return
+	}
+
+	var req certRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	result, err := h.svc.RenewCert(ctx, id, req.CSRPEM)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		case errors.Is(err, service.ErrInvalidCSR):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotRenewable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrRenewalUnavailable):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("renew agent certificate", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to renew certificate"})
+		}
+		return
+	}
+
+	resp := gin.H{
+		"status": "renewed",
+		"agent":  result.Agent,
+	}
+	addCertBundle(resp, result)
+	c.JSON(http.StatusOK, resp)
+}
+
+// certRequest is the optional body of the activate and renew endpoints.
+// With csr_pem set the certificate is issued for the CSR's key and no private
+// key is returned.
+type certRequest struct {
+	CSRPEM string `json:"csr_pem"`
+}
+
+// bindOptionalJSON binds an optional JSON body into req. An empty body leaves
+// req unchanged; a body that does not bind gets a 400, so a mistyped CSR is
+// never silently replaced by a registry-generated key, and it returns false.
+func bindOptionalJSON(c *gin.Context, req any) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// addCertBundle adds the issued certificate, CA and — when the registry
+// generated the key — the private key to an activate or renew response.
+func addCertBundle(resp gin.H, result *service.ActivationResult) {
+	resp["certificate"] = gin.H{
+		"serial":     result.Serial,
+		"pem":        result.CertPEM,
+		"expires_at": result.ExpiresAt,
+	}
+	resp["ca_pem"] = result.CAPEM
+	if result.KeyPEM != "" {
+		resp["private_key_pem"] = result.KeyPEM
+		resp["warning"] = "Store private_key_pem securely. It will not be shown again."
+	}
+}
+
 // RevokeAgent handles POST /agents/:id/revoke — marks agent as revoked.
 func (h *AgentHandler) RevokeAgent(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
@@ -534,12 +650,18 @@ func (h *AgentHandler) RevokeAgent(c *gin.Context) {
 	}
 
 	var body struct {
-		Reason string `json:"reason"`
+		Reason     string `json:"reason"`
+		ReasonCode string `json:"reason_code"` // RFC 5280 name, e.g. "keyCompromise"
 	}
 	// Ignore parse errors — reason is optional.
 	_ = c.ShouldBindJSON(&body)
+	code, err := identity.ParseRevocationReason(body.ReasonCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := h.svc.Revoke(ctx, id, body.Reason); err != nil {
+	if err := h.svc.RevokeWithCode(ctx, id, body.Reason, code); err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
 			return
@@ -585,27 +707,40 @@ func (h *AgentHandler) ResolveAgent(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":          agent.ID,
-		"uri":         agent.URI(),
-		"endpoint":    agent.Endpoint,
-		"status":      agent.Status,
-		"cert_serial": agent.CertSerial,
-	})
+	resp := gin.H{
+		"id":            agent.ID,
+		"uri":           agent.URI(),
+		"endpoint":      agent.Endpoint,
+		"status":        agent.Status,
+		"cert_serial":   agent.CertSerial,
+		"trust_tier":    agent.ComputeTrustTier(),
+		"health_status": agent.HealthStatus,
+	}
+	if agent.SunsetDate != nil {
+		resp["sunset_date"] = agent.SunsetDate.UTC()
+	}
+	if agent.ReplacementURI != "" {
+		resp["replacement_uri"] = agent.ReplacementURI
+	}
+	if len(agent.Endpoints) > 0 {
+		resp["endpoints"] = agent.Endpoints
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // agentCardView is the public-facing shape returned by the lookup endpoint.
 // It contains only what a consumer needs to discover and connect to an agent —
 // no internal fields like cert_serial or public_key_pem.
 type agentCardView struct {
-	ID             string           `json:"id"`
-	URI            string           `json:"uri"`
-	DisplayName    string           `json:"display_name"`
-	Description    string           `json:"description"`
-	CapabilityNode string           `json:"capability_node"`
-	Endpoint       string           `json:"endpoint"`
-	TrustTier      model.TrustTier  `json:"trust_tier"`
-	Metadata       model.AgentMeta  `json:"metadata,omitempty"`
+	ID             string                `json:"id"`
+	URI            string                `json:"uri"`
+	DisplayName    string                `json:"display_name"`
+	Description    string                `json:"description"`
+	CapabilityNode string                `json:"capability_node"`
+	Endpoint       string                `json:"endpoint"`
+	Endpoints      []model.AgentEndpoint `json:"endpoints,omitempty"`
+	TrustTier      model.TrustTier       `json:"trust_tier"`
+	Metadata       model.AgentMeta       `json:"metadata,omitempty"`
 }
 
 func toCardView(a *model.Agent) agentCardView {
@@ -616,6 +751,7 @@ func toCardView(a *model.Agent) agentCardView {
 		Description:    a.Description,
 		CapabilityNode: a.CapabilityNode,
 		Endpoint:       a.Endpoint,
+		Endpoints:      a.Endpoints,
 		TrustTier:      a.TrustTier,
 		Metadata:       a.Metadata,
 	}
@@ -899,9 +1035,10 @@ func (h *AgentHandler) DeprecateAgent(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "deprecated"})
 }
 
-// GetCRL handles GET /crl — returns a JSON certificate revocation list.
+// GetCRL handles GET /crl — returns the certificate revocation list as JSON.
+// TLS stacks should use the signed DER CRL at /crl.der instead.
 func (h *AgentHandler) GetCRL(c *gin.Context) {
-	agents, err := h.svc.ListRevokedCerts(c.Request.Context())
+	revoked, err := h.svc.RevokedCertificates(c.Request.Context())
 	if err != nil {
 		h.logger.Error("list revoked certs", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list revoked certificates"})
@@ -911,15 +1048,17 @@ func (h *AgentHandler) GetCRL(c *gin.Context) {
 	type crlEntry struct {
 		CertSerial string `json:"cert_serial"`
 		Reason     string `json:"reason"`
+		ReasonCode string `json:"reason_code"`
 		RevokedAt  string `json:"revoked_at"`
 	}
 
-	entries := make([]crlEntry, 0, len(agents))
-	for _, a := range agents {
+	entries := make([]crlEntry, 0, len(revoked))
+	for _, rc := range revoked {
 		entries = append(entries, crlEntry{
-			CertSerial: a.CertSerial,
-			Reason:     a.RevocationReason,
-			RevokedAt:  a.UpdatedAt.Format(time.RFC3339),
+			CertSerial: rc.Serial,
+			Reason:     rc.Reason,
+			ReasonCode: identity.RevocationReason(rc.ReasonCode).String(),
+			RevokedAt:  rc.RevokedAt.UTC().Format(time.RFC3339),
 		})
 	}
 
@@ -930,6 +1069,75 @@ func (h *AgentHandler) GetCRL(c *gin.Context) {
 	})
 }
 
+// GetCRLDER handles GET /crl.der — returns the CA-signed X.509 CRL
+// (RFC 5280), the URL in issued certificates' CRL Distribution Points. The
+// optional "ca" parameter selects the CA generation that signs it; without
+// it the active CA signs.
+func (h *AgentHandler) GetCRLDER(c *gin.Context) {
+	der, err := h.svc.CRL(c.Request.Context(), c.Query("ca"))
+	if err != nil {
+		if errors.Is(err, service.ErrRevocationUnavailable) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, identity.ErrUnknownCA) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no such CA; it may have retired"})
+			return
+		}
+		h.logger.Error("generate CRL", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate CRL"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "application/pkix-crl", der)
+}
+
+// OCSP handles POST /ocsp and GET /ocsp/{base64 request} — the RFC 6960
+// responder named in issued certificates' Authority Information Access.
+// Request problems are answered with unsigned OCSP error responses, as
+// clients expect.
+func (h *AgentHandler) OCSP(c *gin.Context) {
+	var reqDER []byte
+	if c.Request.Method == http.MethodPost {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, 64<<10))
+		if err != nil {
+			c.Data(http.StatusOK, ocspContentType, identity.OCSPMalformedRequestResponse)
+			return
+		}
+		reqDER = body
+	} else {
+		// The request is base64 (RFC 4648 §4), URL-encoded, and may contain '/'.
+		encoded := strings.TrimPrefix(c.Param("request"), "/")
+		if unescaped, err := url.PathUnescape(encoded); err == nil {
+			encoded = unescaped
+		}
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			c.Data(http.StatusOK, ocspContentType, identity.OCSPMalformedRequestResponse)
+			return
+		}
+		reqDER = der
+	}
+
+	resp, err := h.svc.OCSPResponse(c.Request.Context(), reqDER)
+	switch {
+	case err == nil:
+		c.Header("Cache-Control", "public, max-age=300")
+		c.Data(http.StatusOK, ocspContentType, resp)
+	case errors.Is(err, service.ErrRevocationUnavailable), errors.Is(err, identity.ErrOCSPUnsupportedKey):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	case errors.Is(err, identity.ErrOCSPMalformedRequest):
+		c.Data(http.StatusOK, ocspContentType, identity.OCSPMalformedRequestResponse)
+	case errors.Is(err, identity.ErrOCSPWrongIssuer):
+		c.Data(http.StatusOK, ocspContentType, identity.OCSPUnauthorizedResponse)
+	default:
+		h.logger.Error("OCSP response", zap.Error(err))
+		c.Data(http.StatusOK, ocspContentType, identity.OCSPInternalErrorResponse)
+	}
+}
+
+const ocspContentType = "application/ocsp-response"
+
 // BatchResolve handles POST /resolve/batch — resolves multiple URIs in one call.
 func (h *AgentHandler) BatchResolve(c *gin.Context) {
 	var req struct {
@@ -945,10 +1153,11 @@ func (h *AgentHandler) BatchResolve(c *gin.Context) {
 	}
 
 	type batchResult struct {
-		URI      string `json:"uri"`
-		Endpoint string `json:"endpoint,omitempty"`
-		Status   string `json:"status,omitempty"`
-		Error    string `json:"error,omitempty"`
+		URI       string                `json:"uri"`
+		Endpoint  string                `json:"endpoint,omitempty"`
+		Endpoints []model.AgentEndpoint `json:"endpoints,omitempty"`
+		Status    string                `json:"status,omitempty"`
+		Error     string                `json:"error,omitempty"`
 	}
 
 	ctx := c.Request.Context()
@@ -976,9 +1185,10 @@ func (h *AgentHandler) BatchResolve(c *gin.Context) {
 			}
 
 			results[idx] = batchResult{
-				URI:      u,
-				Endpoint: agent.Endpoint,
-				Status:   string(agent.Status),
+				URI:       u,
+				Endpoint:  agent.Endpoint,
+				Endpoints: agent.Endpoints,
+				Status:    string(agent.Status),
 			}
 		}(i, rawURI)
 	}
@@ -318,8 +318,8 @@ func (s *stubAgentRepo) ListActiveEndpoints(_ context.Context) ([]*model.Agent,
 	return nil, nil
 }
 
-func (s *stubAgentRepo) UpdateHealthStatus(_ context.Context, id uuid.UUID, status string, lastSeenAt time.Time) error {
-	return nil
+func (s *stubAgentRepo) UpdateHealthStatus(_ context.Context, id uuid.UUID, status string, lastSeenAt time.Time) (bool, error) {
+	return false, nil
 }
 
 func (s *stubAgentRepo) SearchBySkill(_ context.Context, skillID string, limit, offset int) ([]*model.Agent, error) {
@@ -611,6 +611,28 @@ func TestActivateAgent_200(t *testing.T) {
 	}
 }
 
+func TestActivateAgent_400_badBody(t *testing.T) {
+	repo := newStubAgentRepo()
+	router, _, _ := setupTestRouter(t, repo, false)
+
+	created := registerAgent(t, router)
+	id := created["id"].(string)
+
+	for _, body := range []string{`{"csr_pem": 42}`, `csr_pem=...`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/agents/"+id+"/activate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+	if stored, _ := repo.GetByID(context.Background(), uuid.MustParse(id)); stored.Status == model.AgentStatusActive {
+		t.Error("agent was activated despite the unreadable body")
+	}
+}
+
 func TestActivateAgent_404(t *testing.T) {
 	router, _, _ := setupTestRouter(t, newStubAgentRepo(), false)
 
@@ -764,6 +786,46 @@ func TestRevokeAgent_200_adminScope(t *testing.T) {
 	}
 }
 
+func TestRevokeAgent_400_unknownReasonCode(t *testing.T) {
+	repo := newStubAgentRepo()
+	router, svc, tokens := setupTestRouter(t, repo, true)
+
+	created := registerAgent(t, router)
+	id := created["id"].(string)
+	uid, _ := uuid.Parse(id)
+	svc.Activate(context.Background(), uid)
+
+	tok, _ := tokens.Issue("agent://admin.io/system/admin_1", []string{"nexus:admin"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/agents/"+id+"/revoke",
+		strings.NewReader(`{"reason":"stolen","reason_code":"lostInTheMail"}`))
+	req.Header.Set("Authorization", "Bearer "+tok)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if agent, _ := svc.Get(context.Background(), uid); agent.Status == model.AgentStatusRevoked {
+		t.Error("agent was revoked despite the invalid reason code")
+	}
+}
+
+func TestOCSP_501_withoutCertificateStore(t *testing.T) {
+	repo := newStubAgentRepo()
+	router, _, _ := setupTestRouter(t, repo, false)
+
+	for _, path := range []string{"/api/v1/crl.der", "/api/v1/ocsp/MAA%3D"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("GET %s: expected 501, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
 func TestResolveAgent_200(t *testing.T) {
 	repo := newStubAgentRepo()
 	router, svc, _ := setupTestRouter(t, repo, false)
@@ -785,6 +847,41 @@ func TestResolveAgent_200(t *testing.T) {
 	}
 }
 
+func TestResolveAgent_deprecatedIncludesSuccessor(t *testing.T) {
+	repo := newStubAgentRepo()
+	router, svc, _ := setupTestRouter(t, repo, false)
+
+	created := registerAgent(t, router)
+	uid, _ := uuid.Parse(created["id"].(string))
+	svc.Activate(context.Background(), uid)
+	if err := svc.Deprecate(context.Background(), uid, &model.DeprecateRequest{
+		SunsetDate:     "2030-01-31",
+		ReplacementURI: "agent://example.com/finance/agent_next",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	agent, _ := svc.Get(context.Background(), uid)
+
+	url := "/api/v1/resolve?trust_root=example.com&capability_node=finance&agent_id=" + agent.AgentID
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body map[string]any
+	json.Unmarshal(w.Body.Bytes(), &body) //nolint:errcheck
+	if body["replacement_uri"] != "agent://example.com/finance/agent_next" {
+		t.Errorf("replacement_uri = %v", body["replacement_uri"])
+	}
+	if body["sunset_date"] != "2030-01-31T00:00:00Z" {
+		t.Errorf("sunset_date = %v", body["sunset_date"])
+	}
+	if body["trust_tier"] == "" || body["trust_tier"] == nil {
+		t.Error("trust_tier missing")
+	}
+}
+
 func TestResolveAgent_400_missingParams(t *testing.T) {
 	router, _, _ := setupTestRouter(t, newStubAgentRepo(), false)
 
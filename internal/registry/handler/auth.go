@@ -36,7 +36,7 @@ type userSvc interface {
 	ResendVerification(ctx context.Context, userID uuid.UUID) error
 	ResendVerificationByEmail(ctx context.Context, email string) error
 	ForgotPassword(ctx context.Context, email string) error
-	ResetPassword(ctx context.Context, token, newPassword string) error
+	ResetPassword(ctx context.Context, token, newPassword string) (*users.User, error)
 	GetOrCreateFromOAuth(ctx context.Context, provider, providerID, email, displayName string) (*users.User, bool, error)
 }
 
@@ -276,14 +276,29 @@ func (h *AuthHandler) ResendVerification(c *gin.Context) {
 }
 
 // Logout handles POST /auth/logout.
-// JWTs are stateless so revocation is client-side: the client discards the token.
-// This endpoint exists to give clients a clean logout call and returns the token
-// lifetime so the client knows when the existing token would have expired anyway.
+// The session token in the Authorization header is revoked on the server, so
+// it stops working at once rather than when it expires; the client should
+// still discard it. A missing or invalid token is not an error.
 func (h *AuthHandler) Logout(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "logged out — discard your token client-side",
-		"note":    "NAP JWTs are stateless; remove the token from local storage to complete logout",
-	})
+	denylist := h.tokens.Denylist()
+	if denylist == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "logged out — discard your token client-side",
+			"note":    "token revocation is not enabled; the token stays valid until it expires",
+		})
+		return
+	}
+
+	if tokenStr, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		if claims, err := h.tokens.Verify(tokenStr); err == nil {
+			if err := denylist.RevokeToken(c.Request.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+				h.logger.Error("revoke session token", zap.String("user_id", claims.UserID), zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+				return
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out — the token has been revoked"})
 }
 
 // ForgotPassword handles POST /auth/forgot-password.
@@ -303,7 +318,8 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	})
 }
 
-// ResetPassword handles POST /auth/reset-password.
+// ResetPassword handles POST /auth/reset-password. Every session token the
+// user holds is revoked.
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req resetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -311,11 +327,22 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.users.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+	u, err := h.users.ResetPassword(c.Request.Context(), req.Token, req.Password)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// Sign out every session: whoever asked for the reset may not be the only
+	// one holding a token.
+	if denylist := h.tokens.Denylist(); denylist != nil {
+		if err := denylist.RevokeSubject(c.Request.Context(), u.ID.String()); err != nil {
+			h.logger.Error("revoke tokens after password reset", zap.String("user_id", u.ID.String()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "password updated, but existing sessions could not be signed out"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "password updated — please log in with your new password"})
 }
 
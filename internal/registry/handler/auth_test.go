@@ -32,6 +32,7 @@ type stubUserSvc struct {
 	oauthUser   *users.User
 	oauthNew    bool
 	oauthErr    error
+	resetUser   *users.User
 }
 
 func (s *stubUserSvc) Signup(_ context.Context, email, _, _ string) (*users.User, string, error) {
@@ -75,7 +76,13 @@ func (s *stubUserSvc) ResendVerification(_ context.Context, _ uuid.UUID) error {
 
 func (s *stubUserSvc) ResendVerificationByEmail(_ context.Context, _ string) error { return nil }
 func (s *stubUserSvc) ForgotPassword(_ context.Context, _ string) error            { return nil }
-func (s *stubUserSvc) ResetPassword(_ context.Context, _, _ string) error          { return nil }
+
+func (s *stubUserSvc) ResetPassword(_ context.Context, _, _ string) (*users.User, error) {
+	if s.resetUser != nil {
+		return s.resetUser, nil
+	}
+	return &users.User{ID: uuid.New()}, nil
+}
 
 func (s *stubUserSvc) GetOrCreateFromOAuth(_ context.Context, _, _, email, _ string) (*users.User, bool, error) {
 	if s.oauthErr != nil {
@@ -242,3 +249,43 @@ func TestOAuthRedirect_422_unconfiguredProvider(t *testing.T) {
 		t.Fatalf("expected 422, got %d", w.Code)
 	}
 }
+
+func TestLogout_revokesToken(t *testing.T) {
+	router, userTokens := setupAuthRouter(t, &stubUserSvc{})
+	userTokens.SetDenylist(identity.NewDenylist(nil, time.Hour))
+	tok, _ := userTokens.Issue(uuid.New().String(), "alice@example.com", "alice")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := userTokens.Verify(tok); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token still valid after logout: err = %v", err)
+	}
+}
+
+func TestResetPassword_revokesSessions(t *testing.T) {
+	user := &users.User{ID: uuid.New()}
+	router, userTokens := setupAuthRouter(t, &stubUserSvc{resetUser: user})
+	userTokens.SetDenylist(identity.NewDenylist(nil, time.Hour))
+	session, _ := userTokens.Issue(user.ID.String(), "alice@example.com", "alice")
+	other, _ := userTokens.Issue(uuid.New().String(), "bob@example.com", "bob")
+
+	body := `{"token":"reset-tok","password":"new-password"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/reset-password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := userTokens.Verify(session); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("session still valid after password reset: err = %v", err)
+	}
+	if _, err := userTokens.Verify(other); err != nil {
+		t.Errorf("another user's session: %v", err)
+	}
+}
@@ -1,24 +1,61 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/uri"
 	"go.uber.org/zap"
 )
 
 // IdentityHandler handles authentication, token issuance, and identity endpoints.
 type IdentityHandler struct {
-	issuer *identity.Issuer
-	tokens *identity.TokenIssuer
-	logger *zap.Logger
+	issuer     *identity.Issuer
+	tokens     *identity.TokenIssuer
+	userTokens *identity.UserTokenIssuer // nil = no admin clients; user tokens are never introspected
+	binding    *identity.BindingVerifier
+	grantable  map[string]bool // scopes a client_credentials caller may request
+	logger     *zap.Logger
 }
 
 // NewIdentityHandler creates an IdentityHandler.
 func NewIdentityHandler(issuer *identity.Issuer, tokens *identity.TokenIssuer, logger *zap.Logger) *IdentityHandler {
-	return &IdentityHandler{issuer: issuer, tokens: tokens, logger: logger}
+	return &IdentityHandler{
+		issuer:    issuer,
+		tokens:    tokens,
+		binding:   identity.NewBindingVerifier(""),
+		grantable: scopeSet(defaultScopes()),
+		logger:    logger,
+	}
+}
+
+// SetGrantableScopes adds scopes, beyond the default set, that an agent may
+// request for its own Task Tokens. Scopes under the reserved "nexus:" prefix
+// are never granted through the token endpoint, since they authorize actions
+// on other agents' registrations.
+func (h *IdentityHandler) SetGrantableScopes(scopes []string) {
+	for _, s := range scopes {
+		if !strings.HasPrefix(s, reservedScopePrefix) {
+			h.grantable[s] = true
+		}
+	}
+}
+
+// SetBindingVerifier sets the verifier for DPoP proofs sent to the token
+// endpoint, so that it can share its replay cache and public URL with the
+// routes that check token binding.
+func (h *IdentityHandler) SetBindingVerifier(b *identity.BindingVerifier) {
+	h.binding = b
+}
+
+// SetUserTokenIssuer lets admins authenticate to the introspection and
+// revocation endpoints, and those endpoints handle user session tokens.
+func (h *IdentityHandler) SetUserTokenIssuer(u *identity.UserTokenIssuer) {
+	h.userTokens = u
 }
 
 // Register wires the identity routes onto the API group.
@@ -27,8 +64,41 @@ func (h *IdentityHandler) Register(rg *gin.RouterGroup) {
 	// mTLS-protected: exchange client cert → Task Token
 	rg.POST("/token", identity.RequireMTLS(h.issuer), h.IssueToken)
 
+	// mTLS or admin token: RFC 7662 introspection and RFC 7009 revocation
+	rg.POST("/token/introspect", h.Introspect)
+	rg.POST("/token/revoke", h.Revoke)
+
 	// Public: download the Nexus CA certificate (needed to configure mTLS clients)
 	rg.GET("/ca.crt", h.GetCACert)
+	rg.GET("/ca.der", h.GetCACertDER)
+}
+
+// OAuth grant and token type URNs accepted by the token endpoint.
+const (
+	grantClientCredentials = "client_credentials"
+	grantTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	tokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// tokenRequest is the body of POST /api/v1/token, form-encoded or JSON.
+type tokenRequest struct {
+	GrantType          string `json:"grant_type"           form:"grant_type"`
+	Scope              string `json:"scope"                form:"scope"`
+	Audience           string `json:"audience"             form:"audience"`
+	Resource           string `json:"resource"             form:"resource"`
+	SubjectToken       string `json:"subject_token"        form:"subject_token"`
+	SubjectTokenType   string `json:"subject_token_type"   form:"subject_token_type"`
+	ActorToken         string `json:"actor_token"          form:"actor_token"`
+	RequestedTokenType string `json:"requested_token_type" form:"requested_token_type"`
+}
+
+// audience returns the requested audience; RFC 8693 allows either parameter.
+func (r *tokenRequest) audience() string {
+	if r.Audience != "" {
+		return r.Audience
+	}
+	return r.Resource
 }
 
 // IssueToken handles POST /api/v1/token.
@@ -38,10 +108,22 @@ func (h *IdentityHandler) Register(rg *gin.RouterGroup) {
 //
 //	Request (form or JSON):
 //	  grant_type: "client_credentials"   (required)
-//	  scope:      "agent:resolve agent:call"  (optional; defaults to full set)
+//	  scope:      "agent:resolve agent:call"  (optional; defaults to full set;
+//	              nexus:admin and other reserved scopes are refused)
+//	  audience:   "agent://acme.com/finance/agent_b"  (optional; the agent the
+//	              token will be sent to, or the registry's issuer URL)
 //
 //	Response:
 //	  {"access_token":"...", "token_type":"Bearer", "expires_in":3600, "scope":"..."}
+//
+// The token is bound to the caller's client certificate ("cnf" claim with
+// x5t#S256, RFC 8705). A caller that will present it where it cannot use
+// mTLS sends a DPoP proof header instead; the token is then bound to the
+// proof's key ("cnf" with jkt, RFC 9449) and token_type is "DPoP".
+//
+// With grant_type urn:ietf:params:oauth:grant-type:token-exchange (RFC 8693)
+// the caller exchanges a token it received (subject_token) for a delegated
+// token to call audience on the original caller's behalf; see exchangeToken.
 func (h *IdentityHandler) IssueToken(c *gin.Context) {
 	agentURI := identity.AgentURIFromCtx(c)
 	if agentURI == "" {
@@ -49,27 +131,54 @@ func (h *IdentityHandler) IssueToken(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		GrantType string `json:"grant_type" form:"grant_type"`
-		Scope     string `json:"scope"      form:"scope"`
-	}
+	var req tokenRequest
 	_ = c.ShouldBind(&req)
 
-	if req.GrantType != "" && req.GrantType != "client_credentials" {
+	switch req.GrantType {
+	case "", grantClientCredentials:
+	case grantTokenExchange:
+		h.exchangeToken(c, agentURI, &req)
+		return
+	default:
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "unsupported_grant_type",
-			"error_description": "only client_credentials is supported",
+			"error_description": "supported grant types are client_credentials and " + grantTokenExchange,
 		})
 		return
 	}
 
+	audience := req.audience()
+	if audience != "" && audience != h.tokens.IssuerURL() {
+		if _, err := uri.Parse(audience); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_target",
+				"error_description": "audience must be an agent:// URI or " + h.tokens.IssuerURL(),
+			})
+			return
+		}
+	}
+
+	cnf, tokenType, ok := h.confirmation(c)
+	if !ok {
+		return
+	}
+
 	// Parse requested scopes (space-separated); fall back to defaults.
 	scopes := defaultScopes()
 	if req.Scope != "" {
 		scopes = splitScopes(req.Scope)
 	}
+	for _, s := range scopes {
+		if !h.grantable[s] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_scope",
+				"error_description": "scope " + s + " cannot be requested",
+			})
+			return
+		}
+	}
 
-	token, err := h.tokens.Issue(agentURI, scopes)
+	token, err := h.tokens.IssueBound(agentURI, scopes, audience, cnf)
 	if err != nil {
 		h.logger.Error("issue token", zap.String("agent_uri", agentURI), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue token"})
@@ -78,17 +187,106 @@ func (h *IdentityHandler) IssueToken(c *gin.Context) {
 
 	h.logger.Info("token issued",
 		zap.String("agent_uri", agentURI),
+		zap.String("audience", audience),
 		zap.Strings("scopes", scopes),
+		zap.String("token_type", tokenType),
 	)
 
 	c.JSON(http.StatusOK, gin.H{
 		"access_token": token,
-		"token_type":   "Bearer",
+		"token_type":   tokenType,
 		"expires_in":   int(h.tokens.TTL().Seconds()),
 		"scope":        strings.Join(scopes, " "),
 	})
 }
 
+// exchangeToken handles the RFC 8693 token-exchange grant. The mTLS client is
+// the actor; subject_token is a Task Token issued for it, usually by its own
+// caller. The result is a token for audience whose subject is the original
+// caller, with the actor recorded in the "act" claim and scopes no wider than
+// the subject token's.
+func (h *IdentityHandler) exchangeToken(c *gin.Context, actorURI string, req *tokenRequest) {
+	tokenError := func(code, desc string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": code, "error_description": desc})
+	}
+	switch {
+	case req.SubjectToken == "":
+		tokenError("invalid_request", "subject_token is required")
+		return
+	case req.SubjectTokenType != tokenTypeAccessToken && req.SubjectTokenType != tokenTypeJWT:
+		tokenError("invalid_request", "subject_token_type must be "+tokenTypeAccessToken)
+		return
+	case req.ActorToken != "":
+		tokenError("invalid_request", "actor_token is not supported; the actor is the mTLS client")
+		return
+	case req.RequestedTokenType != "" && req.RequestedTokenType != tokenTypeAccessToken:
+		tokenError("invalid_request", "requested_token_type must be "+tokenTypeAccessToken)
+		return
+	}
+
+	subject, err := h.tokens.Verify(req.SubjectToken)
+	if err != nil {
+		tokenError("invalid_grant", "subject_token: "+err.Error())
+		return
+	}
+	var scopes []string
+	if req.Scope != "" {
+		scopes = strings.Fields(req.Scope)
+	}
+	cnf, tokenType, ok := h.confirmation(c)
+	if !ok {
+		return
+	}
+
+	token, claims, err := h.tokens.Exchange(subject, actorURI, req.audience(), scopes, cnf)
+	switch {
+	case errors.Is(err, identity.ErrInvalidTarget):
+		tokenError("invalid_target", err.Error())
+		return
+	case errors.Is(err, identity.ErrInvalidScope):
+		tokenError("invalid_scope", err.Error())
+		return
+	case errors.Is(err, identity.ErrExchangeNotAllowed):
+		tokenError("invalid_grant", err.Error())
+		return
+	case err != nil:
+		h.logger.Error("exchange token", zap.String("actor", actorURI), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue token"})
+		return
+	}
+
+	h.logger.Info("token exchanged",
+		zap.String("subject", claims.Subject),
+		zap.Strings("actors", claims.Actors()),
+		zap.Strings("audience", claims.Audience),
+		zap.Strings("scopes", claims.Scopes),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":      token,
+		"issued_token_type": tokenTypeAccessToken,
+		"token_type":        tokenType,
+		"expires_in":        int(time.Until(claims.ExpiresAt.Time).Seconds()),
+		"scope":             strings.Join(claims.Scopes, " "),
+	})
+}
+
+// confirmation returns the "cnf" claim for a token issued to the mTLS client
+// of c and the token_type to report: the key of a DPoP proof when the request
+// carries one, else the client certificate. On a bad proof it writes the
+// RFC 9449 invalid_dpop_proof error and returns ok = false.
+func (h *IdentityHandler) confirmation(c *gin.Context) (cnf *identity.Confirmation, tokenType string, ok bool) {
+	if c.GetHeader("DPoP") != "" {
+		jkt, err := h.binding.VerifyDPoP(c.Request, "")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_dpop_proof", "error_description": err.Error()})
+			return nil, "", false
+		}
+		return &identity.Confirmation{JKT: jkt}, "DPoP", true
+	}
+	return identity.CertConfirmation(c.Request.TLS.PeerCertificates[0]), "Bearer", true
+}
+
 // GetCACert handles GET /api/v1/ca.crt — returns the Nexus CA certificate in PEM format.
 // Clients download this to configure their TLS trust store before connecting with mTLS.
 // In federated mode the intermediate CA cert is returned so remote clients can
@@ -98,11 +296,32 @@ func (h *IdentityHandler) GetCACert(c *gin.Context) {
 	c.String(http.StatusOK, h.issuer.CACertPEM())
 }
 
+// GetCACertDER handles GET /api/v1/ca.der — the same certificate as /ca.crt in
+// DER form. Issued certificates point here from their Authority Information
+// Access extension, which RFC 5280 requires to be DER.
+func (h *IdentityHandler) GetCACertDER(c *gin.Context) {
+	c.Data(http.StatusOK, "application/pkix-cert", h.issuer.CACertDER())
+}
+
+// reservedScopePrefix marks registry-administration scopes such as
+// nexus:admin, which are only issued out of band and never to an agent asking
+// for its own token.
+const reservedScopePrefix = "nexus:"
+
 // defaultScopes returns the standard set of Task Token scopes.
 func defaultScopes() []string {
 	return []string{"agent:resolve", "agent:call", "agent:register"}
 }
 
+// scopeSet returns scopes as a set.
+func scopeSet(scopes []string) map[string]bool {
+	set := make(map[string]bool, len(scopes))
+	for _, s := range scopes {
+		set[s] = true
+	}
+	return set
+}
+
 // splitScopes parses a space-separated scope string.
 func splitScopes(scope string) []string {
 	parts := strings.Fields(scope)
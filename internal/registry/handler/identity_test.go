@@ -0,0 +1,276 @@
+package handler_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/handler"
+	"go.uber.org/zap"
+)
+
+const (
+	tokenCaller = "agent://acme.com/assistant/agent_u"
+	tokenAgentA = "agent://acme.com/finance/agent_a"
+	tokenAgentB = "agent://acme.com/finance/agent_b"
+)
+
+func setupIdentityRouter(t *testing.T) (*gin.Engine, *identity.Issuer, *identity.TokenIssuer) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ca := testCA(t)
+	issuer := identity.NewIssuer(ca)
+	tokens := identity.NewTokenIssuer(ca.Key(), "http://test", time.Hour)
+	r := gin.New()
+	handler.NewIdentityHandler(issuer, tokens, zap.NewNop()).Register(r.Group("/api/v1"))
+	return r, issuer, tokens
+}
+
+// tokenRequest builds POST /api/v1/token over a simulated mTLS connection
+// as agentURI, returning the client certificate used.
+func tokenRequest(t *testing.T, issuer *identity.Issuer, agentURI string, form url.Values) (*http.Request, *x509.Certificate) {
+	t.Helper()
+	cert, err := issuer.IssueAgentCert(agentURI, "owner", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "https://registry.test/api/v1/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert.Cert}}
+	return req, cert.Cert
+}
+
+// serveToken sends a token request to router and decodes the response.
+func serveToken(router *gin.Engine, req *http.Request) (int, map[string]any) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+// postToken calls POST /api/v1/token over a simulated mTLS connection as agentURI.
+func postToken(t *testing.T, router *gin.Engine, issuer *identity.Issuer, agentURI string, form url.Values) (int, map[string]any) {
+	t.Helper()
+	req, _ := tokenRequest(t, issuer, agentURI, form)
+	return serveToken(router, req)
+}
+
+func TestIssueToken_audience(t *testing.T) {
+	router, issuer, tokens := setupIdentityRouter(t)
+
+	code, resp := postToken(t, router, issuer, tokenAgentA, url.Values{"audience": {tokenAgentB}})
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, resp)
+	}
+	claims, err := tokens.Verify(resp["access_token"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != tokenAgentB {
+		t.Errorf("aud = %v, want [%s]", claims.Audience, tokenAgentB)
+	}
+
+	code, resp = postToken(t, router, issuer, tokenAgentA, url.Values{"audience": {"https://evil.example"}})
+	if code != http.StatusBadRequest || resp["error"] != "invalid_target" {
+		t.Errorf("foreign audience: got %d %v, want 400 invalid_target", code, resp)
+	}
+}
+
+func TestIssueToken_refusesReservedScopes(t *testing.T) {
+	router, issuer, _ := setupIdentityRouter(t)
+
+	for _, scope := range []string{"nexus:admin", "agent:call nexus:admin", "custom:read"} {
+		code, resp := postToken(t, router, issuer, tokenAgentA, url.Values{"scope": {scope}})
+		if code != http.StatusBadRequest || resp["error"] != "invalid_scope" {
+			t.Errorf("scope %q: got %d %v, want 400 invalid_scope", scope, code, resp)
+		}
+	}
+	if code, resp := postToken(t, router, issuer, tokenAgentA, url.Values{"scope": {"agent:call"}}); code != http.StatusOK {
+		t.Errorf("scope agent:call: got %d %v, want 200", code, resp)
+	}
+}
+
+func TestIssueToken_tokenExchange(t *testing.T) {
+	router, issuer, tokens := setupIdentityRouter(t)
+	upstream, _ := tokens.IssueForAudience(tokenCaller, []string{"agent:call", "agent:resolve"}, tokenAgentA)
+
+	exchange := url.Values{
+		"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":      {upstream},
+		"subject_token_type": {"urn:ietf:params:oauth:token-type:access_token"},
+		"audience":           {tokenAgentB},
+		"scope":              {"agent:call"},
+	}
+	code, resp := postToken(t, router, issuer, tokenAgentA, exchange)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, resp)
+	}
+	if resp["issued_token_type"] != "urn:ietf:params:oauth:token-type:access_token" || resp["scope"] != "agent:call" {
+		t.Errorf("exchange response = %v", resp)
+	}
+	claims, err := tokens.VerifyForAudience(resp["access_token"].(string), tokenAgentB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != tokenCaller || claims.Act == nil || claims.Act.Subject != tokenAgentA {
+		t.Errorf("delegated claims: sub %q act %+v", claims.Subject, claims.Act)
+	}
+
+	// Agent B cannot exchange a token that was issued for agent A.
+	code, resp = postToken(t, router, issuer, tokenAgentB, exchange)
+	if code != http.StatusBadRequest || resp["error"] != "invalid_grant" {
+		t.Errorf("exchange by another agent: got %d %v, want 400 invalid_grant", code, resp)
+	}
+
+	exchange.Set("scope", "nexus:admin")
+	if code, resp = postToken(t, router, issuer, tokenAgentA, exchange); resp["error"] != "invalid_scope" {
+		t.Errorf("widened scope: got %d %v, want invalid_scope", code, resp)
+	}
+}
+
+func TestRequireToken_rejectsOtherAudience(t *testing.T) {
+	repo := newStubAgentRepo()
+	router, _, tokens := setupTestRouter(t, repo, true)
+	agent := registerAgent(t, router)
+	id := agent["id"].(string)
+
+	tok, _ := tokens.IssueForAudience("agent://admin.io/system/admin_1", []string{"nexus:admin"}, tokenAgentB)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/agents/"+id+"/revoke", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("token for another agent accepted by the registry: got %d", w.Code)
+	}
+}
+
+func TestIssueToken_boundToCertificateOrDPoPKey(t *testing.T) {
+	router, issuer, tokens := setupIdentityRouter(t)
+
+	req, cert := tokenRequest(t, issuer, tokenAgentA, url.Values{"audience": {tokenAgentB}})
+	code, resp := serveToken(router, req)
+	if code != http.StatusOK || resp["token_type"] != "Bearer" {
+		t.Fatalf("mTLS token: got %d %v", code, resp)
+	}
+	claims, _ := tokens.Verify(resp["access_token"].(string))
+	if claims.Cnf == nil || claims.Cnf.X5TS256 != identity.CertThumbprint(cert) {
+		t.Errorf("cnf = %+v, want x5t#S256 of the client certificate", claims.Cnf)
+	}
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	jwk, _ := identity.PublicKeyToJWK(key.Public(), "")
+	proof := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"jti": "proof-1", "htm": "POST", "htu": "https://registry.test/api/v1/token", "iat": time.Now().Unix(),
+	})
+	proof.Header["typ"] = "dpop+jwt"
+	proof.Header["jwk"] = map[string]string{"kty": jwk.Kty, "crv": jwk.Crv, "x": jwk.X}
+	signed, _ := proof.SignedString(key)
+
+	req, _ = tokenRequest(t, issuer, tokenAgentA, url.Values{"audience": {tokenAgentB}})
+	req.Header.Set("DPoP", signed)
+	code, resp = serveToken(router, req)
+	if code != http.StatusOK || resp["token_type"] != "DPoP" {
+		t.Fatalf("DPoP token: got %d %v", code, resp)
+	}
+	claims, _ = tokens.Verify(resp["access_token"].(string))
+	jkt, _ := identity.JWKThumbprint(key.Public())
+	if claims.Cnf == nil || claims.Cnf.JKT != jkt || claims.Cnf.X5TS256 != "" {
+		t.Errorf("cnf = %+v, want jkt %s", claims.Cnf, jkt)
+	}
+
+	// The same proof cannot be used twice.
+	req, _ = tokenRequest(t, issuer, tokenAgentA, nil)
+	req.Header.Set("DPoP", signed)
+	if code, resp = serveToken(router, req); code != http.StatusBadRequest || resp["error"] != "invalid_dpop_proof" {
+		t.Errorf("replayed proof: got %d %v, want 400 invalid_dpop_proof", code, resp)
+	}
+}
+
+func TestIntrospectAndRevoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ca := testCA(t)
+	issuer := identity.NewIssuer(ca)
+	denylist := identity.NewDenylist(nil, time.Hour)
+	tokens := identity.NewTokenIssuer(ca.Key(), "http://test", time.Hour)
+	tokens.SetDenylist(denylist)
+	userTokens := identity.NewUserTokenIssuer(ca.Key(), "http://test", time.Hour)
+	userTokens.SetDenylist(denylist)
+	h := handler.NewIdentityHandler(issuer, tokens, zap.NewNop())
+	h.SetUserTokenIssuer(userTokens)
+	router := gin.New()
+	h.Register(router.Group("/api/v1"))
+
+	admin, _ := userTokens.IssueAdminToken(time.Hour)
+	// call posts token to path as agentURI over mTLS, or as an admin when
+	// agentURI is empty.
+	call := func(path, agentURI, token string) (int, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "https://registry.test"+path, strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if agentURI != "" {
+			cert, err := issuer.IssueAgentCert(agentURI, "owner", time.Hour, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert.Cert}}
+		} else {
+			req.Header.Set("Authorization", "Bearer "+admin)
+		}
+		return serveToken(router, req)
+	}
+
+	tok, _ := tokens.IssueForAudience(tokenAgentA, []string{"agent:call"}, tokenAgentB)
+	code, resp := call("/api/v1/token/introspect", tokenAgentB, tok)
+	if code != http.StatusOK || resp["active"] != true || resp["sub"] != tokenAgentA || resp["scope"] != "agent:call" {
+		t.Fatalf("introspect as the audience: %d %v", code, resp)
+	}
+	if _, resp := call("/api/v1/token/introspect", tokenCaller, tok); resp["active"] != false {
+		t.Errorf("introspect as an unrelated agent: %v", resp)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/token/introspect", strings.NewReader("token="+tok))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if code, _ := serveToken(router, req); code != http.StatusUnauthorized {
+		t.Errorf("introspect without client authentication: got %d", code)
+	}
+
+	// Only the agent a token was issued to, or an admin, may revoke it.
+	if code, resp := call("/api/v1/token/revoke", tokenAgentB, tok); code != http.StatusForbidden {
+		t.Errorf("revoke as the audience: %d %v", code, resp)
+	}
+	if code, resp := call("/api/v1/token/revoke", tokenAgentA, tok); code != http.StatusOK {
+		t.Fatalf("revoke as the holder: %d %v", code, resp)
+	}
+	if _, resp := call("/api/v1/token/introspect", "", tok); resp["active"] != false {
+		t.Errorf("introspect a revoked token: %v", resp)
+	}
+	if code, _ := call("/api/v1/token/revoke", tokenAgentA, tok); code != http.StatusOK {
+		t.Errorf("revoking twice: got %d, want 200", code)
+	}
+
+	// User tokens are visible to admins only.
+	session, _ := userTokens.Issue("user-1", "alice@example.com", "alice")
+	if _, resp := call("/api/v1/token/introspect", tokenAgentA, session); resp["active"] != false {
+		t.Errorf("agent introspecting a user token: %v", resp)
+	}
+	if _, resp := call("/api/v1/token/introspect", "", session); resp["active"] != true || resp["token_use"] != "user" || resp["username"] != "alice" {
+		t.Errorf("admin introspecting a user token: %v", resp)
+	}
+	if code, _ := call("/api/v1/token/revoke", "", session); code != http.StatusOK {
+		t.Errorf("admin revoking a user token: got %d", code)
+	}
+	if _, err := userTokens.Verify(session); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("revoked user token: err = %v", err)
+	}
+}
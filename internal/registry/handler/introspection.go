@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"go.uber.org/zap"
+)
+
+// tokenParamsRequest is the body of the introspection and revocation
+// endpoints, form-encoded or JSON. token_type_hint is accepted and ignored:
+// the registry tells its token types apart by their claims.
+type tokenParamsRequest struct {
+	Token         string `json:"token"           form:"token"`
+	TokenTypeHint string `json:"token_type_hint" form:"token_type_hint"`
+}
+
+// tokenClient authenticates the caller of the introspection and revocation
+// endpoints: an agent by its mTLS client certificate, or an admin by an admin
+// token. Otherwise it writes an invalid_client error and returns ok = false.
+func (h *IdentityHandler) tokenClient(c *gin.Context) (agentURI string, admin, ok bool) {
+	if c.Request.TLS != nil && len(c.Request.TLS.PeerCertificates) > 0 {
+		cert := c.Request.TLS.PeerCertificates[0]
+		if _, err := h.issuer.VerifyPeerCert(cert); err == nil {
+			if agentURI, err := identity.AgentURIFromCert(cert); err == nil {
+				return agentURI, false, true
+			}
+		}
+	}
+	if tokenStr, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found && h.userTokens != nil {
+		if claims, err := h.userTokens.Verify(tokenStr); err == nil && claims.Role == "admin" {
+			return "", true, true
+		}
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error":             "invalid_client",
+		"error_description": "authenticate with an agent client certificate or an admin token",
+	})
+	return "", false, false
+}
+
+// verifyAnyToken verifies tokenStr as a user session or admin token, then as
+// a Task Token. Exactly one of the results is non-nil when it is valid.
+func (h *IdentityHandler) verifyAnyToken(tokenStr string) (*identity.UserTokenClaims, *identity.TaskTokenClaims) {
+	if h.userTokens != nil {
+		if claims, err := h.userTokens.Verify(tokenStr); err == nil {
+			return claims, nil
+		}
+	}
+	// Both kinds share signing keys; a token without agent_uri is not a Task Token.
+	if claims, err := h.tokens.Verify(tokenStr); err == nil && claims.AgentURI != "" {
+		return nil, claims
+	}
+	return nil, nil
+}
+
+// tokenHeldBy reports whether agentURI holds claims: it was issued to the
+// agent, for itself or as an actor in a delegation.
+func tokenHeldBy(claims *identity.TaskTokenClaims, agentURI string) bool {
+	return agentURI == claims.AgentURI || agentURI == claims.Subject || slices.Contains(claims.Actors(), agentURI)
+}
+
+// Introspect handles POST /api/v1/token/introspect (RFC 7662).
+//
+// The caller authenticates with an agent client certificate or an admin
+// token. The response says whether token is active — valid, unexpired and
+// not revoked — and, if so, its claims:
+//
+//	Request (form or JSON):  token=<token>
+//	Response: {"active":true, "token_type":"DPoP", "sub":"agent://...",
+//	           "client_id":"agent://...", "scope":"agent:call", "aud":[...],
+//	           "exp":..., "iat":..., "jti":"...", "cnf":{...}, "act":{...}}
+//
+// Agents may introspect Task Tokens they hold or that were issued for them
+// (audience); any other token is reported as {"active":false}, as is every
+// user token unless the caller is an admin.
+func (h *IdentityHandler) Introspect(c *gin.Context) {
+	agentURI, admin, ok := h.tokenClient(c)
+	if !ok {
+		return
+	}
+	var req tokenParamsRequest
+	_ = c.ShouldBind(&req)
+	if req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "token is required"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	userClaims, taskClaims := h.verifyAnyToken(req.Token)
+	switch {
+	case userClaims != nil && admin:
+		resp := gin.H{
+			"active":     true,
+			"token_type": "Bearer",
+			"sub":        userClaims.Subject,
+			"iss":        userClaims.Issuer,
+			"jti":        userClaims.ID,
+			"exp":        userClaims.ExpiresAt.Unix(),
+			"iat":        userClaims.IssuedAt.Unix(),
+			"token_use":  userClaims.Type,
+		}
+		if userClaims.Username != "" {
+			resp["username"] = userClaims.Username
+		}
+		c.JSON(http.StatusOK, resp)
+
+	case taskClaims != nil && (admin || tokenHeldBy(taskClaims, agentURI) || slices.Contains(taskClaims.Audience, agentURI)):
+		tokenType, clientID := "Bearer", taskClaims.AgentURI
+		if taskClaims.Cnf != nil && taskClaims.Cnf.JKT != "" {
+			tokenType = "DPoP"
+		}
+		if actors := taskClaims.Actors(); len(actors) > 0 {
+			clientID = actors[0]
+		}
+		resp := gin.H{
+			"active":     true,
+			"token_type": tokenType,
+			"sub":        taskClaims.Subject,
+			"client_id":  clientID,
+			"agent_uri":  taskClaims.AgentURI,
+			"scope":      strings.Join(taskClaims.Scopes, " "),
+			"iss":        taskClaims.Issuer,
+			"jti":        taskClaims.ID,
+			"exp":        taskClaims.ExpiresAt.Unix(),
+			"iat":        taskClaims.IssuedAt.Unix(),
+			"token_use":  "task",
+		}
+		if len(taskClaims.Audience) > 0 {
+			resp["aud"] = taskClaims.Audience
+		}
+		if taskClaims.Cnf != nil {
+			resp["cnf"] = taskClaims.Cnf
+		}
+		if taskClaims.Act != nil {
+			resp["act"] = taskClaims.Act
+		}
+		c.JSON(http.StatusOK, resp)
+
+	default:
+		c.JSON(http.StatusOK, gin.H{"active": false})
+	}
+}
+
+// Revoke handles POST /api/v1/token/revoke (RFC 7009).
+//
+// The caller authenticates with an agent client certificate or an admin
+// token. Agents may revoke Task Tokens issued to them, including delegated
+// tokens they act in; admins may revoke any token. Users revoke their own
+// session token with POST /api/v1/auth/logout.
+//
+//	Request (form or JSON):  token=<token>
+//	Response: 200 with an empty body
+//
+// As RFC 7009 requires, an invalid, expired or already revoked token also
+// gets 200: there is nothing left to revoke.
+func (h *IdentityHandler) Revoke(c *gin.Context) {
+	agentURI, admin, ok := h.tokenClient(c)
+	if !ok {
+		return
+	}
+	var req tokenParamsRequest
+	_ = c.ShouldBind(&req)
+	if req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "token is required"})
+		return
+	}
+	denylist := h.tokens.Denylist()
+	if denylist == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "temporarily_unavailable", "error_description": "token revocation is not enabled"})
+		return
+	}
+
+	var (
+		jti, subject string
+		exp          time.Time
+	)
+	userClaims, taskClaims := h.verifyAnyToken(req.Token)
+	switch {
+	case userClaims != nil:
+		if !admin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized_client", "error_description": "only admins may revoke user tokens; use /api/v1/auth/logout"})
+			return
+		}
+		jti, subject, exp = userClaims.ID, userClaims.Subject, userClaims.ExpiresAt.Time
+	case taskClaims != nil:
+		if !admin && !tokenHeldBy(taskClaims, agentURI) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized_client", "error_description": "the token was not issued to " + agentURI})
+			return
+		}
+		jti, subject, exp = taskClaims.ID, taskClaims.Subject, taskClaims.ExpiresAt.Time
+	default:
+		c.Status(http.StatusOK)
+		return
+	}
+
+	if err := denylist.RevokeToken(c.Request.Context(), jti, exp); err != nil {
+		h.logger.Error("revoke token", zap.String("jti", jti), zap.String("subject", subject), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token"})
+		return
+	}
+	h.logger.Info("token revoked", zap.String("jti", jti), zap.String("subject", subject), zap.Bool("by_admin", admin))
+	c.Status(http.StatusOK)
+}
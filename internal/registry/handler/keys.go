@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"go.uber.org/zap"
+)
+
+// KeysHandler exposes the admin API for rotating the registry's signing keys.
+type KeysHandler struct {
+	ca         *identity.CAManager // nil when the CA cannot be rotated (federated mode)
+	tokenKeys  *identity.KeyRing
+	userTokens *identity.UserTokenIssuer
+	logger     *zap.Logger
+
+	activateAfter time.Duration
+	jwtGrace      time.Duration
+	caGrace       time.Duration
+}
+
+// NewKeysHandler creates a KeysHandler. ca may be nil.
+func NewKeysHandler(ca *identity.CAManager, tokenKeys *identity.KeyRing, userTokens *identity.UserTokenIssuer, logger *zap.Logger) *KeysHandler {
+	return &KeysHandler{
+		ca:         ca,
+		tokenKeys:  tokenKeys,
+		userTokens: userTokens,
+		logger:     logger,
+		jwtGrace:   30 * 24 * time.Hour,
+		caGrace:    365 * 24 * time.Hour,
+	}
+}
+
+// SetRotationDefaults sets the activation delay and grace periods used when a
+// rotate request does not give them.
+func (h *KeysHandler) SetRotationDefaults(activateAfter, jwtGrace, caGrace time.Duration) {
+	h.activateAfter, h.jwtGrace, h.caGrace = activateAfter, jwtGrace, caGrace
+}
+
+// Register mounts the key management routes onto the API group. All routes
+// require an admin token.
+func (h *KeysHandler) Register(rg *gin.RouterGroup) {
+	keys := rg.Group("/admin/keys")
+	keys.Use(identity.RequireAdmin(h.userTokens))
+	keys.GET("", h.ListKeys)
+	keys.POST("/rotate", h.RotateKey)
+}
+
+// keyVersionResponse is a KeyVersion with its current state.
+type keyVersionResponse struct {
+	identity.KeyVersion
+	State string `json:"state"`
+}
+
+func describeVersions(versions []identity.KeyVersion, now time.Time) []keyVersionResponse {
+	states := identity.KeyStates(versions, now)
+	out := make([]keyVersionResponse, len(versions))
+	for i, v := range versions {
+		out[i] = keyVersionResponse{KeyVersion: v, State: states[i]}
+	}
+	return out
+}
+
+// ListKeys handles GET /api/v1/admin/keys.
+func (h *KeysHandler) ListKeys(c *gin.Context) {
+	now := time.Now()
+	resp := gin.H{"jwt": describeVersions(h.tokenKeys.Versions(), now)}
+	if h.ca != nil {
+		resp["ca"] = describeVersions(h.ca.Versions(), now)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type rotateKeyRequest struct {
+	Key           string `json:"key" binding:"required,oneof=jwt ca"`
+	Algorithm     string `json:"algorithm"`
+	ActivateAfter string `json:"activate_after"` // Go duration, e.g. "1h"
+	GracePeriod   string `json:"grace_period"`   // Go duration, e.g. "720h"
+}
+
+// RotateKey handles POST /api/v1/admin/keys/rotate. The new key is published
+// at once, signs after activate_after, and the keys it replaces retire
+// grace_period after that.
+func (h *KeysHandler) RotateKey(c *gin.Context) {
+	var req rotateKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	opts := identity.RotateOptions{ActivateAfter: h.activateAfter, GracePeriod: h.jwtGrace}
+	if req.Key == "ca" {
+		opts.GracePeriod = h.caGrace
+	}
+	if req.Algorithm != "" {
+		alg, err := identity.ParseKeyAlgorithm(req.Algorithm)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts.Algorithm = alg
+	}
+	for _, d := range []struct {
+		value string
+		dst   *time.Duration
+	}{{req.ActivateAfter, &opts.ActivateAfter}, {req.GracePeriod, &opts.GracePeriod}} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration " + d.value})
+			return
+		}
+		*d.dst = parsed
+	}
+
+	var (
+		v        identity.KeyVersion
+		versions func() []identity.KeyVersion
+		err      error
+	)
+	switch {
+	case req.Key == "jwt":
+		v, err = h.tokenKeys.Rotate(c.Request.Context(), opts)
+		versions = h.tokenKeys.Versions
+	case h.ca != nil:
+		v, err = h.ca.Rotate(opts)
+		versions = h.ca.Versions
+	default:
+		err = identity.ErrKeyRotationUnsupported
+	}
+	if errors.Is(err, identity.ErrKeyRotationUnsupported) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("rotate signing key", zap.String("key", req.Key), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate key"})
+		return
+	}
+
+	h.logger.Info("signing key rotated",
+		zap.String("key", req.Key),
+		zap.String("kid", v.KID),
+		zap.Time("activate_at", v.ActivateAt),
+	)
+	all := describeVersions(versions(), time.Now())
+	c.JSON(http.StatusCreated, all[len(all)-1])
+}
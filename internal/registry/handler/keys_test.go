@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/handler"
+	"go.uber.org/zap"
+)
+
+func setupKeysRouter(t *testing.T, ca *identity.CAManager, ring *identity.KeyRing) (*gin.Engine, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	userTokens := identity.NewUserTokenIssuerWithKeyRing(ring, "http://test", time.Hour)
+	admin, err := userTokens.IssueAdminToken(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	handler.NewKeysHandler(ca, ring, userTokens, zap.NewNop()).Register(r.Group("/api/v1"))
+	return r, admin
+}
+
+func postRotate(router *gin.Engine, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/keys/rotate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRotateKey_201_jwt(t *testing.T) {
+	dir := t.TempDir()
+	ring, err := identity.LoadKeyRing(context.Background(), identity.NewFileKeyStore(dir, ""), dir, identity.KeyAlgorithmEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, admin := setupKeysRouter(t, testCA(t), ring)
+
+	w := postRotate(router, admin, `{"key":"jwt","activate_after":"1h","grace_period":"24h"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["state"] != identity.KeyStatePending || resp["kid"] == identity.SigningKeyID {
+		t.Errorf("rotate response = %v, want a pending key with a new kid", resp)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/keys", nil)
+	req.Header.Set("Authorization", "Bearer "+admin)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var list struct {
+		JWT []map[string]any `json:"jwt"`
+		CA  []map[string]any `json:"ca"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.JWT) != 2 || list.JWT[0]["state"] != identity.KeyStateActive || len(list.CA) != 1 {
+		t.Errorf("key list = %s", w.Body.String())
+	}
+}
+
+func TestRotateKey_409_fixedKey(t *testing.T) {
+	ca := testCA(t)
+	router, admin := setupKeysRouter(t, nil, identity.NewKeyRing(ca.Key(), identity.SigningKeyID))
+
+	for _, key := range []string{"jwt", "ca"} {
+		if w := postRotate(router, admin, `{"key":"`+key+`"}`); w.Code != http.StatusConflict {
+			t.Errorf("%s: expected 409, got %d: %s", key, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRotateKey_400_and_401(t *testing.T) {
+	ca := testCA(t)
+	router, admin := setupKeysRouter(t, ca, identity.NewKeyRing(ca.Key(), identity.SigningKeyID))
+
+	for _, body := range []string{`{"key":"tls"}`, `{"key":"ca","grace_period":"soon"}`, `{"key":"ca","algorithm":"dsa"}`} {
+		if w := postRotate(router, admin, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+	if w := postRotate(router, "", `{"key":"ca"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: expected 401, got %d", w.Code)
+	}
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
@@ -11,8 +14,9 @@ import (
 
 // LedgerHandler exposes read-only HTTP endpoints for the trust ledger.
 type LedgerHandler struct {
-	ledger trustledger.Ledger
-	logger *zap.Logger
+	ledger    trustledger.Ledger
+	treeHeads trustledger.TreeHeadStore // nil = signed tree heads not published
+	logger    *zap.Logger
 }
 
 // NewLedgerHandler creates a new LedgerHandler.
@@ -20,17 +24,30 @@ func NewLedgerHandler(ledger trustledger.Ledger, logger *zap.Logger) *LedgerHand
 	return &LedgerHandler{ledger: ledger, logger: logger}
 }
 
+// SetTreeHeadStore enables the signed tree head endpoints.
+func (h *LedgerHandler) SetTreeHeadStore(store trustledger.TreeHeadStore) {
+	h.treeHeads = store
+}
+
 // Register mounts the ledger routes on the given router group.
 func (h *LedgerHandler) Register(rg *gin.RouterGroup) {
 	l := rg.Group("/ledger")
 	{
 		l.GET("", h.Overview)
 		l.GET("/verify", h.Verify)
+		l.GET("/export", h.Export)
+		l.GET("/entries", h.QueryEntries)
 		l.GET("/entries/:idx", h.GetEntry)
+		l.GET("/proof/inclusion", h.InclusionProof)
+		l.GET("/proof/consistency", h.ConsistencyProof)
+		l.GET("/tree-heads", h.ListTreeHeads)
+		l.GET("/tree-heads/latest", h.LatestTreeHead)
+		l.GET("/tree-heads/:size", h.GetTreeHead)
 	}
 }
 
-// Overview handles GET /ledger — returns the chain length and current root hash.
+// Overview handles GET /ledger — returns the chain length, the current root
+// hash (chain tip) and the Merkle tree head over all entries.
 func (h *LedgerHandler) Overview(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -48,28 +65,123 @@ func (h *LedgerHandler) Overview(c *gin.Context) {
 		return
 	}
 
+	merkleRoot, err := h.ledger.MerkleRoot(ctx, count)
+	if err != nil {
+		h.logger.Error("ledger MerkleRoot", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute merkle root"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"entries": count,
-		"root":    root,
+		"entries":     count,
+		"root":        root,
+		"merkle_root": merkleRoot,
 	})
 }
 
-// Verify handles GET /ledger/verify — walks the full chain and reports integrity.
+// Verify handles GET /ledger/verify — reports chain integrity. Ledgers that
+// keep verified checkpoints only walk entries since the last checkpoint;
+// pass ?full=true to force a walk from genesis.
 func (h *LedgerHandler) Verify(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	if iv, ok := h.ledger.(trustledger.IncrementalVerifier); ok && c.Query("full") != "true" {
+		cp, err := iv.VerifyIncremental(ctx)
+		if err != nil {
+			h.verifyFailed(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"valid": true, "checkpoint": cp})
+		return
+	}
+
 	if err := h.ledger.Verify(ctx); err != nil {
-		h.logger.Warn("ledger integrity check failed", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"valid": false,
-			"error": err.Error(),
-		})
+		h.verifyFailed(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"valid": true})
 }
 
+func (h *LedgerHandler) verifyFailed(c *gin.Context, err error) {
+	h.logger.Warn("ledger integrity check failed", zap.Error(err))
+	resp := gin.H{
+		"valid": false,
+		"error": err.Error(),
+	}
+	var broken *trustledger.BrokenChainError
+	if errors.As(err, &broken) {
+		resp["broken_index"] = broken.Index
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// LedgerEntriesHeader carries the ledger length at the start of an export.
+const LedgerEntriesHeader = "X-NAP-Ledger-Entries"
+
+// exportPageSize is the number of entries fetched per Query call while
+// streaming an export.
+const exportPageSize = 500
+
+// Export handles GET /ledger/export — streams every entry (from_index onward,
+// default genesis) as JSON Lines so the chain can be verified offline, e.g.
+// with `nap ledger verify`.
+func (h *LedgerHandler) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	from := 0
+	if raw := c.Query("from_index"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from_index must be a non-negative integer"})
+			return
+		}
+		from = n
+	}
+
+	// Query the length and first page before committing to a 200 so storage
+	// errors can still be reported as JSON.
+	total, err := h.ledger.Len(ctx)
+	if err != nil {
+		h.logger.Error("ledger Len", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ledger"})
+		return
+	}
+	page, err := h.ledger.Query(ctx, trustledger.Filter{FromIndex: from, Limit: exportPageSize})
+	if err != nil {
+		h.logger.Error("ledger export", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ledger"})
+		return
+	}
+
+	// X-NAP-Ledger-Entries lets a verifier detect a truncated stream. Entries
+	// appended while the export runs may also be included.
+	c.Header(LedgerEntriesHeader, strconv.Itoa(total))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="trust-ledger.jsonl"`)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	for {
+		for _, e := range page.Entries {
+			if err := enc.Encode(e); err != nil {
+				h.logger.Warn("ledger export: client write failed", zap.Error(err))
+				return
+			}
+		}
+		c.Writer.Flush()
+		if page.NextIndex == 0 {
+			return
+		}
+		if page, err = h.ledger.Query(ctx, trustledger.Filter{FromIndex: page.NextIndex, Limit: exportPageSize}); err != nil {
+			// Headers are already sent; the stream ends short of the
+			// advertised X-NAP-Ledger-Entries and the verifier reports it.
+			h.logger.Error("ledger export: mid-stream query failed", zap.Error(err))
+			return
+		}
+	}
+}
+
 // GetEntry handles GET /ledger/entries/:idx — returns a single ledger entry.
 func (h *LedgerHandler) GetEntry(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -89,3 +201,215 @@ func (h *LedgerHandler) GetEntry(c *gin.Context) {
 
 	c.JSON(http.StatusOK, entry)
 }
+
+// QueryEntries handles GET /ledger/entries — returns entries filtered by
+// agent_uri, action, actor and an RFC 3339 since/until range, in ascending
+// index order. Pass next_index from the response as from_index to page.
+func (h *LedgerHandler) QueryEntries(c *gin.Context) {
+	f := trustledger.Filter{
+		AgentURI: c.Query("agent_uri"),
+		Action:   c.Query("action"),
+		Actor:    c.Query("actor"),
+	}
+
+	var err error
+	if raw := c.Query("since"); raw != "" {
+		if f.Since, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if raw := c.Query("until"); raw != "" {
+		if f.Until, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if raw := c.Query("from_index"); raw != "" {
+		if f.FromIndex, err = strconv.Atoi(raw); err != nil || f.FromIndex < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from_index must be a non-negative integer"})
+			return
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if f.Limit, err = strconv.Atoi(raw); err != nil || f.Limit < 1 || f.Limit > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+	}
+
+	page, err := h.ledger.Query(c.Request.Context(), f)
+	if err != nil {
+		h.logger.Error("ledger Query", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ledger"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// InclusionProof handles GET /ledger/proof/inclusion?index=N&tree_size=M —
+// returns the RFC 6962 audit path for entry N in the tree over the first M
+// entries. tree_size defaults to the current ledger length.
+func (h *LedgerHandler) InclusionProof(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	index, err := strconv.Atoi(c.Query("index"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "index must be a non-negative integer"})
+		return
+	}
+	treeSize, ok := h.sizeParam(c, "tree_size")
+	if !ok {
+		return
+	}
+
+	proof, err := h.ledger.InclusionProof(ctx, index, treeSize)
+	if err != nil {
+		h.proofError(c, "ledger InclusionProof", err)
+		return
+	}
+	c.JSON(http.StatusOK, proof)
+}
+
+// ConsistencyProof handles GET /ledger/proof/consistency?first=N&second=M —
+// returns a proof that the tree of size N is a prefix of the tree of size M.
+// second defaults to the current ledger length.
+func (h *LedgerHandler) ConsistencyProof(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	first, err := strconv.Atoi(c.Query("first"))
+	if err != nil || first < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "first must be a positive integer"})
+		return
+	}
+	second, ok := h.sizeParam(c, "second")
+	if !ok {
+		return
+	}
+
+	proof, err := h.ledger.ConsistencyProof(ctx, first, second)
+	if err != nil {
+		h.proofError(c, "ledger ConsistencyProof", err)
+		return
+	}
+	c.JSON(http.StatusOK, proof)
+}
+
+// sizeParam parses an optional positive tree-size query parameter, falling
+// back to the current ledger length. It writes the error response itself and
+// returns ok=false on failure.
+func (h *LedgerHandler) sizeParam(c *gin.Context, name string) (int, bool) {
+	if raw := c.Query(name); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a positive integer"})
+			return 0, false
+		}
+		return n, true
+	}
+	n, err := h.ledger.Len(c.Request.Context())
+	if err != nil {
+		h.logger.Error("ledger Len", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ledger"})
+		return 0, false
+	}
+	return n, true
+}
+
+func (h *LedgerHandler) proofError(c *gin.Context, op string, err error) {
+	if errors.Is(err, trustledger.ErrInvalidProofRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(op, zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build proof"})
+}
+
+// LatestTreeHead handles GET /ledger/tree-heads/latest — returns the most
+// recently published signed tree head.
+func (h *LedgerHandler) LatestTreeHead(c *gin.Context) {
+	if !h.treeHeadsEnabled(c) {
+		return
+	}
+	head, err := h.treeHeads.LatestTreeHead(c.Request.Context())
+	if err != nil {
+		h.treeHeadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, head)
+}
+
+// GetTreeHead handles GET /ledger/tree-heads/:size — returns the signed tree
+// head published for an exact tree size.
+func (h *LedgerHandler) GetTreeHead(c *gin.Context) {
+	if !h.treeHeadsEnabled(c) {
+		return
+	}
+	size, err := strconv.Atoi(c.Param("size"))
+	if err != nil || size < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be a positive integer"})
+		return
+	}
+	head, err := h.treeHeads.GetTreeHead(c.Request.Context(), size)
+	if err != nil {
+		h.treeHeadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, head)
+}
+
+// ListTreeHeads handles GET /ledger/tree-heads?before=N&limit=M — returns past
+// signed tree heads, newest first. Pass the smallest tree_size of a page as
+// before to fetch the next page.
+func (h *LedgerHandler) ListTreeHeads(c *gin.Context) {
+	if !h.treeHeadsEnabled(c) {
+		return
+	}
+
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+	before := 0
+	if raw := c.Query("before"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be a positive integer"})
+			return
+		}
+		before = n
+	}
+
+	heads, err := h.treeHeads.ListTreeHeads(c.Request.Context(), before, limit)
+	if err != nil {
+		h.logger.Error("ledger ListTreeHeads", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tree heads"})
+		return
+	}
+	if heads == nil {
+		heads = []*trustledger.TreeHead{}
+	}
+	c.JSON(http.StatusOK, gin.H{"tree_heads": heads, "count": len(heads)})
+}
+
+func (h *LedgerHandler) treeHeadsEnabled(c *gin.Context) bool {
+	if h.treeHeads == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "signed tree heads are not enabled on this registry"})
+		return false
+	}
+	return true
+}
+
+func (h *LedgerHandler) treeHeadError(c *gin.Context, err error) {
+	if errors.Is(err, trustledger.ErrTreeHeadNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tree head not found"})
+		return
+	}
+	h.logger.Error("ledger tree head lookup", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query tree heads"})
+}
@@ -1,10 +1,12 @@
 package handler_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/handler"
@@ -12,6 +14,12 @@ import (
 	"go.uber.org/zap"
 )
 
+type fakeTreeHeadSigner struct{}
+
+func (fakeTreeHeadSigner) SignTreeHead(int, string, time.Time) (string, error) {
+	return "signed", nil
+}
+
 func setupLedgerRouter(t *testing.T) *gin.Engine {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
@@ -95,3 +103,213 @@ func TestLedgerGetEntry_400_invalidIdx(t *testing.T) {
 		t.Fatalf("expected 400, got %d", w.Code)
 	}
 }
+
+func TestLedgerInclusionProof_200(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ledger := trustledger.New()
+	for i := 0; i < 4; i++ {
+		_, _ = ledger.Append(context.Background(), "agent://nexusagentprotocol.com/a/agent_1", "agent.activated", "nexus-system", i)
+	}
+	handler.NewLedgerHandler(ledger, zap.NewNop()).Register(r.Group("/api/v1"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/proof/inclusion?index=2", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var proof trustledger.InclusionProof
+	if err := json.Unmarshal(w.Body.Bytes(), &proof); err != nil {
+		t.Fatal(err)
+	}
+	if proof.TreeSize != 5 {
+		t.Errorf("expected default tree_size 5, got %d", proof.TreeSize)
+	}
+	if err := trustledger.VerifyInclusion(&proof); err != nil {
+		t.Errorf("VerifyInclusion: %v", err)
+	}
+}
+
+func TestLedgerInclusionProof_400_outOfRange(t *testing.T) {
+	router := setupLedgerRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/proof/inclusion?index=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestLedgerConsistencyProof_200(t *testing.T) {
+	router := setupLedgerRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/proof/consistency?first=1&second=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var proof trustledger.ConsistencyProof
+	if err := json.Unmarshal(w.Body.Bytes(), &proof); err != nil {
+		t.Fatal(err)
+	}
+	if err := trustledger.VerifyConsistency(&proof); err != nil {
+		t.Errorf("VerifyConsistency: %v", err)
+	}
+}
+
+func TestLedgerConsistencyProof_400_missingFirst(t *testing.T) {
+	router := setupLedgerRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/proof/consistency", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestLedgerLatestTreeHead_404_notEnabled(t *testing.T) {
+	router := setupLedgerRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/tree-heads/latest", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestLedgerTreeHeads_200(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ledger := trustledger.New()
+	store := trustledger.NewMemoryTreeHeadStore()
+	p := trustledger.NewTreeHeadPublisher(ledger, store, fakeTreeHeadSigner{}, time.Minute, zap.NewNop())
+	if _, err := p.Publish(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = ledger.Append(context.Background(), "agent://nexusagentprotocol.com/a/agent_1", "register", "example.com", nil)
+	if _, err := p.Publish(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h := handler.NewLedgerHandler(ledger, zap.NewNop())
+	h.SetTreeHeadStore(store)
+	h.Register(r.Group("/api/v1"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/tree-heads/latest", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("latest: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var head trustledger.TreeHead
+	json.Unmarshal(w.Body.Bytes(), &head)
+	if head.TreeSize != 2 || head.Signature != "signed" {
+		t.Errorf("latest head: got %+v", head)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/ledger/tree-heads?before=2", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var list struct {
+		TreeHeads []trustledger.TreeHead `json:"tree_heads"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.TreeHeads) != 1 || list.TreeHeads[0].TreeSize != 1 {
+		t.Errorf("list before=2: got %+v", list.TreeHeads)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/ledger/tree-heads/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLedgerQueryEntries_200(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ledger := trustledger.New()
+	uri := "agent://nexusagentprotocol.com/a/agent_1"
+	_, _ = ledger.Append(context.Background(), uri, "register", "example.com", nil)
+	_, _ = ledger.Append(context.Background(), "agent://nexusagentprotocol.com/a/agent_2", "register", "example.com", nil)
+	_, _ = ledger.Append(context.Background(), uri, "revoke", "nexus-system", map[string]string{"reason": "compromised"})
+	handler.NewLedgerHandler(ledger, zap.NewNop()).Register(r.Group("/api/v1"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/entries?agent_uri="+uri, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var page trustledger.Page
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(page.Entries))
+	}
+	if string(page.Entries[1].Payload) != `{"reason":"compromised"}` {
+		t.Errorf("payload: got %s", page.Entries[1].Payload)
+	}
+	if err := page.Entries[1].VerifyPayload(); err != nil {
+		t.Errorf("VerifyPayload: %v", err)
+	}
+}
+
+func TestLedgerQueryEntries_400_badSince(t *testing.T) {
+	router := setupLedgerRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/entries?since=yesterday", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestLedgerExport_200_verifiesOffline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ledger := trustledger.New()
+	for i := 0; i < 3; i++ {
+		_, _ = ledger.Append(context.Background(), "agent://nexusagentprotocol.com/a/agent_1", "update", "example.com", i)
+	}
+	handler.NewLedgerHandler(ledger, zap.NewNop()).Register(r.Group("/api/v1"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/export", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(handler.LedgerEntriesHeader); got != "4" {
+		t.Errorf("%s: got %q, want 4", handler.LedgerEntriesHeader, got)
+	}
+
+	v, err := trustledger.VerifyJSONL(w.Body)
+	if err != nil {
+		t.Fatalf("VerifyJSONL: %v", err)
+	}
+	if v.Count() != 4 {
+		t.Errorf("expected 4 entries, got %d", v.Count())
+	}
+}
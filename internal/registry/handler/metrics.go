@@ -37,10 +37,35 @@ var (
 		Help: "Total trust ledger entries appended.",
 	})
 
+	napLedgerVerificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nap_ledger_verifications_total",
+		Help: "Total background trust ledger verifications by result.",
+	}, []string{"result"})
+
+	napLedgerVerifiedIndex = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nap_ledger_verified_index",
+		Help: "Index of the last trust ledger entry covered by a verified checkpoint.",
+	})
+
+	napLedgerChainBroken = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nap_ledger_chain_broken",
+		Help: "1 if the last trust ledger verification found a broken chain, else 0. Alert on == 1.",
+	})
+
+	napLedgerBrokenIndex = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nap_ledger_broken_index",
+		Help: "First broken trust ledger index found by the last verification, or -1.",
+	})
+
 	napWebhookDeliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "nap_webhook_deliveries_total",
 		Help: "Total webhook deliveries by success status.",
 	}, []string{"status"})
+
+	napResolverPushesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nap_resolver_invalidation_pushes_total",
+		Help: "Total cache invalidation pushes to resolvers by success status.",
+	}, []string{"status"})
 )
 
 // PrometheusMiddleware returns a Gin middleware that records per-request metrics.
@@ -84,6 +109,20 @@ func RecordLedgerAppend() {
 	napLedgerEntriesTotal.Inc()
 }
 
+// RecordLedgerVerification records the result of a background trust ledger
+// verification run. brokenIndex is -1 when the chain is intact.
+func RecordLedgerVerification(ok bool, verifiedIndex, brokenIndex int) {
+	napLedgerBrokenIndex.Set(float64(brokenIndex))
+	if ok {
+		napLedgerVerificationsTotal.WithLabelValues("success").Inc()
+		napLedgerVerifiedIndex.Set(float64(verifiedIndex))
+		napLedgerChainBroken.Set(0)
+	} else {
+		napLedgerVerificationsTotal.WithLabelValues("failure").Inc()
+		napLedgerChainBroken.Set(1)
+	}
+}
+
 // RecordWebhookDelivery records a webhook delivery attempt.
 func RecordWebhookDelivery(success bool) {
 	if success {
@@ -93,6 +132,15 @@ func RecordWebhookDelivery(success bool) {
 	}
 }
 
+// RecordResolverPush records a cache invalidation push to a resolver.
+func RecordResolverPush(success bool) {
+	if success {
+		napResolverPushesTotal.WithLabelValues("success").Inc()
+	} else {
+		napResolverPushesTotal.WithLabelValues("failure").Inc()
+	}
+}
+
 // SetAgentsGauge sets the agent count gauge for a given status.
 func SetAgentsGauge(status string, count float64) {
 	napAgentsTotal.WithLabelValues(status).Set(count)
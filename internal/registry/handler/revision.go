@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/repository"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/service"
+)
+
+// ListRevisions handles GET /agents/:id/revisions — lists the agent's
+// revision history, newest first.
+func (h *AgentHandler) ListRevisions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid agent ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !h.authorizeAgentAction(c, ctx, id, "view the revisions of") {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	revs, err := h.svc.ListRevisions(ctx, id, limit, offset)
+	if err != nil {
+		writeRevisionError(c, err, "failed to list revisions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revs, "count": len(revs)})
+}
+
+// GetRevision handles GET /agents/:id/revisions/:rev — returns one revision
+// with its full snapshot.
+func (h *AgentHandler) GetRevision(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid agent ID"})
+		return
+	}
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !h.authorizeAgentAction(c, ctx, id, "view the revisions of") {
+		return
+	}
+
+	revision, err := h.svc.GetRevision(ctx, id, rev)
+	if err != nil {
+		writeRevisionError(c, err, "failed to get revision")
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffRevisions handles GET /agents/:id/revisions/diff?from=N&to=M — lists the
+// fields that changed between two revisions. to defaults to from+1.
+func (h *AgentHandler) DiffRevisions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid agent ID"})
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a revision number"})
+		return
+	}
+	to := from + 1
+	if v := c.Query("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil || to <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a revision number"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if !h.authorizeAgentAction(c, ctx, id, "view the revisions of") {
+		return
+	}
+
+	changes, err := h.svc.DiffRevisions(ctx, id, from, to)
+	if err != nil {
+		writeRevisionError(c, err, "failed to diff revisions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "changes": changes})
+}
+
+// RollbackAgent handles POST /agents/:id/revisions/:rev/rollback — restores
+// the agent's fields from an earlier revision, recording a new revision.
+func (h *AgentHandler) RollbackAgent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid agent ID"})
+		return
+	}
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if !h.authorizeAgentAction(c, ctx, id, "roll back") {
+		return
+	}
+
+	agent, revision, err := h.svc.Rollback(ctx, id, rev, callerActor(c))
+	if err != nil {
+		writeRevisionError(c, err, "failed to roll back agent")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"agent": agent, "revision": revision})
+}
+
+// writeRevisionError maps revision service errors to HTTP responses; other
+// errors become a 500 with msg.
+func writeRevisionError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+	case errors.Is(err, repository.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+	case errors.Is(err, service.ErrRevisionHistoryDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
+
+// callerActor identifies the authenticated caller for the revision history:
+// the agent URI of a Task Token, else the user ID of a user JWT. It is empty
+// for unauthenticated requests.
+func callerActor(c *gin.Context) string {
+	if claims := identity.ClaimsFromCtx(c); claims != nil {
+		return claims.AgentURI
+	}
+	if claims := userFromCtx(c); claims != nil {
+		return claims.UserID
+	}
+	return ""
+}
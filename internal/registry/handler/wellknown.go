@@ -53,6 +53,8 @@ func (h *WellKnownHandler) ServeAgentCard(c *gin.Context) {
 			DisplayName:    a.DisplayName,
 			Description:    a.Description,
 			Endpoint:       a.Endpoint,
+			Protocols:      a.Protocols(),
+			Endpoints:      a.CardEndpoints(),
 			CapabilityNode: a.CapabilityNode,
 			Status:         string(a.Status),
 			Metadata:       a.Metadata,
@@ -107,6 +109,7 @@ func (h *WellKnownHandler) ServeA2ACard(c *gin.Context) {
 		Capabilities: agentcard.A2ACapabilities{},
 		NAPURI:       a.URI(),
 		NAPTrustTier: string(tier),
+		NAPEndpoints: a.CardEndpoints(),
 	}
 
 	c.JSON(http.StatusOK, card)
@@ -82,6 +82,29 @@ type Agent struct {
 	// TrustTier is computed at read time from status, registration_type, and cert_serial.
 	// It is never stored in the database.
 	TrustTier TrustTier `json:"trust_tier" db:"-"`
+	// Endpoints are the agent's additional transport endpoints, stored in the
+	// agent_endpoints table and loaded by the repository on single-agent reads.
+	// Endpoint remains the primary HTTPS endpoint.
+	Endpoints []AgentEndpoint `json:"endpoints,omitempty" db:"-"`
+}
+
+// Endpoint protocols. An empty protocol is inferred from the URL scheme.
+const (
+	EndpointProtocolHTTPS     = "https"
+	EndpointProtocolHTTP      = "http"
+	EndpointProtocolGRPC      = "grpc"
+	EndpointProtocolWebSocket = "wss"
+)
+
+// AgentEndpoint is one transport endpoint of an agent. Clients try endpoints
+// in ascending Priority order and spread load across endpoints of equal
+// priority in proportion to Weight, as with DNS SRV records.
+type AgentEndpoint struct {
+	URL      string `json:"url"              db:"url"      binding:"required,url"`
+	Protocol string `json:"protocol"         db:"protocol" binding:"omitempty,oneof=https http grpc wss"`
+	Region   string `json:"region,omitempty" db:"region"`
+	Priority int    `json:"priority"         db:"priority" binding:"min=0,max=65535"`
+	Weight   int    `json:"weight"           db:"weight"   binding:"min=0,max=65535"`
 }
 
 // AgentMeta holds extensible key-value metadata for an agent.
@@ -108,6 +131,38 @@ func (a *Agent) URI() string {
 	return "agent://" + a.TrustRoot + "/" + category + "/" + a.AgentID
 }
 
+// CardEndpoints returns the agent's endpoints in agent-card form.
+func (a *Agent) CardEndpoints() []agentcard.Endpoint {
+	if len(a.Endpoints) == 0 {
+		return nil
+	}
+	out := make([]agentcard.Endpoint, len(a.Endpoints))
+	for i, ep := range a.Endpoints {
+		out[i] = agentcard.Endpoint{
+			URL:      ep.URL,
+			Protocol: ep.Protocol,
+			Region:   ep.Region,
+			Priority: ep.Priority,
+			Weight:   ep.Weight,
+		}
+	}
+	return out
+}
+
+// Protocols returns the distinct protocols the agent's endpoints speak, in
+// the order they first appear.
+func (a *Agent) Protocols() []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, ep := range a.Endpoints {
+		if !seen[ep.Protocol] {
+			seen[ep.Protocol] = true
+			out = append(out, ep.Protocol)
+		}
+	}
+	return out
+}
+
 // ComputeTrustTier derives the credibility tier from the agent's current state.
 // The result depends only on fields already present in the agents table row.
 func (a *Agent) ComputeTrustTier() TrustTier {
@@ -126,13 +181,41 @@ func (a *Agent) ComputeTrustTier() TrustTier {
 
 // Certificate represents an X.509 certificate issued to an agent.
 type Certificate struct {
-	ID        uuid.UUID  `json:"id"                   db:"id"`
-	AgentID   uuid.UUID  `json:"agent_id"             db:"agent_id"`
-	Serial    string     `json:"serial"               db:"serial"`
-	PEM       string     `json:"pem"                  db:"pem"`
-	IssuedAt  time.Time  `json:"issued_at"            db:"issued_at"`
-	ExpiresAt time.Time  `json:"expires_at"           db:"expires_at"`
-	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ID               uuid.UUID  `json:"id"                          db:"id"`
+	AgentID          uuid.UUID  `json:"agent_id"                    db:"agent_id"`
+	Serial           string     `json:"serial"                      db:"serial"`
+	PEM              string     `json:"pem"                         db:"pem"`
+	IssuedAt         time.Time  `json:"issued_at"                   db:"issued_at"`
+	ExpiresAt        time.Time  `json:"expires_at"                  db:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"        db:"revoked_at"`
+	RevocationReason int        `json:"revocation_reason,omitempty" db:"revocation_reason"` // RFC 5280 reason code
+}
+
+// RevokedCertificate is a revoked certificate that has not yet expired, as
+// listed in the certificate revocation list.
+type RevokedCertificate struct {
+	Serial     string    `json:"cert_serial"`
+	AgentID    uuid.UUID `json:"agent_id"`
+	RevokedAt  time.Time `json:"revoked_at"`
+	ReasonCode int       `json:"reason_code"` // RFC 5280 reason code
+	Reason     string    `json:"reason"`      // free-text reason given at revocation
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Expiry kinds.
+const (
+	ExpiryKindCertificate  = "certificate"
+	ExpiryKindRegistration = "registration"
+)
+
+// Expiry is an upcoming or passed expiry of an agent's current certificate
+// or of its registration (Agent.ExpiresAt).
+type Expiry struct {
+	AgentID   uuid.UUID `db:"agent_id"`
+	Kind      string    `db:"kind"`
+	ExpiresAt time.Time `db:"expires_at"`
+	// Notified is true once the owner has been warned about this expiry.
+	Notified bool `db:"notified"`
 }
 
 // RegisterRequest is the payload for creating a new agent registration.
@@ -198,4 +281,10 @@ type UpdateRequest struct {
 	Version     string   `json:"version"`
 	Tags        []string `json:"tags"`
 	SupportURL  string   `json:"support_url"  binding:"omitempty,url"`
+	// Endpoints replaces the agent's endpoint list when non-nil; send an
+	// empty list to remove all endpoints.
+	Endpoints []AgentEndpoint `json:"endpoints" binding:"omitempty,max=16,dive"`
+	// Actor is set by the handler from the caller's token and recorded in
+	// the revision history; not from the client body.
+	Actor string `json:"-"`
 }
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/agentcard"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/mcpmanifest"
+)
+
+// Revision actions.
+const (
+	RevisionActionBaseline = "baseline" // state of an agent registered before revisions were kept
+	RevisionActionRegister = "register"
+	RevisionActionUpdate   = "update"
+	RevisionActionRollback = "rollback"
+)
+
+// Metadata keys under which declared skills and MCP tools are stored.
+const (
+	MetaKeySkills   = "_skills"
+	MetaKeyMCPTools = "_mcp_tools"
+)
+
+// AgentRevision is one recorded state of an agent's user-editable fields.
+// A revision is written at registration and after every update or rollback.
+type AgentRevision struct {
+	ID       uuid.UUID     `json:"id"       db:"id"`
+	AgentID  uuid.UUID     `json:"agent_id" db:"agent_id"`
+	Revision int           `json:"revision" db:"revision"` // 1, 2, 3, … per agent
+	Action   string        `json:"action"   db:"action"`
+	Actor    string        `json:"actor"    db:"actor"`
+	Snapshot AgentSnapshot `json:"snapshot" db:"snapshot"`
+	// RollbackOf is the revision restored by a rollback.
+	RollbackOf *int `json:"rollback_of,omitempty" db:"rollback_of"`
+	// LedgerIndex is the trust ledger entry recording this change; nil when
+	// no ledger is configured or the append failed.
+	LedgerIndex *int      `json:"ledger_index,omitempty" db:"ledger_index"`
+	CreatedAt   time.Time `json:"created_at"             db:"created_at"`
+}
+
+// AgentSnapshot is the full set of user-editable agent fields at one point in
+// time. Skills and MCP tools are decoded from metadata; Metadata holds the
+// remaining keys. Certificate and key material is left out: it changes on
+// activation and renewal, which are not revisions, so a rollback must never
+// restore it.
+type AgentSnapshot struct {
+	DisplayName string                `json:"display_name"`
+	Description string                `json:"description"`
+	Endpoint    string                `json:"endpoint"`
+	Endpoints   []AgentEndpoint       `json:"endpoints"`
+	Version     string                `json:"version"`
+	Tags        []string              `json:"tags"`
+	SupportURL  string                `json:"support_url"`
+	Metadata    AgentMeta             `json:"metadata"`
+	Skills      []agentcard.A2ASkill  `json:"skills"`
+	MCPTools    []mcpmanifest.MCPTool `json:"mcp_tools"`
+}
+
+// Snapshot captures the agent's user-editable fields.
+func (a *Agent) Snapshot() AgentSnapshot {
+	s := AgentSnapshot{
+		DisplayName: a.DisplayName,
+		Description: a.Description,
+		Endpoint:    a.Endpoint,
+		Endpoints:   append([]AgentEndpoint{}, a.Endpoints...),
+		Version:     a.Version,
+		Tags:        append([]string{}, a.Tags...),
+		SupportURL:  a.SupportURL,
+		Metadata:    AgentMeta{},
+	}
+	for k, v := range a.Metadata {
+		switch k {
+		case MetaKeySkills:
+			_ = json.Unmarshal([]byte(v), &s.Skills)
+		case MetaKeyMCPTools:
+			_ = json.Unmarshal([]byte(v), &s.MCPTools)
+		default:
+			s.Metadata[k] = v
+		}
+	}
+	return s
+}
+
+// ApplySnapshot overwrites the agent's user-editable fields with s.
+func (a *Agent) ApplySnapshot(s AgentSnapshot) {
+	a.DisplayName = s.DisplayName
+	a.Description = s.Description
+	a.Endpoint = s.Endpoint
+	a.Endpoints = append([]AgentEndpoint{}, s.Endpoints...)
+	a.Version = s.Version
+	a.Tags = append([]string{}, s.Tags...)
+	a.SupportURL = s.SupportURL
+
+	a.Metadata = make(AgentMeta, len(s.Metadata)+2)
+	for k, v := range s.Metadata {
+		a.Metadata[k] = v
+	}
+	if len(s.Skills) > 0 {
+		if b, err := json.Marshal(s.Skills); err == nil {
+			a.Metadata[MetaKeySkills] = string(b)
+		}
+	}
+	if len(s.MCPTools) > 0 {
+		if b, err := json.Marshal(s.MCPTools); err == nil {
+			a.Metadata[MetaKeyMCPTools] = string(b)
+		}
+	}
+}
+
+// FieldChange is one difference between two snapshots. Metadata keys are
+// reported individually as "metadata.<key>"; every other field is reported
+// as a whole. From or To is null when the value is absent on that side.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// DiffSnapshots returns the fields that differ between from and to, in a
+// stable order.
+func DiffSnapshots(from, to AgentSnapshot) []FieldChange {
+	var changes []FieldChange
+	add := func(field string, a, b any) {
+		ja, jb := diffJSON(a), diffJSON(b)
+		if !bytes.Equal(ja, jb) {
+			changes = append(changes, FieldChange{Field: field, From: ja, To: jb})
+		}
+	}
+
+	add("display_name", from.DisplayName, to.DisplayName)
+	add("description", from.Description, to.Description)
+	add("endpoint", from.Endpoint, to.Endpoint)
+	add("endpoints", from.Endpoints, to.Endpoints)
+	add("version", from.Version, to.Version)
+	add("tags", from.Tags, to.Tags)
+	add("support_url", from.SupportURL, to.SupportURL)
+	add("skills", from.Skills, to.Skills)
+	add("mcp_tools", from.MCPTools, to.MCPTools)
+
+	keys := make(map[string]bool, len(from.Metadata)+len(to.Metadata))
+	for k := range from.Metadata {
+		keys[k] = true
+	}
+	for k := range to.Metadata {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		a, inFrom := from.Metadata[k]
+		b, inTo := to.Metadata[k]
+		switch {
+		case inFrom && inTo:
+			add("metadata."+k, a, b)
+		case inFrom:
+			add("metadata."+k, a, nil)
+		default:
+			add("metadata."+k, nil, b)
+		}
+	}
+	return changes
+}
+
+// diffJSON encodes v for comparison, treating empty slices as absent so that
+// nil and [] do not show up as a change.
+func diffJSON(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "[]" || string(b) == `""` {
+		return json.RawMessage("null")
+	}
+	return b
+}
@@ -82,7 +82,11 @@ func (r *AgentRepository) Create(ctx context.Context, agent *model.Agent) error
 // GetByID retrieves an agent by its internal UUID.
 func (r *AgentRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Agent, error) {
 	query := `SELECT * FROM agents WHERE id = $1`
-	return r.scanOne(ctx, query, id)
+	a, err := r.scanOne(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return a, r.loadEndpoints(ctx, a)
 }
 
 // GetByAgentID retrieves an agent by trust_root + agent_id, with capability prefix matching.
@@ -96,7 +100,11 @@ func (r *AgentRepository) GetByAgentID(ctx context.Context, trustRoot, capNode,
 		WHERE trust_root = $1
 		  AND (capability_node = $2 OR capability_node LIKE $3)
 		  AND agent_id = $4`
-	return r.scanOne(ctx, query, trustRoot, capNode, prefix, agentID)
+	a, err := r.scanOne(ctx, query, trustRoot, capNode, prefix, agentID)
+	if err != nil {
+		return nil, err
+	}
+	return a, r.loadEndpoints(ctx, a)
 }
 
 // List returns all agents, with optional filtering by trust_root and capability_node.
@@ -130,7 +138,8 @@ func (r *AgentRepository) List(ctx context.Context, trustRoot, capNode string, l
 	return agents, rows.Err()
 }
 
-// ListByOwnerDomain returns active agents for a given owner domain.
+// ListByOwnerDomain returns active agents for a given owner domain, with their
+// endpoints (it backs the domain's agent card).
 func (r *AgentRepository) ListByOwnerDomain(ctx context.Context, ownerDomain string, limit, offset int) ([]*model.Agent, error) {
 	if limit <= 0 {
 		limit = 50
@@ -156,7 +165,11 @@ func (r *AgentRepository) ListByOwnerDomain(ctx context.Context, ownerDomain str
 		}
 		agents = append(agents, a)
 	}
-	return agents, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return agents, r.loadEndpoints(ctx, agents...)
 }
 
 // SearchByOrg returns all active agents registered under the given org namespace.
@@ -187,7 +200,11 @@ func (r *AgentRepository) SearchByOrg(ctx context.Context, orgName string, limit
 		}
 		agents = append(agents, a)
 	}
-	return agents, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return agents, r.loadEndpoints(ctx, agents...)
 }
 
 // SearchByCapability returns active agents whose capability_node exactly matches
@@ -229,7 +246,11 @@ func (r *AgentRepository) SearchByCapability(ctx context.Context, capability, or
 		}
 		agents = append(agents, a)
 	}
-	return agents, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return agents, r.loadEndpoints(ctx, agents...)
 }
 
 // ListByOwnerUserID returns all agents owned by a specific user, newest first.
@@ -399,7 +420,8 @@ func (r *AgentRepository) ListVerifiedDomainsByUserID(ctx context.Context, owner
 	return domains, rows.Err()
 }
 
-// Update modifies an existing agent record.
+// Update modifies an existing agent record. When agent.Endpoints is non-nil
+// the agent's endpoints are replaced with it in the same transaction.
 func (r *AgentRepository) Update(ctx context.Context, agent *model.Agent) error {
 	meta, err := json.Marshal(agent.Metadata)
 	if err != nil {
@@ -424,7 +446,14 @@ func (r *AgentRepository) Update(ctx context.Context, agent *model.Agent) error
 	if tags == nil {
 		tags = []string{}
 	}
-	tag, err := r.db.Exec(ctx, query,
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, query,
 		agent.ID, agent.DisplayName, agent.Description,
 		agent.Endpoint, agent.PublicKeyPEM, meta, agent.UpdatedAt,
 		agent.Version, tags, agent.SupportURL,
@@ -435,6 +464,25 @@ func (r *AgentRepository) Update(ctx context.Context, agent *model.Agent) error
 	if tag.RowsAffected() == 0 {
 		return ErrNotFound
 	}
+
+	if agent.Endpoints != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM agent_endpoints WHERE agent_id = $1`, agent.ID); err != nil {
+			return fmt.Errorf("delete endpoints: %w", err)
+		}
+		for _, ep := range agent.Endpoints {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO agent_endpoints (agent_id, url, protocol, region, priority, weight)
+				VALUES ($1, $2, $3, $4, $5, $6)`,
+				agent.ID, ep.URL, ep.Protocol, ep.Region, ep.Priority, ep.Weight,
+			); err != nil {
+				return fmt.Errorf("insert endpoint %s: %w", ep.URL, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
 	return nil
 }
 
@@ -577,17 +625,23 @@ func (r *AgentRepository) ListActiveEndpoints(ctx context.Context) ([]*model.Age
 	return agents, rows.Err()
 }
 
-// UpdateHealthStatus updates an agent's health_status and last_seen_at timestamp.
-func (r *AgentRepository) UpdateHealthStatus(ctx context.Context, id uuid.UUID, status string, lastSeenAt time.Time) error {
-	query := `UPDATE agents SET health_status = $2, last_seen_at = $3, updated_at = $4 WHERE id = $1`
-	tag, err := r.db.Exec(ctx, query, id, status, lastSeenAt, time.Now().UTC())
-	if err != nil {
-		return err
+// UpdateHealthStatus updates an agent's health_status and last_seen_at
+// timestamp. changed reports whether health_status differs from its
+// previous value.
+func (r *AgentRepository) UpdateHealthStatus(ctx context.Context, id uuid.UUID, status string, lastSeenAt time.Time) (changed bool, err error) {
+	query := `
+		UPDATE agents a SET health_status = $2, last_seen_at = $3, updated_at = $4
+		FROM (SELECT id, health_status FROM agents WHERE id = $1 FOR UPDATE) prev
+		WHERE a.id = prev.id
+		RETURNING prev.health_status IS DISTINCT FROM $2`
+	err = r.db.QueryRow(ctx, query, id, status, lastSeenAt, time.Now().UTC()).Scan(&changed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrNotFound
 	}
-	if tag.RowsAffected() == 0 {
-		return ErrNotFound
+	if err != nil {
+		return false, err
 	}
-	return nil
+	return changed, nil
 }
 
 // SearchBySkill returns active agents that declare the given skill ID.
@@ -658,6 +712,41 @@ func (r *AgentRepository) SearchByTool(ctx context.Context, toolName string, lim
 	return agents, rows.Err()
 }
 
+// loadEndpoints fills in the Endpoints of each agent, ordered by priority.
+func (r *AgentRepository) loadEndpoints(ctx context.Context, agents ...*model.Agent) error {
+	if len(agents) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*model.Agent, len(agents))
+	ids := make([]uuid.UUID, 0, len(agents))
+	for _, a := range agents {
+		byID[a.ID] = a
+		ids = append(ids, a.ID)
+	}
+
+	query := `
+		SELECT agent_id, url, protocol, region, priority, weight
+		FROM agent_endpoints
+		WHERE agent_id = ANY($1)
+		ORDER BY priority, weight DESC, created_at`
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("query endpoints: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var agentID uuid.UUID
+		var ep model.AgentEndpoint
+		if err := rows.Scan(&agentID, &ep.URL, &ep.Protocol, &ep.Region, &ep.Priority, &ep.Weight); err != nil {
+			return fmt.Errorf("scan endpoint: %w", err)
+		}
+		if a, ok := byID[agentID]; ok {
+			a.Endpoints = append(a.Endpoints, ep)
+		}
+	}
+	return rows.Err()
+}
+
 // scanOne executes a query returning a single agent row.
 func (r *AgentRepository) scanOne(ctx context.Context, query string, args ...any) (*model.Agent, error) {
 	rows, err := r.db.Query(ctx, query, args...)
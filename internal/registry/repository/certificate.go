@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/model"
+)
+
+// CertificateRepository records issued agent certificates and tracks
+// certificate and registration expiry.
+type CertificateRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCertificateRepository creates a new CertificateRepository.
+func NewCertificateRepository(db *pgxpool.Pool) *CertificateRepository {
+	return &CertificateRepository{db: db}
+}
+
+// Create records an issued certificate. Recording the same serial twice is a
+// no-op.
+func (r *CertificateRepository) Create(ctx context.Context, cert *model.Certificate) error {
+	cert.ID = uuid.New()
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO certificates (id, agent_id, serial, pem, issued_at, expires_at, revoked_at, revocation_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (serial) DO NOTHING`,
+		cert.ID, cert.AgentID, cert.Serial, cert.PEM, cert.IssuedAt, cert.ExpiresAt, cert.RevokedAt, cert.RevocationReason,
+	)
+	if err != nil {
+		return fmt.Errorf("insert certificate: %w", err)
+	}
+	return nil
+}
+
+// Renew makes cert the agent's current certificate and records it. An
+// expired agent returns to active; a deprecated agent stays deprecated. When
+// expiresAt is non-nil the registration expiry is moved to it. Only active,
+// deprecated and expired agents can be renewed; ErrNotFound is returned for
+// any other.
+func (r *CertificateRepository) Renew(ctx context.Context, cert *model.Certificate, expiresAt *time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE agents SET
+			cert_serial    = $2,
+			public_key_pem = $3,
+			status         = CASE WHEN status = 'expired' THEN 'active' ELSE status END,
+			expires_at     = COALESCE($4, expires_at),
+			updated_at     = $5
+		WHERE id = $1 AND status IN ('active', 'deprecated', 'expired')`,
+		cert.AgentID, cert.Serial, cert.PEM, expiresAt, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("update agent certificate: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	cert.ID = uuid.New()
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO certificates (id, agent_id, serial, pem, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		cert.ID, cert.AgentID, cert.Serial, cert.PEM, cert.IssuedAt, cert.ExpiresAt,
+	); err != nil {
+		return fmt.Errorf("insert certificate: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// ListUntrackedCerts returns agents whose current certificate has no
+// certificates row — those activated before certificates were recorded. Only
+// ID, CertSerial, PublicKeyPEM (the certificate PEM), Status and UpdatedAt
+// are set.
+func (r *CertificateRepository) ListUntrackedCerts(ctx context.Context) ([]*model.Agent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT a.id, a.cert_serial, a.public_key_pem, a.status, a.updated_at
+		FROM agents a
+		WHERE a.cert_serial <> ''
+		  AND NOT EXISTS (SELECT 1 FROM certificates c WHERE c.serial = a.cert_serial)`)
+	if err != nil {
+		return nil, fmt.Errorf("list untracked certificates: %w", err)
+	}
+	defer rows.Close()
+
+	var agents []*model.Agent
+	for rows.Next() {
+		a := &model.Agent{}
+		if err := rows.Scan(&a.ID, &a.CertSerial, &a.PublicKeyPEM, &a.Status, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan untracked certificate: %w", err)
+		}
+		agents = append(agents, a)
+	}
+	return agents, rows.Err()
+}
+
+// RevokeAgentCerts revokes every certificate of the agent not already
+// revoked, recording the RFC 5280 reason code and revocation time.
+func (r *CertificateRepository) RevokeAgentCerts(ctx context.Context, agentID uuid.UUID, reason int, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE certificates SET revoked_at = $3, revocation_reason = $2
+		WHERE agent_id = $1 AND revoked_at IS NULL`,
+		agentID, reason, at,
+	)
+	if err != nil {
+		return fmt.Errorf("revoke agent certificates: %w", err)
+	}
+	return nil
+}
+
+// ListRevoked returns revoked certificates that have not yet expired, most
+// recently revoked first. Expired certificates drop off the CRL (RFC 5280
+// §3.3).
+func (r *CertificateRepository) ListRevoked(ctx context.Context) ([]model.RevokedCertificate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT c.serial, c.agent_id, c.revoked_at, c.revocation_reason, a.revocation_reason, c.expires_at
+		FROM certificates c
+		JOIN agents a ON a.id = c.agent_id
+		WHERE c.revoked_at IS NOT NULL AND c.expires_at > now()
+		ORDER BY c.revoked_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list revoked certificates: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.RevokedCertificate
+	for rows.Next() {
+		var rc model.RevokedCertificate
+		if err := rows.Scan(&rc.Serial, &rc.AgentID, &rc.RevokedAt, &rc.ReasonCode, &rc.Reason, &rc.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan revoked certificate: %w", err)
+		}
+		out = append(out, rc)
+	}
+	return out, rows.Err()
+}
+
+// GetBySerial returns the certificate with the given serial, or ErrNotFound.
+func (r *CertificateRepository) GetBySerial(ctx context.Context, serial string) (*model.Certificate, error) {
+	c := &model.Certificate{}
+	err := r.db.QueryRow(ctx, `
+		SELECT id, agent_id, serial, pem, issued_at, expires_at, revoked_at, revocation_reason
+		FROM certificates WHERE serial = $1`,
+		serial,
+	).Scan(&c.ID, &c.AgentID, &c.Serial, &c.PEM, &c.IssuedAt, &c.ExpiresAt, &c.RevokedAt, &c.RevocationReason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get certificate: %w", err)
+	}
+	return c, nil
+}
+
+// ListExpiring returns the certificate and registration expiries before
+// `before` of active and deprecated agents, soonest first.
+func (r *CertificateRepository) ListExpiring(ctx context.Context, before time.Time) ([]model.Expiry, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH due AS (
+			SELECT a.id AS agent_id, 'certificate' AS kind, c.expires_at
+			FROM agents a
+			JOIN certificates c ON c.serial = a.cert_serial AND c.agent_id = a.id
+			WHERE a.status IN ('active', 'deprecated')
+			  AND c.revoked_at IS NULL
+			  AND c.expires_at < $1
+			UNION ALL
+			SELECT a.id, 'registration', a.expires_at
+			FROM agents a
+			WHERE a.status IN ('active', 'deprecated')
+			  AND a.expires_at < $1
+		)
+		SELECT d.agent_id, d.kind, d.expires_at, n.agent_id IS NOT NULL
+		FROM due d
+		LEFT JOIN expiry_notices n
+		  ON n.agent_id = d.agent_id AND n.kind = d.kind AND n.expires_at = d.expires_at
+		ORDER BY d.expires_at`,
+		before,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list expiring agents: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.Expiry
+	for rows.Next() {
+		var e model.Expiry
+		if err := rows.Scan(&e.AgentID, &e.Kind, &e.ExpiresAt, &e.Notified); err != nil {
+			return nil, fmt.Errorf("scan expiry: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// Expire marks the agent of e expired, but only while it is still active or
+// deprecated, still holds the certificate with serial and is still past the
+// expiry of that kind: a revocation, suspension or renewal made since e was
+// listed wins. It reports whether the agent was expired.
+func (r *CertificateRepository) Expire(ctx context.Context, e model.Expiry, serial string) (bool, error) {
+	now := time.Now().UTC()
+	tag, err := r.db.Exec(ctx, `
+		UPDATE agents a SET status = 'expired', updated_at = $4
+		WHERE a.id = $1
+		  AND a.status IN ('active', 'deprecated')
+		  AND a.cert_serial = $2
+		  AND CASE WHEN $3::text = 'registration' THEN a.expires_at <= $4
+		           ELSE EXISTS (
+		               SELECT 1 FROM certificates c
+		               WHERE c.agent_id = a.id AND c.serial = a.cert_serial
+		                 AND c.revoked_at IS NULL AND c.expires_at <= $4)
+		      END`,
+		e.AgentID, serial, e.Kind, now,
+	)
+	if err != nil {
+		return false, fmt.Errorf("expire agent: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkNotified records that the owner has been warned about e.
+func (r *CertificateRepository) MarkNotified(ctx context.Context, e model.Expiry) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO expiry_notices (agent_id, kind, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		e.AgentID, e.Kind, e.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("record expiry notice: %w", err)
+	}
+	return nil
+}
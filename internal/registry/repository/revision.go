@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/model"
+)
+
+// ErrRevisionNotFound is returned when an agent revision does not exist.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// RevisionRepository persists agent revision history.
+type RevisionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRevisionRepository creates a new RevisionRepository.
+func NewRevisionRepository(db *pgxpool.Pool) *RevisionRepository {
+	return &RevisionRepository{db: db}
+}
+
+// Create inserts rev as the agent's next revision and sets rev.Revision,
+// rev.ID and rev.CreatedAt. The number is assigned inside the insert; a
+// concurrent writer racing for the same number fails on the unique
+// constraint instead of duplicating it.
+func (r *RevisionRepository) Create(ctx context.Context, rev *model.AgentRevision) error {
+	snapshot, err := json.Marshal(rev.Snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+
+	err = r.db.QueryRow(ctx, `
+		INSERT INTO agent_revisions (agent_id, revision, action, actor, snapshot, rollback_of, ledger_index)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6
+		FROM agent_revisions WHERE agent_id = $1
+		RETURNING id, revision, created_at`,
+		rev.AgentID, rev.Action, rev.Actor, snapshot, rev.RollbackOf, rev.LedgerIndex,
+	).Scan(&rev.ID, &rev.Revision, &rev.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert revision: %w", err)
+	}
+	return nil
+}
+
+// SetLedgerIndex records the trust ledger entry for a revision.
+func (r *RevisionRepository) SetLedgerIndex(ctx context.Context, agentID uuid.UUID, revision, ledgerIndex int) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE agent_revisions SET ledger_index = $3 WHERE agent_id = $1 AND revision = $2`,
+		agentID, revision, ledgerIndex,
+	)
+	if err != nil {
+		return fmt.Errorf("set revision ledger index: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRevisionNotFound
+	}
+	return nil
+}
+
+// List returns an agent's revisions, newest first.
+func (r *RevisionRepository) List(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]*model.AgentRevision, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT id, agent_id, revision, action, actor, snapshot, rollback_of, ledger_index, created_at
+		FROM agent_revisions
+		WHERE agent_id = $1
+		ORDER BY revision DESC
+		LIMIT $2 OFFSET $3`,
+		agentID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revs []*model.AgentRevision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, rows.Err()
+}
+
+// Get returns one revision of an agent.
+func (r *RevisionRepository) Get(ctx context.Context, agentID uuid.UUID, revision int) (*model.AgentRevision, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT id, agent_id, revision, action, actor, snapshot, rollback_of, ledger_index, created_at
+		FROM agent_revisions
+		WHERE agent_id = $1 AND revision = $2`,
+		agentID, revision,
+	)
+	rev, err := scanRevision(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	return rev, err
+}
+
+func scanRevision(row pgx.Row) (*model.AgentRevision, error) {
+	rev := &model.AgentRevision{}
+	var snapshot []byte
+	if err := row.Scan(
+		&rev.ID, &rev.AgentID, &rev.Revision, &rev.Action, &rev.Actor,
+		&snapshot, &rev.RollbackOf, &rev.LedgerIndex, &rev.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan revision: %w", err)
+	}
+	if err := json.Unmarshal(snapshot, &rev.Snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshal revision snapshot: %w", err)
+	}
+	return rev, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+)
+
+// TokenDenylistRepository persists the registry's token denylist. It
+// implements identity.DenylistStore.
+type TokenDenylistRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTokenDenylistRepository creates a new TokenDenylistRepository.
+func NewTokenDenylistRepository(db *pgxpool.Pool) *TokenDenylistRepository {
+	return &TokenDenylistRepository{db: db}
+}
+
+// AddToken records a revoked token. Revoking the same jti twice is a no-op.
+func (r *TokenDenylistRepository) AddToken(ctx context.Context, t identity.RevokedToken) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`,
+		t.JTI, t.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert revoked token: %w", err)
+	}
+	return nil
+}
+
+// PutSubject records a subject's revocation, replacing any earlier one.
+func (r *TokenDenylistRepository) PutSubject(ctx context.Context, s identity.RevokedSubject) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO revoked_token_subjects (subject, revoked_before, expires_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (subject) DO UPDATE SET
+			revoked_before = EXCLUDED.revoked_before,
+			expires_at     = EXCLUDED.expires_at,
+			updated_at     = EXCLUDED.updated_at`,
+		s.Subject, nullTime(s.Before), nullTime(s.ExpiresAt), time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("upsert revoked subject: %w", err)
+	}
+	return nil
+}
+
+// List returns the revoked tokens and subjects that have not expired at now.
+func (r *TokenDenylistRepository) List(ctx context.Context, now time.Time) ([]identity.RevokedToken, []identity.RevokedSubject, error) {
+	rows, err := r.db.Query(ctx, `SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > $1`, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list revoked tokens: %w", err)
+	}
+	var tokens []identity.RevokedToken
+	for rows.Next() {
+		var t identity.RevokedToken
+		if err := rows.Scan(&t.JTI, &t.ExpiresAt); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("scan revoked token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("list revoked tokens: %w", err)
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT subject, revoked_before, expires_at FROM revoked_token_subjects
+		WHERE expires_at IS NULL OR expires_at > $1`, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list revoked subjects: %w", err)
+	}
+	defer rows.Close()
+	var subjects []identity.RevokedSubject
+	for rows.Next() {
+		var (
+			s               identity.RevokedSubject
+			before, expires *time.Time
+		)
+		if err := rows.Scan(&s.Subject, &before, &expires); err != nil {
+			return nil, nil, fmt.Errorf("scan revoked subject: %w", err)
+		}
+		if before != nil {
+			s.Before = *before
+		}
+		if expires != nil {
+			s.ExpiresAt = *expires
+		}
+		subjects = append(subjects, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("list revoked subjects: %w", err)
+	}
+	return tokens, subjects, nil
+}
+
+// DeleteExpired removes revoked tokens and subjects that expired before now.
+func (r *TokenDenylistRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("delete expired revoked tokens: %w", err)
+	}
+	if _, err := r.db.Exec(ctx, `DELETE FROM revoked_token_subjects WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("delete expired revoked subjects: %w", err)
+	}
+	return nil
+}
+
+// nullTime maps the zero time to SQL NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
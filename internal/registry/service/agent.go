@@ -3,9 +3,15 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/base32"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +36,7 @@ type ActivationResult struct {
 
 	// KeyPEM is the agent's RSA private key in PEM format.
 	// This is delivered ONCE at activation time — it is not persisted.
+	// Empty when the certificate was issued for a CSR.
 	KeyPEM string
 
 	// Serial is the certificate serial number in hex.
@@ -88,7 +95,7 @@ type agentRepo interface {
 	ListRevokedCerts(ctx context.Context) ([]*model.Agent, error)
 	Deprecate(ctx context.Context, id uuid.UUID, sunsetDate *time.Time, replacementURI string) error
 	ListActiveEndpoints(ctx context.Context) ([]*model.Agent, error)
-	UpdateHealthStatus(ctx context.Context, id uuid.UUID, status string, lastSeenAt time.Time) error
+	UpdateHealthStatus(ctx context.Context, id uuid.UUID, status string, lastSeenAt time.Time) (changed bool, err error)
 }
 
 // DomainVerifier checks whether a domain has completed DNS-01 verification.
@@ -122,6 +129,35 @@ type WebhookDispatcher interface {
 	Dispatch(ctx context.Context, eventType string, payload map[string]string)
 }
 
+// ResolverNotifier pushes agent lifecycle changes to resolver instances so
+// they drop cached resolutions immediately. *invalidation.Notifier satisfies this.
+type ResolverNotifier interface {
+	NotifyAgentChange(ctx context.Context, eventType, uri, trustRoot, agentID string)
+}
+
+// revisionRepo is the persistence interface for agent revision history.
+// *repository.RevisionRepository satisfies this interface.
+type revisionRepo interface {
+	Create(ctx context.Context, rev *model.AgentRevision) error
+	SetLedgerIndex(ctx context.Context, agentID uuid.UUID, revision, ledgerIndex int) error
+	List(ctx context.Context, agentID uuid.UUID, limit, offset int) ([]*model.AgentRevision, error)
+	Get(ctx context.Context, agentID uuid.UUID, revision int) (*model.AgentRevision, error)
+}
+
+// certStore records issued certificates and tracks expiry.
+// *repository.CertificateRepository satisfies this interface.
+type certStore interface {
+	Create(ctx context.Context, cert *model.Certificate) error
+	Renew(ctx context.Context, cert *model.Certificate, expiresAt *time.Time) error
+	ListUntrackedCerts(ctx context.Context) ([]*model.Agent, error)
+	ListExpiring(ctx context.Context, before time.Time) ([]model.Expiry, error)
+	MarkNotified(ctx context.Context, e model.Expiry) error
+	Expire(ctx context.Context, e model.Expiry, serial string) (bool, error)
+	RevokeAgentCerts(ctx context.Context, agentID uuid.UUID, reason int, at time.Time) error
+	ListRevoked(ctx context.Context) ([]model.RevokedCertificate, error)
+	GetBySerial(ctx context.Context, serial string) (*model.Certificate, error)
+}
+
 // AgentService contains business logic for agent lifecycle management.
 type AgentService struct {
 	repo              agentRepo
@@ -134,6 +170,11 @@ type AgentService struct {
 	threatScorer      threat.Scorer         // nil = no threat scoring
 	remoteResolver    RemoteResolver        // nil = no cross-registry resolution
 	webhookDispatcher WebhookDispatcher     // nil = no webhook dispatch
+	resolverNotifier  ResolverNotifier      // nil = resolvers rely on cache TTL
+	revisions         revisionRepo          // nil = no revision history
+	certs             certStore             // nil = issued certs are not recorded; no renewal, CRL or OCSP
+	crl               crlCache
+	denylist          *identity.Denylist // nil = suspension and revocation leave live tokens valid
 	freeTier          FreeTierConfig
 	registryURL       string // base URL of this registry, used in endorsement JWTs
 	logger            *zap.Logger
@@ -205,6 +246,38 @@ func (s *AgentService) dispatchWebhook(ctx context.Context, eventType string, pa
 	go s.webhookDispatcher.Dispatch(ctx, eventType, payload)
 }
 
+// SetResolverNotifier configures the notifier used to push lifecycle changes
+// (update, activate, revoke, suspend, restore, deprecate, delete, health) to resolvers.
+func (s *AgentService) SetResolverNotifier(rn ResolverNotifier) {
+	s.resolverNotifier = rn
+}
+
+// SetRevisionStore configures where agent revision history is kept. Set to
+// nil to disable revision history.
+func (s *AgentService) SetRevisionStore(store revisionRepo) {
+	s.revisions = store
+}
+
+// SetCertificateStore configures where issued certificates are recorded.
+// Certificate renewal and the expiry sweeper require it.
+func (s *AgentService) SetCertificateStore(store certStore) {
+	s.certs = store
+}
+
+// SetTokenDenylist makes suspension and revocation revoke the agent's Task
+// Tokens, including delegated tokens it acts in, and restoration lift that.
+func (s *AgentService) SetTokenDenylist(d *identity.Denylist) {
+	s.denylist = d
+}
+
+// notifyResolvers tells resolvers that agent changed. The notifier must not block.
+func (s *AgentService) notifyResolvers(ctx context.Context, eventType string, agent *model.Agent) {
+	if s.resolverNotifier == nil {
+		return
+	}
+	s.resolverNotifier.NotifyAgentChange(ctx, eventType, agent.URI(), agent.TrustRoot, agent.AgentID)
+}
+
 // ScoreThreat runs the threat scorer against a registration request without
 // performing any registration. Returns nil when no scorer is configured.
 func (s *AgentService) ScoreThreat(ctx context.Context, req *model.RegisterRequest) (*threat.Report, error) {
@@ -221,11 +294,14 @@ func (s *AgentService) ScoreThreat(ctx context.Context, req *model.RegisterReque
 }
 
 // appendLedger appends an audit entry to the ledger in a non-fatal manner.
-func (s *AgentService) appendLedger(ctx context.Context, agentURI, action, actor string, payload any) {
+// It returns the appended entry, or nil when no ledger is configured or the
+// append failed.
+func (s *AgentService) appendLedger(ctx context.Context, agentURI, action, actor string, payload any) *trustledger.Entry {
 	if s.ledger == nil {
-		return
+		return nil
 	}
-	if _, err := s.ledger.Append(ctx, agentURI, action, actor, payload); err != nil {
+	entry, err := s.ledger.Append(ctx, agentURI, action, actor, payload)
+	if err != nil {
 		s.logger.Error("ledger append failed (non-fatal)",
 			zap.String("action", action),
 			zap.String("agent_uri", agentURI),
@@ -237,6 +313,57 @@ func (s *AgentService) appendLedger(ctx context.Context, agentURI, action, actor
 	// a circular import. This is safe because it's a no-op if metrics aren't
 	// initialised.
 	recordLedgerAppendFn()
+	return entry
+}
+
+// recordRevision stores the agent's current state as its next revision in a
+// non-fatal manner. It returns nil when revision history is disabled or the
+// write failed.
+func (s *AgentService) recordRevision(ctx context.Context, agent *model.Agent, action, actor string, rollbackOf *int) *model.AgentRevision {
+	if s.revisions == nil {
+		return nil
+	}
+	rev := &model.AgentRevision{
+		AgentID:    agent.ID,
+		Action:     action,
+		Actor:      actor,
+		Snapshot:   agent.Snapshot(),
+		RollbackOf: rollbackOf,
+	}
+	if err := s.revisions.Create(ctx, rev); err != nil {
+		s.logger.Error("revision write failed (non-fatal)",
+			zap.String("action", action),
+			zap.String("agent_uri", agent.URI()),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return rev
+}
+
+// linkRevision records the ledger entry for rev. Either may be nil.
+func (s *AgentService) linkRevision(ctx context.Context, rev *model.AgentRevision, entry *trustledger.Entry) {
+	if rev == nil || entry == nil {
+		return
+	}
+	if err := s.revisions.SetLedgerIndex(ctx, rev.AgentID, rev.Revision, entry.Index); err != nil {
+		s.logger.Warn("failed to link revision to ledger entry",
+			zap.String("agent_id", rev.AgentID.String()),
+			zap.Int("revision", rev.Revision),
+			zap.Error(err),
+		)
+		return
+	}
+	rev.LedgerIndex = &entry.Index
+}
+
+// revisionPayload adds the revision number to a ledger payload so the ledger
+// entry links back to the revision.
+func revisionPayload(payload map[string]string, rev *model.AgentRevision) map[string]string {
+	if rev != nil {
+		payload["revision"] = strconv.Itoa(rev.Revision)
+	}
+	return payload
 }
 
 // recordLedgerAppendFn is set by the main package to the metrics recorder.
@@ -391,13 +518,15 @@ func (s *AgentService) Register(ctx context.Context, req *model.RegisterRequest)
 	if actor == "" && req.OwnerUserID != nil {
 		actor = req.OwnerUserID.String()
 	}
-	s.appendLedger(ctx, agent.URI(), "register", actor, map[string]string{
+	rev := s.recordRevision(ctx, agent, model.RevisionActionRegister, actor, nil)
+	entry := s.appendLedger(ctx, agent.URI(), "register", actor, revisionPayload(map[string]string{
 		"trust_root":        agent.TrustRoot,
 		"capability_node":   agent.CapabilityNode,
 		"agent_id":          agent.AgentID,
 		"registration_type": agent.RegistrationType,
 		"endpoint":          agent.Endpoint,
-	})
+	}, rev))
+	s.linkRevision(ctx, rev, entry)
 	s.dispatchWebhook(ctx, "agent.registered", map[string]string{
 		"agent_id": agent.ID.String(),
 		"uri":      agent.URI(),
@@ -476,30 +605,149 @@ func (s *AgentService) Update(ctx context.Context, id uuid.UUID, req *model.Upda
 	if req.SupportURL != "" {
 		agent.SupportURL = req.SupportURL
 	}
+	if req.Endpoints != nil {
+		endpoints, err := normalizeEndpoints(req.Endpoints)
+		if err != nil {
+			return nil, err
+		}
+		agent.Endpoints = endpoints
+		// Without an explicit primary endpoint, the preferred HTTP(S)
+		// endpoint becomes the primary one.
+		if req.Endpoint == "" {
+			for _, ep := range endpoints {
+				if ep.Protocol == model.EndpointProtocolHTTPS || ep.Protocol == model.EndpointProtocolHTTP {
+					agent.Endpoint = ep.URL
+					break
+				}
+			}
+		}
+	}
 
 	if err := s.repo.Update(ctx, agent); err != nil {
 		return nil, fmt.Errorf("update agent: %w", err)
 	}
 
-	s.appendLedger(ctx, agent.URI(), "update", agent.OwnerDomain, map[string]string{
+	payload := map[string]string{
 		"agent_id":     agent.AgentID,
 		"display_name": agent.DisplayName,
 		"endpoint":     agent.Endpoint,
-	})
+	}
+	if req.Endpoints != nil {
+		urls := make([]string, len(agent.Endpoints))
+		for i, ep := range agent.Endpoints {
+			urls[i] = ep.URL
+		}
+		payload["endpoints"] = strings.Join(urls, ",")
+	}
+	actor := req.Actor
+	if actor == "" {
+		actor = agent.OwnerDomain
+	}
+	rev := s.recordRevision(ctx, agent, model.RevisionActionUpdate, actor, nil)
+	entry := s.appendLedger(ctx, agent.URI(), "update", actor, revisionPayload(payload, rev))
+	s.linkRevision(ctx, rev, entry)
+	s.notifyResolvers(ctx, "agent.updated", agent)
 
 	return agent, nil
 }
 
+// ErrRevisionHistoryDisabled is returned by the revision methods when the
+// service has no revision store.
+var ErrRevisionHistoryDisabled = errors.New("revision history is not enabled")
+
+// ListRevisions returns an agent's revisions, newest first.
+func (s *AgentService) ListRevisions(ctx context.Context, id uuid.UUID, limit, offset int) ([]*model.AgentRevision, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionHistoryDisabled
+	}
+	return s.revisions.List(ctx, id, limit, offset)
+}
+
+// GetRevision returns one revision of an agent.
+func (s *AgentService) GetRevision(ctx context.Context, id uuid.UUID, revision int) (*model.AgentRevision, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionHistoryDisabled
+	}
+	return s.revisions.Get(ctx, id, revision)
+}
+
+// DiffRevisions returns the field changes needed to go from revision from to
+// revision to.
+func (s *AgentService) DiffRevisions(ctx context.Context, id uuid.UUID, from, to int) ([]model.FieldChange, error) {
+	a, err := s.GetRevision(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.GetRevision(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	return model.DiffSnapshots(a.Snapshot, b.Snapshot), nil
+}
+
+// Rollback restores the agent's user-editable fields to those of an earlier
+// revision. The restored state is recorded as a new revision, so a rollback
+// can itself be rolled back.
+func (s *AgentService) Rollback(ctx context.Context, id uuid.UUID, revision int, actor string) (*model.Agent, *model.AgentRevision, error) {
+	if s.revisions == nil {
+		return nil, nil, ErrRevisionHistoryDisabled
+	}
+	agent, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := s.revisions.Get(ctx, id, revision)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	agent.ApplySnapshot(target.Snapshot)
+	if err := s.repo.Update(ctx, agent); err != nil {
+		return nil, nil, fmt.Errorf("update agent: %w", err)
+	}
+
+	if actor == "" {
+		actor = agent.OwnerDomain
+	}
+	rev := s.recordRevision(ctx, agent, model.RevisionActionRollback, actor, &target.Revision)
+	entry := s.appendLedger(ctx, agent.URI(), "rollback", actor, revisionPayload(map[string]string{
+		"agent_id":    agent.AgentID,
+		"rollback_of": strconv.Itoa(target.Revision),
+		"endpoint":    agent.Endpoint,
+	}, rev))
+	s.linkRevision(ctx, rev, entry)
+	s.notifyResolvers(ctx, "agent.updated", agent)
+
+	s.logger.Info("agent rolled back",
+		zap.String("agent_id", agent.AgentID),
+		zap.Int("revision", target.Revision),
+		zap.String("actor", actor),
+	)
+	return agent, rev, nil
+}
+
 // Activate transitions an agent from pending to active and, if an Issuer is
 // configured, issues an X.509 agent identity certificate signed by the Nexus CA.
 //
 // For domain agents, DNS-01 verification is required (when dnsVerifier is set).
 // For nap_hosted agents, email verification is required (when emailChecker is set).
 func (s *AgentService) Activate(ctx context.Context, id uuid.UUID) (*ActivationResult, error) {
+	return s.ActivateWithCSR(ctx, id, "")
+}
+
+// ActivateWithCSR is Activate with a PEM-encoded PKCS#10 CSR: the certificate
+// is issued for the CSR's public key and no private key is returned, so the
+// agent's key never leaves the machine that generated it. An empty csrPEM
+// behaves exactly like Activate.
+func (s *AgentService) ActivateWithCSR(ctx context.Context, id uuid.UUID, csrPEM string) (*ActivationResult, error) {
 	agent, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	csr, err := parseAgentCSR(agent, csrPEM)
+	if err != nil {
+		return nil, err
+	}
 
 	if agent.RegistrationType == model.RegistrationTypeNAPHosted {
 		// Gate activation on email verification for hosted agents.
@@ -528,25 +776,19 @@ func (s *AgentService) Activate(ctx context.Context, id uuid.UUID) (*ActivationR
 	result := &ActivationResult{Agent: agent}
 
 	if s.issuer != nil {
-		ownerCN, ownerEmail := agent.OwnerDomain, ""
-		if agent.RegistrationType == model.RegistrationTypeNAPHosted && agent.OwnerUserID != nil && s.ownerInfo != nil {
-			displayName, email, err := s.ownerInfo.GetOwnerInfo(ctx, *agent.OwnerUserID)
-			if err != nil {
-				s.logger.Warn("fetch owner info for cert CN (non-fatal)", zap.Error(err))
-			} else {
-				ownerCN = displayName
-				ownerEmail = email
-			}
-		}
-
-		cert, err := s.issuer.IssueAgentCert(agent.URI(), ownerCN, 365*24*time.Hour, ownerEmail)
+		cert, err := s.issueAgentCert(ctx, agent, csr)
 		if err != nil {
-			return nil, fmt.Errorf("issue agent cert: %w", err)
+			return nil, err
 		}
 
 		if err := s.repo.ActivateWithCert(ctx, id, cert.Serial, cert.CertPEM); err != nil {
 			return nil, fmt.Errorf("activate with cert: %w", err)
 		}
+		if s.certs != nil {
+			if err := s.certs.Create(ctx, certificateRecord(agent.ID, cert)); err != nil {
+				s.logger.Warn("record issued certificate (non-fatal)", zap.Error(err))
+			}
+		}
 
 		result.CertPEM = cert.CertPEM
 		result.KeyPEM = cert.KeyPEM
@@ -594,12 +836,180 @@ func (s *AgentService) Activate(ctx context.Context, id uuid.UUID) (*ActivationR
 		"agent_id": agent.ID.String(),
 		"uri":      agent.URI(),
 	})
+	s.notifyResolvers(ctx, "agent.activated", agent)
 
 	return result, nil
 }
 
+// agentCertValidity is the lifetime of issued agent certificates.
+const agentCertValidity = 365 * 24 * time.Hour
+
+// ErrInvalidCSR is returned by ActivateWithCSR and RenewCert when the CSR
+// cannot be parsed, is not validly self-signed, or carries a key other than
+// the agent's registered public key.
+var ErrInvalidCSR = errors.New("invalid certificate signing request")
+
+// parseAgentCSR parses csrPEM for agent; an empty csrPEM yields a nil CSR.
+// When the agent registered a public key, the CSR must carry that key. A
+// certificate stored by an earlier activation is not a registered key, so a
+// renewal CSR may carry a new one.
+func parseAgentCSR(agent *model.Agent, csrPEM string) (*x509.CertificateRequest, error) {
+	if strings.TrimSpace(csrPEM) == "" {
+		return nil, nil
+	}
+	csr, err := identity.ParseCSR(csrPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+
+	if block, _ := pem.Decode([]byte(agent.PublicKeyPEM)); block == nil || block.Type == "CERTIFICATE" {
+		return csr, nil
+	}
+	registered, err := identity.PublicKeyFromPEM(agent.PublicKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("%w: registered public key: %v", ErrInvalidCSR, err)
+	}
+	if !identity.PublicKeysEqual(registered, csr.PublicKey) {
+		return nil, fmt.Errorf("%w: key does not match the agent's registered public key", ErrInvalidCSR)
+	}
+	return csr, nil
+}
+
+// issueAgentCert issues a certificate for agent. NAP-hosted agents get the
+// owner's display name as CN and verified email as Email SAN; domain agents
+// get the owner domain. With a CSR the certificate is for the CSR's key;
+// otherwise a key pair is generated and returned with the certificate.
+func (s *AgentService) issueAgentCert(ctx context.Context, agent *model.Agent, csr *x509.CertificateRequest) (*identity.IssuedCert, error) {
+	ownerCN, ownerEmail := agent.OwnerDomain, ""
+	if agent.RegistrationType == model.RegistrationTypeNAPHosted && agent.OwnerUserID != nil && s.ownerInfo != nil {
+		displayName, email, err := s.ownerInfo.GetOwnerInfo(ctx, *agent.OwnerUserID)
+		if err != nil {
+			s.logger.Warn("fetch owner info for cert CN (non-fatal)", zap.Error(err))
+		} else {
+			ownerCN = displayName
+			ownerEmail = email
+		}
+	}
+
+	var (
+		cert *identity.IssuedCert
+		err  error
+	)
+	if csr != nil {
+		cert, err = s.issuer.IssueAgentCertForKey(csr.PublicKey, agent.URI(), ownerCN, agentCertValidity, ownerEmail)
+	} else {
+		cert, err = s.issuer.IssueAgentCert(agent.URI(), ownerCN, agentCertValidity, ownerEmail)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("issue agent cert: %w", err)
+	}
+	return cert, nil
+}
+
+// certificateRecord converts an issued certificate to its stored form.
+func certificateRecord(agentID uuid.UUID, cert *identity.IssuedCert) *model.Certificate {
+	return &model.Certificate{
+		AgentID:   agentID,
+		Serial:    cert.Serial,
+		PEM:       cert.CertPEM,
+		IssuedAt:  cert.Cert.NotBefore,
+		ExpiresAt: cert.Cert.NotAfter,
+	}
+}
+
+// ErrRenewalUnavailable is returned by RenewCert when the service has no
+// certificate issuer or certificate store.
+var ErrRenewalUnavailable = errors.New("certificate renewal is not enabled")
+
+// ErrNotRenewable is returned by RenewCert for agents that are not active,
+// deprecated or expired.
+var ErrNotRenewable = errors.New("agent certificate cannot be renewed")
+
+// RenewCert issues a new certificate for an agent, keeping its URI. Expired
+// agents return to active. When the agent has a registration expiry earlier
+// than the new certificate's, the registration is extended to match.
+// The previous certificate stays valid until it expires. As with
+// ActivateWithCSR, a non-empty csrPEM has the certificate issued for the
+// CSR's key instead of a registry-generated one.
+func (s *AgentService) RenewCert(ctx context.Context, id uuid.UUID, csrPEM string) (*ActivationResult, error) {
+	if s.issuer == nil || s.certs == nil {
+		return nil, ErrRenewalUnavailable
+	}
+	agent, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch agent.Status {
+	case model.AgentStatusActive, model.AgentStatusDeprecated, model.AgentStatusExpired:
+	default:
+		return nil, fmt.Errorf("%w: agent is %s", ErrNotRenewable, agent.Status)
+	}
+	csr, err := parseAgentCSR(agent, csrPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := s.issueAgentCert(ctx, agent, csr)
+	if err != nil {
+		return nil, err
+	}
+	var expiresAt *time.Time
+	if agent.ExpiresAt != nil && agent.ExpiresAt.Before(cert.Cert.NotAfter) {
+		expiresAt = &cert.Cert.NotAfter
+	}
+	if err := s.certs.Renew(ctx, certificateRecord(agent.ID, cert), expiresAt); err != nil {
+		return nil, fmt.Errorf("renew certificate: %w", err)
+	}
+
+	previousSerial := agent.CertSerial
+	agent.CertSerial = cert.Serial
+	agent.PublicKeyPEM = cert.CertPEM
+	if agent.Status == model.AgentStatusExpired {
+		agent.Status = model.AgentStatusActive
+	}
+	if expiresAt != nil {
+		agent.ExpiresAt = expiresAt
+	}
+	agent.TrustTier = agent.ComputeTrustTier()
+
+	s.logger.Info("agent certificate renewed",
+		zap.String("agent_uri", agent.URI()),
+		zap.String("serial", cert.Serial),
+		zap.Time("expires_at", cert.Cert.NotAfter),
+	)
+
+	s.appendLedger(ctx, agent.URI(), "renew", "nexus-system", map[string]string{
+		"agent_id":        agent.AgentID,
+		"cert_serial":     cert.Serial,
+		"previous_serial": previousSerial,
+		"expires_at":      cert.Cert.NotAfter.UTC().Format(time.RFC3339),
+	})
+	s.dispatchWebhook(ctx, "agent.renewed", map[string]string{
+		"agent_id":   agent.ID.String(),
+		"uri":        agent.URI(),
+		"expires_at": cert.Cert.NotAfter.UTC().Format(time.RFC3339),
+	})
+	s.notifyResolvers(ctx, "agent.renewed", agent)
+
+	return &ActivationResult{
+		Agent:     agent,
+		CertPEM:   cert.CertPEM,
+		KeyPEM:    cert.KeyPEM,
+		Serial:    cert.Serial,
+		ExpiresAt: cert.Cert.NotAfter,
+		CAPEM:     s.issuer.CACertPEM(),
+	}, nil
+}
+
 // Revoke marks an agent as revoked with an optional reason.
 func (s *AgentService) Revoke(ctx context.Context, id uuid.UUID, reason string) error {
+	return s.RevokeWithCode(ctx, id, reason, identity.ReasonUnspecified)
+}
+
+// RevokeWithCode marks an agent as revoked with an optional free-text reason
+// and revokes its certificates with the given RFC 5280 reason code, so they
+// appear in the CRL and OCSP responses. Its Task Tokens are revoked too.
+func (s *AgentService) RevokeWithCode(ctx context.Context, id uuid.UUID, reason string, code identity.RevocationReason) error {
 	agent, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -615,20 +1025,41 @@ func (s *AgentService) Revoke(ctx context.Context, id uuid.UUID, reason string)
 		}
 	}
 
+	// The agent is revoked from here on, so the ledger, webhooks and
+	// resolvers are told even if revoking its certificates or tokens fails;
+	// those errors are returned afterwards.
+	var errs []error
+	if s.certs != nil {
+		if err := s.certs.RevokeAgentCerts(ctx, id, int(code), time.Now().UTC()); err != nil {
+			errs = append(errs, fmt.Errorf("revoke certificates: %w", err))
+		}
+		s.crl.invalidate()
+	}
+
+	if s.denylist != nil {
+		if err := s.denylist.BlockSubject(ctx, agent.URI()); err != nil {
+			errs = append(errs, fmt.Errorf("revoke tokens: %w", err))
+		}
+	}
+
 	s.appendLedger(ctx, agent.URI(), "revoke", "nexus-system", map[string]string{
-		"agent_id": agent.AgentID,
-		"reason":   reason,
+		"agent_id":    agent.AgentID,
+		"reason":      reason,
+		"reason_code": code.String(),
 	})
 	s.dispatchWebhook(ctx, "agent.revoked", map[string]string{
-		"agent_id": agent.ID.String(),
-		"uri":      agent.URI(),
-		"reason":   reason,
+		"agent_id":    agent.ID.String(),
+		"uri":         agent.URI(),
+		"reason":      reason,
+		"reason_code": code.String(),
 	})
+	s.notifyResolvers(ctx, "agent.revoked", agent)
 
-	return nil
+	return errors.Join(errs...)
 }
 
-// Suspend temporarily disables an active agent.
+// Suspend temporarily disables an active agent. Its Task Tokens are revoked
+// until it is restored.
 func (s *AgentService) Suspend(ctx context.Context, id uuid.UUID) error {
 	agent, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -641,6 +1072,11 @@ func (s *AgentService) Suspend(ctx context.Context, id uuid.UUID) error {
 	if err := s.repo.Suspend(ctx, id); err != nil {
 		return err
 	}
+	if s.denylist != nil {
+		if err := s.denylist.BlockSubject(ctx, agent.URI()); err != nil {
+			return err
+		}
+	}
 
 	s.appendLedger(ctx, agent.URI(), "suspend", "nexus-system", map[string]string{
 		"agent_id": agent.AgentID,
@@ -649,11 +1085,13 @@ func (s *AgentService) Suspend(ctx context.Context, id uuid.UUID) error {
 		"agent_id": agent.ID.String(),
 		"uri":      agent.URI(),
 	})
+	s.notifyResolvers(ctx, "agent.suspended", agent)
 
 	return nil
 }
 
-// Restore re-activates a suspended agent.
+// Restore re-activates a suspended agent. Tokens issued before or during the
+// suspension stay revoked.
 func (s *AgentService) Restore(ctx context.Context, id uuid.UUID) error {
 	agent, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -666,10 +1104,16 @@ func (s *AgentService) Restore(ctx context.Context, id uuid.UUID) error {
 	if err := s.repo.Restore(ctx, id); err != nil {
 		return err
 	}
+	if s.denylist != nil {
+		if err := s.denylist.UnblockSubject(ctx, agent.URI()); err != nil {
+			return err
+		}
+	}
 
 	s.appendLedger(ctx, agent.URI(), "restore", "nexus-system", map[string]string{
 		"agent_id": agent.AgentID,
 	})
+	s.notifyResolvers(ctx, "agent.restored", agent)
 
 	return nil
 }
@@ -716,6 +1160,7 @@ func (s *AgentService) Deprecate(ctx context.Context, id uuid.UUID, req *model.D
 		"uri":             agent.URI(),
 		"replacement_uri": replacementURI,
 	})
+	s.notifyResolvers(ctx, "agent.deprecated", agent)
 
 	return nil
 }
@@ -726,8 +1171,19 @@ func (s *AgentService) ListActiveEndpoints(ctx context.Context) ([]*model.Agent,
 }
 
 // UpdateHealthStatus updates an agent's health status and last-seen timestamp.
+// Resolvers are notified when the status changes, because health_status is
+// part of the resolve answer; a repeated status only refreshes last_seen_at.
 func (s *AgentService) UpdateHealthStatus(ctx context.Context, id uuid.UUID, status string, lastSeenAt time.Time) error {
-	return s.repo.UpdateHealthStatus(ctx, id, status, lastSeenAt)
+	changed, err := s.repo.UpdateHealthStatus(ctx, id, status, lastSeenAt)
+	if err != nil {
+		return err
+	}
+	if changed && s.resolverNotifier != nil {
+		if agent, err := s.repo.GetByID(ctx, id); err == nil {
+			s.notifyResolvers(ctx, "agent.health_changed", agent)
+		}
+	}
+	return nil
 }
 
 // ListByOwnerDomain returns active agents for a given owner domain.
@@ -787,7 +1243,19 @@ func (s *AgentService) SearchByTool(ctx context.Context, toolName string, limit,
 
 // Delete permanently removes an agent record.
 func (s *AgentService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+	if s.resolverNotifier == nil {
+		return s.repo.Delete(ctx, id)
+	}
+
+	agent, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.notifyResolvers(ctx, "agent.deleted", agent)
+	return nil
 }
 
 // generateAgentID produces a unique, sortable Base32 agent identifier.
@@ -807,6 +1275,70 @@ func generateAgentID() (string, error) {
 	return "agent_" + strings.ToLower(encoded), nil
 }
 
+// maxEndpoints bounds how many endpoints one agent may declare.
+const maxEndpoints = 16
+
+// ErrInvalidEndpoints is returned by Update when UpdateRequest.Endpoints fails
+// validation.
+var ErrInvalidEndpoints = errors.New("invalid endpoints")
+
+// normalizeEndpoints validates endpoints, infers missing protocols from the
+// URL scheme and sorts them by priority (highest weight first among equal
+// priorities). The input slice is not modified.
+func normalizeEndpoints(in []model.AgentEndpoint) ([]model.AgentEndpoint, error) {
+	if len(in) > maxEndpoints {
+		return nil, fmt.Errorf("%w: at most %d endpoints are allowed", ErrInvalidEndpoints, maxEndpoints)
+	}
+	out := make([]model.AgentEndpoint, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, ep := range in {
+		u, err := url.Parse(ep.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("%w: endpoint %q is not an absolute URL", ErrInvalidEndpoints, ep.URL)
+		}
+		if seen[ep.URL] {
+			return nil, fmt.Errorf("%w: endpoint %q is listed twice", ErrInvalidEndpoints, ep.URL)
+		}
+		seen[ep.URL] = true
+
+		ep.Protocol = strings.ToLower(ep.Protocol)
+		if ep.Protocol == "" {
+			ep.Protocol = protocolForScheme(u.Scheme)
+		}
+		switch ep.Protocol {
+		case model.EndpointProtocolHTTPS, model.EndpointProtocolHTTP,
+			model.EndpointProtocolGRPC, model.EndpointProtocolWebSocket:
+		default:
+			return nil, fmt.Errorf("%w: endpoint %q: unsupported protocol %q", ErrInvalidEndpoints, ep.URL, ep.Protocol)
+		}
+		if ep.Priority < 0 || ep.Priority > 65535 || ep.Weight < 0 || ep.Weight > 65535 {
+			return nil, fmt.Errorf("%w: endpoint %q: priority and weight must be between 0 and 65535", ErrInvalidEndpoints, ep.URL)
+		}
+		out = append(out, ep)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority < out[j].Priority
+		}
+		return out[i].Weight > out[j].Weight
+	})
+	return out, nil
+}
+
+// protocolForScheme maps a URL scheme to an endpoint protocol.
+func protocolForScheme(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "grpc", "grpcs":
+		return model.EndpointProtocolGRPC
+	case "ws", "wss":
+		return model.EndpointProtocolWebSocket
+	case "http":
+		return model.EndpointProtocolHTTP
+	default:
+		return model.EndpointProtocolHTTPS
+	}
+}
+
 // normalizeCapability lowercases the capability string, trims whitespace, and
 // converts the legacy "/" separator to ">" for backward compatibility.
 func normalizeCapability(cap string) string {
@@ -905,6 +1437,7 @@ func (s *AgentService) generateAgentCard(agent *model.Agent, certSerial string)
 		NAPRegistry:    registry,
 		NAPCertSerial:  certSerial,
 		NAPEndorsement: endorsement,
+		NAPEndpoints:   agent.CardEndpoints(),
 	}
 
 	data, err := json.MarshalIndent(card, "", "  ")
@@ -942,6 +1475,7 @@ func (s *AgentService) GetAgentCardJSON(agent *model.Agent) (string, error) {
 		NAPURI:       agent.URI(),
 		NAPTrustTier: string(tier),
 		NAPRegistry:  registry,
+		NAPEndpoints: agent.CardEndpoints(),
 	}
 	data, err := json.MarshalIndent(card, "", "  ")
 	if err != nil {
@@ -2,17 +2,25 @@ package service_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/model"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/repository"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/service"
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/agentcard"
 	"go.uber.org/zap"
 )
 
@@ -347,16 +355,17 @@ func (s *stubAgentRepo) ListActiveEndpoints(_ context.Context) ([]*model.Agent,
 	return out, nil
 }
 
-func (s *stubAgentRepo) UpdateHealthStatus(_ context.Context, id uuid.UUID, status string, lastSeenAt time.Time) error {
+func (s *stubAgentRepo) UpdateHealthStatus(_ context.Context, id uuid.UUID, status string, lastSeenAt time.Time) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	a, ok := s.rows[id]
 	if !ok {
-		return repository.ErrNotFound
+		return false, repository.ErrNotFound
 	}
+	changed := a.HealthStatus != status
 	a.HealthStatus = status
 	a.LastSeenAt = &lastSeenAt
-	return nil
+	return changed, nil
 }
 
 func (s *stubAgentRepo) SearchBySkill(_ context.Context, skillID string, limit, offset int) ([]*model.Agent, error) {
@@ -612,6 +621,69 @@ func TestActivate_issuesCert(t *testing.T) {
 	}
 }
 
+// testCSR returns a PEM CSR for a fresh P-256 key and the key's PKIX PEM.
+func testCSR(t *testing.T) (csrPEM, pubPEM string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		t.Fatalf("create CSR: %v", err)
+	}
+	pubDER, _ := x509.MarshalPKIXPublicKey(key.Public())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+}
+
+func TestActivateWithCSR_issuesForCSRKey(t *testing.T) {
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, identity.NewIssuer(testCA(t)), nil, nil)
+	csrPEM, pubPEM := testCSR(t)
+
+	agent, _ := svc.Register(context.Background(), testRegisterRequest())
+	result, err := svc.ActivateWithCSR(context.Background(), agent.ID, csrPEM)
+	if err != nil {
+		t.Fatalf("ActivateWithCSR: %v", err)
+	}
+	if result.KeyPEM != "" {
+		t.Error("no private key should be returned for a CSR")
+	}
+	certKey, err := identity.PublicKeyFromPEM(result.CertPEM)
+	if err != nil {
+		t.Fatalf("parse issued cert: %v", err)
+	}
+	csrKey, _ := identity.PublicKeyFromPEM(pubPEM)
+	if !identity.PublicKeysEqual(certKey, csrKey) {
+		t.Error("certificate is not for the CSR's key")
+	}
+	if result.Agent.Status != model.AgentStatusActive {
+		t.Errorf("expected active, got %s", result.Agent.Status)
+	}
+}
+
+func TestActivateWithCSR_mustMatchRegisteredKey(t *testing.T) {
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, identity.NewIssuer(testCA(t)), nil, nil)
+	csrPEM, pubPEM := testCSR(t)
+	otherCSR, _ := testCSR(t)
+
+	req := testRegisterRequest()
+	req.PublicKeyPEM = pubPEM
+	agent, _ := svc.Register(context.Background(), req)
+
+	if _, err := svc.ActivateWithCSR(context.Background(), agent.ID, otherCSR); !errors.Is(err, service.ErrInvalidCSR) {
+		t.Fatalf("CSR for another key: got %v, want ErrInvalidCSR", err)
+	}
+	if stored, _ := repo.GetByID(context.Background(), agent.ID); stored.Status != model.AgentStatusPending {
+		t.Errorf("rejected CSR changed status to %s", stored.Status)
+	}
+	if _, err := svc.ActivateWithCSR(context.Background(), agent.ID, csrPEM); err != nil {
+		t.Fatalf("CSR for the registered key: %v", err)
+	}
+}
+
 func TestActivate_requiresDomainVerification(t *testing.T) {
 	repo := newStubAgentRepo()
 	verifier := &stubDomainVerifier{verified: false}
@@ -701,6 +773,67 @@ func TestUpdate_fields(t *testing.T) {
 	}
 }
 
+func TestUpdate_endpoints(t *testing.T) {
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, nil, nil)
+
+	agent, _ := svc.Register(context.Background(), testRegisterRequest())
+	updated, err := svc.Update(context.Background(), agent.ID, &model.UpdateRequest{
+		Endpoints: []model.AgentEndpoint{
+			{URL: "grpc://grpc.example.com:443", Priority: 0},
+			{URL: "https://eu.example.com", Region: "eu-west-1", Priority: 20},
+			{URL: "https://us.example.com", Protocol: "HTTPS", Region: "us-east-1", Priority: 10, Weight: 5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	want := []string{"grpc://grpc.example.com:443", "https://us.example.com", "https://eu.example.com"}
+	if len(updated.Endpoints) != len(want) {
+		t.Fatalf("got %d endpoints, want %d", len(updated.Endpoints), len(want))
+	}
+	for i, ep := range updated.Endpoints {
+		if ep.URL != want[i] {
+			t.Errorf("endpoint %d = %q, want %q", i, ep.URL, want[i])
+		}
+	}
+	if p := updated.Endpoints[0].Protocol; p != model.EndpointProtocolGRPC {
+		t.Errorf("protocol inferred as %q, want grpc", p)
+	}
+	if p := updated.Endpoints[1].Protocol; p != model.EndpointProtocolHTTPS {
+		t.Errorf("protocol normalised to %q, want https", p)
+	}
+	// The preferred HTTPS endpoint becomes the primary one.
+	if updated.Endpoint != "https://us.example.com" {
+		t.Errorf("primary endpoint = %q, want https://us.example.com", updated.Endpoint)
+	}
+
+	stored, _ := repo.GetByID(context.Background(), agent.ID)
+	if len(stored.Endpoints) != 3 {
+		t.Errorf("stored %d endpoints, want 3", len(stored.Endpoints))
+	}
+}
+
+func TestUpdate_endpointsInvalid(t *testing.T) {
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, nil, nil)
+	agent, _ := svc.Register(context.Background(), testRegisterRequest())
+
+	cases := map[string][]model.AgentEndpoint{
+		"relative URL": {{URL: "/api"}},
+		"bad protocol": {{URL: "ftp://files.example.com", Protocol: "ftp"}},
+		"duplicate":    {{URL: "https://a.example.com"}, {URL: "https://a.example.com"}},
+		"priority":     {{URL: "https://a.example.com", Priority: 70000}},
+	}
+	for name, eps := range cases {
+		_, err := svc.Update(context.Background(), agent.ID, &model.UpdateRequest{Endpoints: eps})
+		if !errors.Is(err, service.ErrInvalidEndpoints) {
+			t.Errorf("%s: got %v, want ErrInvalidEndpoints", name, err)
+		}
+	}
+}
+
 func TestUpdate_notFound(t *testing.T) {
 	svc := newTestAgentService(newStubAgentRepo(), nil, nil, nil)
 	_, err := svc.Update(context.Background(), uuid.New(), &model.UpdateRequest{DisplayName: "x"})
@@ -948,6 +1081,36 @@ func TestRevoke_withReason(t *testing.T) {
 	}
 }
 
+func TestRevoke_certFailureStillAnnounced(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	ledger := trustledger.New()
+	svc := newTestAgentService(repo, nil, ledger, nil)
+	certs := newStubCertStore(repo)
+	certs.revokeErr = errors.New("connection reset")
+	svc.SetCertificateStore(certs)
+	n := &recordingNotifier{}
+	svc.SetResolverNotifier(n)
+
+	agent, _ := svc.Register(ctx, testRegisterRequest())
+	svc.Activate(ctx, agent.ID)
+
+	if err := svc.Revoke(ctx, agent.ID, "key compromise"); !errors.Is(err, certs.revokeErr) {
+		t.Fatalf("Revoke: got %v, want the certificate store error", err)
+	}
+	got, _ := svc.Get(ctx, agent.ID)
+	if got.Status != model.AgentStatusRevoked {
+		t.Errorf("expected revoked, got %s", got.Status)
+	}
+	size, _ := ledger.Len(ctx)
+	if last, _ := ledger.Get(ctx, size-1); last.Action != "revoke" {
+		t.Errorf("last ledger action = %s, want revoke", last.Action)
+	}
+	if len(n.events) == 0 || !strings.HasPrefix(n.events[len(n.events)-1], "agent.revoked ") {
+		t.Errorf("resolver events = %v, want agent.revoked last", n.events)
+	}
+}
+
 func TestSuspend_activeToSuspended(t *testing.T) {
 	repo := newStubAgentRepo()
 	svc := newTestAgentService(repo, nil, nil, nil)
@@ -989,6 +1152,35 @@ func TestRestore_suspendedToActive(t *testing.T) {
 	}
 }
 
+func TestSuspend_revokesTokensUntilRestored(t *testing.T) {
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, nil, nil)
+	denylist := identity.NewDenylist(nil, time.Hour)
+	svc.SetTokenDenylist(denylist)
+
+	agent, _ := svc.Register(context.Background(), testRegisterRequest())
+	svc.Activate(context.Background(), agent.ID)
+	issued := jwt.RegisteredClaims{ID: "t1", Subject: agent.URI(), IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}
+
+	if err := svc.Suspend(context.Background(), agent.ID); err != nil {
+		t.Fatalf("Suspend: %v", err)
+	}
+	if err := denylist.Check(&issued, agent.URI()); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token of a suspended agent: err = %v", err)
+	}
+
+	if err := svc.Restore(context.Background(), agent.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if err := denylist.Check(&issued, agent.URI()); !errors.Is(err, identity.ErrTokenRevoked) {
+		t.Errorf("token issued before the suspension, after restore: err = %v", err)
+	}
+	fresh := jwt.RegisteredClaims{ID: "t2", Subject: agent.URI(), IssuedAt: jwt.NewNumericDate(time.Now().Add(2 * time.Second))}
+	if err := denylist.Check(&fresh, agent.URI()); err != nil {
+		t.Errorf("token issued after restore: %v", err)
+	}
+}
+
 func TestRestore_failsOnRevoked(t *testing.T) {
 	repo := newStubAgentRepo()
 	svc := newTestAgentService(repo, nil, nil, nil)
@@ -1073,3 +1265,251 @@ func TestDeprecate_failsOnPending(t *testing.T) {
 
 // Suppress unused import warning for time package.
 var _ = time.Now
+
+// ── Resolver notification Tests ──────────────────────────────────────────
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (n *recordingNotifier) NotifyAgentChange(_ context.Context, eventType, uri, trustRoot, agentID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, eventType+" "+trustRoot+"/"+agentID)
+}
+
+func TestResolverNotifier_lifecycle(t *testing.T) {
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, nil, nil)
+	n := &recordingNotifier{}
+	svc.SetResolverNotifier(n)
+
+	ctx := context.Background()
+	agent, _ := svc.Register(ctx, testRegisterRequest())
+	svc.Activate(ctx, agent.ID)
+	svc.Update(ctx, agent.ID, &model.UpdateRequest{Endpoint: "https://moved.example.com"})
+	svc.Suspend(ctx, agent.ID)
+	svc.Restore(ctx, agent.ID)
+	svc.Deprecate(ctx, agent.ID, nil)
+	svc.Revoke(ctx, agent.ID, "key compromise")
+	if err := svc.Delete(ctx, agent.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	suffix := " " + agent.TrustRoot + "/" + agent.AgentID
+	want := []string{
+		"agent.activated", "agent.updated", "agent.suspended", "agent.restored",
+		"agent.deprecated", "agent.revoked", "agent.deleted",
+	}
+	if len(n.events) != len(want) {
+		t.Fatalf("events = %v, want %d events", n.events, len(want))
+	}
+	for i, ev := range want {
+		if n.events[i] != ev+suffix {
+			t.Errorf("event %d = %q, want %q", i, n.events[i], ev+suffix)
+		}
+	}
+}
+
+func TestResolverNotifier_healthOnlyOnChange(t *testing.T) {
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, nil, nil)
+	n := &recordingNotifier{}
+	svc.SetResolverNotifier(n)
+
+	ctx := context.Background()
+	agent, _ := svc.Register(ctx, testRegisterRequest())
+	for _, status := range []string{"healthy", "healthy", "healthy", "degraded", "degraded", "healthy"} {
+		if err := svc.UpdateHealthStatus(ctx, agent.ID, status, time.Now()); err != nil {
+			t.Fatalf("UpdateHealthStatus: %v", err)
+		}
+	}
+	if len(n.events) != 3 {
+		t.Errorf("events = %v, want one per status change", n.events)
+	}
+}
+
+// ── Revision history ──────────────────────────────────────────────────────
+
+type stubRevisionRepo struct {
+	mu   sync.Mutex
+	revs map[uuid.UUID][]*model.AgentRevision
+}
+
+func newStubRevisionRepo() *stubRevisionRepo {
+	return &stubRevisionRepo{revs: make(map[uuid.UUID][]*model.AgentRevision)}
+}
+
+func (s *stubRevisionRepo) Create(_ context.Context, rev *model.AgentRevision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rev.ID = uuid.New()
+	rev.Revision = len(s.revs[rev.AgentID]) + 1
+	rev.CreatedAt = time.Now().UTC()
+	cp := *rev
+	s.revs[rev.AgentID] = append(s.revs[rev.AgentID], &cp)
+	return nil
+}
+
+func (s *stubRevisionRepo) SetLedgerIndex(_ context.Context, agentID uuid.UUID, revision, ledgerIndex int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision < 1 || revision > len(s.revs[agentID]) {
+		return repository.ErrRevisionNotFound
+	}
+	s.revs[agentID][revision-1].LedgerIndex = &ledgerIndex
+	return nil
+}
+
+func (s *stubRevisionRepo) List(_ context.Context, agentID uuid.UUID, limit, offset int) ([]*model.AgentRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*model.AgentRevision
+	for i := len(s.revs[agentID]) - 1; i >= 0; i-- {
+		cp := *s.revs[agentID][i]
+		out = append(out, &cp)
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	return out[offset:min(offset+limit, len(out))], nil
+}
+
+func (s *stubRevisionRepo) Get(_ context.Context, agentID uuid.UUID, revision int) (*model.AgentRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision < 1 || revision > len(s.revs[agentID]) {
+		return nil, repository.ErrRevisionNotFound
+	}
+	cp := *s.revs[agentID][revision-1]
+	return &cp, nil
+}
+
+func TestRevisions_recordedAndLinkedToLedger(t *testing.T) {
+	ctx := context.Background()
+	ledger := trustledger.New()
+	svc := newTestAgentService(newStubAgentRepo(), nil, ledger, nil)
+	svc.SetRevisionStore(newStubRevisionRepo())
+
+	req := testRegisterRequest()
+	req.Skills = []agentcard.A2ASkill{{ID: "tax", Name: "Tax"}}
+	agent, _ := svc.Register(ctx, req)
+	if _, err := svc.Update(ctx, agent.ID, &model.UpdateRequest{
+		DisplayName: "Tax Agent v2",
+		Actor:       "user-42",
+	}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	revs, err := svc.ListRevisions(ctx, agent.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListRevisions: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(revs))
+	}
+	latest, first := revs[0], revs[1]
+	if first.Action != model.RevisionActionRegister || first.Actor != "example.com" {
+		t.Errorf("first revision = %s by %s, want register by example.com", first.Action, first.Actor)
+	}
+	if latest.Revision != 2 || latest.Action != model.RevisionActionUpdate || latest.Actor != "user-42" {
+		t.Errorf("latest revision = #%d %s by %s, want #2 update by user-42", latest.Revision, latest.Action, latest.Actor)
+	}
+	if len(latest.Snapshot.Skills) != 1 || latest.Snapshot.Skills[0].ID != "tax" {
+		t.Errorf("snapshot skills = %+v, want the declared skill", latest.Snapshot.Skills)
+	}
+	if _, ok := latest.Snapshot.Metadata[model.MetaKeySkills]; ok {
+		t.Error("snapshot metadata still holds the encoded skills")
+	}
+
+	// Each revision points at its ledger entry, and the entry names the revision.
+	if latest.LedgerIndex == nil {
+		t.Fatal("latest revision has no ledger index")
+	}
+	entry, err := ledger.Get(ctx, *latest.LedgerIndex)
+	if err != nil {
+		t.Fatalf("ledger Get: %v", err)
+	}
+	if entry.Action != "update" || !strings.Contains(string(entry.Payload), `"revision":"2"`) {
+		t.Errorf("ledger entry %d = %s %s, want update naming revision 2", entry.Index, entry.Action, entry.Payload)
+	}
+}
+
+func TestRollback_restoresSnapshot(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, trustledger.New(), nil)
+	svc.SetRevisionStore(newStubRevisionRepo())
+
+	agent, _ := svc.Register(ctx, testRegisterRequest())
+	svc.Update(ctx, agent.ID, &model.UpdateRequest{
+		DisplayName: "Renamed",
+		Endpoint:    "https://moved.example.com",
+		Metadata:    model.AgentMeta{"tier": "gold"},
+	})
+
+	changes, err := svc.DiffRevisions(ctx, agent.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("DiffRevisions: %v", err)
+	}
+	var fields []string
+	for _, ch := range changes {
+		fields = append(fields, ch.Field)
+	}
+	if got, want := strings.Join(fields, ","), "display_name,endpoint,metadata.tier"; got != want {
+		t.Errorf("diff fields = %s, want %s", got, want)
+	}
+
+	restored, rev, err := svc.Rollback(ctx, agent.ID, 1, "user-42")
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if restored.DisplayName != "Tax Agent" || restored.Endpoint != "https://tax.example.com" {
+		t.Errorf("rolled back to %q at %s, want original fields", restored.DisplayName, restored.Endpoint)
+	}
+	if _, ok := restored.Metadata["tier"]; ok {
+		t.Error("metadata added after revision 1 survived the rollback")
+	}
+	if rev.Revision != 3 || rev.Action != model.RevisionActionRollback || rev.RollbackOf == nil || *rev.RollbackOf != 1 {
+		t.Errorf("rollback revision = %+v, want #3 rollback of 1", rev)
+	}
+	stored, _ := repo.GetByID(ctx, agent.ID)
+	if stored.DisplayName != "Tax Agent" {
+		t.Errorf("stored display name = %q, want Tax Agent", stored.DisplayName)
+	}
+
+	if _, _, err := svc.Rollback(ctx, agent.ID, 9, ""); !errors.Is(err, repository.ErrRevisionNotFound) {
+		t.Errorf("rollback to missing revision: got %v, want ErrRevisionNotFound", err)
+	}
+}
+
+func TestRollback_keepsCertificate(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, nil, nil)
+	svc.SetRevisionStore(newStubRevisionRepo())
+
+	agent, _ := svc.Register(ctx, testRegisterRequest())
+	// Activation and renewal replace the certificate without a revision.
+	stored, _ := repo.GetByID(ctx, agent.ID)
+	stored.PublicKeyPEM = "renewed certificate"
+	repo.Update(ctx, stored)
+	svc.Update(ctx, agent.ID, &model.UpdateRequest{DisplayName: "Renamed"})
+
+	restored, _, err := svc.Rollback(ctx, agent.ID, 1, "")
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if restored.PublicKeyPEM != "renewed certificate" {
+		t.Errorf("rollback restored public_key_pem %q", restored.PublicKeyPEM)
+	}
+}
+
+func TestRevisions_disabledWithoutStore(t *testing.T) {
+	svc := newTestAgentService(newStubAgentRepo(), nil, nil, nil)
+	agent, _ := svc.Register(context.Background(), testRegisterRequest())
+	if _, err := svc.ListRevisions(context.Background(), agent.ID, 10, 0); !errors.Is(err, service.ErrRevisionHistoryDisabled) {
+		t.Errorf("got %v, want ErrRevisionHistoryDisabled", err)
+	}
+}
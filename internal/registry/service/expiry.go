@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/email"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/model"
+	"go.uber.org/zap"
+)
+
+// ExpiryConfig holds expiry sweeper configuration.
+type ExpiryConfig struct {
+	CheckInterval time.Duration // how often to sweep; default 1h
+	WarnBefore    time.Duration // how long before expiry owners are warned; default 30 days
+}
+
+// ExpirySweeper warns owners before an agent's certificate or registration
+// expires, and marks the agent expired once it has.
+type ExpirySweeper struct {
+	svc    *AgentService
+	mailer email.EmailSender // nil = webhook warnings only
+	cfg    ExpiryConfig
+	logger *zap.Logger
+}
+
+// NewExpirySweeper creates an ExpirySweeper for svc, which must have a
+// certificate store configured.
+func NewExpirySweeper(svc *AgentService, cfg ExpiryConfig, logger *zap.Logger) *ExpirySweeper {
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = time.Hour
+	}
+	if cfg.WarnBefore <= 0 {
+		cfg.WarnBefore = 30 * 24 * time.Hour
+	}
+	return &ExpirySweeper{svc: svc, cfg: cfg, logger: logger}
+}
+
+// SetEmailSender configures the sender used to email agent owners who have a
+// NAP account. Addresses are looked up through the service's OwnerInfoFetcher.
+func (e *ExpirySweeper) SetEmailSender(m email.EmailSender) {
+	e.mailer = m
+}
+
+// Start runs the sweep loop until ctx is done.
+func (e *ExpirySweeper) Start(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(ctx, e.cfg.CheckInterval-time.Second)
+			if _, _, err := e.Sweep(ctx); err != nil {
+				e.logger.Error("expiry sweep failed", zap.Error(err))
+			}
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sweep warns about every expiry within WarnBefore that has not been warned
+// about yet and expires every agent whose certificate or registration has
+// lapsed. It returns the number of warnings sent and agents expired.
+func (e *ExpirySweeper) Sweep(ctx context.Context) (warned, expired int, err error) {
+	store := e.svc.certs
+	if store == nil {
+		return 0, 0, ErrRenewalUnavailable
+	}
+	e.backfill(ctx)
+
+	now := time.Now().UTC()
+	due, err := store.ListExpiring(ctx, now.Add(e.cfg.WarnBefore))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	handled := make(map[uuid.UUID]bool) // agents already expired this sweep
+	for _, exp := range due {
+		if handled[exp.AgentID] {
+			continue
+		}
+		agent, err := e.svc.repo.GetByID(ctx, exp.AgentID)
+		if err != nil {
+			e.logger.Warn("expiry: load agent", zap.String("id", exp.AgentID.String()), zap.Error(err))
+			continue
+		}
+
+		if !exp.ExpiresAt.After(now) {
+			ok, err := e.expire(ctx, agent, exp)
+			if err != nil {
+				e.logger.Error("expiry: expire agent", zap.String("agent_uri", agent.URI()), zap.Error(err))
+				continue
+			}
+			handled[exp.AgentID] = true
+			if ok {
+				expired++
+			}
+			continue
+		}
+		if exp.Notified {
+			continue
+		}
+		e.warn(ctx, agent, exp)
+		if err := store.MarkNotified(ctx, exp); err != nil {
+			e.logger.Warn("expiry: record notice", zap.String("agent_uri", agent.URI()), zap.Error(err))
+		}
+		warned++
+	}
+
+	if warned > 0 || expired > 0 {
+		e.logger.Info("expiry sweep", zap.Int("warned", warned), zap.Int("expired", expired))
+	}
+	return warned, expired, nil
+}
+
+// backfill records certificates issued before issued certificates were
+// tracked, reading their validity from the stored PEM.
+func (e *ExpirySweeper) backfill(ctx context.Context) {
+	agents, err := e.svc.certs.ListUntrackedCerts(ctx)
+	if err != nil {
+		e.logger.Warn("expiry: list untracked certificates", zap.Error(err))
+		return
+	}
+	for _, a := range agents {
+		block, _ := pem.Decode([]byte(a.PublicKeyPEM))
+		if block == nil || block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			e.logger.Warn("expiry: parse stored certificate", zap.String("id", a.ID.String()), zap.Error(err))
+			continue
+		}
+		record := &model.Certificate{
+			AgentID:   a.ID,
+			Serial:    a.CertSerial,
+			PEM:       a.PublicKeyPEM,
+			IssuedAt:  cert.NotBefore,
+			ExpiresAt: cert.NotAfter,
+		}
+		if a.Status == model.AgentStatusRevoked {
+			revokedAt := a.UpdatedAt
+			record.RevokedAt = &revokedAt
+		}
+		if err := e.svc.certs.Create(ctx, record); err != nil {
+			e.logger.Warn("expiry: record certificate", zap.String("id", a.ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// expire marks agent expired and records why. It reports false, and records
+// nothing, when the agent was revoked, suspended or renewed since exp was
+// listed.
+func (e *ExpirySweeper) expire(ctx context.Context, agent *model.Agent, exp model.Expiry) (bool, error) {
+	ok, err := e.svc.certs.Expire(ctx, exp, agent.CertSerial)
+	if err != nil || !ok {
+		return false, err
+	}
+	agent.Status = model.AgentStatusExpired
+
+	expiresAt := exp.ExpiresAt.UTC().Format(time.RFC3339)
+	e.logger.Info("agent expired",
+		zap.String("agent_uri", agent.URI()),
+		zap.String("kind", exp.Kind),
+		zap.String("expires_at", expiresAt),
+	)
+
+	e.svc.appendLedger(ctx, agent.URI(), "expire", "nexus-system", map[string]string{
+		"agent_id":    agent.AgentID,
+		"kind":        exp.Kind,
+		"expires_at":  expiresAt,
+		"cert_serial": agent.CertSerial,
+	})
+	e.svc.dispatchWebhook(ctx, "agent.expired", map[string]string{
+		"agent_id":   agent.ID.String(),
+		"uri":        agent.URI(),
+		"kind":       exp.Kind,
+		"expires_at": expiresAt,
+	})
+	e.svc.notifyResolvers(ctx, "agent.expired", agent)
+
+	e.mailOwner(ctx, agent,
+		fmt.Sprintf("Your agent %s has expired", agent.DisplayName),
+		fmt.Sprintf("The %s of your agent %s (%s) expired on %s, and it no longer resolves.\n\n%s",
+			exp.Kind, agent.DisplayName, agent.URI(), exp.ExpiresAt.UTC().Format("2 January 2006"), renewalHint(agent)),
+	)
+	return true, nil
+}
+
+// warn tells the owner that agent's certificate or registration expires soon.
+func (e *ExpirySweeper) warn(ctx context.Context, agent *model.Agent, exp model.Expiry) {
+	expiresAt := exp.ExpiresAt.UTC().Format(time.RFC3339)
+	e.svc.dispatchWebhook(ctx, "agent.expiring", map[string]string{
+		"agent_id":   agent.ID.String(),
+		"uri":        agent.URI(),
+		"kind":       exp.Kind,
+		"expires_at": expiresAt,
+	})
+	e.mailOwner(ctx, agent,
+		fmt.Sprintf("Your agent %s expires on %s", agent.DisplayName, exp.ExpiresAt.UTC().Format("2 January 2006")),
+		fmt.Sprintf("The %s of your agent %s (%s) expires on %s. Once it does, the agent will stop resolving.\n\n%s",
+			exp.Kind, agent.DisplayName, agent.URI(), exp.ExpiresAt.UTC().Format("2 January 2006 15:04 MST"), renewalHint(agent)),
+	)
+}
+
+// mailOwner emails the agent's owner when the owner is a NAP user with a
+// known address. Failures are logged, not returned.
+func (e *ExpirySweeper) mailOwner(ctx context.Context, agent *model.Agent, subject, body string) {
+	if e.mailer == nil || e.svc.ownerInfo == nil || agent.OwnerUserID == nil {
+		return
+	}
+	_, addr, err := e.svc.ownerInfo.GetOwnerInfo(ctx, *agent.OwnerUserID)
+	if err != nil || addr == "" {
+		return
+	}
+	if err := e.mailer.Send(ctx, addr, subject, body); err != nil {
+		e.logger.Warn("expiry: send email", zap.String("agent_uri", agent.URI()), zap.Error(err))
+	}
+}
+
+func renewalHint(agent *model.Agent) string {
+	return fmt.Sprintf("Renew the certificate with:\n\n  nap cert renew %s\n\nor POST /api/v1/agents/%s/renew. Renewal keeps the agent URI.",
+		agent.URI(), agent.ID)
+}
@@ -0,0 +1,416 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/model"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/repository"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/service"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
+	"go.uber.org/zap"
+)
+
+// ── In-memory stub for the certificate store ──────────────────────────────
+
+type stubCertStore struct {
+	mu      sync.Mutex
+	agents  *stubAgentRepo
+	certs   map[string]*model.Certificate // serial → cert
+	notices map[string]bool
+
+	beforeExpire func() // runs at the start of Expire when set
+	revokeErr    error  // returned by RevokeAgentCerts when set
+}
+
+func newStubCertStore(agents *stubAgentRepo) *stubCertStore {
+	return &stubCertStore{
+		agents:  agents,
+		certs:   make(map[string]*model.Certificate),
+		notices: make(map[string]bool),
+	}
+}
+
+func noticeKey(e model.Expiry) string {
+	return e.AgentID.String() + "/" + e.Kind + "/" + e.ExpiresAt.String()
+}
+
+func (s *stubCertStore) Create(_ context.Context, cert *model.Certificate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *cert
+	s.certs[cert.Serial] = &cp
+	return nil
+}
+
+func (s *stubCertStore) Renew(_ context.Context, cert *model.Certificate, expiresAt *time.Time) error {
+	s.agents.mu.Lock()
+	a, ok := s.agents.rows[cert.AgentID]
+	if !ok {
+		s.agents.mu.Unlock()
+		return repository.ErrNotFound
+	}
+	a.CertSerial = cert.Serial
+	a.PublicKeyPEM = cert.PEM
+	if a.Status == model.AgentStatusExpired {
+		a.Status = model.AgentStatusActive
+	}
+	if expiresAt != nil {
+		a.ExpiresAt = expiresAt
+	}
+	s.agents.mu.Unlock()
+	return s.Create(context.Background(), cert)
+}
+
+func (s *stubCertStore) ListUntrackedCerts(_ context.Context) ([]*model.Agent, error) {
+	return nil, nil
+}
+
+func (s *stubCertStore) ListExpiring(_ context.Context, before time.Time) ([]model.Expiry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents.mu.RLock()
+	defer s.agents.mu.RUnlock()
+
+	var out []model.Expiry
+	for _, a := range s.agents.rows {
+		if a.Status != model.AgentStatusActive && a.Status != model.AgentStatusDeprecated {
+			continue
+		}
+		if c, ok := s.certs[a.CertSerial]; ok && c.ExpiresAt.Before(before) {
+			out = append(out, model.Expiry{AgentID: a.ID, Kind: model.ExpiryKindCertificate, ExpiresAt: c.ExpiresAt})
+		}
+		if a.ExpiresAt != nil && a.ExpiresAt.Before(before) {
+			out = append(out, model.Expiry{AgentID: a.ID, Kind: model.ExpiryKindRegistration, ExpiresAt: *a.ExpiresAt})
+		}
+	}
+	for i := range out {
+		out[i].Notified = s.notices[noticeKey(out[i])]
+	}
+	return out, nil
+}
+
+func (s *stubCertStore) MarkNotified(_ context.Context, e model.Expiry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notices[noticeKey(e)] = true
+	return nil
+}
+
+func (s *stubCertStore) Expire(_ context.Context, e model.Expiry, serial string) (bool, error) {
+	if s.beforeExpire != nil {
+		s.beforeExpire()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents.mu.Lock()
+	defer s.agents.mu.Unlock()
+
+	a, ok := s.agents.rows[e.AgentID]
+	if !ok || (a.Status != model.AgentStatusActive && a.Status != model.AgentStatusDeprecated) || a.CertSerial != serial {
+		return false, nil
+	}
+	now := time.Now()
+	switch e.Kind {
+	case model.ExpiryKindRegistration:
+		if a.ExpiresAt == nil || a.ExpiresAt.After(now) {
+			return false, nil
+		}
+	default:
+		if c, ok := s.certs[a.CertSerial]; !ok || c.RevokedAt != nil || c.ExpiresAt.After(now) {
+			return false, nil
+		}
+	}
+	a.Status = model.AgentStatusExpired
+	return true, nil
+}
+
+func (s *stubCertStore) RevokeAgentCerts(_ context.Context, agentID uuid.UUID, reason int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.revokeErr != nil {
+		return s.revokeErr
+	}
+	for _, c := range s.certs {
+		if c.AgentID == agentID && c.RevokedAt == nil {
+			revokedAt := at
+			c.RevokedAt, c.RevocationReason = &revokedAt, reason
+		}
+	}
+	return nil
+}
+
+func (s *stubCertStore) ListRevoked(_ context.Context) ([]model.RevokedCertificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []model.RevokedCertificate
+	for _, c := range s.certs {
+		if c.RevokedAt != nil && c.ExpiresAt.After(time.Now()) {
+			out = append(out, model.RevokedCertificate{
+				Serial: c.Serial, AgentID: c.AgentID, RevokedAt: *c.RevokedAt,
+				ReasonCode: c.RevocationReason, ExpiresAt: c.ExpiresAt,
+			})
+		}
+	}
+	return out, nil
+}
+
+func (s *stubCertStore) GetBySerial(_ context.Context, serial string) (*model.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.certs[serial]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+// setCertExpiry moves the expiry of the certificate with serial.
+func (s *stubCertStore) setCertExpiry(serial string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certs[serial].ExpiresAt = at
+}
+
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []string // "to: subject"
+}
+
+func (m *recordingMailer) Send(_ context.Context, to, subject, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, to+": "+subject)
+	return nil
+}
+
+type stubOwnerInfo struct{ email string }
+
+func (s *stubOwnerInfo) GetOwnerInfo(_ context.Context, _ uuid.UUID) (string, string, error) {
+	return "Alice", s.email, nil
+}
+
+// activeAgentWithCert registers and activates an agent with an issued,
+// recorded certificate.
+func activeAgentWithCert(t *testing.T, svc *service.AgentService) *model.Agent {
+	t.Helper()
+	agent, err := svc.Register(context.Background(), napHostedRequest(uuid.New(), "alice"))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	result, err := svc.Activate(context.Background(), agent.ID)
+	if err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	return result.Agent
+}
+
+func TestExpirySweeper_warnsOnceThenExpires(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	certs := newStubCertStore(repo)
+	ledger := trustledger.New()
+	svc := newTestAgentService(repo, identity.NewIssuer(testCA(t)), ledger, nil)
+	svc.SetCertificateStore(certs)
+	svc.SetOwnerInfoFetcher(&stubOwnerInfo{email: "alice@example.com"})
+	notifier := &recordingNotifier{}
+	svc.SetResolverNotifier(notifier)
+	mailer := &recordingMailer{}
+	sweeper := service.NewExpirySweeper(svc, service.ExpiryConfig{WarnBefore: 7 * 24 * time.Hour}, zap.NewNop())
+	sweeper.SetEmailSender(mailer)
+
+	agent := activeAgentWithCert(t, svc)
+
+	// A year out: nothing to do.
+	if warned, expired, err := sweeper.Sweep(ctx); err != nil || warned != 0 || expired != 0 {
+		t.Fatalf("first sweep = (%d, %d, %v), want nothing", warned, expired, err)
+	}
+
+	// Inside the warning window: one warning, not repeated.
+	certs.setCertExpiry(agent.CertSerial, time.Now().Add(48*time.Hour))
+	if warned, _, _ := sweeper.Sweep(ctx); warned != 1 {
+		t.Fatalf("warned = %d, want 1", warned)
+	}
+	if warned, _, _ := sweeper.Sweep(ctx); warned != 0 {
+		t.Errorf("repeat sweep warned %d times, want 0", warned)
+	}
+	if len(mailer.sent) != 1 || !strings.HasPrefix(mailer.sent[0], "alice@example.com: ") {
+		t.Errorf("emails = %v, want one warning to alice@example.com", mailer.sent)
+	}
+
+	// Lapsed: the agent is expired and the ledger records it.
+	certs.setCertExpiry(agent.CertSerial, time.Now().Add(-time.Minute))
+	if _, expired, _ := sweeper.Sweep(ctx); expired != 1 {
+		t.Fatalf("expired = %d, want 1", expired)
+	}
+	stored, _ := repo.GetByID(ctx, agent.ID)
+	if stored.Status != model.AgentStatusExpired {
+		t.Errorf("status = %s, want expired", stored.Status)
+	}
+	n, _ := ledger.Len(ctx)
+	last, _ := ledger.Get(ctx, n-1)
+	if last.Action != "expire" || last.AgentURI != agent.URI() {
+		t.Errorf("last ledger entry = %s %s, want expire %s", last.Action, last.AgentURI, agent.URI())
+	}
+	if got := notifier.events[len(notifier.events)-1]; !strings.HasPrefix(got, "agent.expired ") {
+		t.Errorf("last resolver event = %q, want agent.expired", got)
+	}
+	if len(mailer.sent) != 2 {
+		t.Errorf("sent %d emails, want warning + expiry", len(mailer.sent))
+	}
+}
+
+func TestExpirySweeper_registrationExpiry(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, nil, nil, nil)
+	svc.SetCertificateStore(newStubCertStore(repo))
+
+	agent, _ := svc.Register(ctx, testRegisterRequest())
+	svc.Activate(ctx, agent.ID)
+	past := time.Now().Add(-time.Hour)
+	repo.rows[agent.ID].ExpiresAt = &past
+
+	if _, expired, err := service.NewExpirySweeper(svc, service.ExpiryConfig{}, zap.NewNop()).Sweep(ctx); err != nil || expired != 1 {
+		t.Fatalf("Sweep = (%d, %v), want 1 expired", expired, err)
+	}
+	stored, _ := repo.GetByID(ctx, agent.ID)
+	if stored.Status != model.AgentStatusExpired {
+		t.Errorf("status = %s, want expired", stored.Status)
+	}
+}
+
+func TestExpirySweeper_revocationWins(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	certs := newStubCertStore(repo)
+	ledger := trustledger.New()
+	svc := newTestAgentService(repo, identity.NewIssuer(testCA(t)), ledger, nil)
+	svc.SetCertificateStore(certs)
+	notifier := &recordingNotifier{}
+	svc.SetResolverNotifier(notifier)
+
+	agent := activeAgentWithCert(t, svc)
+	certs.setCertExpiry(agent.CertSerial, time.Now().Add(-time.Minute))
+	// The agent is revoked after the sweep has listed it as expired.
+	certs.beforeExpire = func() {
+		if err := svc.Revoke(ctx, agent.ID, "key compromise"); err != nil {
+			t.Errorf("Revoke: %v", err)
+		}
+	}
+	before, _ := ledger.Len(ctx)
+	events := len(notifier.events)
+
+	if _, expired, err := service.NewExpirySweeper(svc, service.ExpiryConfig{}, zap.NewNop()).Sweep(ctx); err != nil || expired != 0 {
+		t.Fatalf("Sweep = (%d, %v), want nothing expired", expired, err)
+	}
+	stored, _ := repo.GetByID(ctx, agent.ID)
+	if stored.Status != model.AgentStatusRevoked {
+		t.Errorf("status = %s, want revoked", stored.Status)
+	}
+	if n, _ := ledger.Len(ctx); n != before+1 {
+		t.Errorf("ledger grew by %d entries, want only the revocation", n-before)
+	}
+	for _, ev := range notifier.events[events:] {
+		if strings.HasPrefix(ev, "agent.expired ") {
+			t.Errorf("resolver told %q after the revocation", ev)
+		}
+	}
+	if _, err := svc.RenewCert(ctx, agent.ID, ""); !errors.Is(err, service.ErrNotRenewable) {
+		t.Errorf("RenewCert after the sweep: got %v, want ErrNotRenewable", err)
+	}
+}
+
+func TestRenewCert_reissuesAndReactivates(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	certs := newStubCertStore(repo)
+	ledger := trustledger.New()
+	svc := newTestAgentService(repo, identity.NewIssuer(testCA(t)), ledger, nil)
+	svc.SetCertificateStore(certs)
+
+	agent := activeAgentWithCert(t, svc)
+	soon := time.Now().Add(24 * time.Hour)
+	repo.rows[agent.ID].ExpiresAt = &soon
+	repo.UpdateStatus(ctx, agent.ID, model.AgentStatusExpired)
+
+	result, err := svc.RenewCert(ctx, agent.ID, "")
+	if err != nil {
+		t.Fatalf("RenewCert: %v", err)
+	}
+	if result.Serial == "" || result.Serial == agent.CertSerial {
+		t.Errorf("serial = %q, want a new serial (old %q)", result.Serial, agent.CertSerial)
+	}
+	if result.KeyPEM == "" {
+		t.Error("expected a new private key")
+	}
+	if result.Agent.URI() != agent.URI() {
+		t.Errorf("URI changed: %s → %s", agent.URI(), result.Agent.URI())
+	}
+
+	stored, _ := repo.GetByID(ctx, agent.ID)
+	if stored.Status != model.AgentStatusActive {
+		t.Errorf("status = %s, want active", stored.Status)
+	}
+	if stored.CertSerial != result.Serial {
+		t.Errorf("stored serial = %s, want %s", stored.CertSerial, result.Serial)
+	}
+	if stored.ExpiresAt == nil || !stored.ExpiresAt.Equal(result.ExpiresAt) {
+		t.Errorf("registration expiry = %v, want extended to %v", stored.ExpiresAt, result.ExpiresAt)
+	}
+	n, _ := ledger.Len(ctx)
+	if last, _ := ledger.Get(ctx, n-1); last.Action != "renew" {
+		t.Errorf("last ledger action = %s, want renew", last.Action)
+	}
+}
+
+func TestRenewCert_rejectsRevoked(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, identity.NewIssuer(testCA(t)), nil, nil)
+	svc.SetCertificateStore(newStubCertStore(repo))
+
+	agent := activeAgentWithCert(t, svc)
+	svc.Revoke(ctx, agent.ID, "key compromise")
+
+	if _, err := svc.RenewCert(ctx, agent.ID, ""); !errors.Is(err, service.ErrNotRenewable) {
+		t.Errorf("got %v, want ErrNotRenewable", err)
+	}
+}
+
+func TestRenewCert_unavailableWithoutStore(t *testing.T) {
+	svc := newTestAgentService(newStubAgentRepo(), identity.NewIssuer(testCA(t)), nil, nil)
+	if _, err := svc.RenewCert(context.Background(), uuid.New(), ""); !errors.Is(err, service.ErrRenewalUnavailable) {
+		t.Errorf("got %v, want ErrRenewalUnavailable", err)
+	}
+}
+
+func TestRenewCert_withCSRRotatesKey(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, identity.NewIssuer(testCA(t)), nil, nil)
+	svc.SetCertificateStore(newStubCertStore(repo))
+
+	agent := activeAgentWithCert(t, svc)
+	csrPEM, pubPEM := testCSR(t)
+
+	result, err := svc.RenewCert(ctx, agent.ID, csrPEM)
+	if err != nil {
+		t.Fatalf("RenewCert: %v", err)
+	}
+	if result.KeyPEM != "" {
+		t.Error("no private key should be returned for a CSR")
+	}
+	certKey, _ := identity.PublicKeyFromPEM(result.CertPEM)
+	csrKey, _ := identity.PublicKeyFromPEM(pubPEM)
+	if !identity.PublicKeysEqual(certKey, csrKey) {
+		t.Error("renewed certificate is not for the CSR's key")
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/model"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/repository"
+)
+
+const (
+	crlValidity  = 24 * time.Hour // NextUpdate of each published CRL
+	crlRefresh   = time.Hour      // a cached CRL is re-signed this often, and at once after a revocation
+	ocspValidity = time.Hour      // NextUpdate of each OCSP response
+)
+
+// ErrRevocationUnavailable is returned by CRL and OCSPResponse when the
+// service has no certificate issuer or certificate store.
+var ErrRevocationUnavailable = errors.New("certificate revocation checking is not enabled")
+
+// crlCache holds the last signed CRL of each CA generation so every fetch
+// does not cost a signature (a KMS round trip with a remote key store).
+type crlCache struct {
+	mu     sync.Mutex
+	byCA   map[string]signedCRL
+	number int64 // last CRL number issued, shared by all CAs
+}
+
+type signedCRL struct {
+	der        []byte
+	thisUpdate time.Time
+}
+
+func (c *crlCache) invalidate() {
+	c.mu.Lock()
+	c.byCA = nil
+	c.mu.Unlock()
+}
+
+// RevokedCertificates returns the revoked, unexpired agent certificates. Without
+// a certificate store it falls back to the current certificates of revoked
+// agents, with the agent's last update as the revocation time.
+func (s *AgentService) RevokedCertificates(ctx context.Context) ([]model.RevokedCertificate, error) {
+	if s.certs != nil {
+		return s.certs.ListRevoked(ctx)
+	}
+	agents, err := s.repo.ListRevokedCerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.RevokedCertificate, 0, len(agents))
+	for _, a := range agents {
+		out = append(out, model.RevokedCertificate{
+			Serial:    a.CertSerial,
+			AgentID:   a.ID,
+			RevokedAt: a.UpdatedAt,
+			Reason:    a.RevocationReason,
+		})
+	}
+	return out, nil
+}
+
+// CRL returns the DER-encoded X.509 CRL of revoked agent certificates, signed
+// by the CA generation caKID (the active CA when empty). Serials are unique
+// across CA generations, so every CRL lists all revoked certificates. A cached
+// CRL is reused for up to crlRefresh, or until the next revocation. An unknown
+// caKID returns identity.ErrUnknownCA.
+func (s *AgentService) CRL(ctx context.Context, caKID string) ([]byte, error) {
+	if s.issuer == nil || s.certs == nil {
+		return nil, ErrRevocationUnavailable
+	}
+	if caKID == "" {
+		if ids := s.issuer.CAKeyIDs(); len(ids) > 0 {
+			caKID = ids[0]
+		}
+	}
+
+	s.crl.mu.Lock()
+	defer s.crl.mu.Unlock()
+	now := time.Now().UTC()
+	if cached, ok := s.crl.byCA[caKID]; ok && now.Sub(cached.thisUpdate) < crlRefresh {
+		return cached.der, nil
+	}
+
+	revoked, err := s.certs.ListRevoked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]identity.RevokedCert, 0, len(revoked))
+	for _, rc := range revoked {
+		entries = append(entries, identity.RevokedCert{
+			Serial:    rc.Serial,
+			RevokedAt: rc.RevokedAt,
+			Reason:    identity.RevocationReason(rc.ReasonCode),
+		})
+	}
+
+	// CRL numbers must increase; a millisecond clock does so across restarts.
+	number := now.UnixMilli()
+	if number <= s.crl.number {
+		number = s.crl.number + 1
+	}
+	der, err := s.issuer.CreateCRL(caKID, entries, big.NewInt(number), now, now.Add(crlValidity))
+	if err != nil {
+		return nil, err
+	}
+	if s.crl.byCA == nil {
+		s.crl.byCA = make(map[string]signedCRL)
+	}
+	s.crl.byCA[caKID] = signedCRL{der: der, thisUpdate: now}
+	s.crl.number = number
+	return der, nil
+}
+
+// OCSPResponse answers a DER-encoded OCSP request about an agent certificate
+// with a signed response: good, revoked (with time and reason) or unknown.
+// Request errors wrap identity.ErrOCSPMalformedRequest or
+// identity.ErrOCSPWrongIssuer.
+func (s *AgentService) OCSPResponse(ctx context.Context, reqDER []byte) ([]byte, error) {
+	if s.issuer == nil || s.certs == nil {
+		return nil, ErrRevocationUnavailable
+	}
+	serial, caKID, err := s.issuer.ParseOCSPRequest(reqDER)
+	if err != nil {
+		return nil, err
+	}
+
+	known := true
+	var revoked *identity.RevokedCert
+	cert, err := s.certs.GetBySerial(ctx, serial)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		known = false
+	case err != nil:
+		return nil, err
+	case cert.RevokedAt != nil:
+		revoked = &identity.RevokedCert{
+			Serial:    serial,
+			RevokedAt: *cert.RevokedAt,
+			Reason:    identity.RevocationReason(cert.RevocationReason),
+		}
+	}
+
+	now := time.Now().UTC()
+	return s.issuer.CreateOCSPResponse(caKID, serial, known, revoked, now, now.Add(ocspValidity))
+}
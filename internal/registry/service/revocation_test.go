@@ -0,0 +1,245 @@
+package service_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/registry/service"
+	"golang.org/x/crypto/ocsp"
+)
+
+// newRevocationTestService returns a service that issues and records
+// certificates, with CDP and AIA locations set on its issuer.
+func newRevocationTestService(t *testing.T) (*service.AgentService, *identity.CAManager) {
+	t.Helper()
+	ca := testCA(t)
+	issuer := identity.NewIssuer(ca)
+	issuer.SetRevocationURLs("http://registry.test/api/v1/crl.der", "http://registry.test/api/v1/ocsp", "http://registry.test/api/v1/ca.der")
+	repo := newStubAgentRepo()
+	svc := newTestAgentService(repo, issuer, nil, nil)
+	svc.SetCertificateStore(newStubCertStore(repo))
+	return svc, ca
+}
+
+func activationCert(t *testing.T, svc *service.AgentService) (uuid.UUID, *x509.Certificate) {
+	t.Helper()
+	agent, err := svc.Register(context.Background(), napHostedRequest(uuid.New(), "alice"))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	result, err := svc.Activate(context.Background(), agent.ID)
+	if err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	block, _ := pem.Decode([]byte(result.CertPEM))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return agent.ID, cert
+}
+
+func TestIssuedCert_hasRevocationLocations(t *testing.T) {
+	svc, ca := newRevocationTestService(t)
+	_, cert := activationCert(t, svc)
+
+	wantCDP := "http://registry.test/api/v1/crl.der?ca=" + hex.EncodeToString(ca.Cert().SubjectKeyId)
+	if len(cert.CRLDistributionPoints) != 1 || cert.CRLDistributionPoints[0] != wantCDP {
+		t.Errorf("CRLDistributionPoints = %v", cert.CRLDistributionPoints)
+	}
+	if len(cert.OCSPServer) != 1 || cert.OCSPServer[0] != "http://registry.test/api/v1/ocsp" {
+		t.Errorf("OCSPServer = %v", cert.OCSPServer)
+	}
+	if len(cert.IssuingCertificateURL) != 1 || cert.IssuingCertificateURL[0] != "http://registry.test/api/v1/ca.der" {
+		t.Errorf("IssuingCertificateURL = %v", cert.IssuingCertificateURL)
+	}
+}
+
+func TestCRL_listsRevokedCertsWithReason(t *testing.T) {
+	ctx := context.Background()
+	svc, ca := newRevocationTestService(t)
+	id, cert := activationCert(t, svc)
+
+	der, err := svc.CRL(ctx, "")
+	if err != nil {
+		t.Fatalf("CRL: %v", err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("ParseRevocationList: %v", err)
+	}
+	if len(crl.RevokedCertificateEntries) != 0 {
+		t.Fatalf("CRL has %d entries before any revocation", len(crl.RevokedCertificateEntries))
+	}
+	firstNumber := crl.Number
+
+	if err := svc.RevokeWithCode(ctx, id, "laptop stolen", identity.ReasonKeyCompromise); err != nil {
+		t.Fatalf("RevokeWithCode: %v", err)
+	}
+
+	// The revocation invalidates the cached CRL.
+	der, err = svc.CRL(ctx, "")
+	if err != nil {
+		t.Fatalf("CRL: %v", err)
+	}
+	crl, err = x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("ParseRevocationList: %v", err)
+	}
+	if err := crl.CheckSignatureFrom(ca.Cert()); err != nil {
+		t.Errorf("CRL signature: %v", err)
+	}
+	if crl.Number.Cmp(firstNumber) <= 0 {
+		t.Errorf("CRL number did not increase: %v then %v", firstNumber, crl.Number)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 {
+		t.Fatalf("CRL has %d entries, want 1", len(crl.RevokedCertificateEntries))
+	}
+	entry := crl.RevokedCertificateEntries[0]
+	if entry.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		t.Errorf("CRL serial = %x, want %x", entry.SerialNumber, cert.SerialNumber)
+	}
+	if entry.ReasonCode != int(identity.ReasonKeyCompromise) {
+		t.Errorf("CRL reason = %d, want keyCompromise", entry.ReasonCode)
+	}
+	if !crl.NextUpdate.After(crl.ThisUpdate) {
+		t.Errorf("NextUpdate %v not after ThisUpdate %v", crl.NextUpdate, crl.ThisUpdate)
+	}
+}
+
+func TestOCSPResponse_goodThenRevoked(t *testing.T) {
+	ctx := context.Background()
+	svc, ca := newRevocationTestService(t)
+	id, cert := activationCert(t, svc)
+
+	query := func(c *x509.Certificate) *ocsp.Response {
+		t.Helper()
+		req, err := ocsp.CreateRequest(c, ca.Cert(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := svc.OCSPResponse(ctx, req)
+		if err != nil {
+			t.Fatalf("OCSPResponse: %v", err)
+		}
+		resp, err := ocsp.ParseResponseForCert(der, c, ca.Cert())
+		if err != nil {
+			t.Fatalf("ParseResponseForCert: %v", err)
+		}
+		return resp
+	}
+
+	if resp := query(cert); resp.Status != ocsp.Good {
+		t.Errorf("status = %d, want good", resp.Status)
+	}
+
+	if err := svc.RevokeWithCode(ctx, id, "", identity.ReasonCessationOfOperation); err != nil {
+		t.Fatalf("RevokeWithCode: %v", err)
+	}
+	resp := query(cert)
+	if resp.Status != ocsp.Revoked {
+		t.Fatalf("status = %d, want revoked", resp.Status)
+	}
+	if resp.RevocationReason != int(identity.ReasonCessationOfOperation) {
+		t.Errorf("reason = %d, want cessationOfOperation", resp.RevocationReason)
+	}
+
+	// A serial this registry never issued is unknown.
+	stranger := *cert
+	stranger.SerialNumber = big.NewInt(42)
+	if resp := query(&stranger); resp.Status != ocsp.Unknown {
+		t.Errorf("status = %d, want unknown", resp.Status)
+	}
+}
+
+func TestOCSPResponse_rejectsOtherIssuer(t *testing.T) {
+	svc, _ := newRevocationTestService(t)
+	_, cert := activationCert(t, svc)
+
+	other := testCA(t)
+	req, err := ocsp.CreateRequest(cert, other.Cert(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.OCSPResponse(context.Background(), req); !errors.Is(err, identity.ErrOCSPWrongIssuer) {
+		t.Errorf("err = %v, want ErrOCSPWrongIssuer", err)
+	}
+	if _, err := svc.OCSPResponse(context.Background(), []byte("not der")); !errors.Is(err, identity.ErrOCSPMalformedRequest) {
+		t.Errorf("err = %v, want ErrOCSPMalformedRequest", err)
+	}
+}
+
+func TestCRL_unavailableWithoutStore(t *testing.T) {
+	svc := newTestAgentService(newStubAgentRepo(), identity.NewIssuer(testCA(t)), nil, nil)
+	if _, err := svc.CRL(context.Background(), ""); !errors.Is(err, service.ErrRevocationUnavailable) {
+		t.Errorf("err = %v, want ErrRevocationUnavailable", err)
+	}
+}
+
+func TestRevocation_afterCARotation(t *testing.T) {
+	ctx := context.Background()
+	svc, ca := newRevocationTestService(t)
+	oldCA := ca.Cert()
+	id, oldCert := activationCert(t, svc)
+
+	if _, err := ca.Rotate(identity.RotateOptions{GracePeriod: time.Hour}); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if err := svc.RevokeWithCode(ctx, id, "", identity.ReasonSuperseded); err != nil {
+		t.Fatalf("RevokeWithCode: %v", err)
+	}
+
+	// The old certificate's CDP names its own CA, which still signs its CRL.
+	der, err := svc.CRL(ctx, strings.TrimPrefix(oldCert.CRLDistributionPoints[0], "http://registry.test/api/v1/crl.der?ca="))
+	if err != nil {
+		t.Fatalf("CRL for old CA: %v", err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignatureFrom(oldCA); err != nil {
+		t.Errorf("old CA's CRL signature: %v", err)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 {
+		t.Errorf("old CA's CRL has %d entries, want 1", len(crl.RevokedCertificateEntries))
+	}
+	if _, err := svc.CRL(ctx, "0000"); !errors.Is(err, identity.ErrUnknownCA) {
+		t.Errorf("unknown CA: err = %v, want ErrUnknownCA", err)
+	}
+
+	// OCSP answers for the old CA too, signed by it.
+	req, err := ocsp.CreateRequest(oldCert, oldCA, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	respDER, err := svc.OCSPResponse(ctx, req)
+	if err != nil {
+		t.Fatalf("OCSPResponse: %v", err)
+	}
+	resp, err := ocsp.ParseResponseForCert(respDER, oldCert, oldCA)
+	if err != nil {
+		t.Fatalf("ParseResponseForCert: %v", err)
+	}
+	if resp.Status != ocsp.Revoked {
+		t.Errorf("status = %d, want revoked", resp.Status)
+	}
+
+	// New certificates come from the new CA.
+	_, newCert := activationCert(t, svc)
+	if err := newCert.CheckSignatureFrom(ca.Cert()); err != nil {
+		t.Errorf("new cert not signed by the new CA: %v", err)
+	}
+	if newCert.CRLDistributionPoints[0] == oldCert.CRLDistributionPoints[0] {
+		t.Error("new cert has the old CA's CRL location")
+	}
+}
@@ -0,0 +1,238 @@
+package resolver
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TokenVerifier validates bearer tokens. identity.TokenIssuer and
+// identity.TaskTokenVerifier implement it.
+type TokenVerifier interface {
+	Verify(token string) (*identity.TaskTokenClaims, error)
+}
+
+// healthMethodPrefix is exempt from authentication so that load balancers and
+// orchestrators can probe the resolver without credentials.
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+// Authenticator enforces client certificates and/or bearer tokens on resolver
+// calls. With neither enabled every call is allowed.
+//
+// Client certificates must be verified at the TLS layer (ClientAuth
+// VerifyClientCertIfGiven with the registry CA as ClientCAs); the
+// interceptors only require that one was presented and verified, so the
+// health service keeps working without one.
+//
+// A sender-constrained token (one with a "cnf" claim) is only accepted from
+// its holder: over TLS with the client certificate it is bound to, or with a
+// DPoP proof from its key in the "dpop" metadata.
+type Authenticator struct {
+	requireClientCert bool
+	tokens            TokenVerifier
+	audience          string
+	allowNoAudience   bool
+	binding           *identity.BindingVerifier // nil = checked before the call reaches gRPC
+	logger            *zap.Logger
+}
+
+// NewAuthenticator creates an Authenticator that allows every call until
+// SetRequireClientCert or SetTokenVerifier is called.
+func NewAuthenticator(logger *zap.Logger) *Authenticator {
+	return &Authenticator{binding: identity.NewBindingVerifier(""), logger: logger}
+}
+
+// SetRequireClientCert requires a verified TLS client certificate.
+func (a *Authenticator) SetRequireClientCert(require bool) {
+	a.requireClientCert = require
+}
+
+// SetTokenVerifier requires an "authorization: Bearer <token>" header that v
+// accepts, issued for the audience set with SetTokenAudience.
+func (a *Authenticator) SetTokenVerifier(v TokenVerifier) {
+	a.tokens = v
+}
+
+// SetTokenAudience sets the audience bearer tokens must be issued for,
+// usually the registry's issuer URL. Tokens for other audiences, such as an
+// agent's URI, are rejected; so are tokens without an audience unless
+// allowNoAudience is set for tokens issued before audience restriction.
+func (a *Authenticator) SetTokenAudience(audience string, allowNoAudience bool) {
+	a.audience = audience
+	a.allowNoAudience = allowNoAudience
+}
+
+// SetTokenBinding sets the verifier that checks sender-constrained tokens, so
+// that the gRPC and HTTP listeners share its DPoP replay cache. nil skips the
+// check; use it only for a server that is reached solely through a listener
+// wrapped with TokenBindingHTTP.
+func (a *Authenticator) SetTokenBinding(b *identity.BindingVerifier) {
+	a.binding = b
+}
+
+// UnaryInterceptor returns a gRPC unary server interceptor enforcing a.
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := a.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor returns a gRPC stream server interceptor enforcing a.
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// RequireClientCertHTTP wraps next so that requests without a verified TLS
+// client certificate are rejected, except for the paths in exempt. It is a
+// no-op unless SetRequireClientCert(true) was called. Bearer tokens are not
+// checked here: the REST gateway forwards the Authorization header to the
+// gRPC interceptors.
+func (a *Authenticator) RequireClientCertHTTP(next http.Handler, exempt ...string) http.Handler {
+	if !a.requireClientCert {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range exempt {
+			if r.URL.Path == p {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, `{"error":"mTLS required: no verified client certificate"}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TokenBindingHTTP wraps next so that a request carrying a sender-constrained
+// token is rejected unless it comes from the token's holder, shown by the TLS
+// client certificate or a DPoP proof on the HTTP request. The REST gateway
+// needs this because the gRPC server behind it only sees the gateway as its
+// peer. Other token checks are left to the gRPC interceptors.
+func (a *Authenticator) TokenBindingHTTP(next http.Handler) http.Handler {
+	if a.tokens == nil || a.binding == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r.Header.Get("Authorization")); ok {
+			claims, err := a.tokens.Verify(token)
+			if err == nil && claims.Cnf != nil {
+				if err := a.binding.Verify(claims, r, token); err != nil {
+					a.logger.Debug("rejected bearer token from another holder", zap.String("path", r.URL.Path), zap.Error(err))
+					http.Error(w, `{"error":"token not presented by its holder"}`, http.StatusUnauthorized)
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) authorize(ctx context.Context, method string) error {
+	if strings.HasPrefix(method, healthMethodPrefix) {
+		return nil
+	}
+
+	if a.requireClientCert {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return status.Error(codes.Unauthenticated, "mTLS required: no peer information")
+		}
+		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+			return status.Error(codes.Unauthenticated, "mTLS required: no verified client certificate")
+		}
+	}
+
+	if a.tokens != nil {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var header string
+		if v := md.Get("authorization"); len(v) > 0 {
+			header = v[0]
+		}
+		token, ok := bearerToken(header)
+		if !ok {
+			return status.Error(codes.Unauthenticated, "Bearer token required")
+		}
+		claims, err := a.tokens.Verify(token)
+		if err != nil {
+			a.logger.Debug("rejected bearer token", zap.String("method", method), zap.Error(err))
+			return status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if !a.audienceOK(claims) {
+			a.logger.Debug("rejected bearer token for another audience",
+				zap.String("method", method),
+				zap.Strings("audience", claims.Audience),
+			)
+			return status.Error(codes.Unauthenticated, "token not issued for this resolver")
+		}
+		if err := a.checkBinding(ctx, method, token, claims); err != nil {
+			a.logger.Debug("rejected bearer token from another holder", zap.String("method", method), zap.Error(err))
+			return status.Error(codes.Unauthenticated, "token not presented by its holder")
+		}
+	}
+	return nil
+}
+
+// checkBinding verifies that a sender-constrained token is presented by its
+// holder. A gRPC call is an HTTP/2 POST to the method's path, so a DPoP proof
+// for it names POST and scheme://authority/<full method>.
+func (a *Authenticator) checkBinding(ctx context.Context, method, token string, claims *identity.TaskTokenClaims) error {
+	if claims.Cnf == nil || a.binding == nil {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Method: http.MethodPost, URL: &url.URL{Path: method}, Header: http.Header{}}
+	for _, proof := range md.Get("dpop") {
+		r.Header.Add("DPoP", proof)
+	}
+	if v := md.Get(":authority"); len(v) > 0 {
+		r.Host = v[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &tlsInfo.State
+		}
+	}
+	return a.binding.Verify(claims, r, token)
+}
+
+// bearerToken returns the token of an Authorization header sent with the
+// "Bearer" or, for a DPoP-bound token, the "DPoP" scheme.
+func bearerToken(header string) (string, bool) {
+	for _, scheme := range []string{"Bearer ", "DPoP "} {
+		if token, ok := strings.CutPrefix(header, scheme); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// audienceOK reports whether claims were issued for the resolver.
+func (a *Authenticator) audienceOK(claims *identity.TaskTokenClaims) bool {
+	if len(claims.Audience) == 0 {
+		return a.allowNoAudience
+	}
+	return slices.Contains(claims.Audience, a.audience)
+}
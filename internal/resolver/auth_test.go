@@ -0,0 +1,356 @@
+package resolver_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/identity"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startAuthGRPC serves svc and the health service behind auth, with optional
+// TLS, and returns the listen address.
+func startAuthGRPC(t *testing.T, svc *resolver.Service, auth *resolver.Authenticator, tlsCfg *tls.Config) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(auth.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(auth.StreamInterceptor()),
+	}
+	if tlsCfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	srv := grpc.NewServer(opts...)
+	resolverv1.RegisterResolverServiceServer(srv, svc)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestAuthenticator_tokensAndClientCerts(t *testing.T) {
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.example.com", "status": "active"},
+	})
+	defer reg.Close()
+	svc := newTestService(t, reg, time.Minute)
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a"}
+
+	ca := identity.NewCAManager(t.TempDir())
+	if err := ca.Create(); err != nil {
+		t.Fatal(err)
+	}
+	issuer := identity.NewIssuer(ca)
+	tokens := identity.NewTokenIssuer(ca.Key(), "https://registry.example.com", time.Hour)
+
+	t.Run("bearer token", func(t *testing.T) {
+		auth := resolver.NewAuthenticator(zap.NewNop())
+		auth.SetTokenVerifier(identity.NewTaskTokenVerifier(tokens.PublicKey(), "https://registry.example.com"))
+		auth.SetTokenAudience("https://registry.example.com", false)
+		addr := startAuthGRPC(t, svc, auth, nil)
+
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		client := resolverv1.NewResolverServiceClient(conn)
+
+		if _, err := client.Resolve(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("no token: got %v, want Unauthenticated", err)
+		}
+		bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer not-a-token")
+		if _, err := client.Resolve(bad, req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("bad token: got %v, want Unauthenticated", err)
+		}
+
+		caller := "agent://acme.com/finance/agent_caller"
+		token, err := tokens.IssueForAudience(caller, []string{"agent:resolve"}, "https://registry.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		good := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+		if _, err := client.Resolve(good, req); err != nil {
+			t.Errorf("valid token: %v", err)
+		}
+
+		// A token for an agent, or without an audience, is not for the resolver.
+		forAgent, _ := tokens.IssueForAudience(caller, []string{"agent:call"}, "agent://acme.com/finance/agent_b")
+		unrestricted, _ := tokens.Issue(caller, []string{"agent:resolve"})
+		for name, tok := range map[string]string{"other audience": forAgent, "no audience": unrestricted} {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+tok)
+			if _, err := client.Resolve(ctx, req); status.Code(err) != codes.Unauthenticated {
+				t.Errorf("%s: got %v, want Unauthenticated", name, err)
+			}
+		}
+
+		// Health checks never need credentials.
+		if _, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+			t.Errorf("health check without token: %v", err)
+		}
+	})
+
+	t.Run("client certificate", func(t *testing.T) {
+		serverCert, err := issuer.IssueServerCert(nil, []net.IP{net.ParseIP("127.0.0.1")}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverTLS, err := serverCert.TLSCertificate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth := resolver.NewAuthenticator(zap.NewNop())
+		auth.SetRequireClientCert(true)
+		addr := startAuthGRPC(t, svc, auth, &tls.Config{
+			Certificates: []tls.Certificate{serverTLS},
+			ClientCAs:    ca.CertPool(),
+			ClientAuth:   tls.VerifyClientCertIfGiven,
+		})
+
+		dial := func(certs ...tls.Certificate) resolverv1.ResolverServiceClient {
+			conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+				RootCAs:      ca.CertPool(),
+				Certificates: certs,
+			})))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			return resolverv1.NewResolverServiceClient(conn)
+		}
+
+		if _, err := dial().Resolve(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("no client cert: got %v, want Unauthenticated", err)
+		}
+
+		agentCert, err := issuer.IssueAgentCert("agent://acme.com/finance/agent_caller", "acme.com", time.Hour, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientTLS, err := agentCert.TLSCertificate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dial(clientTLS).Resolve(context.Background(), req); err != nil {
+			t.Errorf("with client cert: %v", err)
+		}
+	})
+}
+
+// dpopProof signs a DPoP proof for method and htu with key, covering
+// accessToken.
+func dpopProof(t *testing.T, key ed25519.PrivateKey, method, htu, accessToken string) string {
+	t.Helper()
+	jwk, err := identity.PublicKeyToJWK(key.Public(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"jti": uuid.New().String(),
+		"htm": method,
+		"htu": htu,
+		"iat": time.Now().Unix(),
+		"ath": identity.AccessTokenHash(accessToken),
+	})
+	tok.Header["typ"] = "dpop+jwt"
+	tok.Header["jwk"] = map[string]string{"kty": jwk.Kty, "crv": jwk.Crv, "x": jwk.X}
+	proof, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func TestAuthenticator_boundTokens(t *testing.T) {
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.example.com", "status": "active"},
+	})
+	defer reg.Close()
+	svc := newTestService(t, reg, time.Minute)
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a"}
+
+	ca := identity.NewCAManager(t.TempDir())
+	if err := ca.Create(); err != nil {
+		t.Fatal(err)
+	}
+	issuer := identity.NewIssuer(ca)
+	tokens := identity.NewTokenIssuer(ca.Key(), "https://registry.example.com", time.Hour)
+	const caller = "agent://acme.com/finance/agent_caller"
+	newAuth := func() *resolver.Authenticator {
+		auth := resolver.NewAuthenticator(zap.NewNop())
+		auth.SetTokenVerifier(identity.NewTaskTokenVerifier(tokens.PublicKey(), "https://registry.example.com"))
+		auth.SetTokenAudience("https://registry.example.com", false)
+		return auth
+	}
+
+	t.Run("certificate", func(t *testing.T) {
+		serverCert, err := issuer.IssueServerCert(nil, []net.IP{net.ParseIP("127.0.0.1")}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serverTLS, err := serverCert.TLSCertificate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := startAuthGRPC(t, svc, newAuth(), &tls.Config{
+			Certificates: []tls.Certificate{serverTLS},
+			ClientCAs:    ca.CertPool(),
+			ClientAuth:   tls.VerifyClientCertIfGiven,
+		})
+		holder, _ := issuer.IssueAgentCert(caller, "acme.com", time.Hour, "")
+		other, _ := issuer.IssueAgentCert("agent://acme.com/finance/agent_other", "acme.com", time.Hour, "")
+		token, err := tokens.IssueBound(caller, []string{"agent:resolve"}, "https://registry.example.com", identity.CertConfirmation(holder.Cert))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+
+		for name, tc := range map[string]struct {
+			cert *identity.IssuedCert
+			want codes.Code
+		}{
+			"holder":            {holder, codes.OK},
+			"other certificate": {other, codes.Unauthenticated},
+			"no certificate":    {nil, codes.Unauthenticated},
+		} {
+			var certs []tls.Certificate
+			if tc.cert != nil {
+				c, err := tc.cert.TLSCertificate()
+				if err != nil {
+					t.Fatal(err)
+				}
+				certs = append(certs, c)
+			}
+			conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+				RootCAs:      ca.CertPool(),
+				Certificates: certs,
+			})))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = resolverv1.NewResolverServiceClient(conn).Resolve(ctx, req)
+			conn.Close()
+			if status.Code(err) != tc.want {
+				t.Errorf("%s: got %v, want %v", name, err, tc.want)
+			}
+		}
+	})
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	jkt, err := identity.JWKThumbprint(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := tokens.IssueBound(caller, []string{"agent:resolve"}, "https://registry.example.com", &identity.Confirmation{JKT: jkt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("DPoP over gRPC", func(t *testing.T) {
+		addr := startAuthGRPC(t, svc, newAuth(), nil)
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		client := resolverv1.NewResolverServiceClient(conn)
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "DPoP "+token)
+		if _, err := client.Resolve(ctx, req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("no proof: got %v, want Unauthenticated", err)
+		}
+		proof := dpopProof(t, key, http.MethodPost, "http://"+addr+resolverv1.ResolverService_Resolve_FullMethodName, token)
+		if _, err := client.Resolve(metadata.AppendToOutgoingContext(ctx, "dpop", proof), req); err != nil {
+			t.Errorf("with proof: %v", err)
+		}
+		if _, err := client.Resolve(metadata.AppendToOutgoingContext(ctx, "dpop", proof), req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("replayed proof: got %v, want Unauthenticated", err)
+		}
+	})
+
+	t.Run("DPoP over HTTP", func(t *testing.T) {
+		h := newAuth().TokenBindingHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		for name, tc := range map[string]struct {
+			proof bool
+			want  int
+		}{
+			"no proof":   {false, http.StatusUnauthorized},
+			"with proof": {true, http.StatusOK},
+		} {
+			r := httptest.NewRequest(http.MethodGet, "http://resolver.example/v1/resolve", nil)
+			r.Header.Set("Authorization", "DPoP "+token)
+			if tc.proof {
+				r.Header.Set("DPoP", dpopProof(t, key, http.MethodGet, "http://resolver.example/v1/resolve", token))
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tc.want {
+				t.Errorf("%s: got %d, want %d", name, w.Code, tc.want)
+			}
+		}
+	})
+}
+
+func TestResolve_registryOverMTLS(t *testing.T) {
+	ca := identity.NewCAManager(t.TempDir())
+	if err := ca.Create(); err != nil {
+		t.Fatal(err)
+	}
+	issuer := identity.NewIssuer(ca)
+
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.example.com", "status": "active"},
+	})
+	reg.Close()
+	serverCert, _ := issuer.IssueServerCert(nil, []net.IP{net.ParseIP("127.0.0.1")}, time.Hour)
+	serverTLS, _ := serverCert.TLSCertificate()
+	tlsReg := httptest.NewUnstartedServer(reg.Config.Handler)
+	tlsReg.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverTLS},
+		ClientCAs:    ca.CertPool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	tlsReg.StartTLS()
+	defer tlsReg.Close()
+
+	clientCert, _ := issuer.IssueAgentCert("agent://acme.com/ops/agent_resolver", "acme.com", time.Hour, "")
+	clientTLS, _ := clientCert.TLSCertificate()
+	svc := resolver.New(resolver.Config{
+		RegistryAddr: tlsReg.URL,
+		RegistryTLS:  &tls.Config{RootCAs: ca.CertPool(), Certificates: []tls.Certificate{clientTLS}},
+	}, zap.NewNop())
+
+	resp, err := svc.Resolve(context.Background(), &resolverv1.ResolveRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a",
+	})
+	if err != nil {
+		t.Fatalf("Resolve over mTLS: %v", err)
+	}
+	if resp.Endpoint != "https://a.example.com" {
+		t.Errorf("Endpoint = %q", resp.Endpoint)
+	}
+}
@@ -1,16 +1,46 @@
 package resolver
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
 
+// cacheState describes what a lookup found.
+type cacheState int
+
+const (
+	cacheMiss     cacheState = iota // no usable entry
+	cacheFresh                      // positive entry within its TTL
+	cacheNegative                   // not-found entry within its negative TTL
+	cacheStale                      // positive entry past its TTL but inside the stale window
+)
+
 // cacheEntry holds a cached resolution result.
 type cacheEntry struct {
-	endpoint   string
-	status     string
-	certSerial string
-	expiresAt  time.Time
+	endpoint       string
+	status         string
+	certSerial     string
+	trustTier      string
+	healthStatus   string
+	sunsetDate     *time.Time
+	replacementURI string
+	endpoints      []registryEndpoint
+	expiresAt      time.Time
+
+	// negative marks a cached not-found result.
+	negative bool
+
+	// staleUntil is how long the entry may still be served when the registry
+	// is failing. Equal to expiresAt when serve-stale is disabled.
+	staleUntil time.Time
+
+	// failedAt is when the last refresh of this entry failed; zero when the
+	// registry has answered since the entry was stored.
+	failedAt time.Time
+
+	// refreshing is set while a background refresh is in flight.
+	refreshing bool
 }
 
 func (e *cacheEntry) expired() bool {
@@ -23,53 +53,223 @@ type resolverCache struct {
 	mu      sync.RWMutex
 	entries map[string]*cacheEntry
 	ttl     time.Duration
+
+	negativeTTL time.Duration // 0 disables negative caching
+	staleTTL    time.Duration // 0 disables serve-stale
+
+	// offline keeps every entry servable: expired positive entries are
+	// reported as stale however old they are, and nothing is evicted.
+	offline bool
+
+	// gen counts invalidations. invalidated holds, per trust root + agent ID,
+	// the generation of its last invalidation; a registry answer fetched
+	// before then is dropped instead of stored. The map is only needed while
+	// fetches are in flight and is cleared when the last one ends.
+	gen         uint64
+	invalidated map[string]uint64
+	fetches     int
 }
 
 func newResolverCache(ttl time.Duration) *resolverCache {
 	rc := &resolverCache{
-		entries: make(map[string]*cacheEntry),
-		ttl:     ttl,
+		entries:     make(map[string]*cacheEntry),
+		ttl:         ttl,
+		invalidated: make(map[string]uint64),
 	}
 	return rc
 }
 
-// get looks up a cached entry by agent URI key.
+// beginFetch registers a registry query and returns the generation to pass to
+// setResultSince or setNegativeSince. Every call must be paired with endFetch.
+func (c *resolverCache) beginFetch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetches++
+	return c.gen
+}
+
+// endFetch ends a registry query started with beginFetch.
+func (c *resolverCache) endFetch() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetches--
+	if c.fetches == 0 {
+		clear(c.invalidated)
+	}
+}
+
+// staleLocked reports whether key was invalidated after generation gen. The
+// caller must hold c.mu.
+func (c *resolverCache) staleLocked(key string, gen uint64) bool {
+	return c.invalidated[agentOfKey(key)] > gen
+}
+
+// markInvalidatedLocked records an invalidation of trustRoot + agentID. The
+// caller must hold c.mu for writing.
+func (c *resolverCache) markInvalidatedLocked(agent string) {
+	c.gen++
+	if c.fetches > 0 {
+		c.invalidated[agent] = c.gen
+	}
+}
+
+// get looks up a fresh positive entry by agent URI key.
 func (c *resolverCache) get(key string) (*cacheEntry, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	e, ok := c.entries[key]
-	if !ok || e.expired() {
+	if !ok || e.negative || e.expired() {
 		return nil, false
 	}
 	return e, true
 }
 
+// lookup returns a copy of the entry for key and its state.
+func (c *resolverCache) lookup(key string) (cacheEntry, cacheState) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, cacheMiss
+	}
+	now := time.Now()
+	switch {
+	case e.negative && (c.offline || !now.After(e.expiresAt)):
+		return *e, cacheNegative
+	case !now.After(e.expiresAt):
+		return *e, cacheFresh
+	case !e.negative && (c.offline || !now.After(e.staleUntil)):
+		return *e, cacheStale
+	}
+	return cacheEntry{}, cacheMiss
+}
+
 // set stores an entry in the cache.
 func (c *resolverCache) set(key, endpoint, status, certSerial string) {
+	c.setResult(key, &registryResolveResult{Endpoint: endpoint, Status: status, CertSerial: certSerial})
+}
+
+// setResult stores a registry answer in the cache.
+func (c *resolverCache) setResult(key string, r *registryResolveResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setResultLocked(key, r)
+}
+
+// setResultSince stores a registry answer fetched at generation gen, unless
+// key has been invalidated since: the answer may predate the change that
+// caused the invalidation.
+func (c *resolverCache) setResultSince(key string, r *registryResolveResult, gen uint64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if !c.staleLocked(key, gen) {
+		c.setResultLocked(key, r)
+	}
+}
+
+func (c *resolverCache) setResultLocked(key string, r *registryResolveResult) {
+	expiresAt := time.Now().Add(c.ttl)
 	c.entries[key] = &cacheEntry{
-		endpoint:   endpoint,
-		status:     status,
-		certSerial: certSerial,
-		expiresAt:  time.Now().Add(c.ttl),
+		endpoint:       r.Endpoint,
+		status:         r.Status,
+		certSerial:     r.CertSerial,
+		trustTier:      r.TrustTier,
+		healthStatus:   r.HealthStatus,
+		sunsetDate:     r.SunsetDate,
+		replacementURI: r.ReplacementURI,
+		endpoints:      r.Endpoints,
+		expiresAt:      expiresAt,
+		staleUntil:     expiresAt.Add(c.staleTTL),
 	}
 }
 
+// setNegative records that key was not found. It is a no-op when negative
+// caching is disabled.
+func (c *resolverCache) setNegative(key string) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setNegativeLocked(key)
+}
+
+// setNegativeSince is setNegative for a not-found answer fetched at
+// generation gen; it is dropped if key has been invalidated since.
+func (c *resolverCache) setNegativeSince(key string, gen uint64) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.staleLocked(key, gen) {
+		c.setNegativeLocked(key)
+	}
+}
+
+func (c *resolverCache) setNegativeLocked(key string) {
+	expiresAt := time.Now().Add(c.negativeTTL)
+	c.entries[key] = &cacheEntry{negative: true, expiresAt: expiresAt, staleUntil: expiresAt}
+}
+
+// markFailed records that refreshing key failed and clears its refreshing flag.
+func (c *resolverCache) markFailed(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.failedAt = time.Now()
+		e.refreshing = false
+	}
+}
+
+// startRefresh claims the background refresh of key. It returns false when a
+// refresh is already running or the last failure was less than backoff ago.
+func (c *resolverCache) startRefresh(key string, backoff time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.refreshing || time.Since(e.failedAt) < backoff {
+		return false
+	}
+	e.refreshing = true
+	return true
+}
+
 // invalidate removes a specific entry from the cache.
 func (c *resolverCache) invalidate(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.entries, key)
+	c.markInvalidatedLocked(agentOfKey(key))
 }
 
-// evict removes all expired entries.
+// invalidateAgent removes every entry for trustRoot + agentID, whatever
+// capability path it was looked up under.
+func (c *resolverCache) invalidateAgent(trustRoot, agentID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.markInvalidatedLocked(trustRoot + "/" + agentID)
+	n := 0
+	for k := range c.entries {
+		if keyMatchesAgent(k, trustRoot, agentID) {
+			delete(c.entries, k)
+			n++
+		}
+	}
+	return n
+}
+
+// evict removes all entries that can no longer be served, even as stale.
 func (c *resolverCache) evict() int {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.offline {
+		return 0
+	}
+	now := time.Now()
 	n := 0
 	for k, e := range c.entries {
-		if e.expired() {
+		if now.After(e.staleUntil) {
 			delete(c.entries, k)
 			n++
 		}
@@ -83,3 +283,18 @@ func (c *resolverCache) len() int {
 	defer c.mu.RUnlock()
 	return len(c.entries)
 }
+
+// keyMatchesAgent reports whether a cache key built by buildCacheKey refers to
+// trustRoot + agentID. The registry resolves any prefix of an agent's
+// capability path, so the same agent can be cached under several keys.
+func keyMatchesAgent(key, trustRoot, agentID string) bool {
+	return strings.HasPrefix(key, "agent://"+trustRoot+"/") && strings.HasSuffix(key, "/"+agentID)
+}
+
+// agentOfKey returns the trust root + agent ID a cache key refers to, in the
+// form invalidateAgent records.
+func agentOfKey(key string) string {
+	rest := strings.TrimPrefix(key, "agent://")
+	trustRoot, _, _ := strings.Cut(rest, "/")
+	return trustRoot + "/" + rest[strings.LastIndex(rest, "/")+1:]
+}
@@ -1,8 +1,18 @@
 package resolver
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestCache_SetAndGet(t *testing.T) {
@@ -93,3 +103,172 @@ func TestCache_Len(t *testing.T) {
 		t.Errorf("after 2 sets, len: got %d, want 2", c.len())
 	}
 }
+
+func TestCache_LookupStates(t *testing.T) {
+	c := newResolverCache(10 * time.Millisecond)
+	c.negativeTTL = 10 * time.Millisecond
+	c.staleTTL = time.Minute
+
+	c.set("pos", "e", "active", "")
+	c.setNegative("neg")
+
+	if _, st := c.lookup("pos"); st != cacheFresh {
+		t.Errorf("pos: state %d, want fresh", st)
+	}
+	if _, st := c.lookup("neg"); st != cacheNegative {
+		t.Errorf("neg: state %d, want negative", st)
+	}
+	if _, st := c.lookup("none"); st != cacheMiss {
+		t.Errorf("none: state %d, want miss", st)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, st := c.lookup("pos"); st != cacheStale {
+		t.Errorf("expired pos: state %d, want stale", st)
+	}
+	if _, st := c.lookup("neg"); st != cacheMiss {
+		t.Errorf("expired neg: state %d, want miss", st)
+	}
+	if n := c.evict(); n != 1 {
+		t.Errorf("evict() removed %d entries, want 1 (stale entry is kept)", n)
+	}
+}
+
+func TestResolve_servesStaleWhileRegistryDown(t *testing.T) {
+	defer func(d time.Duration) { staleRefreshBackoff = d }(staleRefreshBackoff)
+	staleRefreshBackoff = 0
+
+	var down atomic.Bool
+	var endpoint atomic.Value
+	endpoint.Store("https://old.example.com")
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"uri": "agent://acme.com/finance/agent_x", "endpoint": endpoint.Load().(string), "status": "active",
+		})
+	}))
+	defer reg.Close()
+
+	svc := New(Config{
+		RegistryAddr: reg.Listener.Addr().String(),
+		CacheTTL:     10 * time.Millisecond,
+		StaleTTL:     time.Minute,
+	}, zap.NewNop())
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_x"}
+	ctx := context.Background()
+
+	if _, err := svc.Resolve(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	down.Store(true)
+	time.Sleep(20 * time.Millisecond)
+
+	// The registry is down: the expired entry is served instead of an error.
+	for i := 0; i < 2; i++ {
+		resp, err := svc.Resolve(ctx, req)
+		if err != nil {
+			t.Fatalf("call %d while registry down: %v", i+1, err)
+		}
+		if resp.Endpoint != "https://old.example.com" {
+			t.Errorf("call %d: endpoint %q", i+1, resp.Endpoint)
+		}
+	}
+
+	// Once the registry recovers, a background refresh replaces the entry.
+	endpoint.Store("https://new.example.com")
+	down.Store(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := svc.Resolve(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Endpoint == "https://new.example.com" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale entry was never refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestResolve_staleNotServedForNotFound(t *testing.T) {
+	var gone atomic.Bool
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gone.Load() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"uri": "agent://acme.com/finance/agent_x", "endpoint": "https://e.example.com", "status": "active",
+		})
+	}))
+	defer reg.Close()
+
+	svc := New(Config{
+		RegistryAddr: reg.Listener.Addr().String(),
+		CacheTTL:     10 * time.Millisecond,
+		StaleTTL:     time.Minute,
+	}, zap.NewNop())
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_x"}
+
+	if _, err := svc.Resolve(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	gone.Store(true)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := svc.Resolve(context.Background(), req); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound once the registry forgets the agent, got %v", err)
+	}
+}
+
+func TestResolve_invalidationDuringFetchIsNotUndone(t *testing.T) {
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"uri": "agent://acme.com/finance/agent_x", "endpoint": "https://old.example.com", "status": "active",
+		})
+	}))
+	defer reg.Close()
+
+	svc := New(Config{RegistryAddr: reg.Listener.Addr().String(), CacheTTL: time.Minute}, zap.NewNop())
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_x"}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.Resolve(context.Background(), req)
+		done <- err
+	}()
+	<-arrived
+	// The agent changes while the registry's (now outdated) answer is in flight.
+	svc.InvalidateAgent("acme.com", "agent_x")
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, st := svc.cache.lookup(buildCacheKey("acme.com", "finance", "agent_x")); st != cacheMiss {
+		t.Errorf("state after invalidation during fetch = %d, want miss", st)
+	}
+	if len(svc.cache.invalidated) != 0 {
+		t.Errorf("%d invalidations still tracked with no fetch in flight", len(svc.cache.invalidated))
+	}
+
+	// Fetches that start after the invalidation are cached as usual.
+	if _, err := svc.Resolve(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if _, st := svc.cache.lookup(buildCacheKey("acme.com", "finance", "agent_x")); st != cacheFresh {
+		t.Errorf("state after a later fetch = %d, want fresh", st)
+	}
+}
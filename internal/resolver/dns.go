@@ -0,0 +1,330 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dnsQueryTimeout bounds how long one DNS query may wait on the registry.
+const dnsQueryTimeout = 3 * time.Second
+
+// DNSServer is an authoritative DNS front-end for agent:// resolution. It
+// answers SRV, TXT and HTTPS queries under a single zone from the same
+// Service (and cache) as the gRPC API, so clients that can only do DNS can
+// still find agents.
+//
+// An agent://{trust-root}/{category}/.../{agent-id} URI is published as
+//
+//	{agent-id}.{category}.{trust-root}.{zone}
+//
+// e.g. agent://acme.com/finance/billing/agent_x → agent_x.finance.acme.com.nap.internal.
+// Only the top-level category is part of the name; the registry resolves an
+// agent by any prefix of its capability path. SRV queries may also use the
+// conventional "_service._proto." prefix, which is ignored.
+type DNSServer struct {
+	svc    *Service
+	zone   string
+	ttl    uint32
+	logger *zap.Logger
+}
+
+// NewDNSServer creates a DNSServer for zone (e.g. "nap.internal") whose
+// answers carry the given TTL.
+func NewDNSServer(svc *Service, zone string, ttl time.Duration, logger *zap.Logger) *DNSServer {
+	return &DNSServer{
+		svc:    svc,
+		zone:   dns.CanonicalName(zone),
+		ttl:    uint32(ttl / time.Second),
+		logger: logger,
+	}
+}
+
+// ListenAndServe answers queries on addr over UDP and TCP until ctx is
+// cancelled.
+func (d *DNSServer) ListenAndServe(ctx context.Context, addr string) error {
+	servers := []*dns.Server{
+		{Addr: addr, Net: "udp", Handler: d},
+		{Addr: addr, Net: "tcp", Handler: d},
+	}
+	errCh := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() { errCh <- srv.ListenAndServe() }()
+	}
+
+	select {
+	case <-ctx.Done():
+	case err := <-errCh:
+		for _, srv := range servers {
+			srv.Shutdown() //nolint:errcheck
+		}
+		return err
+	}
+	for _, srv := range servers {
+		srv.ShutdownContext(context.Background()) //nolint:errcheck
+	}
+	return nil
+}
+
+// ServeDNS implements dns.Handler.
+func (d *DNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) != 1 {
+		m.Rcode = dns.RcodeFormatError
+		d.write(w, r, m)
+		return
+	}
+	q := r.Question[0]
+	name := dns.CanonicalName(q.Name)
+
+	if !dns.IsSubDomain(d.zone, name) {
+		m.Authoritative = false
+		m.Rcode = dns.RcodeRefused
+		d.write(w, r, m)
+		return
+	}
+	if name == d.zone {
+		if q.Qtype == dns.TypeSOA || q.Qtype == dns.TypeANY {
+			m.Answer = append(m.Answer, d.soa())
+		} else {
+			m.Ns = append(m.Ns, d.soa())
+		}
+		d.write(w, r, m)
+		return
+	}
+
+	req, ok := d.requestForName(name)
+	if !ok {
+		d.negative(m, dns.RcodeNameError)
+		d.write(w, r, m)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsQueryTimeout)
+	defer cancel()
+	resp, err := d.svc.Resolve(ctx, req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound, codes.InvalidArgument, codes.FailedPrecondition:
+			d.negative(m, dns.RcodeNameError)
+		default:
+			d.logger.Warn("dns resolve failed", zap.String("name", name), zap.Error(err))
+			m.Rcode = dns.RcodeServerFailure
+		}
+		d.write(w, r, m)
+		return
+	}
+
+	m.Answer = d.answer(q.Name, q.Qtype, resp)
+	if len(m.Answer) == 0 {
+		d.negative(m, dns.RcodeSuccess) // NODATA
+	}
+	d.write(w, r, m)
+}
+
+// requestForName maps a DNS name inside the zone to a ResolveRequest.
+func (d *DNSServer) requestForName(name string) (*resolverv1.ResolveRequest, bool) {
+	rel := strings.TrimSuffix(strings.TrimSuffix(name, d.zone), ".")
+	labels := dns.SplitDomainName(rel)
+	for len(labels) > 0 && strings.HasPrefix(labels[0], "_") {
+		labels = labels[1:]
+	}
+	if len(labels) < 3 {
+		return nil, false
+	}
+	return &resolverv1.ResolveRequest{
+		AgentId:        labels[0],
+		CapabilityNode: labels[1],
+		TrustRoot:      strings.Join(labels[2:], "."),
+	}, true
+}
+
+// answer builds the records of type qtype for a resolution.
+func (d *DNSServer) answer(qname string, qtype uint16, resp *resolverv1.ResolveResponse) []dns.RR {
+	u, err := url.Parse(resp.Endpoint)
+	if err != nil || u.Hostname() == "" {
+		u = nil
+	}
+
+	var out []dns.RR
+	if qtype == dns.TypeTXT || qtype == dns.TypeANY {
+		out = append(out, &dns.TXT{Hdr: d.hdr(qname, dns.TypeTXT), Txt: txtFields(resp)})
+	}
+	if qtype == dns.TypeSRV || qtype == dns.TypeANY {
+		out = append(out, d.srvRecords(qname, u, resp.Endpoints)...)
+	}
+	if u == nil {
+		return out
+	}
+	host, port := u.Hostname(), endpointPort(u)
+	ip := net.ParseIP(host)
+
+	// An HTTPS record advertises an HTTPS origin, so it is only published
+	// for https endpoints.
+	if (qtype == dns.TypeHTTPS || qtype == dns.TypeANY) && u.Scheme == "https" {
+		rr := &dns.HTTPS{SVCB: dns.SVCB{Hdr: d.hdr(qname, dns.TypeHTTPS), Priority: 1, Target: "."}}
+		switch {
+		case ip == nil:
+			rr.Target = dns.Fqdn(host)
+		case ip.To4() != nil:
+			rr.Value = append(rr.Value, &dns.SVCBIPv4Hint{Hint: []net.IP{ip}})
+		default:
+			rr.Value = append(rr.Value, &dns.SVCBIPv6Hint{Hint: []net.IP{ip}})
+		}
+		if port != 443 {
+			rr.Value = append(rr.Value, &dns.SVCBPort{Port: port})
+		}
+		out = append(out, rr)
+	}
+	return out
+}
+
+// srvRecords returns one SRV record per endpoint, carrying its priority and
+// weight, or a single record for the primary endpoint u when the agent
+// declares no endpoints. Endpoints whose host is an IP address are skipped
+// because an SRV target must be a name.
+func (d *DNSServer) srvRecords(qname string, u *url.URL, endpoints []*resolverv1.AgentEndpoint) []dns.RR {
+	var out []dns.RR
+	if len(endpoints) == 0 {
+		if u != nil && net.ParseIP(u.Hostname()) == nil {
+			out = append(out, &dns.SRV{
+				Hdr:    d.hdr(qname, dns.TypeSRV),
+				Port:   endpointPort(u),
+				Target: dns.Fqdn(u.Hostname()),
+			})
+		}
+		return out
+	}
+	for _, ep := range endpoints {
+		eu, err := url.Parse(ep.Url)
+		if err != nil || eu.Hostname() == "" || net.ParseIP(eu.Hostname()) != nil {
+			continue
+		}
+		out = append(out, &dns.SRV{
+			Hdr:      d.hdr(qname, dns.TypeSRV),
+			Priority: uint16(min(ep.Priority, 65535)),
+			Weight:   uint16(min(ep.Weight, 65535)),
+			Port:     endpointPort(eu),
+			Target:   dns.Fqdn(eu.Hostname()),
+		})
+	}
+	return out
+}
+
+// maxTXTString is the longest character-string a TXT record can carry.
+const maxTXTString = 255
+
+// txtFields renders a resolution as "key=value" TXT strings. A field longer
+// than one character-string is split; each continuation string starts with
+// "+" and is appended to the field before it.
+func txtFields(resp *resolverv1.ResolveResponse) []string {
+	fields := []string{"v=nap1", "uri=" + resp.Uri, "endpoint=" + resp.Endpoint, "status=" + resp.Status}
+	for _, kv := range [][2]string{
+		{"cert_serial", resp.CertSerial},
+		{"trust_tier", resp.TrustTier},
+		{"health", resp.HealthStatus},
+		{"replacement", resp.ReplacementUri},
+	} {
+		if kv[1] != "" {
+			fields = append(fields, kv[0]+"="+kv[1])
+		}
+	}
+	var out []string
+	for _, f := range fields {
+		out = append(out, txtChunks(f)...)
+	}
+	return out
+}
+
+// txtChunks splits field into character-strings of at most maxTXTString
+// bytes, prefixing every string after the first with "+". Backslashes and
+// double quotes are escaped because the dns package treats Txt as
+// presentation format.
+func txtChunks(field string) []string {
+	var out []string
+	var b strings.Builder
+	n := 0 // wire bytes in b
+	for i := 0; i < len(field); i++ {
+		if n == maxTXTString {
+			out = append(out, b.String())
+			b.Reset()
+			b.WriteByte('+')
+			n = 1
+		}
+		if c := field[i]; c == '\\' || c == '"' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(field[i])
+		n++
+	}
+	return append(out, b.String())
+}
+
+// endpointPort returns the port of an endpoint URL, defaulting by scheme.
+func endpointPort(u *url.URL) uint16 {
+	if p, err := strconv.ParseUint(u.Port(), 10, 16); err == nil {
+		return uint16(p)
+	}
+	if u.Scheme == "http" {
+		return 80
+	}
+	return 443
+}
+
+func (d *DNSServer) hdr(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: d.ttl}
+}
+
+// soa is the zone's SOA record. Its minimum TTL bounds negative caching.
+func (d *DNSServer) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: d.zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: d.ttl},
+		Ns:      "ns." + d.zone,
+		Mbox:    "hostmaster." + d.zone,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  d.ttl,
+	}
+}
+
+// negative turns m into an NXDOMAIN or NODATA answer.
+func (d *DNSServer) negative(m *dns.Msg, rcode int) {
+	m.Rcode = rcode
+	m.Ns = append(m.Ns, d.soa())
+}
+
+// write sends m, truncating it to the client's UDP buffer size.
+func (d *DNSServer) write(w dns.ResponseWriter, r, m *dns.Msg) {
+	var q dns.Question
+	if len(r.Question) > 0 {
+		q = r.Question[0]
+	}
+	recordDNSQuery(dns.TypeToString[q.Qtype], dns.RcodeToString[m.Rcode])
+
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); udp {
+		size := dns.MinMsgSize
+		if opt := r.IsEdns0(); opt != nil {
+			size = int(opt.UDPSize())
+			m.SetEdns0(opt.UDPSize(), false)
+		}
+		m.Truncate(size)
+	}
+	if err := w.WriteMsg(m); err != nil && !errors.Is(err, net.ErrClosed) {
+		d.logger.Debug("dns write failed", zap.Error(err))
+	}
+}
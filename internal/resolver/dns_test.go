@@ -0,0 +1,202 @@
+package resolver_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/resolver"
+	"github.com/miekg/dns"
+	"go.uber.org/zap"
+)
+
+// startDNS serves d over UDP on a random port and returns its address.
+func startDNS(t *testing.T, d *resolver.DNSServer) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: pc, Handler: d, NotifyStartedFunc: func() { close(started) }}
+	go srv.ActivateAndServe()            //nolint:errcheck
+	t.Cleanup(func() { srv.Shutdown() }) //nolint:errcheck
+	<-started
+	return pc.LocalAddr().String()
+}
+
+func query(t *testing.T, addr, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	resp, _, err := new(dns.Client).Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("query %s %s: %v", name, dns.TypeToString[qtype], err)
+	}
+	return resp
+}
+
+func TestDNS_answersFromResolver(t *testing.T) {
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_x": {"endpoint": "https://billing.acme.com:8443/a2a", "status": "active", "cert_serial": "0a"},
+	})
+	defer reg.Close()
+	svc := newTestService(t, reg, time.Minute)
+	addr := startDNS(t, resolver.NewDNSServer(svc, "nap.internal", 30*time.Second, zap.NewNop()))
+
+	const name = "agent_x.finance.acme.com.nap.internal."
+
+	resp := query(t, addr, "_https._tcp."+name, dns.TypeSRV)
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 || !resp.Authoritative {
+		t.Fatalf("SRV response: %v", resp)
+	}
+	srv := resp.Answer[0].(*dns.SRV)
+	if srv.Target != "billing.acme.com." || srv.Port != 8443 || srv.Hdr.Ttl != 30 {
+		t.Errorf("SRV = %v", srv)
+	}
+
+	resp = query(t, addr, name, dns.TypeTXT)
+	if len(resp.Answer) != 1 {
+		t.Fatalf("TXT response: %v", resp)
+	}
+	txt := strings.Join(resp.Answer[0].(*dns.TXT).Txt, " ")
+	for _, want := range []string{"v=nap1", "uri=agent://acme.com/finance/agent_x", "endpoint=https://billing.acme.com:8443/a2a", "cert_serial=0a"} {
+		if !strings.Contains(txt, want) {
+			t.Errorf("TXT %q missing %q", txt, want)
+		}
+	}
+
+	resp = query(t, addr, name, dns.TypeHTTPS)
+	if len(resp.Answer) != 1 {
+		t.Fatalf("HTTPS response: %v", resp)
+	}
+	https := resp.Answer[0].(*dns.HTTPS)
+	if https.Target != "billing.acme.com." || len(https.Value) != 1 || https.Value[0].String() != "8443" {
+		t.Errorf("HTTPS = %v", https)
+	}
+
+	// A record types the front-end does not publish are NODATA, not NXDOMAIN.
+	resp = query(t, addr, name, dns.TypeA)
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 0 || len(resp.Ns) != 1 {
+		t.Errorf("A response: %v", resp)
+	}
+}
+
+func TestDNS_negativeAnswers(t *testing.T) {
+	reg := stubRegistry(t, map[string]map[string]string{})
+	defer reg.Close()
+	svc := newTestService(t, reg, time.Minute)
+	addr := startDNS(t, resolver.NewDNSServer(svc, "nap.internal.", 30*time.Second, zap.NewNop()))
+
+	resp := query(t, addr, "agent_missing.finance.acme.com.nap.internal.", dns.TypeTXT)
+	if resp.Rcode != dns.RcodeNameError {
+		t.Errorf("missing agent: rcode %s, want NXDOMAIN", dns.RcodeToString[resp.Rcode])
+	}
+	if len(resp.Ns) != 1 || resp.Ns[0].Header().Rrtype != dns.TypeSOA {
+		t.Errorf("NXDOMAIN should carry the zone SOA, got %v", resp.Ns)
+	}
+
+	resp = query(t, addr, "finance.nap.internal.", dns.TypeTXT)
+	if resp.Rcode != dns.RcodeNameError {
+		t.Errorf("short name: rcode %s, want NXDOMAIN", dns.RcodeToString[resp.Rcode])
+	}
+
+	resp = query(t, addr, "agent_x.finance.acme.com.example.org.", dns.TypeTXT)
+	if resp.Rcode != dns.RcodeRefused {
+		t.Errorf("out of zone: rcode %s, want REFUSED", dns.RcodeToString[resp.Rcode])
+	}
+}
+
+func TestDNS_srvPerEndpoint(t *testing.T) {
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"uri":      "agent://acme.com/finance/agent_x",
+			"endpoint": "https://us.acme.com",
+			"status":   "active",
+			"endpoints": []map[string]any{
+				{"url": "https://us.acme.com", "protocol": "https", "region": "us", "priority": 10, "weight": 60},
+				{"url": "grpc://grpc.acme.com:50051", "protocol": "grpc", "priority": 10, "weight": 40},
+				{"url": "https://10.0.0.1", "protocol": "https", "priority": 30},
+			},
+		})
+	}))
+	defer reg.Close()
+	svc := newTestService(t, reg, time.Minute)
+	addr := startDNS(t, resolver.NewDNSServer(svc, "nap.internal", 30*time.Second, zap.NewNop()))
+
+	resp := query(t, addr, "agent_x.finance.acme.com.nap.internal.", dns.TypeSRV)
+	// The IP-addressed endpoint cannot be an SRV target and is skipped.
+	if len(resp.Answer) != 2 {
+		t.Fatalf("SRV response: %v", resp)
+	}
+	first, second := resp.Answer[0].(*dns.SRV), resp.Answer[1].(*dns.SRV)
+	if first.Target != "us.acme.com." || first.Port != 443 || first.Priority != 10 || first.Weight != 60 {
+		t.Errorf("first SRV = %v", first)
+	}
+	if second.Target != "grpc.acme.com." || second.Port != 50051 || second.Weight != 40 {
+		t.Errorf("second SRV = %v", second)
+	}
+}
+
+func TestDNS_longTXTFieldsAreSplit(t *testing.T) {
+	endpoint := "https://billing.acme.com/a2a?token=" + strings.Repeat("x", 600)
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_x": {"endpoint": endpoint, "status": "active"},
+	})
+	defer reg.Close()
+	svc := newTestService(t, reg, time.Minute)
+	addr := startDNS(t, resolver.NewDNSServer(svc, "nap.internal", 30*time.Second, zap.NewNop()))
+
+	m := new(dns.Msg)
+	m.SetQuestion("agent_x.finance.acme.com.nap.internal.", dns.TypeTXT)
+	m.SetEdns0(4096, false)
+	resp, _, err := new(dns.Client).Exchange(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 {
+		t.Fatalf("TXT response: %v", resp)
+	}
+
+	// Continuation strings start with "+" and join onto the field before.
+	var fields []string
+	for _, s := range resp.Answer[0].(*dns.TXT).Txt {
+		if len(s) > 255 {
+			t.Errorf("TXT string of %d bytes", len(s))
+		}
+		if rest, ok := strings.CutPrefix(s, "+"); ok && len(fields) > 0 {
+			fields[len(fields)-1] += rest
+			continue
+		}
+		fields = append(fields, s)
+	}
+	want := []string{"v=nap1", "uri=agent://acme.com/finance/agent_x", "endpoint=" + endpoint, "status=active"}
+	if strings.Join(fields, "\n") != strings.Join(want, "\n") {
+		t.Errorf("reassembled TXT = %q, want %q", fields, want)
+	}
+}
+
+func TestDNS_httpsOnlyForHTTPSEndpoints(t *testing.T) {
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_x": {"endpoint": "grpc://grpc.acme.com:50051", "status": "active"},
+		"acme.com/finance/agent_y": {"endpoint": "http://plain.acme.com", "status": "active"},
+	})
+	defer reg.Close()
+	svc := newTestService(t, reg, time.Minute)
+	addr := startDNS(t, resolver.NewDNSServer(svc, "nap.internal", 30*time.Second, zap.NewNop()))
+
+	for _, name := range []string{"agent_x.finance.acme.com.nap.internal.", "agent_y.finance.acme.com.nap.internal."} {
+		resp := query(t, addr, name, dns.TypeHTTPS)
+		if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 0 {
+			t.Errorf("%s HTTPS: %v, want NODATA", name, resp)
+		}
+		// The SRV record still points at the endpoint.
+		if resp := query(t, addr, name, dns.TypeSRV); len(resp.Answer) != 1 {
+			t.Errorf("%s SRV: %v", name, resp)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Lookup paths recorded in nap_resolver_lookups_total.
+const (
+	pathHit         = "hit"          // fresh cache entry
+	pathNegativeHit = "negative_hit" // cached not-found
+	pathMiss        = "miss"         // queried the registry
+	pathCoalesced   = "coalesced"    // joined another caller's registry query
+	pathStale       = "stale"        // served an expired entry because the registry failed
+	pathError       = "error"        // registry failed and nothing could be served
+)
+
+var (
+	napResolverLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nap_resolver_lookups_total",
+		Help: "Resolve lookups by how they were answered.",
+	}, []string{"path"})
+
+	napResolverBackgroundRefreshesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nap_resolver_background_refreshes_total",
+		Help: "Background refreshes of stale cache entries by result.",
+	}, []string{"result"})
+
+	napResolverResolveDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nap_resolver_resolve_duration_seconds",
+		Help:    "Resolve latency by gRPC status code, including cache hits.",
+		Buckets: []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"code"})
+
+	napResolverRegistryRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nap_resolver_registry_requests_total",
+		Help: "Requests from the resolver to registries by result.",
+	}, []string{"result"})
+
+	napResolverRegistryRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "nap_resolver_registry_request_duration_seconds",
+		Help:    "Latency of requests from the resolver to registries.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	napResolverDNSQueriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nap_resolver_dns_queries_total",
+		Help: "DNS front-end queries by query type and response code.",
+	}, []string{"qtype", "rcode"})
+)
+
+func recordLookup(path string) {
+	napResolverLookupsTotal.WithLabelValues(path).Inc()
+}
+
+func recordResolve(code string, seconds float64) {
+	napResolverResolveDuration.WithLabelValues(code).Observe(seconds)
+}
+
+// recordRegistryRequest records one registry request. result is "ok",
+// "not_found", "rejected", "unreachable" or "error".
+func recordRegistryRequest(result string, seconds float64) {
+	napResolverRegistryRequestsTotal.WithLabelValues(result).Inc()
+	napResolverRegistryRequestDuration.Observe(seconds)
+}
+
+func recordBackgroundRefresh(ok bool) {
+	if ok {
+		napResolverBackgroundRefreshesTotal.WithLabelValues("success").Inc()
+	} else {
+		napResolverBackgroundRefreshesTotal.WithLabelValues("failure").Inc()
+	}
+}
+
+func recordDNSQuery(qtype, rcode string) {
+	napResolverDNSQueriesTotal.WithLabelValues(qtype, rcode).Inc()
+}
@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/federation"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RegistryLocator finds the registry responsible for a trust root.
+// federation.RemoteResolver implements it. DiscoverEndpoint returns an error
+// wrapping federation.ErrNoRegistry when no registry serves the trust root;
+// any other error is treated as a transient discovery failure.
+type RegistryLocator interface {
+	DiscoverEndpoint(ctx context.Context, trustRoot string) (string, error)
+}
+
+// registryEntry is a discovered registry base URL.
+type registryEntry struct {
+	baseURL   string
+	expiresAt time.Time
+}
+
+// registryCache remembers which registry serves each trust root so discovery
+// (federation table, DNS) runs once per trust root per TTL rather than once
+// per lookup.
+type registryCache struct {
+	mu      sync.Mutex
+	entries map[string]registryEntry
+	ttl     time.Duration
+}
+
+func newRegistryCache(ttl time.Duration) *registryCache {
+	return &registryCache{entries: make(map[string]registryEntry), ttl: ttl}
+}
+
+func (c *registryCache) get(trustRoot string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[trustRoot]
+	if !ok || time.Now().After(e.expiresAt) {
+		return "", false
+	}
+	return e.baseURL, true
+}
+
+func (c *registryCache) set(trustRoot, baseURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[trustRoot] = registryEntry{baseURL: baseURL, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// len returns the number of cached trust roots (including expired).
+func (c *registryCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// SetRegistryLocator enables federated resolution: each trust root is
+// resolved against the registry the locator finds for it instead of
+// Config.RegistryAddr. Discovered registries are cached for
+// Config.RegistryCacheTTL.
+func (s *Service) SetRegistryLocator(loc RegistryLocator) {
+	s.locator = loc
+}
+
+// RegistryCacheStats returns the number of cached trust root → registry
+// mappings (for metrics/health).
+func (s *Service) RegistryCacheStats() int {
+	return s.registries.len()
+}
+
+// registryURL returns the base URL of the registry to query for trustRoot.
+// Concurrent discoveries of the same trust root share one lookup.
+func (s *Service) registryURL(ctx context.Context, trustRoot string) (string, error) {
+	if s.locator == nil {
+		return s.baseURL(s.cfg.RegistryAddr), nil
+	}
+	if u, ok := s.registries.get(trustRoot); ok {
+		return u, nil
+	}
+
+	v, err, _ := s.flight.Do("registry:"+trustRoot, func() (any, error) {
+		u, err := s.locator.DiscoverEndpoint(context.WithoutCancel(ctx), trustRoot)
+		if err != nil {
+			return "", err
+		}
+		u = s.baseURL(u)
+		s.registries.set(trustRoot, u)
+		s.logger.Info("discovered registry",
+			zap.String("trust_root", trustRoot),
+			zap.String("registry", u),
+		)
+		return u, nil
+	})
+	switch {
+	case errors.Is(err, federation.ErrNoRegistry):
+		return "", status.Errorf(codes.NotFound, "no registry found for trust root %q: %v", trustRoot, err)
+	case err != nil:
+		return "", status.Errorf(codes.Unavailable, "discover registry for trust root %q: %v", trustRoot, err)
+	}
+	return v.(string), nil
+}
+
+// baseURL turns a configured registry address ("localhost:8080" or
+// "https://registry.example.com/") into a URL prefix. An address without a
+// scheme uses https when Config.RegistryTLS is set, http otherwise.
+func (s *Service) baseURL(addr string) string {
+	if !strings.Contains(addr, "://") {
+		scheme := "http://"
+		if s.cfg.RegistryTLS != nil {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+	return strings.TrimRight(addr, "/")
+}
@@ -0,0 +1,146 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/federation"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mapLocator is a RegistryLocator backed by a fixed table.
+// While down is set, every discovery fails transiently.
+type mapLocator struct {
+	registries map[string]string
+	calls      atomic.Int32
+	down       atomic.Bool
+}
+
+func (m *mapLocator) DiscoverEndpoint(_ context.Context, trustRoot string) (string, error) {
+	m.calls.Add(1)
+	if m.down.Load() {
+		return "", errors.New("dns lookup: i/o timeout")
+	}
+	if u, ok := m.registries[trustRoot]; ok {
+		return u, nil
+	}
+	return "", fmt.Errorf("%w for trust_root %q", federation.ErrNoRegistry, trustRoot)
+}
+
+func TestResolve_federatedRegistries(t *testing.T) {
+	acme := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.acme.com", "status": "active"},
+	})
+	defer acme.Close()
+	globex := stubRegistry(t, map[string]map[string]string{
+		"globex.com/finance/agent_g": {"endpoint": "https://g.globex.com", "status": "active"},
+	})
+	defer globex.Close()
+
+	loc := &mapLocator{registries: map[string]string{
+		"acme.com":   acme.URL,
+		"globex.com": globex.URL + "/",
+	}}
+	// RegistryAddr points nowhere: every lookup must go through the locator.
+	svc := resolver.New(resolver.Config{RegistryAddr: "127.0.0.1:1", HTTPTimeout: time.Second}, zap.NewNop())
+	svc.SetRegistryLocator(loc)
+
+	for _, tc := range []struct{ trustRoot, agentID, endpoint string }{
+		{"acme.com", "agent_a", "https://a.acme.com"},
+		{"globex.com", "agent_g", "https://g.globex.com"},
+		{"acme.com", "agent_a", "https://a.acme.com"},
+	} {
+		resp, err := svc.Resolve(context.Background(), &resolverv1.ResolveRequest{
+			TrustRoot: tc.trustRoot, CapabilityNode: "finance", AgentId: tc.agentID,
+		})
+		if err != nil {
+			t.Fatalf("Resolve %s: %v", tc.trustRoot, err)
+		}
+		if resp.Endpoint != tc.endpoint {
+			t.Errorf("%s: Endpoint = %q, want %q", tc.trustRoot, resp.Endpoint, tc.endpoint)
+		}
+	}
+
+	if n := loc.calls.Load(); n != 2 {
+		t.Errorf("locator called %d times, want 2 (one per trust root)", n)
+	}
+	if n := svc.RegistryCacheStats(); n != 2 {
+		t.Errorf("RegistryCacheStats = %d, want 2", n)
+	}
+
+	_, err := svc.Resolve(context.Background(), &resolverv1.ResolveRequest{
+		TrustRoot: "unknown.com", CapabilityNode: "finance", AgentId: "agent_x",
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("unknown trust root: got %v, want NotFound", err)
+	}
+}
+
+// TestResolve_registryDiscoveryUnavailable verifies that a transient discovery
+// failure is reported as Unavailable and is not cached as a negative answer.
+func TestResolve_registryDiscoveryUnavailable(t *testing.T) {
+	acme := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.acme.com", "status": "active"},
+	})
+	defer acme.Close()
+
+	loc := &mapLocator{registries: map[string]string{"acme.com": acme.URL}}
+	loc.down.Store(true)
+	svc := resolver.New(resolver.Config{
+		RegistryAddr: "127.0.0.1:1",
+		HTTPTimeout:  time.Second,
+		CacheTTL:     time.Minute,
+		NegativeTTL:  time.Minute,
+	}, zap.NewNop())
+	svc.SetRegistryLocator(loc)
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a"}
+
+	if _, err := svc.Resolve(context.Background(), req); status.Code(err) != codes.Unavailable {
+		t.Fatalf("discovery down: got %v, want Unavailable", err)
+	}
+
+	loc.down.Store(false)
+	resp, err := svc.Resolve(context.Background(), req)
+	if err != nil {
+		t.Fatalf("discovery back up: %v", err)
+	}
+	if resp.Endpoint != "https://a.acme.com" {
+		t.Errorf("Endpoint = %q, want https://a.acme.com", resp.Endpoint)
+	}
+}
+
+// TestResolve_registryTLSWithoutScheme verifies that a scheme-less
+// RegistryAddr is reached over https when RegistryTLS is set.
+func TestResolve_registryTLSWithoutScheme(t *testing.T) {
+	plain := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.acme.com", "status": "active"},
+	})
+	defer plain.Close()
+	reg := httptest.NewTLSServer(plain.Config.Handler)
+	defer reg.Close()
+
+	svc := resolver.New(resolver.Config{
+		RegistryAddr: reg.Listener.Addr().String(),
+		HTTPTimeout:  time.Second,
+		RegistryTLS:  reg.Client().Transport.(*http.Transport).TLSClientConfig,
+	}, zap.NewNop())
+	resp, err := svc.Resolve(context.Background(), &resolverv1.ResolveRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a",
+	})
+	if err != nil {
+		t.Fatalf("Resolve over TLS: %v", err)
+	}
+	if resp.Endpoint != "https://a.acme.com" {
+		t.Errorf("Endpoint = %q, want https://a.acme.com", resp.Endpoint)
+	}
+}
@@ -1,12 +1,14 @@
 // Package resolver implements the Nexus gRPC resolver service.
 //
 // The resolver translates agent:// URIs into live transport endpoints by
-// querying the registry over HTTP. Results are cached in-memory with a
-// configurable TTL to minimise latency and registry load.
+// querying the registry over HTTP. With a RegistryLocator set, each trust
+// root is resolved against its own federated registry. Results are cached
+// in-memory with a configurable TTL to minimise latency and registry load.
 package resolver
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,9 +16,12 @@ import (
 	"time"
 
 	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/uri"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Config holds resolver service configuration.
@@ -24,8 +29,39 @@ type Config struct {
 	RegistryAddr string        // e.g. "localhost:8080" or "https://registry.nexusagentprotocol.com"
 	CacheTTL     time.Duration // 0 disables caching
 	HTTPTimeout  time.Duration // default 5s
+
+	// NegativeTTL is how long a not-found answer is cached. 0 disables
+	// negative caching.
+	NegativeTTL time.Duration
+
+	// StaleTTL is how long past CacheTTL an entry may still be served while
+	// the registry is failing. 0 disables serve-stale.
+	StaleTTL time.Duration
+
+	// WatchInterval is how often each Watch stream re-queries the registry.
+	// Default 5s.
+	WatchInterval time.Duration
+
+	// RegistryTLS, when set, is used for HTTPS requests to registries, e.g.
+	// to trust the registry CA and present a client certificate (mTLS).
+	// Registry addresses without a scheme are then reached over https.
+	RegistryTLS *tls.Config
+
+	// RegistryCacheTTL is how long a registry discovered through the
+	// RegistryLocator is used for its trust root. Default 5m.
+	RegistryCacheTTL time.Duration
+
+	// Offline answers only from the cache (normally loaded with
+	// LoadSnapshot) and never contacts the registry. Expired entries are
+	// served however old they are; URIs not in the cache fail with
+	// Unavailable.
+	Offline bool
 }
 
+// staleRefreshBackoff is the minimum time between background refreshes of a
+// stale entry whose last refresh failed. A variable so tests can shorten it.
+var staleRefreshBackoff = 2 * time.Second
+
 // Service implements resolverv1.ResolverServiceServer.
 type Service struct {
 	resolverv1.UnimplementedResolverServiceServer
@@ -33,7 +69,14 @@ type Service struct {
 	cfg        Config
 	httpClient *http.Client
 	cache      *resolverCache
+	flight     singleflight.Group
 	logger     *zap.Logger
+
+	watchers      *watchHub
+	watchInterval time.Duration
+
+	locator    RegistryLocator
+	registries *registryCache
 }
 
 // New creates a resolver Service.
@@ -43,14 +86,37 @@ func New(cfg Config, logger *zap.Logger) *Service {
 		timeout = 5 * time.Second
 	}
 
+	watchInterval := cfg.WatchInterval
+	if watchInterval == 0 {
+		watchInterval = 5 * time.Second
+	}
+
+	var transport http.RoundTripper
+	if cfg.RegistryTLS != nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = cfg.RegistryTLS
+		transport = t
+	}
+
+	registryTTL := cfg.RegistryCacheTTL
+	if registryTTL == 0 {
+		registryTTL = 5 * time.Minute
+	}
+
 	svc := &Service{
-		cfg:        cfg,
-		httpClient: &http.Client{Timeout: timeout},
-		logger:     logger,
+		cfg:           cfg,
+		httpClient:    &http.Client{Timeout: timeout, Transport: transport},
+		logger:        logger,
+		watchers:      newWatchHub(),
+		watchInterval: watchInterval,
+		registries:    newRegistryCache(registryTTL),
 	}
 
-	if cfg.CacheTTL > 0 {
+	if cfg.CacheTTL > 0 || cfg.Offline {
 		svc.cache = newResolverCache(cfg.CacheTTL)
+		svc.cache.negativeTTL = cfg.NegativeTTL
+		svc.cache.staleTTL = cfg.StaleTTL
+		svc.cache.offline = cfg.Offline
 	}
 
 	return svc
@@ -59,52 +125,196 @@ func New(cfg Config, logger *zap.Logger) *Service {
 // Resolve implements ResolverServiceServer.Resolve.
 //
 // It translates a single agent:// URI into its transport endpoint by:
-//  1. Checking the in-memory cache
-//  2. Querying the registry HTTP API on a cache miss
-//  3. Caching the result and returning it
-func (s *Service) Resolve(ctx context.Context, req *resolverv1.ResolveRequest) (*resolverv1.ResolveResponse, error) {
+//  1. Answering from the in-memory cache (positive or negative) when fresh
+//  2. Querying the registry on a miss, coalescing concurrent misses for the
+//     same URI into one request
+//  3. Serving an expired entry, and refreshing it in the background, when the
+//     registry is failing and the entry is still inside Config.StaleTTL
+//
+// With Config.Offline set only step 1 runs, and expired entries are served
+// instead of being refreshed.
+//
+// With follow_replacement set, a deprecated agent is replaced by its successor
+// (see followReplacements).
+func (s *Service) Resolve(ctx context.Context, req *resolverv1.ResolveRequest) (resp *resolverv1.ResolveResponse, err error) {
+	start := time.Now()
+	defer func() { recordResolve(status.Code(err).String(), time.Since(start).Seconds()) }()
+
 	if err := validateRequest(req); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	resp, err = s.resolveOne(ctx, req)
+	if err != nil || !req.FollowReplacement {
+		return resp, err
+	}
+	return s.followReplacements(ctx, req, resp)
+}
+
+// resolveOne resolves a single URI from the cache or the registry.
+func (s *Service) resolveOne(ctx context.Context, req *resolverv1.ResolveRequest) (*resolverv1.ResolveResponse, error) {
 	cacheKey := buildCacheKey(req.TrustRoot, req.CapabilityNode, req.AgentId)
 
-	// Cache hit
+	state := cacheMiss
+	var entry cacheEntry
 	if s.cache != nil {
-		if entry, ok := s.cache.get(cacheKey); ok {
+		entry, state = s.cache.lookup(cacheKey)
+		switch state {
+		case cacheFresh:
+			recordLookup(pathHit)
 			s.logger.Debug("cache hit", zap.String("key", cacheKey))
-			return &resolverv1.ResolveResponse{
-				Uri:        buildURI(req),
-				Endpoint:   entry.endpoint,
-				Status:     entry.status,
-				CertSerial: entry.certSerial,
-			}, nil
+			return entryResponse(req, &entry), nil
+		case cacheNegative:
+			recordLookup(pathNegativeHit)
+			return nil, notFoundError(req)
+		case cacheStale:
+			if s.cfg.Offline {
+				recordLookup(pathStale)
+				return entryResponse(req, &entry), nil
+			}
+			if !entry.failedAt.IsZero() {
+				// The registry failed recently for this URI; answer from the
+				// stale entry without making the caller wait on it again.
+				recordLookup(pathStale)
+				s.refreshInBackground(cacheKey, req)
+				return entryResponse(req, &entry), nil
+			}
 		}
 	}
+	if s.cfg.Offline {
+		recordLookup(pathError)
+		return nil, status.Errorf(codes.Unavailable, "resolver is offline and %s is not in its cache", cacheKey)
+	}
 
-	// Registry lookup
-	regResult, err := s.queryRegistry(ctx, req)
+	regResult, err := s.fetch(ctx, cacheKey, req)
 	if err != nil {
+		if state == cacheStale && isTransient(err) {
+			recordLookup(pathStale)
+			s.cache.markFailed(cacheKey)
+			s.logger.Warn("registry failed; serving stale resolution",
+				zap.String("key", cacheKey),
+				zap.Error(err),
+			)
+			return entryResponse(req, &entry), nil
+		}
 		return nil, err
 	}
 
-	// Cache the result
-	if s.cache != nil {
-		s.cache.set(cacheKey, regResult.Endpoint, regResult.Status, regResult.CertSerial)
-	}
-
 	s.logger.Info("resolved",
 		zap.String("uri", regResult.URI),
 		zap.String("endpoint", regResult.Endpoint),
 		zap.String("status", regResult.Status),
 	)
 
-	return &resolverv1.ResolveResponse{
-		Uri:        regResult.URI,
-		Endpoint:   regResult.Endpoint,
-		Status:     regResult.Status,
-		CertSerial: regResult.CertSerial,
-	}, nil
+	return resultResponse(regResult), nil
+}
+
+// maxReplacementHops bounds how many deprecated agents follow_replacement
+// walks through before giving up.
+const maxReplacementHops = 8
+
+// followReplacements walks replacement_uri links from resp until it reaches an
+// agent without a successor. Agents are identified by trust root and agent
+// ID, so a chain that returns to an agent it already visited is reported as a
+// loop regardless of the capability path in each URI.
+func (s *Service) followReplacements(ctx context.Context, req *resolverv1.ResolveRequest, resp *resolverv1.ResolveResponse) (*resolverv1.ResolveResponse, error) {
+	seen := map[string]bool{req.TrustRoot + "/" + req.AgentId: true}
+	var chain []string
+
+	for resp.ReplacementUri != "" {
+		if len(chain) == maxReplacementHops {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"replacement chain from %s is longer than %d hops", buildURI(req), maxReplacementHops)
+		}
+		next, err := uri.Parse(resp.ReplacementUri)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"%s has an invalid replacement_uri %q: %v", resp.Uri, resp.ReplacementUri, err)
+		}
+		id := next.OrgName + "/" + next.AgentID
+		if seen[id] {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"replacement loop: %s points back to %s", resp.Uri, resp.ReplacementUri)
+		}
+		seen[id] = true
+		chain = append(chain, resp.Uri)
+
+		nextResp, err := s.resolveOne(ctx, &resolverv1.ResolveRequest{
+			TrustRoot:      next.OrgName,
+			CapabilityNode: next.Category,
+			AgentId:        next.AgentID,
+		})
+		if err != nil {
+			st := status.Convert(err)
+			return nil, status.Errorf(st.Code(), "follow replacement %s: %s", resp.ReplacementUri, st.Message())
+		}
+		resp = nextResp
+	}
+
+	resp.ReplacementChain = chain
+	return resp, nil
+}
+
+// fetch queries the registry for req and updates the cache. Concurrent
+// fetches of the same key share one registry request. The shared request is
+// not tied to any one caller's context, so a caller that gives up does not
+// fail the others.
+func (s *Service) fetch(ctx context.Context, cacheKey string, req *resolverv1.ResolveRequest) (*registryResolveResult, error) {
+	leader := false
+	ch := s.flight.DoChan(cacheKey, func() (any, error) {
+		leader = true
+		if s.cache == nil {
+			return s.queryRegistry(context.WithoutCancel(ctx), req)
+		}
+		// An invalidation that arrives while the query is in flight means
+		// its answer may already be out of date, so it is not cached.
+		gen := s.cache.beginFetch()
+		defer s.cache.endFetch()
+		res, err := s.queryRegistry(context.WithoutCancel(ctx), req)
+		switch {
+		case err == nil:
+			s.cache.setResultSince(cacheKey, res, gen)
+		case status.Code(err) == codes.NotFound:
+			s.cache.setNegativeSince(cacheKey, gen)
+		}
+		return res, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case r := <-ch:
+		switch {
+		case r.Err != nil:
+			recordLookup(pathError)
+			return nil, r.Err
+		case leader:
+			recordLookup(pathMiss)
+		default:
+			recordLookup(pathCoalesced)
+		}
+		return r.Val.(*registryResolveResult), nil
+	}
+}
+
+// refreshInBackground re-queries the registry for a stale entry, at most once
+// per staleRefreshBackoff per key.
+func (s *Service) refreshInBackground(cacheKey string, req *resolverv1.ResolveRequest) {
+	if !s.cache.startRefresh(cacheKey, staleRefreshBackoff) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.httpClient.Timeout)
+		defer cancel()
+		if _, err := s.fetch(ctx, cacheKey, req); err != nil {
+			recordBackgroundRefresh(false)
+			if isTransient(err) {
+				s.cache.markFailed(cacheKey)
+			}
+			return
+		}
+		recordBackgroundRefresh(true)
+	}()
 }
 
 // ResolveMany implements ResolverServiceServer.ResolveMany.
@@ -150,11 +360,25 @@ func (s *Service) ResolveMany(ctx context.Context, req *resolverv1.ResolveManyRe
 	return &resolverv1.ResolveManyResponse{Results: results}, nil
 }
 
-// Invalidate removes a URI from the cache. Called when an agent is revoked or updated.
+// Invalidate removes a URI from the cache and wakes any Watch streams on it so
+// they re-query the registry immediately. Called when an agent is revoked or
+// updated.
 func (s *Service) Invalidate(trustRoot, capNode, agentID string) {
+	key := buildCacheKey(trustRoot, capNode, agentID)
 	if s.cache != nil {
-		s.cache.invalidate(buildCacheKey(trustRoot, capNode, agentID))
+		s.cache.invalidate(key)
 	}
+	s.watchers.notify(key)
+}
+
+// InvalidateAgent removes every cached resolution of trustRoot + agentID,
+// whatever capability path it was looked up under, and wakes the matching
+// Watch streams. It is applied when the registry pushes a lifecycle change.
+func (s *Service) InvalidateAgent(trustRoot, agentID string) {
+	if s.cache != nil {
+		s.cache.invalidateAgent(trustRoot, agentID)
+	}
+	s.watchers.notifyAgent(trustRoot, agentID)
 }
 
 // CacheStats returns current cache size (for metrics/health).
@@ -193,18 +417,78 @@ func (s *Service) StartCacheEviction(ctx context.Context, interval time.Duration
 
 // registryResolveResult mirrors the JSON response from GET /api/v1/resolve.
 type registryResolveResult struct {
-	URI        string `json:"uri"`
-	Endpoint   string `json:"endpoint"`
-	Status     string `json:"status"`
-	CertSerial string `json:"cert_serial,omitempty"`
-	Error      string `json:"error,omitempty"`
+	URI            string             `json:"uri"`
+	Endpoint       string             `json:"endpoint"`
+	Status         string             `json:"status"`
+	CertSerial     string             `json:"cert_serial,omitempty"`
+	TrustTier      string             `json:"trust_tier,omitempty"`
+	HealthStatus   string             `json:"health_status,omitempty"`
+	SunsetDate     *time.Time         `json:"sunset_date,omitempty"`
+	ReplacementURI string             `json:"replacement_uri,omitempty"`
+	Endpoints      []registryEndpoint `json:"endpoints,omitempty"`
+	Error          string             `json:"error,omitempty"`
+}
+
+// registryEndpoint is one entry of the registry's endpoints list.
+type registryEndpoint struct {
+	URL      string `json:"url"`
+	Protocol string `json:"protocol"`
+	Region   string `json:"region,omitempty"`
+	Priority uint32 `json:"priority"`
+	Weight   uint32 `json:"weight"`
+}
+
+// endpointsProto converts endpoints to their protobuf form.
+func endpointsProto(eps []registryEndpoint) []*resolverv1.AgentEndpoint {
+	if len(eps) == 0 {
+		return nil
+	}
+	out := make([]*resolverv1.AgentEndpoint, len(eps))
+	for i, ep := range eps {
+		out[i] = &resolverv1.AgentEndpoint{
+			Url:      ep.URL,
+			Protocol: ep.Protocol,
+			Region:   ep.Region,
+			Priority: ep.Priority,
+			Weight:   ep.Weight,
+		}
+	}
+	return out
 }
 
 // queryRegistry calls the registry HTTP API to resolve an agent URI.
 func (s *Service) queryRegistry(ctx context.Context, req *resolverv1.ResolveRequest) (*registryResolveResult, error) {
+	if s.cfg.Offline {
+		return nil, status.Error(codes.Unavailable, "resolver is offline")
+	}
+	base, err := s.registryURL(ctx, req.TrustRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := s.requestRegistry(ctx, base, req)
+	result := "ok"
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.NotFound:
+		result = "not_found"
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		result = "rejected"
+	case codes.Unavailable:
+		result = "unreachable"
+	default:
+		result = "error"
+	}
+	recordRegistryRequest(result, time.Since(start).Seconds())
+	return res, err
+}
+
+// requestRegistry performs GET {base}/api/v1/resolve for req.
+func (s *Service) requestRegistry(ctx context.Context, base string, req *resolverv1.ResolveRequest) (*registryResolveResult, error) {
 	url := fmt.Sprintf(
-		"http://%s/api/v1/resolve?trust_root=%s&capability_node=%s&agent_id=%s",
-		s.cfg.RegistryAddr,
+		"%s/api/v1/resolve?trust_root=%s&capability_node=%s&agent_id=%s",
+		base,
 		req.TrustRoot,
 		req.CapabilityNode,
 		req.AgentId,
@@ -235,8 +519,7 @@ func (s *Service) queryRegistry(ctx context.Context, req *resolverv1.ResolveRequ
 	case http.StatusOK:
 		// handled below
 	case http.StatusNotFound:
-		return nil, status.Errorf(codes.NotFound, "agent not found: %s/%s/%s",
-			req.TrustRoot, req.CapabilityNode, req.AgentId)
+		return nil, notFoundError(req)
 	case http.StatusBadRequest:
 		return nil, status.Errorf(codes.InvalidArgument, "registry rejected request: %s", string(body))
 	default:
@@ -253,6 +536,58 @@ func (s *Service) queryRegistry(ctx context.Context, req *resolverv1.ResolveRequ
 	return &result, nil
 }
 
+// entryResponse builds a ResolveResponse from a cached entry.
+func entryResponse(req *resolverv1.ResolveRequest, e *cacheEntry) *resolverv1.ResolveResponse {
+	resp := &resolverv1.ResolveResponse{
+		Uri:            buildURI(req),
+		Endpoint:       e.endpoint,
+		Status:         e.status,
+		CertSerial:     e.certSerial,
+		TrustTier:      e.trustTier,
+		HealthStatus:   e.healthStatus,
+		ReplacementUri: e.replacementURI,
+		Endpoints:      endpointsProto(e.endpoints),
+	}
+	if e.sunsetDate != nil {
+		resp.SunsetDate = timestamppb.New(*e.sunsetDate)
+	}
+	return resp
+}
+
+// resultResponse builds a ResolveResponse from a registry answer.
+func resultResponse(r *registryResolveResult) *resolverv1.ResolveResponse {
+	resp := &resolverv1.ResolveResponse{
+		Uri:            r.URI,
+		Endpoint:       r.Endpoint,
+		Status:         r.Status,
+		CertSerial:     r.CertSerial,
+		TrustTier:      r.TrustTier,
+		HealthStatus:   r.HealthStatus,
+		ReplacementUri: r.ReplacementURI,
+		Endpoints:      endpointsProto(r.Endpoints),
+	}
+	if r.SunsetDate != nil {
+		resp.SunsetDate = timestamppb.New(*r.SunsetDate)
+	}
+	return resp
+}
+
+// notFoundError is the error returned for an agent the registry does not know.
+func notFoundError(req *resolverv1.ResolveRequest) error {
+	return status.Errorf(codes.NotFound, "agent not found: %s/%s/%s",
+		req.TrustRoot, req.CapabilityNode, req.AgentId)
+}
+
+// isTransient reports whether a registry error says nothing about the agent
+// itself, so that a previously cached answer is still the best one available.
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.DeadlineExceeded, codes.Unknown:
+		return true
+	}
+	return false
+}
+
 // validateRequest checks that all required URI components are present.
 func validateRequest(req *resolverv1.ResolveRequest) error {
 	if req.TrustRoot == "" {
@@ -7,6 +7,8 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -204,6 +206,249 @@ func TestResolve_cacheInvalidate(t *testing.T) {
 	}
 }
 
+func TestResolve_invalidateAgentAcrossCapabilityPaths(t *testing.T) {
+	callCount := 0
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"uri": "agent://acme.com/finance/agent_x", "endpoint": "https://e.example.com", "status": "active",
+		})
+	}))
+	defer reg.Close()
+
+	svc := newTestService(t, reg, time.Minute)
+	short := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_x"}
+	long := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance/billing", AgentId: "agent_x"}
+	other := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_y"}
+
+	for _, r := range []*resolverv1.ResolveRequest{short, long, other} {
+		if _, err := svc.Resolve(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc.InvalidateAgent("acme.com", "agent_x")
+
+	if got := svc.CacheStats(); got != 1 {
+		t.Errorf("cache entries after InvalidateAgent = %d, want 1 (agent_y only)", got)
+	}
+	if _, err := svc.Resolve(context.Background(), long); err != nil {
+		t.Fatal(err)
+	}
+	if callCount != 4 {
+		t.Errorf("expected 4 registry calls, got %d", callCount)
+	}
+}
+
+func TestResolve_coalescesConcurrentMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"uri": "agent://acme.com/finance/agent_x", "endpoint": "https://e.example.com", "status": "active",
+		})
+	}))
+	defer reg.Close()
+
+	svc := newTestService(t, reg, time.Minute)
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_x"}
+
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := svc.Resolve(context.Background(), req)
+			if err == nil && resp.Endpoint != "https://e.example.com" {
+				err = fmt.Errorf("endpoint %q", resp.Endpoint)
+			}
+			errs <- err
+		}()
+	}
+
+	// Let every caller reach the in-flight request before answering it.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("registry called %d times for %d concurrent misses, want 1", got, n)
+	}
+}
+
+func TestResolve_negativeCache(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		negativeTTL time.Duration
+		wantCalls   int
+	}{
+		{"enabled", time.Minute, 1},
+		{"disabled", 0, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer reg.Close()
+
+			svc := resolver.New(resolver.Config{
+				RegistryAddr: reg.Listener.Addr().String(),
+				CacheTTL:     time.Minute,
+				NegativeTTL:  tc.negativeTTL,
+			}, zap.NewNop())
+			req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_missing"}
+
+			for i := 0; i < 2; i++ {
+				_, err := svc.Resolve(context.Background(), req)
+				if status.Code(err) != codes.NotFound {
+					t.Fatalf("call %d: expected NotFound, got %v", i+1, err)
+				}
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("registry called %d times, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+// ── follow_replacement ───────────────────────────────────────────────────────
+
+// jsonRegistry serves fixed JSON bodies keyed by "trust_root/capability_node/agent_id".
+func jsonRegistry(t *testing.T, agents map[string]map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		agent, ok := agents[q.Get("trust_root")+"/"+q.Get("capability_node")+"/"+q.Get("agent_id")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(agent) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolve_extendedFields(t *testing.T) {
+	reg := jsonRegistry(t, map[string]map[string]any{
+		"acme.com/finance/agent_old": {
+			"uri": "agent://acme.com/finance/agent_old", "endpoint": "https://old.example.com",
+			"status": "deprecated", "trust_tier": "verified", "health_status": "degraded",
+			"sunset_date": "2030-01-31T00:00:00Z", "replacement_uri": "agent://acme.com/finance/agent_new",
+		},
+	})
+	svc := newTestService(t, reg, time.Minute)
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_old"}
+
+	// Once from the registry, once from the cache.
+	for i := 0; i < 2; i++ {
+		resp, err := svc.Resolve(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.TrustTier != "verified" || resp.HealthStatus != "degraded" {
+			t.Errorf("call %d: tier=%q health=%q", i+1, resp.TrustTier, resp.HealthStatus)
+		}
+		if resp.ReplacementUri != "agent://acme.com/finance/agent_new" {
+			t.Errorf("call %d: ReplacementUri = %q", i+1, resp.ReplacementUri)
+		}
+		if got := resp.SunsetDate.AsTime(); !got.Equal(time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("call %d: SunsetDate = %v", i+1, got)
+		}
+	}
+}
+
+func TestResolve_followReplacement(t *testing.T) {
+	reg := jsonRegistry(t, map[string]map[string]any{
+		"acme.com/finance/agent_v1": {
+			"uri": "agent://acme.com/finance/agent_v1", "endpoint": "https://v1.example.com",
+			"status": "deprecated", "replacement_uri": "agent://acme.com/finance/agent_v2",
+		},
+		"acme.com/finance/agent_v2": {
+			"uri": "agent://acme.com/finance/agent_v2", "endpoint": "https://v2.example.com",
+			"status": "deprecated", "replacement_uri": "agent://acme.com/finance/billing/agent_v3",
+		},
+		"acme.com/finance/agent_v3": {
+			"uri": "agent://acme.com/finance/billing/agent_v3", "endpoint": "https://v3.example.com",
+			"status": "active", "trust_tier": "trusted",
+		},
+	})
+	svc := newTestService(t, reg, 0)
+
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_v1"}
+	resp, err := svc.Resolve(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Endpoint != "https://v1.example.com" || len(resp.ReplacementChain) != 0 {
+		t.Errorf("without follow_replacement: endpoint=%q chain=%v", resp.Endpoint, resp.ReplacementChain)
+	}
+
+	req.FollowReplacement = true
+	resp, err = svc.Resolve(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Endpoint != "https://v3.example.com" || resp.TrustTier != "trusted" {
+		t.Errorf("followed to endpoint=%q tier=%q", resp.Endpoint, resp.TrustTier)
+	}
+	want := []string{"agent://acme.com/finance/agent_v1", "agent://acme.com/finance/agent_v2"}
+	if fmt.Sprint(resp.ReplacementChain) != fmt.Sprint(want) {
+		t.Errorf("ReplacementChain = %v, want %v", resp.ReplacementChain, want)
+	}
+}
+
+func TestResolve_followReplacementLoop(t *testing.T) {
+	reg := jsonRegistry(t, map[string]map[string]any{
+		"acme.com/finance/agent_a": {
+			"uri": "agent://acme.com/finance/agent_a", "status": "deprecated",
+			"replacement_uri": "agent://acme.com/finance/agent_b",
+		},
+		"acme.com/finance/agent_b": {
+			"uri": "agent://acme.com/finance/agent_b", "status": "deprecated",
+			// Same agent as agent_a, reached through a different capability path.
+			"replacement_uri": "agent://acme.com/finance/billing/agent_a",
+		},
+	})
+	svc := newTestService(t, reg, time.Minute)
+
+	_, err := svc.Resolve(context.Background(), &resolverv1.ResolveRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a", FollowReplacement: true,
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition for a replacement loop, got %v", err)
+	}
+}
+
+func TestResolve_followReplacementMissingSuccessor(t *testing.T) {
+	reg := jsonRegistry(t, map[string]map[string]any{
+		"acme.com/finance/agent_a": {
+			"uri": "agent://acme.com/finance/agent_a", "status": "deprecated",
+			"replacement_uri": "agent://acme.com/finance/agent_gone",
+		},
+	})
+	svc := newTestService(t, reg, 0)
+
+	_, err := svc.Resolve(context.Background(), &resolverv1.ResolveRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a", FollowReplacement: true,
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for a missing successor, got %v", err)
+	}
+}
+
 // ── ResolveMany ───────────────────────────────────────────────────────────────
 
 func TestResolveMany(t *testing.T) {
@@ -0,0 +1,195 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// snapshotVersion is bumped whenever the snapshot format changes
+// incompatibly. Snapshots with another version are rejected.
+const snapshotVersion = 1
+
+// snapshotFile is the on-disk form of the resolver cache.
+type snapshotFile struct {
+	Version int             `json:"version"`
+	SavedAt time.Time       `json:"saved_at"`
+	Entries []snapshotEntry `json:"entries"`
+}
+
+// snapshotEntry is one cache entry. Expiry times are absolute, so an entry
+// loaded after a restart keeps the TTL and stale window it was stored with.
+type snapshotEntry struct {
+	Key            string             `json:"key"`
+	Endpoint       string             `json:"endpoint,omitempty"`
+	Status         string             `json:"status,omitempty"`
+	CertSerial     string             `json:"cert_serial,omitempty"`
+	TrustTier      string             `json:"trust_tier,omitempty"`
+	HealthStatus   string             `json:"health_status,omitempty"`
+	SunsetDate     *time.Time         `json:"sunset_date,omitempty"`
+	ReplacementURI string             `json:"replacement_uri,omitempty"`
+	Endpoints      []registryEndpoint `json:"endpoints,omitempty"`
+	Negative       bool               `json:"negative,omitempty"`
+	ExpiresAt      time.Time          `json:"expires_at"`
+	StaleUntil     time.Time          `json:"stale_until"`
+	FailedAt       time.Time          `json:"failed_at,omitzero"`
+}
+
+// snapshot returns every entry that can still be served.
+func (c *resolverCache) snapshot() []snapshotEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	out := make([]snapshotEntry, 0, len(c.entries))
+	for k, e := range c.entries {
+		if !c.offline && now.After(e.staleUntil) {
+			continue
+		}
+		out = append(out, snapshotEntry{
+			Key:            k,
+			Endpoint:       e.endpoint,
+			Status:         e.status,
+			CertSerial:     e.certSerial,
+			TrustTier:      e.trustTier,
+			HealthStatus:   e.healthStatus,
+			SunsetDate:     e.sunsetDate,
+			ReplacementURI: e.replacementURI,
+			Endpoints:      e.endpoints,
+			Negative:       e.negative,
+			ExpiresAt:      e.expiresAt,
+			StaleUntil:     e.staleUntil,
+			FailedAt:       e.failedAt,
+		})
+	}
+	return out
+}
+
+// restore adds snapshot entries to the cache, skipping those that can no
+// longer be served. Entries already in the cache are not overwritten.
+func (c *resolverCache) restore(entries []snapshotEntry) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for _, se := range entries {
+		if se.Key == "" || (!c.offline && now.After(se.StaleUntil)) {
+			continue
+		}
+		if _, ok := c.entries[se.Key]; ok {
+			continue
+		}
+		c.entries[se.Key] = &cacheEntry{
+			endpoint:       se.Endpoint,
+			status:         se.Status,
+			certSerial:     se.CertSerial,
+			trustTier:      se.TrustTier,
+			healthStatus:   se.HealthStatus,
+			sunsetDate:     se.SunsetDate,
+			replacementURI: se.ReplacementURI,
+			endpoints:      se.Endpoints,
+			negative:       se.Negative,
+			expiresAt:      se.ExpiresAt,
+			staleUntil:     se.StaleUntil,
+			failedAt:       se.FailedAt,
+		}
+		n++
+	}
+	return n
+}
+
+// SaveSnapshot writes the cache to path. The file is written to a temporary
+// name and renamed into place, so a crash never leaves a truncated snapshot.
+func (s *Service) SaveSnapshot(path string) error {
+	if s.cache == nil {
+		return nil
+	}
+	data, err := json.Marshal(snapshotFile{
+		Version: snapshotVersion,
+		SavedAt: time.Now().UTC(),
+		Entries: s.cache.snapshot(),
+	})
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot adds the entries saved in path to the cache and returns how
+// many were loaded. Entries past their stale window are dropped unless the
+// service is offline. A missing file is reported as an error wrapping
+// os.ErrNotExist.
+func (s *Service) LoadSnapshot(path string) (int, error) {
+	if s.cache == nil {
+		return 0, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("read snapshot: %w", err)
+	}
+	var f snapshotFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return 0, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if f.Version != snapshotVersion {
+		return 0, fmt.Errorf("snapshot version %d is not supported (want %d)", f.Version, snapshotVersion)
+	}
+	n := s.cache.restore(f.Entries)
+	s.logger.Info("loaded cache snapshot",
+		zap.String("path", path),
+		zap.Time("saved_at", f.SavedAt),
+		zap.Int("entries", n),
+		zap.Int("skipped", len(f.Entries)-n),
+	)
+	return n, nil
+}
+
+// StartSnapshotting saves the cache to path every interval until ctx is
+// cancelled. Failures are logged and retried at the next interval.
+func (s *Service) StartSnapshotting(ctx context.Context, path string, interval time.Duration) {
+	if s.cache == nil || s.cfg.Offline {
+		return
+	}
+	if interval == 0 {
+		interval = time.Minute
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := s.SaveSnapshot(path); err != nil {
+					s.logger.Warn("save cache snapshot", zap.String("path", path), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
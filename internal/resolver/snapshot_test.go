@@ -0,0 +1,97 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSnapshot_roundTrip(t *testing.T) {
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.example.com", "status": "active", "cert_serial": "01"},
+	})
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	svc := newTestService(t, reg, time.Minute)
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a"}
+	if _, err := svc.Resolve(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	// A restarted resolver answers from the snapshot while the registry is down.
+	reg.Close()
+	restarted := newTestService(t, reg, time.Minute)
+	n, err := restarted.LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("loaded %d entries, want 1", n)
+	}
+	resp, err := restarted.Resolve(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Resolve after restart: %v", err)
+	}
+	if resp.Endpoint != "https://a.example.com" || resp.CertSerial != "01" {
+		t.Errorf("resolved %v", resp)
+	}
+}
+
+func TestSnapshot_missingFile(t *testing.T) {
+	svc := resolver.New(resolver.Config{CacheTTL: time.Minute}, zap.NewNop())
+	_, err := svc.LoadSnapshot(filepath.Join(t.TempDir(), "nope.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+}
+
+func TestSnapshot_offlineServesExpiredEntries(t *testing.T) {
+	reg := stubRegistry(t, map[string]map[string]string{
+		"acme.com/finance/agent_a": {"endpoint": "https://a.example.com", "status": "active"},
+	})
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	// Entries expire almost immediately and have no stale window.
+	online := newTestService(t, reg, 10*time.Millisecond)
+	req := &resolverv1.ResolveRequest{TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_a"}
+	if _, err := online.Resolve(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if err := online.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	offline := resolver.New(resolver.Config{RegistryAddr: reg.Listener.Addr().String(), Offline: true}, zap.NewNop())
+	if _, err := offline.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	reg.Close()
+
+	resp, err := offline.Resolve(context.Background(), req)
+	if err != nil {
+		t.Fatalf("offline Resolve: %v", err)
+	}
+	if resp.Endpoint != "https://a.example.com" {
+		t.Errorf("Endpoint = %q", resp.Endpoint)
+	}
+
+	_, err = offline.Resolve(context.Background(), &resolverv1.ResolveRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_unknown",
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("offline miss: got %v, want Unavailable", err)
+	}
+}
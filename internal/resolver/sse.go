@@ -0,0 +1,36 @@
+package resolver
+
+import (
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// EventStreamMIME is the content type of Server-Sent Events.
+const EventStreamMIME = "text/event-stream"
+
+// EventStreamMarshaler wraps a grpc-gateway marshaler so that streaming
+// responses are framed as Server-Sent Events: each message becomes a
+// "data: <json>" line followed by a blank line. Register it on the gateway
+// mux for EventStreamMIME; grpc-gateway picks it when the client sends
+// Accept: text/event-stream.
+type EventStreamMarshaler struct {
+	runtime.Marshaler
+}
+
+// ContentType implements runtime.Marshaler.
+func (m *EventStreamMarshaler) ContentType(_ interface{}) string {
+	return EventStreamMIME
+}
+
+// Marshal implements runtime.Marshaler.
+func (m *EventStreamMarshaler) Marshal(v interface{}) ([]byte, error) {
+	b, err := m.Marshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("data: "), b...), nil
+}
+
+// Delimiter implements runtime.Delimited.
+func (m *EventStreamMarshaler) Delimiter() []byte {
+	return []byte("\n\n")
+}
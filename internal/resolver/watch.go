@@ -0,0 +1,243 @@
+package resolver
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchHub tracks open Watch streams by cache key so that Invalidate can wake
+// them up immediately instead of waiting for the next poll.
+type watchHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{subs: make(map[string]map[chan struct{}]struct{})}
+}
+
+// subscribe registers interest in key. The returned channel receives a value
+// whenever key is notified; cancel must be called when the watch ends.
+func (h *watchHub) subscribe(key string) (ch chan struct{}, cancel func()) {
+	ch = make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.subs[key] == nil {
+		h.subs[key] = make(map[chan struct{}]struct{})
+	}
+	h.subs[key][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[key], ch)
+		if len(h.subs[key]) == 0 {
+			delete(h.subs, key)
+		}
+	}
+}
+
+// notify wakes every watcher of key. It never blocks: a watcher that already
+// has a pending wake-up is not sent a second one.
+func (h *watchHub) notify(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyAgent wakes every watcher of any key for trustRoot + agentID.
+func (h *watchHub) notifyAgent(trustRoot, agentID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, subs := range h.subs {
+		if !keyMatchesAgent(key, trustRoot, agentID) {
+			continue
+		}
+		for ch := range subs {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// count returns the number of open watches.
+func (h *watchHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, s := range h.subs {
+		n += len(s)
+	}
+	return n
+}
+
+// watchState is the last observed resolution of a watched URI.
+type watchState struct {
+	resolved   bool
+	endpoint   string
+	status     string
+	certSerial string
+	endpoints  []registryEndpoint
+}
+
+// Watch implements ResolverServiceServer.Watch.
+//
+// It sends an INITIAL event with the current resolution (empty, with a
+// reason, when the URI does not resolve), then re-queries the registry every
+// Config.WatchInterval (or immediately when the URI is invalidated) and sends an event whenever endpoint, endpoints, status or
+// cert serial changes. Transient registry errors are logged and the previous state is
+// kept, so clients are not told an agent disappeared because of a blip.
+func (s *Service) Watch(req *resolverv1.WatchRequest, stream resolverv1.ResolverService_WatchServer) error {
+	rr := &resolverv1.ResolveRequest{
+		TrustRoot:      req.TrustRoot,
+		CapabilityNode: req.CapabilityNode,
+		AgentId:        req.AgentId,
+	}
+	if err := validateRequest(rr); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx := stream.Context()
+	key := buildCacheKey(rr.TrustRoot, rr.CapabilityNode, rr.AgentId)
+	wake, cancel := s.watchers.subscribe(key)
+	defer cancel()
+
+	var last *watchState
+	poll := func() error {
+		next, reason, err := s.observe(ctx, rr)
+		if err != nil {
+			if last == nil {
+				return err
+			}
+			s.logger.Warn("watch poll failed", zap.String("uri", key), zap.Error(err))
+			return nil
+		}
+		ev := diffWatchState(last, next)
+		if ev == nil {
+			return nil
+		}
+		ev.Reason = reason
+		ev.ObservedAt = timestamppb.Now()
+		ev.Resolution.Uri = key
+		last = next
+		return stream.Send(ev)
+	}
+
+	if err := poll(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-wake:
+		}
+		if err := poll(); err != nil {
+			return err
+		}
+	}
+}
+
+// WatchCount returns the number of open Watch streams (for metrics/health).
+func (s *Service) WatchCount() int {
+	return s.watchers.count()
+}
+
+// observe queries the registry for the current state of a URI. A URI that the
+// registry refuses to resolve (not found, revoked, suspended) is returned as
+// an unresolved state with a reason; only transient failures return an error.
+// The query goes through fetch, so polls of one URI by many watchers, and by
+// Resolve, share a single registry request and keep the cache current.
+func (s *Service) observe(ctx context.Context, req *resolverv1.ResolveRequest) (*watchState, string, error) {
+	key := buildCacheKey(req.TrustRoot, req.CapabilityNode, req.AgentId)
+
+	res, err := s.fetch(ctx, key, req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound:
+			// fetch has cached the negative answer.
+			return &watchState{}, status.Convert(err).Message(), nil
+		case codes.InvalidArgument, codes.FailedPrecondition:
+			if s.cache != nil {
+				s.cache.invalidate(key)
+			}
+			return &watchState{}, status.Convert(err).Message(), nil
+		}
+		return nil, "", err
+	}
+
+	return &watchState{
+		resolved:   true,
+		endpoint:   res.Endpoint,
+		status:     res.Status,
+		certSerial: res.CertSerial,
+		endpoints:  res.Endpoints,
+	}, "", nil
+}
+
+// diffWatchState returns the event to send when moving from prev to next, or
+// nil when nothing a client routes on has changed.
+func diffWatchState(prev, next *watchState) *resolverv1.WatchEvent {
+	ev := &resolverv1.WatchEvent{Resolution: &resolverv1.ResolveResponse{}}
+	if next.resolved {
+		ev.Resolution.Endpoint = next.endpoint
+		ev.Resolution.Status = next.status
+		ev.Resolution.CertSerial = next.certSerial
+		ev.Resolution.Endpoints = endpointsProto(next.endpoints)
+	}
+
+	switch {
+	case prev == nil:
+		// The first event is INITIAL even when the URI does not resolve; its
+		// resolution is then empty and Reason says why.
+		ev.Type = resolverv1.WatchEvent_TYPE_INITIAL
+	case !next.resolved:
+		if !prev.resolved {
+			return nil
+		}
+		ev.Type = resolverv1.WatchEvent_TYPE_REMOVED
+	case !prev.resolved:
+		ev.Type = resolverv1.WatchEvent_TYPE_CHANGED
+		ev.ChangedFields = []string{"endpoint", "status", "cert_serial"}
+		if len(next.endpoints) > 0 {
+			ev.ChangedFields = append(ev.ChangedFields, "endpoints")
+		}
+	default:
+		if prev.endpoint != next.endpoint {
+			ev.ChangedFields = append(ev.ChangedFields, "endpoint")
+		}
+		if !slices.Equal(prev.endpoints, next.endpoints) {
+			ev.ChangedFields = append(ev.ChangedFields, "endpoints")
+		}
+		if prev.status != next.status {
+			ev.ChangedFields = append(ev.ChangedFields, "status")
+		}
+		if prev.certSerial != next.certSerial {
+			ev.ChangedFields = append(ev.ChangedFields, "cert_serial")
+		}
+		if len(ev.ChangedFields) == 0 {
+			return nil
+		}
+		ev.Type = resolverv1.WatchEvent_TYPE_CHANGED
+	}
+	return ev
+}
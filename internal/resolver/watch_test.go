@@ -0,0 +1,356 @@
+package resolver_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	resolverv1 "github.com/jmerrifield20/NexusAgentProtocol/api/proto/resolver/v1"
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/resolver"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// mutableRegistry is a stub registry whose agents can be changed while
+// watches are open.
+type mutableRegistry struct {
+	mu     sync.Mutex
+	agents map[string]map[string]string
+	srv    *httptest.Server
+}
+
+func newMutableRegistry(t *testing.T) *mutableRegistry {
+	t.Helper()
+	m := &mutableRegistry{agents: map[string]map[string]string{}}
+	m.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		key := fmt.Sprintf("%s/%s/%s", q.Get("trust_root"), q.Get("capability_node"), q.Get("agent_id"))
+
+		m.mu.Lock()
+		agent, ok := m.agents[key]
+		m.mu.Unlock()
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "agent not found"}) //nolint:errcheck
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"uri":         "agent://" + key,
+			"endpoint":    agent["endpoint"],
+			"status":      agent["status"],
+			"cert_serial": agent["cert_serial"],
+		})
+	}))
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+func (m *mutableRegistry) set(key string, agent map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if agent == nil {
+		delete(m.agents, key)
+		return
+	}
+	m.agents[key] = agent
+}
+
+// startGRPC serves svc on a random port and returns a connected client.
+func startGRPC(t *testing.T, svc *resolver.Service) (resolverv1.ResolverServiceClient, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcSrv := grpc.NewServer()
+	resolverv1.RegisterResolverServiceServer(grpcSrv, svc)
+	go grpcSrv.Serve(lis) //nolint:errcheck
+	t.Cleanup(grpcSrv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return resolverv1.NewResolverServiceClient(conn), lis.Addr().String()
+}
+
+func TestWatch_pushesChangesOnInvalidate(t *testing.T) {
+	reg := newMutableRegistry(t)
+	reg.set("acme.com/finance/agent_w", map[string]string{
+		"endpoint": "https://a.example.com", "status": "active", "cert_serial": "01",
+	})
+
+	// A long interval proves that events are driven by Invalidate, not polling.
+	svc := resolver.New(resolver.Config{
+		RegistryAddr:  reg.srv.Listener.Addr().String(),
+		CacheTTL:      time.Minute,
+		WatchInterval: time.Hour,
+	}, zap.NewNop())
+	client, _ := startGRPC(t, svc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &resolverv1.WatchRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_w",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv initial: %v", err)
+	}
+	if ev.Type != resolverv1.WatchEvent_TYPE_INITIAL || ev.Resolution.Endpoint != "https://a.example.com" {
+		t.Fatalf("initial event = %v", ev)
+	}
+	if ev.Resolution.Uri != "agent://acme.com/finance/agent_w" {
+		t.Errorf("Uri = %q", ev.Resolution.Uri)
+	}
+
+	reg.set("acme.com/finance/agent_w", map[string]string{
+		"endpoint": "https://b.example.com", "status": "active", "cert_serial": "01",
+	})
+	svc.Invalidate("acme.com", "finance", "agent_w")
+
+	ev, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv changed: %v", err)
+	}
+	if ev.Type != resolverv1.WatchEvent_TYPE_CHANGED || ev.Resolution.Endpoint != "https://b.example.com" {
+		t.Fatalf("changed event = %v", ev)
+	}
+	if len(ev.ChangedFields) != 1 || ev.ChangedFields[0] != "endpoint" {
+		t.Errorf("ChangedFields = %v, want [endpoint]", ev.ChangedFields)
+	}
+
+	reg.set("acme.com/finance/agent_w", nil)
+	svc.Invalidate("acme.com", "finance", "agent_w")
+
+	ev, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv removed: %v", err)
+	}
+	if ev.Type != resolverv1.WatchEvent_TYPE_REMOVED || ev.Reason == "" {
+		t.Fatalf("removed event = %v", ev)
+	}
+	if ev.Resolution.Endpoint != "" {
+		t.Errorf("removed event should not carry an endpoint, got %q", ev.Resolution.Endpoint)
+	}
+}
+
+func TestWatch_pollsOnInterval(t *testing.T) {
+	reg := newMutableRegistry(t)
+	reg.set("acme.com/finance/agent_p", map[string]string{
+		"endpoint": "https://a.example.com", "status": "active", "cert_serial": "01",
+	})
+
+	svc := resolver.New(resolver.Config{
+		RegistryAddr:  reg.srv.Listener.Addr().String(),
+		WatchInterval: 20 * time.Millisecond,
+	}, zap.NewNop())
+	client, _ := startGRPC(t, svc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &resolverv1.WatchRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_p",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv initial: %v", err)
+	}
+
+	reg.set("acme.com/finance/agent_p", map[string]string{
+		"endpoint": "https://a.example.com", "status": "active", "cert_serial": "02",
+	})
+
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv changed: %v", err)
+	}
+	if ev.Type != resolverv1.WatchEvent_TYPE_CHANGED || ev.Resolution.CertSerial != "02" {
+		t.Fatalf("changed event = %v", ev)
+	}
+	if svc.WatchCount() != 1 {
+		t.Errorf("WatchCount = %d, want 1", svc.WatchCount())
+	}
+}
+
+func TestWatch_gatewayServesSSE(t *testing.T) {
+	reg := newMutableRegistry(t)
+	reg.set("acme.com/finance/agent_s", map[string]string{
+		"endpoint": "https://a.example.com", "status": "active",
+	})
+
+	svc := resolver.New(resolver.Config{
+		RegistryAddr:  reg.srv.Listener.Addr().String(),
+		WatchInterval: time.Hour,
+	}, zap.NewNop())
+	_, addr := startGRPC(t, svc)
+
+	jsonMarshaler := &runtime.JSONPb{MarshalOptions: protojson.MarshalOptions{UseProtoNames: true}}
+	gwMux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, jsonMarshaler),
+		runtime.WithMarshalerOption(resolver.EventStreamMIME, &resolver.EventStreamMarshaler{Marshaler: jsonMarshaler}),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if err := resolverv1.RegisterResolverServiceHandlerFromEndpoint(ctx, gwMux, addr, dialOpts); err != nil {
+		t.Fatal(err)
+	}
+	gw := httptest.NewServer(gwMux)
+	defer gw.Close()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet,
+		gw.URL+"/v1/watch?trust_root=acme.com&capability_node=finance&agent_id=agent_s", nil)
+	req.Header.Set("Accept", resolver.EventStreamMIME)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != resolver.EventStreamMIME {
+		t.Errorf("Content-Type = %q, want %q", ct, resolver.EventStreamMIME)
+	}
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+	if !ok {
+		t.Fatalf("first line is not an SSE data field: %q", line)
+	}
+	var chunk struct {
+		Result struct {
+			Type       string `json:"type"`
+			Resolution struct {
+				Endpoint string `json:"endpoint"`
+			} `json:"resolution"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		t.Fatalf("decode %q: %v", data, err)
+	}
+	if chunk.Result.Type != "TYPE_INITIAL" || chunk.Result.Resolution.Endpoint != "https://a.example.com" {
+		t.Errorf("event = %+v", chunk.Result)
+	}
+}
+
+func TestWatch_initialEventForUnresolvedURI(t *testing.T) {
+	reg := newMutableRegistry(t)
+	svc := resolver.New(resolver.Config{
+		RegistryAddr:  reg.srv.Listener.Addr().String(),
+		WatchInterval: time.Hour,
+	}, zap.NewNop())
+	client, _ := startGRPC(t, svc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &resolverv1.WatchRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_u",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv initial: %v", err)
+	}
+	if ev.Type != resolverv1.WatchEvent_TYPE_INITIAL || ev.Reason == "" || ev.Resolution.Endpoint != "" {
+		t.Fatalf("initial event = %v, want an empty INITIAL with a reason", ev)
+	}
+
+	reg.set("acme.com/finance/agent_u", map[string]string{
+		"endpoint": "https://a.example.com", "status": "active",
+	})
+	svc.Invalidate("acme.com", "finance", "agent_u")
+
+	ev, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv changed: %v", err)
+	}
+	if ev.Type != resolverv1.WatchEvent_TYPE_CHANGED || ev.Resolution.Endpoint != "https://a.example.com" {
+		t.Fatalf("changed event = %v", ev)
+	}
+}
+
+func TestWatch_pollsShareRegistryQueries(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	release := make(chan struct{})
+	reg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		<-release
+		json.NewEncoder(w).Encode(map[string]string{ //nolint:errcheck
+			"uri": "agent://acme.com/finance/agent_c", "endpoint": "https://a.example.com", "status": "active",
+		})
+	}))
+	defer reg.Close()
+
+	svc := resolver.New(resolver.Config{
+		RegistryAddr:  reg.Listener.Addr().String(),
+		CacheTTL:      time.Minute,
+		WatchInterval: time.Hour,
+	}, zap.NewNop())
+	client, _ := startGRPC(t, svc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var streams []resolverv1.ResolverService_WatchClient
+	for range 3 {
+		stream, err := client.Watch(ctx, &resolverv1.WatchRequest{
+			TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_c",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, stream)
+	}
+	for svc.WatchCount() < 3 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond) // let every watch join the in-flight query
+	close(release)
+
+	for _, stream := range streams {
+		if ev, err := stream.Recv(); err != nil || ev.Type != resolverv1.WatchEvent_TYPE_INITIAL {
+			t.Fatalf("Recv initial: %v, %v", ev, err)
+		}
+	}
+	// The shared answer is cached, so Resolve does not query again.
+	if _, err := svc.Resolve(ctx, &resolverv1.ResolveRequest{
+		TrustRoot: "acme.com", CapabilityNode: "finance", AgentId: "agent_c",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 1 {
+		t.Errorf("registry requests = %d, want 1", requests)
+	}
+}
@@ -0,0 +1,261 @@
+package trustledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Checkpoint records that the chain was verified from genesis up to and
+// including Index, whose entry hash was Hash at VerifiedAt.
+type Checkpoint struct {
+	Index      int       `json:"index"`
+	Hash       string    `json:"hash"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// IncrementalVerifier is implemented by ledgers that keep verified
+// checkpoints, so that verification only walks entries appended since the
+// last checkpoint. MemoryLedger and PostgresLedger implement it; FileLedger
+// does not, so BackgroundVerifier runs a full Verify of it on every run.
+//
+// Entries before the checkpoint are not re-read, so tampering with them goes
+// unnoticed until the next full Verify (see
+// BackgroundVerifier.SetFullVerifyInterval).
+type IncrementalVerifier interface {
+	// VerifyIncremental verifies entries after the latest checkpoint, checks
+	// that the checkpointed entry itself is unchanged, and records a new
+	// checkpoint at the chain tip. It returns that checkpoint.
+	VerifyIncremental(ctx context.Context) (*Checkpoint, error)
+}
+
+// ResumeChainVerifier returns a ChainVerifier that continues from a trusted
+// checkpoint instead of genesis. The next entry added must have index
+// cp.Index+1 and chain to cp.Hash.
+func ResumeChainVerifier(cp *Checkpoint) *ChainVerifier {
+	return &ChainVerifier{
+		prev:  &Entry{Index: cp.Index, Hash: cp.Hash},
+		count: cp.Index + 1,
+	}
+}
+
+// checkpointMismatch is returned when the entry at a checkpoint no longer has
+// the hash that was verified.
+func checkpointMismatch(cp *Checkpoint, got string) error {
+	return &BrokenChainError{
+		Index:  cp.Index,
+		Reason: fmt.Sprintf("entry %d no longer matches verified checkpoint: got %q, want %q", cp.Index, got, cp.Hash),
+	}
+}
+
+// ── MemoryLedger ────────────────────────────────────────────────────────────
+
+// VerifyIncremental implements IncrementalVerifier.
+func (l *MemoryLedger) VerifyIncremental(_ context.Context) (*Checkpoint, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v := NewChainVerifier()
+	from := 0
+	if cp := l.checkpoint; cp != nil {
+		if got := l.entries[cp.Index].Hash; got != cp.Hash {
+			return nil, checkpointMismatch(cp, got)
+		}
+		v = ResumeChainVerifier(cp)
+		from = cp.Index + 1
+	}
+	for _, e := range l.entries[from:] {
+		if err := v.Add(e); err != nil {
+			return nil, err
+		}
+	}
+
+	last := v.Last()
+	l.checkpoint = &Checkpoint{Index: last.Index, Hash: last.Hash, VerifiedAt: time.Now().UTC()}
+	return l.checkpoint, nil
+}
+
+// ── PostgresLedger ──────────────────────────────────────────────────────────
+
+// VerifyIncremental implements IncrementalVerifier. Checkpoints are stored in
+// trust_ledger_checkpoints; only rows with idx greater than the latest
+// checkpoint are streamed.
+func (l *PostgresLedger) VerifyIncremental(ctx context.Context) (*Checkpoint, error) {
+	cp, err := l.latestCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v := NewChainVerifier()
+	from := 0
+	if cp != nil {
+		var got string
+		if err := l.pool.QueryRow(ctx,
+			"SELECT hash FROM trust_ledger WHERE idx = $1", cp.Index,
+		).Scan(&got); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, checkpointMismatch(cp, "")
+			}
+			return nil, fmt.Errorf("read checkpointed entry: %w", err)
+		}
+		if got != cp.Hash {
+			return nil, checkpointMismatch(cp, got)
+		}
+		v = ResumeChainVerifier(cp)
+		from = cp.Index + 1
+	}
+
+	rows, err := l.pool.Query(ctx,
+		"SELECT "+entryColumns+" FROM trust_ledger WHERE idx >= $1 ORDER BY idx ASC", from,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ledger row: %w", err)
+		}
+		if err := v.Add(e); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ledger rows: %w", err)
+	}
+
+	last := v.Last()
+	if last == nil {
+		return nil, &BrokenChainError{Index: 0, Reason: "ledger has no genesis entry"}
+	}
+	if cp != nil && last.Index == cp.Index {
+		return cp, nil
+	}
+
+	next := &Checkpoint{Index: last.Index, Hash: last.Hash, VerifiedAt: time.Now().UTC()}
+	if _, err := l.pool.Exec(ctx,
+		`INSERT INTO trust_ledger_checkpoints (idx, hash, verified_at)
+		 VALUES ($1, $2, $3) ON CONFLICT (idx) DO NOTHING`,
+		next.Index, next.Hash, next.VerifiedAt,
+	); err != nil {
+		return nil, fmt.Errorf("save ledger checkpoint: %w", err)
+	}
+	return next, nil
+}
+
+func (l *PostgresLedger) latestCheckpoint(ctx context.Context) (*Checkpoint, error) {
+	cp := &Checkpoint{}
+	if err := l.pool.QueryRow(ctx,
+		"SELECT idx, hash, verified_at FROM trust_ledger_checkpoints ORDER BY idx DESC LIMIT 1",
+	).Scan(&cp.Index, &cp.Hash, &cp.VerifiedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read ledger checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// ── BackgroundVerifier ──────────────────────────────────────────────────────
+
+// VerificationRecordFunc is an optional callback invoked after every
+// background verification run. brokenIndex is -1 when the chain is intact.
+type VerificationRecordFunc func(ok bool, verifiedIndex, brokenIndex int)
+
+// BackgroundVerifier periodically verifies the ledger. It uses
+// VerifyIncremental when the ledger supports it, with a full Verify every
+// full-verify interval, and a full Verify on every run otherwise.
+type BackgroundVerifier struct {
+	ledger       Ledger
+	interval     time.Duration
+	fullInterval time.Duration // 0 = incremental only
+	lastFull     time.Time
+	onResult     VerificationRecordFunc
+	logger       *zap.Logger
+}
+
+// NewBackgroundVerifier creates a BackgroundVerifier. interval defaults to
+// ten minutes when zero.
+func NewBackgroundVerifier(ledger Ledger, interval time.Duration, logger *zap.Logger) *BackgroundVerifier {
+	if interval == 0 {
+		interval = 10 * time.Minute
+	}
+	return &BackgroundVerifier{ledger: ledger, interval: interval, logger: logger}
+}
+
+// SetFullVerifyInterval makes a run walk the whole chain from genesis when
+// at least d has passed since the last full walk, so entries behind the
+// checkpoint are re-checked too. The first run is always full. Zero
+// disables full walks for ledgers that support VerifyIncremental.
+func (b *BackgroundVerifier) SetFullVerifyInterval(d time.Duration) {
+	b.fullInterval = d
+}
+
+// SetMetricsRecord configures the callback invoked after each run.
+func (b *BackgroundVerifier) SetMetricsRecord(fn VerificationRecordFunc) {
+	b.onResult = fn
+}
+
+// RunOnce performs a single verification pass and reports the result.
+func (b *BackgroundVerifier) RunOnce(ctx context.Context) error {
+	verifiedIndex := -1
+	var err error
+	iv, incremental := b.ledger.(IncrementalVerifier)
+	if !incremental || (b.fullInterval > 0 && time.Since(b.lastFull) >= b.fullInterval) {
+		if err = b.ledger.Verify(ctx); err == nil {
+			b.lastFull = time.Now()
+			if n, lenErr := b.ledger.Len(ctx); lenErr == nil {
+				verifiedIndex = n - 1
+			}
+		}
+	}
+	// The incremental pass also moves the checkpoint past a full walk.
+	if incremental && err == nil {
+		var cp *Checkpoint
+		if cp, err = iv.VerifyIncremental(ctx); err == nil {
+			verifiedIndex = cp.Index
+		}
+	}
+
+	brokenIndex := -1
+	var broken *BrokenChainError
+	if errors.As(err, &broken) {
+		brokenIndex = broken.Index
+		b.logger.Error("trust ledger integrity check FAILED",
+			zap.Int("broken_index", broken.Index),
+			zap.Error(err),
+		)
+	} else if err != nil {
+		// Storage errors are not evidence of tampering; don't raise the alert.
+		b.logger.Warn("trust ledger verification could not run", zap.Error(err))
+		return err
+	}
+
+	if b.onResult != nil {
+		b.onResult(err == nil, verifiedIndex, brokenIndex)
+	}
+	return err
+}
+
+// Start runs a verification pass on every interval until ctx is done.
+func (b *BackgroundVerifier) Start(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(ctx, b.interval)
+			_ = b.RunOnce(ctx)
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
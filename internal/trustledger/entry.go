@@ -3,6 +3,7 @@ package trustledger
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -17,11 +18,84 @@ type Entry struct {
 	Index     int       `json:"index"`
 	Timestamp time.Time `json:"timestamp"`
 	AgentURI  string    `json:"agent_uri"`
-	Action    string    `json:"action"`    // register, activate, revoke, update, genesis
+	Action    string    `json:"action"`    // register, activate, renew, expire, revoke, update, rollback, genesis
 	Actor     string    `json:"actor"`     // owner domain or "nexus-system"
 	DataHash  string    `json:"data_hash"` // SHA-256 of the associated payload
 	PrevHash  string    `json:"prev_hash"`
 	Hash      string    `json:"hash"`
+
+	// Payload is the exact JSON that DataHash was computed over. It is not
+	// part of the entry hash; VerifyPayload ties it back to DataHash.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// VerifyPayload checks that Payload hashes to DataHash. Entries without a
+// stored payload (genesis, or entries written before payloads were kept)
+// cannot be checked and return nil.
+func (e *Entry) VerifyPayload() error {
+	if len(e.Payload) == 0 {
+		return nil
+	}
+	if got := sha256Sum(e.Payload); got != e.DataHash {
+		return fmt.Errorf("entry %d payload hash %s does not match data_hash %s", e.Index, got, e.DataHash)
+	}
+	return nil
+}
+
+// Filter selects ledger entries for Query. Zero-valued fields match
+// everything.
+type Filter struct {
+	AgentURI  string
+	Action    string
+	Actor     string
+	Since     time.Time // inclusive
+	Until     time.Time // exclusive
+	FromIndex int       // first index to consider; use the previous page's NextIndex
+	Limit     int       // default 50, max 500
+}
+
+// Page is one page of Query results in ascending index order. NextIndex is
+// the FromIndex for the following page, or 0 when there are no more results.
+type Page struct {
+	Entries   []*Entry `json:"entries"`
+	NextIndex int      `json:"next_index,omitempty"`
+}
+
+// normalize applies Filter defaults and bounds.
+func (f Filter) normalize() Filter {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	if f.Limit > 500 {
+		f.Limit = 500
+	}
+	if f.FromIndex < 0 {
+		f.FromIndex = 0
+	}
+	return f
+}
+
+// matches reports whether e satisfies every non-zero field of f.
+func (f Filter) matches(e *Entry) bool {
+	if e.Index < f.FromIndex {
+		return false
+	}
+	if f.AgentURI != "" && e.AgentURI != f.AgentURI {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Timestamp.Before(f.Until) {
+		return false
+	}
+	return true
 }
 
 // hashEntry computes a deterministic SHA-256 hash over an entry's fields.
@@ -0,0 +1,597 @@
+package trustledger
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// On-disk layout of a FileLedger directory:
+//
+//	<first-index>.log  append-only frames, one per entry:
+//	                   uint32 length | uint32 CRC-32C | JSON-encoded Entry
+//	<first-index>.idx  fixed-size records, one per entry:
+//	                   uint64 frame offset | 32-byte entry hash
+//
+// Segment file names are the zero-padded index of their first entry, so the
+// directory sorts in chain order. Every append fsyncs the log before the
+// index; the log is authoritative and the index is repaired from it on open.
+const (
+	frameHeaderSize = 8
+	indexRecordSize = 8 + 32
+	maxFrameSize    = 16 << 20
+
+	logSuffix   = ".log"
+	indexSuffix = ".idx"
+
+	// DefaultSegmentEntries is the number of entries written to a segment
+	// before a new one is started.
+	DefaultSegmentEntries = 1 << 16
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// segment is one log file and its index.
+type segment struct {
+	first   int         // index of the segment's first entry
+	log     *os.File    // frames
+	idx     segmentFile // index records
+	offsets []int64     // frame offset of each entry
+	size    int64       // bytes of valid frames in log
+}
+
+// segmentFile is the part of *os.File a segment uses for its index, so that
+// tests can make index writes fail.
+type segmentFile interface {
+	io.ReaderAt
+	io.WriterAt
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// FileLedger is a durable Ledger stored as fsync'd append-only segment files.
+// It is intended for single-node registries that do not keep the ledger in
+// PostgreSQL. The directory can be copied and checked elsewhere with
+// NewFileLedger + Verify.
+//
+// Entry hashes are held in memory (about 64 bytes per entry) so that Root and
+// Merkle proofs never touch the log files.
+type FileLedger struct {
+	mu                sync.RWMutex
+	dir               string
+	segments          []*segment
+	hashes            []string
+	maxSegmentEntries int
+}
+
+// NewFileLedger opens the ledger stored in dir, creating the directory and
+// the genesis entry if needed. A torn final write left by a crash is
+// truncated; any other damage is reported as an error.
+func NewFileLedger(dir string) (*FileLedger, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create ledger dir: %w", err)
+	}
+	l := &FileLedger{dir: dir, maxSegmentEntries: DefaultSegmentEntries}
+
+	firsts, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, first := range firsts {
+		if first != len(l.hashes) {
+			l.Close()
+			return nil, fmt.Errorf("segment %d: expected first index %d", first, len(l.hashes))
+		}
+		seg, hashes, err := openSegment(dir, first, i == len(firsts)-1)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		l.segments = append(l.segments, seg)
+		l.hashes = append(l.hashes, hashes...)
+	}
+
+	if len(l.hashes) == 0 {
+		if err := l.writeGenesis(); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// SetMaxSegmentEntries changes how many entries are written to a segment
+// before rolling to a new one. Existing segments are unaffected.
+func (l *FileLedger) SetMaxSegmentEntries(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n > 0 {
+		l.maxSegmentEntries = n
+	}
+}
+
+// Close closes all segment files.
+func (l *FileLedger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var firstErr error
+	for _, s := range l.segments {
+		for _, f := range []io.Closer{s.log, s.idx} {
+			if err := f.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	l.segments = nil
+	return firstErr
+}
+
+// Append implements Ledger.
+func (l *FileLedger) Append(_ context.Context, agentURI, action, actor string, payload any) (*Entry, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := &Entry{
+		Index:     len(l.hashes),
+		Timestamp: time.Now().UTC(),
+		AgentURI:  agentURI,
+		Action:    action,
+		Actor:     actor,
+		DataHash:  sha256Sum(payloadJSON),
+		PrevHash:  l.hashes[len(l.hashes)-1],
+		Payload:   payloadJSON,
+	}
+	entry.Hash = hashEntry(entry)
+
+	if err := l.write(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Get implements Ledger.
+func (l *FileLedger) Get(_ context.Context, index int) (*Entry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.read(index)
+}
+
+// Len implements Ledger.
+func (l *FileLedger) Len(_ context.Context) (int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.hashes), nil
+}
+
+// Verify implements Ledger. It reads every entry back from disk, checks the
+// hash chain, and checks that the index agrees with the log.
+func (l *FileLedger) Verify(_ context.Context) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	v := NewChainVerifier()
+	for i := range l.hashes {
+		e, err := l.read(i)
+		if err != nil {
+			return &BrokenChainError{Index: i, Reason: err.Error()}
+		}
+		if err := v.Add(e); err != nil {
+			return err
+		}
+		if l.hashes[i] != e.Hash {
+			return &BrokenChainError{Index: i, Reason: fmt.Sprintf("index record for entry %d does not match log", i)}
+		}
+	}
+	return nil
+}
+
+// Query implements Ledger.
+func (l *FileLedger) Query(_ context.Context, f Filter) (*Page, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	f = f.normalize()
+	page := &Page{Entries: []*Entry{}}
+	for i := f.FromIndex; i < len(l.hashes); i++ {
+		e, err := l.read(i)
+		if err != nil {
+			return nil, err
+		}
+		if !f.matches(e) {
+			continue
+		}
+		if len(page.Entries) == f.Limit {
+			page.NextIndex = e.Index
+			break
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	return page, nil
+}
+
+// Root implements Ledger.
+func (l *FileLedger) Root(_ context.Context) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.hashes[len(l.hashes)-1], nil
+}
+
+// MerkleRoot implements Ledger.
+func (l *FileLedger) MerkleRoot(_ context.Context, treeSize int) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	hashes, err := l.prefix(treeSize)
+	if err != nil {
+		return "", err
+	}
+	return merkleRootHex(hashes)
+}
+
+// InclusionProof implements Ledger.
+func (l *FileLedger) InclusionProof(_ context.Context, index, treeSize int) (*InclusionProof, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	hashes, err := l.prefix(treeSize)
+	if err != nil {
+		return nil, err
+	}
+	return buildInclusionProof(hashes, index)
+}
+
+// ConsistencyProof implements Ledger.
+func (l *FileLedger) ConsistencyProof(_ context.Context, oldSize, newSize int) (*ConsistencyProof, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	hashes, err := l.prefix(newSize)
+	if err != nil {
+		return nil, err
+	}
+	return buildConsistencyProof(hashes, oldSize)
+}
+
+// prefix returns the first treeSize entry hashes. The caller must hold l.mu.
+func (l *FileLedger) prefix(treeSize int) ([]string, error) {
+	if treeSize < 1 || treeSize > len(l.hashes) {
+		return nil, fmt.Errorf("%w: tree size %d not in 1..%d", ErrInvalidProofRange, treeSize, len(l.hashes))
+	}
+	return l.hashes[:treeSize], nil
+}
+
+// writeGenesis writes the canonical genesis entry to a fresh directory.
+func (l *FileLedger) writeGenesis() error {
+	return l.write(&Entry{
+		Index:     0,
+		Timestamp: time.Now().UTC(),
+		Action:    "genesis",
+		Actor:     "nexus-system",
+		DataHash:  GenesisHash,
+		PrevHash:  GenesisHash,
+		Hash:      GenesisHash,
+	})
+}
+
+// write appends entry to the active segment, rolling to a new segment when
+// the active one is full. The caller must hold l.mu for writing.
+func (l *FileLedger) write(entry *Entry) error {
+	if n := len(l.segments); n == 0 || len(l.segments[n-1].offsets) >= l.maxSegmentEntries {
+		seg, err := createSegment(l.dir, entry.Index)
+		if err != nil {
+			return err
+		}
+		l.segments = append(l.segments, seg)
+	}
+	seg := l.segments[len(l.segments)-1]
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+	frame := make([]byte, frameHeaderSize+len(body))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(body, crcTable))
+	copy(frame[frameHeaderSize:], body)
+
+	if _, err := seg.log.WriteAt(frame, seg.size); err != nil {
+		seg.discardTail()
+		return fmt.Errorf("write ledger frame: %w", err)
+	}
+	if err := seg.log.Sync(); err != nil {
+		seg.discardTail()
+		return fmt.Errorf("sync ledger log: %w", err)
+	}
+
+	rec, err := indexRecord(seg.size, entry.Hash)
+	if err != nil {
+		seg.discardTail()
+		return err
+	}
+	// The append is reported as failed unless the index is durable too, so
+	// the frame must not survive a failed index write: open would otherwise
+	// recover it from the log and the entry would reappear.
+	if _, err := seg.idx.WriteAt(rec, int64(len(seg.offsets))*indexRecordSize); err != nil {
+		seg.discardTail()
+		return fmt.Errorf("write ledger index: %w", err)
+	}
+	if err := seg.idx.Sync(); err != nil {
+		seg.discardTail()
+		return fmt.Errorf("sync ledger index: %w", err)
+	}
+
+	seg.offsets = append(seg.offsets, seg.size)
+	seg.size += int64(len(frame))
+	l.hashes = append(l.hashes, entry.Hash)
+	return nil
+}
+
+// discardTail truncates the log and index back to the entries already
+// appended, dropping whatever a failed append wrote after them.
+func (s *segment) discardTail() {
+	_ = s.log.Truncate(s.size)
+	_ = s.log.Sync()
+	_ = s.idx.Truncate(int64(len(s.offsets)) * indexRecordSize)
+	_ = s.idx.Sync()
+}
+
+// read loads the entry at index from disk. The caller must hold l.mu.
+func (l *FileLedger) read(index int) (*Entry, error) {
+	if index < 0 || index >= len(l.hashes) {
+		return nil, fmt.Errorf("index %d out of range", index)
+	}
+	i := sort.Search(len(l.segments), func(i int) bool { return l.segments[i].first > index }) - 1
+	seg := l.segments[i]
+	e, _, err := readFrame(seg.log, seg.offsets[index-seg.first], seg.size)
+	if err != nil {
+		return nil, fmt.Errorf("read entry %d: %w", index, err)
+	}
+	if e.Index != index {
+		return nil, fmt.Errorf("read entry %d: log holds index %d", index, e.Index)
+	}
+	return e, nil
+}
+
+// ── segment files ───────────────────────────────────────────────────────────
+
+func segmentPath(dir string, first int, suffix string) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", first, suffix))
+}
+
+// listSegments returns the first index of every segment in dir, ascending.
+func listSegments(dir string) ([]int, error) {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read ledger dir: %w", err)
+	}
+	var firsts []int
+	for _, e := range ents {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, logSuffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(name, logSuffix))
+		if err != nil {
+			continue
+		}
+		firsts = append(firsts, n)
+	}
+	sort.Ints(firsts)
+	return firsts, nil
+}
+
+// createSegment creates empty log and index files for a new segment and
+// fsyncs the directory so the new names survive a crash.
+func createSegment(dir string, first int) (*segment, error) {
+	log, err := os.OpenFile(segmentPath(dir, first, logSuffix), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create ledger segment: %w", err)
+	}
+	idx, err := os.OpenFile(segmentPath(dir, first, indexSuffix), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Close()
+		return nil, fmt.Errorf("create ledger index: %w", err)
+	}
+	if err := syncDir(dir); err != nil {
+		log.Close()
+		idx.Close()
+		return nil, err
+	}
+	return &segment{first: first, log: log, idx: idx}, nil
+}
+
+// openSegment opens an existing segment, loads its index and reconciles it
+// with the log. For the last segment, complete frames missing from the index
+// are indexed and a torn trailing frame is truncated.
+func openSegment(dir string, first int, last bool) (*segment, []string, error) {
+	log, err := os.OpenFile(segmentPath(dir, first, logSuffix), os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open ledger segment: %w", err)
+	}
+	idx, err := os.OpenFile(segmentPath(dir, first, indexSuffix), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		log.Close()
+		return nil, nil, fmt.Errorf("open ledger index: %w", err)
+	}
+	seg := &segment{first: first, log: log, idx: idx}
+	hashes, err := seg.load(last)
+	if err != nil {
+		log.Close()
+		idx.Close()
+		return nil, nil, fmt.Errorf("segment %d: %w", first, err)
+	}
+	return seg, hashes, nil
+}
+
+// load reads the index, trusts every record that points at a complete frame,
+// then scans the log from the end of the last indexed frame.
+func (s *segment) load(last bool) ([]string, error) {
+	logInfo, err := s.log.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat log: %w", err)
+	}
+	logSize := logInfo.Size()
+
+	raw, err := io.ReadAll(io.NewSectionReader(s.idx, 0, 1<<62))
+	if err != nil {
+		return nil, fmt.Errorf("read index: %w", err)
+	}
+
+	var hashes []string
+	for off := 0; off+indexRecordSize <= len(raw); off += indexRecordSize {
+		frameOff := int64(binary.BigEndian.Uint64(raw[off : off+8]))
+		if frameOff != s.size || frameOff >= logSize {
+			break // index ran ahead of the log, or is damaged; rescan from here
+		}
+		n, err := frameLen(s.log, frameOff, logSize)
+		if err != nil {
+			break
+		}
+		s.offsets = append(s.offsets, frameOff)
+		hashes = append(hashes, hex.EncodeToString(raw[off+8:off+indexRecordSize]))
+		s.size = frameOff + n
+	}
+	indexed := len(s.offsets)
+
+	// Scan frames not covered by the index.
+	for s.size < logSize {
+		e, n, err := readFrame(s.log, s.size, logSize)
+		if err != nil {
+			torn := errors.Is(err, errTornFrame)
+			if !last || !torn {
+				return nil, fmt.Errorf("corrupt frame at offset %d: %w", s.size, err)
+			}
+			if err := s.log.Truncate(s.size); err != nil {
+				return nil, fmt.Errorf("truncate torn write: %w", err)
+			}
+			if err := s.log.Sync(); err != nil {
+				return nil, fmt.Errorf("sync log: %w", err)
+			}
+			break
+		}
+		if e.Index != s.first+len(s.offsets) {
+			return nil, fmt.Errorf("frame at offset %d has index %d, want %d", s.size, e.Index, s.first+len(s.offsets))
+		}
+		s.offsets = append(s.offsets, s.size)
+		hashes = append(hashes, e.Hash)
+		s.size += n
+	}
+
+	// Rewrite the index if it was short, long or damaged.
+	if indexed != len(s.offsets) || int64(len(raw)) != int64(len(s.offsets))*indexRecordSize {
+		if err := s.rewriteIndex(hashes); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.offsets) == 0 && !last {
+		return nil, fmt.Errorf("empty segment")
+	}
+	return hashes, nil
+}
+
+func (s *segment) rewriteIndex(hashes []string) error {
+	buf := make([]byte, 0, len(s.offsets)*indexRecordSize)
+	for i, off := range s.offsets {
+		rec, err := indexRecord(off, hashes[i])
+		if err != nil {
+			return err
+		}
+		buf = append(buf, rec...)
+	}
+	if err := s.idx.Truncate(0); err != nil {
+		return fmt.Errorf("truncate index: %w", err)
+	}
+	if _, err := s.idx.WriteAt(buf, 0); err != nil {
+		return fmt.Errorf("rewrite index: %w", err)
+	}
+	if err := s.idx.Sync(); err != nil {
+		return fmt.Errorf("sync index: %w", err)
+	}
+	return nil
+}
+
+func indexRecord(offset int64, hash string) ([]byte, error) {
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("entry hash %q is not 32 hex bytes", hash)
+	}
+	rec := make([]byte, indexRecordSize)
+	binary.BigEndian.PutUint64(rec[0:8], uint64(offset))
+	copy(rec[8:], raw)
+	return rec, nil
+}
+
+// errTornFrame marks a frame that runs past the end of the log, i.e. a write
+// that was interrupted before it completed.
+var errTornFrame = errors.New("torn frame")
+
+// frameLen returns the total length of the complete frame at off.
+func frameLen(f *os.File, off, logSize int64) (int64, error) {
+	var hdr [frameHeaderSize]byte
+	if off+frameHeaderSize > logSize {
+		return 0, errTornFrame
+	}
+	if _, err := f.ReadAt(hdr[:], off); err != nil {
+		return 0, err
+	}
+	n := int64(binary.BigEndian.Uint32(hdr[0:4]))
+	if n > maxFrameSize {
+		return 0, fmt.Errorf("frame length %d exceeds limit", n)
+	}
+	if off+frameHeaderSize+n > logSize {
+		return 0, errTornFrame
+	}
+	return frameHeaderSize + n, nil
+}
+
+// readFrame decodes and CRC-checks the frame at off. A checksum failure on
+// the final frame of the log is treated as torn.
+func readFrame(f *os.File, off, logSize int64) (*Entry, int64, error) {
+	n, err := frameLen(f, off, logSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	buf := make([]byte, n)
+	if _, err := f.ReadAt(buf, off); err != nil {
+		return nil, 0, err
+	}
+	body := buf[frameHeaderSize:]
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(buf[4:8]) {
+		if off+n == logSize {
+			return nil, 0, errTornFrame
+		}
+		return nil, 0, errors.New("checksum mismatch")
+	}
+	var e Entry
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, 0, fmt.Errorf("decode entry: %w", err)
+	}
+	return &e, n, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open ledger dir: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync ledger dir: %w", err)
+	}
+	return nil
+}
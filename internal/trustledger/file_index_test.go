@@ -0,0 +1,80 @@
+package trustledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// failingIndex is a segment index whose writes or syncs fail.
+type failingIndex struct {
+	segmentFile
+	failWrite, failSync bool
+}
+
+func (f *failingIndex) WriteAt(p []byte, off int64) (int, error) {
+	if f.failWrite {
+		return 0, errors.New("no space left on device")
+	}
+	return f.segmentFile.WriteAt(p, off)
+}
+
+func (f *failingIndex) Sync() error {
+	if f.failSync {
+		return errors.New("input/output error")
+	}
+	return f.segmentFile.Sync()
+}
+
+func TestFileLedger_indexFailureDiscardsFrame(t *testing.T) {
+	ctx := context.Background()
+	for name, fail := range map[string]failingIndex{
+		"write": {failWrite: true},
+		"sync":  {failSync: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			l, err := NewFileLedger(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "register", "nexus-system", nil); err != nil {
+				t.Fatal(err)
+			}
+			root, _ := l.Root(ctx)
+
+			seg := l.segments[len(l.segments)-1]
+			idx := seg.idx
+			fail.segmentFile = idx
+			seg.idx = &fail
+			if _, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_2", "register", "nexus-system", nil); err == nil {
+				t.Fatal("Append succeeded with a failing index")
+			}
+			seg.idx = idx
+			l.Close()
+
+			// The failed entry must not come back from the log.
+			l, err = NewFileLedger(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			if n, _ := l.Len(ctx); n != 2 {
+				t.Errorf("Len after reopen: got %d, want 2", n)
+			}
+			if got, _ := l.Root(ctx); got != root {
+				t.Errorf("Root after reopen: got %q, want %q", got, root)
+			}
+			e, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_3", "register", "nexus-system", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Index != 2 {
+				t.Errorf("next append: index %d, want 2", e.Index)
+			}
+			if err := l.Verify(ctx); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package trustledger_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
+)
+
+func openFileLedger(t *testing.T, dir string) *trustledger.FileLedger {
+	t.Helper()
+	l, err := trustledger.NewFileLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetMaxSegmentEntries(3)
+	return l
+}
+
+func lastSegmentLog(t *testing.T, dir string) string {
+	t.Helper()
+	logs, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil || len(logs) == 0 {
+		t.Fatalf("no segment logs in %s", dir)
+	}
+	sort.Strings(logs)
+	return logs[len(logs)-1]
+}
+
+func TestFileLedger_persistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	l := openFileLedger(t, dir)
+	appendEntries(t, l, 7)
+	root, _ := l.Root(ctx)
+	l.Close()
+
+	l = openFileLedger(t, dir)
+	defer l.Close()
+
+	n, _ := l.Len(ctx)
+	if n != 8 {
+		t.Errorf("Len after reopen: got %d, want 8", n)
+	}
+	if got, _ := l.Root(ctx); got != root {
+		t.Errorf("Root after reopen: got %q, want %q", got, root)
+	}
+	if err := l.Verify(ctx); err != nil {
+		t.Errorf("Verify after reopen: %v", err)
+	}
+
+	e, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_x", "revoke", "nexus-system", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Index != 8 || e.PrevHash != root {
+		t.Errorf("append after reopen: index=%d prev=%q", e.Index, e.PrevHash)
+	}
+}
+
+func TestFileLedger_recoversTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	l := openFileLedger(t, dir)
+	appendEntries(t, l, 4)
+	root, _ := l.Root(ctx)
+	l.Close()
+
+	// Simulate a crash half-way through writing the next frame.
+	f, err := os.OpenFile(lastSegmentLog(t, dir), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{0, 0, 1, 0, 0xde, 0xad, 0xbe, 0xef, '{', '"'})
+	f.Close()
+
+	l = openFileLedger(t, dir)
+	defer l.Close()
+
+	n, _ := l.Len(ctx)
+	if n != 5 {
+		t.Errorf("Len after recovery: got %d, want 5", n)
+	}
+	if got, _ := l.Root(ctx); got != root {
+		t.Errorf("Root after recovery: got %q, want %q", got, root)
+	}
+	if _, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_x", "register", "example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Verify(ctx); err != nil {
+		t.Errorf("Verify after recovery: %v", err)
+	}
+}
+
+func TestFileLedger_rebuildsMissingIndex(t *testing.T) {
+	dir := t.TempDir()
+	l := openFileLedger(t, dir)
+	appendEntries(t, l, 5)
+	root, _ := l.MerkleRoot(ctx, 6)
+	l.Close()
+
+	idxFiles, _ := filepath.Glob(filepath.Join(dir, "*.idx"))
+	for _, p := range idxFiles {
+		if err := os.Remove(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l = openFileLedger(t, dir)
+	defer l.Close()
+
+	if got, _ := l.MerkleRoot(ctx, 6); got != root {
+		t.Errorf("MerkleRoot after index rebuild: got %q, want %q", got, root)
+	}
+	if err := l.Verify(ctx); err != nil {
+		t.Errorf("Verify after index rebuild: %v", err)
+	}
+}
+
+func TestFileLedger_rejectsCorruptionBeforeTail(t *testing.T) {
+	dir := t.TempDir()
+	l := openFileLedger(t, dir)
+	appendEntries(t, l, 1) // genesis + 1 entry in the first segment
+	l.Close()
+
+	path := lastSegmentLog(t, dir)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[12] ^= 0xff // flip a byte inside the genesis frame body
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	idxFiles, _ := filepath.Glob(filepath.Join(dir, "*.idx"))
+	for _, p := range idxFiles {
+		_ = os.Remove(p)
+	}
+
+	if _, err := trustledger.NewFileLedger(dir); err == nil {
+		t.Fatal("expected corruption before the last frame to be reported, not truncated")
+	}
+}
@@ -6,7 +6,8 @@ import "context"
 // Both MemoryLedger and PostgresLedger implement this interface.
 type Ledger interface {
 	// Append adds a new entry chained to the previous one.
-	// payload is JSON-marshalled and its SHA-256 is stored as DataHash.
+	// payload is JSON-marshalled, stored as Payload, and its SHA-256 is
+	// stored as DataHash.
 	Append(ctx context.Context, agentURI, action, actor string, payload any) (*Entry, error)
 
 	// Get returns the entry at the given zero-based index.
@@ -19,6 +20,21 @@ type Ledger interface {
 	// Returns nil if the chain is intact.
 	Verify(ctx context.Context) error
 
+	// Query returns entries matching f in ascending index order.
+	Query(ctx context.Context, f Filter) (*Page, error)
+
 	// Root returns the hash of the most recent entry (the chain tip).
 	Root(ctx context.Context) (string, error)
+
+	// MerkleRoot returns the RFC 6962 Merkle tree head over the first
+	// treeSize entries.
+	MerkleRoot(ctx context.Context, treeSize int) (string, error)
+
+	// InclusionProof returns an audit path proving that the entry at index
+	// is included in the Merkle tree over the first treeSize entries.
+	InclusionProof(ctx context.Context, index, treeSize int) (*InclusionProof, error)
+
+	// ConsistencyProof returns a proof that the Merkle tree over the first
+	// oldSize entries is a prefix of the tree over the first newSize entries.
+	ConsistencyProof(ctx context.Context, oldSize, newSize int) (*ConsistencyProof, error)
 }
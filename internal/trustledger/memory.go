@@ -12,8 +12,9 @@ import (
 // It is primarily useful for testing and for single-process deployments
 // that do not require durable persistence across restarts.
 type MemoryLedger struct {
-	mu      sync.RWMutex
-	entries []*Entry
+	mu         sync.RWMutex
+	entries    []*Entry
+	checkpoint *Checkpoint // latest VerifyIncremental result
 }
 
 // New creates a MemoryLedger initialised with the canonical genesis entry.
@@ -54,6 +55,7 @@ func (l *MemoryLedger) Append(_ context.Context, agentURI, action, actor string,
 		Actor:     actor,
 		DataHash:  dataHash,
 		PrevHash:  prev.Hash,
+		Payload:   payloadJSON,
 	}
 	entry.Hash = hashEntry(entry)
 	l.entries = append(l.entries, entry)
@@ -83,24 +85,34 @@ func (l *MemoryLedger) Verify(_ context.Context) error {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	for i, curr := range l.entries {
-		if i == 0 {
-			// Genesis: must equal the well-known constant.
-			if curr.Hash != GenesisHash {
-				return fmt.Errorf("genesis entry has wrong hash: got %q", curr.Hash)
-			}
-			continue
+	v := NewChainVerifier()
+	for _, curr := range l.entries {
+		if err := v.Add(curr); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		prev := l.entries[i-1]
-		if curr.PrevHash != prev.Hash {
-			return fmt.Errorf("hash chain broken at index %d", curr.Index)
+// Query implements Ledger.
+func (l *MemoryLedger) Query(_ context.Context, f Filter) (*Page, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	f = f.normalize()
+	page := &Page{Entries: []*Entry{}}
+	for i := f.FromIndex; i < len(l.entries); i++ {
+		e := l.entries[i]
+		if !f.matches(e) {
+			continue
 		}
-		if curr.Hash != hashEntry(curr) {
-			return fmt.Errorf("entry %d has invalid hash", curr.Index)
+		if len(page.Entries) == f.Limit {
+			page.NextIndex = e.Index
+			break
 		}
+		page.Entries = append(page.Entries, e)
 	}
-	return nil
+	return page, nil
 }
 
 // Root implements Ledger.
@@ -112,3 +124,49 @@ func (l *MemoryLedger) Root(_ context.Context) (string, error) {
 	}
 	return l.entries[len(l.entries)-1].Hash, nil
 }
+
+// entryHashes returns the hashes of the first treeSize entries.
+// The caller must hold l.mu.
+func (l *MemoryLedger) entryHashes(treeSize int) ([]string, error) {
+	if treeSize < 1 || treeSize > len(l.entries) {
+		return nil, fmt.Errorf("%w: tree size %d not in 1..%d", ErrInvalidProofRange, treeSize, len(l.entries))
+	}
+	hashes := make([]string, treeSize)
+	for i := 0; i < treeSize; i++ {
+		hashes[i] = l.entries[i].Hash
+	}
+	return hashes, nil
+}
+
+// MerkleRoot implements Ledger.
+func (l *MemoryLedger) MerkleRoot(_ context.Context, treeSize int) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	hashes, err := l.entryHashes(treeSize)
+	if err != nil {
+		return "", err
+	}
+	return merkleRootHex(hashes)
+}
+
+// InclusionProof implements Ledger.
+func (l *MemoryLedger) InclusionProof(_ context.Context, index, treeSize int) (*InclusionProof, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	hashes, err := l.entryHashes(treeSize)
+	if err != nil {
+		return nil, err
+	}
+	return buildInclusionProof(hashes, index)
+}
+
+// ConsistencyProof implements Ledger.
+func (l *MemoryLedger) ConsistencyProof(_ context.Context, oldSize, newSize int) (*ConsistencyProof, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	hashes, err := l.entryHashes(newSize)
+	if err != nil {
+		return nil, err
+	}
+	return buildConsistencyProof(hashes, oldSize)
+}
@@ -0,0 +1,314 @@
+package trustledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// ErrInvalidProofRange is returned when a proof is requested for an index or
+// tree size that does not exist in the ledger.
+var ErrInvalidProofRange = errors.New("invalid proof range")
+
+// RFC 6962 §2.1 domain-separation prefixes. Leaves and interior nodes are
+// hashed with different prefixes so a leaf can never be passed off as a node.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// InclusionProof proves that the entry at LeafIndex is part of the Merkle
+// tree built over the first TreeSize ledger entries.
+type InclusionProof struct {
+	LeafIndex int      `json:"leaf_index"`
+	TreeSize  int      `json:"tree_size"`
+	EntryHash string   `json:"entry_hash"` // Entry.Hash of the proven entry
+	LeafHash  string   `json:"leaf_hash"`  // SHA-256(0x00 || EntryHash)
+	RootHash  string   `json:"root_hash"`  // Merkle tree head for TreeSize
+	AuditPath []string `json:"audit_path"`
+}
+
+// ConsistencyProof proves that the Merkle tree over the first FirstSize
+// entries is a prefix of the tree over the first SecondSize entries, i.e.
+// the ledger was only appended to between the two tree heads.
+type ConsistencyProof struct {
+	FirstSize  int      `json:"first_size"`
+	SecondSize int      `json:"second_size"`
+	FirstRoot  string   `json:"first_root"`
+	SecondRoot string   `json:"second_root"`
+	Path       []string `json:"path"`
+}
+
+// merkleLeafHash returns SHA-256(0x00 || leaf).
+func merkleLeafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+// merkleNodeHash returns SHA-256(0x01 || left || right).
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// leafHashes converts entry hashes (hex) into Merkle leaf hashes.
+func leafHashes(entryHashes []string) ([][]byte, error) {
+	leaves := make([][]byte, len(entryHashes))
+	for i, eh := range entryHashes {
+		raw, err := hex.DecodeString(eh)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d has non-hex hash: %w", i, err)
+		}
+		leaves[i] = merkleLeafHash(raw)
+	}
+	return leaves, nil
+}
+
+// splitPoint returns the largest power of two strictly less than n (n > 1).
+func splitPoint(n int) int {
+	return 1 << (bits.Len(uint(n-1)) - 1)
+}
+
+// merkleTreeHash computes MTH(D[n]) over already leaf-hashed inputs.
+func merkleTreeHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return merkleNodeHash(merkleTreeHash(leaves[:k]), merkleTreeHash(leaves[k:]))
+}
+
+// merklePath computes PATH(m, D[n]) as defined in RFC 6962 §2.1.1.
+func merklePath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if m < k {
+		return append(merklePath(m, leaves[:k]), merkleTreeHash(leaves[k:]))
+	}
+	return append(merklePath(m-k, leaves[k:]), merkleTreeHash(leaves[:k]))
+}
+
+// merkleSubproof computes SUBPROOF(m, D[n], b) as defined in RFC 6962 §2.1.2.
+func merkleSubproof(m int, leaves [][]byte, complete bool) [][]byte {
+	n := len(leaves)
+	if m == n {
+		if complete {
+			return nil
+		}
+		return [][]byte{merkleTreeHash(leaves)}
+	}
+	k := splitPoint(n)
+	if m <= k {
+		return append(merkleSubproof(m, leaves[:k], complete), merkleTreeHash(leaves[k:]))
+	}
+	return append(merkleSubproof(m-k, leaves[k:], false), merkleTreeHash(leaves[:k]))
+}
+
+// buildInclusionProof builds an InclusionProof from the entry hashes of the
+// first treeSize ledger entries.
+func buildInclusionProof(entryHashes []string, index int) (*InclusionProof, error) {
+	treeSize := len(entryHashes)
+	if index < 0 || index >= treeSize {
+		return nil, fmt.Errorf("%w: index %d not in tree of size %d", ErrInvalidProofRange, index, treeSize)
+	}
+	leaves, err := leafHashes(entryHashes)
+	if err != nil {
+		return nil, err
+	}
+	return &InclusionProof{
+		LeafIndex: index,
+		TreeSize:  treeSize,
+		EntryHash: entryHashes[index],
+		LeafHash:  hex.EncodeToString(leaves[index]),
+		RootHash:  hex.EncodeToString(merkleTreeHash(leaves)),
+		AuditPath: encodeHashes(merklePath(index, leaves)),
+	}, nil
+}
+
+// buildConsistencyProof builds a ConsistencyProof between the first oldSize
+// entries and all of entryHashes.
+func buildConsistencyProof(entryHashes []string, oldSize int) (*ConsistencyProof, error) {
+	newSize := len(entryHashes)
+	if oldSize < 1 || oldSize > newSize {
+		return nil, fmt.Errorf("%w: first size %d not in 1..%d", ErrInvalidProofRange, oldSize, newSize)
+	}
+	leaves, err := leafHashes(entryHashes)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsistencyProof{
+		FirstSize:  oldSize,
+		SecondSize: newSize,
+		FirstRoot:  hex.EncodeToString(merkleTreeHash(leaves[:oldSize])),
+		SecondRoot: hex.EncodeToString(merkleTreeHash(leaves)),
+		Path:       encodeHashes(merkleSubproof(oldSize, leaves, true)),
+	}, nil
+}
+
+// merkleRootHex returns the hex Merkle tree head over the given entry hashes.
+func merkleRootHex(entryHashes []string) (string, error) {
+	leaves, err := leafHashes(entryHashes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(merkleTreeHash(leaves)), nil
+}
+
+func encodeHashes(hs [][]byte) []string {
+	out := make([]string, len(hs))
+	for i, h := range hs {
+		out[i] = hex.EncodeToString(h)
+	}
+	return out
+}
+
+func decodeHashes(hs []string) ([][]byte, error) {
+	out := make([][]byte, len(hs))
+	for i, s := range hs {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("proof hash %d is not hex: %w", i, err)
+		}
+		out[i] = b
+	}
+	return out, nil
+}
+
+// VerifyInclusion checks an InclusionProof against its own RootHash using the
+// algorithm from RFC 9162 §2.1.3.2. Callers must compare RootHash with a tree
+// head they trust; this function only proves the path is self-consistent.
+func VerifyInclusion(p *InclusionProof) error {
+	if p == nil {
+		return errors.New("nil inclusion proof")
+	}
+	if p.LeafIndex < 0 || p.LeafIndex >= p.TreeSize {
+		return fmt.Errorf("leaf index %d not in tree of size %d", p.LeafIndex, p.TreeSize)
+	}
+	entry, err := hex.DecodeString(p.EntryHash)
+	if err != nil {
+		return fmt.Errorf("entry hash is not hex: %w", err)
+	}
+	root, err := hex.DecodeString(p.RootHash)
+	if err != nil {
+		return fmt.Errorf("root hash is not hex: %w", err)
+	}
+	path, err := decodeHashes(p.AuditPath)
+	if err != nil {
+		return err
+	}
+
+	fn, sn := p.LeafIndex, p.TreeSize-1
+	r := merkleLeafHash(entry)
+	for _, h := range path {
+		if sn == 0 {
+			return errors.New("audit path is too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNodeHash(h, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNodeHash(r, h)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("audit path is too short")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.New("computed root does not match root hash")
+	}
+	return nil
+}
+
+// VerifyConsistency checks a ConsistencyProof using the algorithm from
+// RFC 9162 §2.1.4.2.
+func VerifyConsistency(p *ConsistencyProof) error {
+	if p == nil {
+		return errors.New("nil consistency proof")
+	}
+	if p.FirstSize < 1 || p.FirstSize > p.SecondSize {
+		return fmt.Errorf("first size %d not in 1..%d", p.FirstSize, p.SecondSize)
+	}
+	first, err := hex.DecodeString(p.FirstRoot)
+	if err != nil {
+		return fmt.Errorf("first root is not hex: %w", err)
+	}
+	second, err := hex.DecodeString(p.SecondRoot)
+	if err != nil {
+		return fmt.Errorf("second root is not hex: %w", err)
+	}
+	path, err := decodeHashes(p.Path)
+	if err != nil {
+		return err
+	}
+
+	if p.FirstSize == p.SecondSize {
+		if len(path) != 0 {
+			return errors.New("proof for equal sizes must be empty")
+		}
+		if !bytes.Equal(first, second) {
+			return errors.New("roots differ for equal tree sizes")
+		}
+		return nil
+	}
+
+	// If the old tree is a complete subtree its root is the first node.
+	if p.FirstSize&(p.FirstSize-1) == 0 {
+		path = append([][]byte{first}, path...)
+	}
+	if len(path) == 0 {
+		return errors.New("empty consistency path")
+	}
+
+	fn, sn := p.FirstSize-1, p.SecondSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := path[0], path[0]
+	for _, c := range path[1:] {
+		if sn == 0 {
+			return errors.New("consistency path is too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = merkleNodeHash(c, fr)
+			sr = merkleNodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = merkleNodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("consistency path is too short")
+	}
+	if !bytes.Equal(fr, first) {
+		return errors.New("computed first root does not match")
+	}
+	if !bytes.Equal(sr, second) {
+		return errors.New("computed second root does not match")
+	}
+	return nil
+}
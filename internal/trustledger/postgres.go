@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -15,6 +16,26 @@ import (
 // across all registry instances.
 const advisoryLockKey = int64(1_159_876_543)
 
+// entryColumns is the column list scanned by scanEntry.
+const entryColumns = "idx, timestamp, agent_uri, action, actor, data_hash, prev_hash, hash, payload"
+
+// scanEntry scans a row selected with entryColumns.
+func scanEntry(row pgx.Row) (*Entry, error) {
+	e := &Entry{}
+	var payload []byte
+	if err := row.Scan(
+		&e.Index, &e.Timestamp, &e.AgentURI,
+		&e.Action, &e.Actor, &e.DataHash,
+		&e.PrevHash, &e.Hash, &payload,
+	); err != nil {
+		return nil, err
+	}
+	e.Payload = payload
+	// hashEntry formats the timestamp in UTC; pgx returns local time.
+	e.Timestamp = e.Timestamp.UTC()
+	return e, nil
+}
+
 // PostgresLedger persists the Merkle-chain audit log to a PostgreSQL database.
 // It implements the Ledger interface.
 type PostgresLedger struct {
@@ -58,7 +79,9 @@ func (l *PostgresLedger) Append(ctx context.Context, agentURI, action, actor str
 		return nil, fmt.Errorf("read ledger tail: %w", err)
 	}
 
-	now := time.Now().UTC()
+	// PostgreSQL stores microseconds; truncate before hashing so the hash can
+	// be recomputed from the stored row.
+	now := time.Now().UTC().Truncate(time.Microsecond)
 	entry := &Entry{
 		Index:     prevIdx + 1,
 		Timestamp: now,
@@ -67,15 +90,16 @@ func (l *PostgresLedger) Append(ctx context.Context, agentURI, action, actor str
 		Actor:     actor,
 		DataHash:  dataHash,
 		PrevHash:  prevHash,
+		Payload:   payloadJSON,
 	}
 	entry.Hash = hashEntry(entry)
 
 	if _, err := tx.Exec(ctx,
-		`INSERT INTO trust_ledger (idx, timestamp, agent_uri, action, actor, data_hash, prev_hash, hash)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		`INSERT INTO trust_ledger (idx, timestamp, agent_uri, action, actor, data_hash, prev_hash, hash, payload)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
 		entry.Index, entry.Timestamp, entry.AgentURI,
 		entry.Action, entry.Actor, entry.DataHash,
-		entry.PrevHash, entry.Hash,
+		entry.PrevHash, entry.Hash, []byte(payloadJSON),
 	); err != nil {
 		return nil, fmt.Errorf("insert ledger entry: %w", err)
 	}
@@ -94,15 +118,10 @@ func (l *PostgresLedger) Append(ctx context.Context, agentURI, action, actor str
 
 // Get implements Ledger.
 func (l *PostgresLedger) Get(ctx context.Context, index int) (*Entry, error) {
-	entry := &Entry{}
-	if err := l.pool.QueryRow(ctx,
-		`SELECT idx, timestamp, agent_uri, action, actor, data_hash, prev_hash, hash
-		 FROM trust_ledger WHERE idx = $1`, index,
-	).Scan(
-		&entry.Index, &entry.Timestamp, &entry.AgentURI,
-		&entry.Action, &entry.Actor, &entry.DataHash,
-		&entry.PrevHash, &entry.Hash,
-	); err != nil {
+	entry, err := scanEntry(l.pool.QueryRow(ctx,
+		"SELECT "+entryColumns+" FROM trust_ledger WHERE idx = $1", index,
+	))
+	if err != nil {
 		return nil, fmt.Errorf("get ledger entry %d: %w", index, err)
 	}
 	return entry, nil
@@ -121,43 +140,68 @@ func (l *PostgresLedger) Len(ctx context.Context) (int, error) {
 // the hash chain. O(n) in ledger length; may be slow for very large ledgers.
 func (l *PostgresLedger) Verify(ctx context.Context) error {
 	rows, err := l.pool.Query(ctx,
-		`SELECT idx, timestamp, agent_uri, action, actor, data_hash, prev_hash, hash
-		 FROM trust_ledger ORDER BY idx ASC`,
+		"SELECT "+entryColumns+" FROM trust_ledger ORDER BY idx ASC",
 	)
 	if err != nil {
 		return fmt.Errorf("query ledger: %w", err)
 	}
 	defer rows.Close()
 
-	var prev *Entry
+	v := NewChainVerifier()
 	for rows.Next() {
-		curr := &Entry{}
-		if err := rows.Scan(
-			&curr.Index, &curr.Timestamp, &curr.AgentURI,
-			&curr.Action, &curr.Actor, &curr.DataHash,
-			&curr.PrevHash, &curr.Hash,
-		); err != nil {
+		curr, err := scanEntry(rows)
+		if err != nil {
 			return fmt.Errorf("scan ledger row: %w", err)
 		}
-
-		if prev == nil {
-			// Validate genesis: hash must be the well-known constant.
-			if curr.Hash != GenesisHash {
-				return fmt.Errorf("genesis entry has wrong hash: got %q", curr.Hash)
-			}
-			prev = curr
-			continue
+		if err := v.Add(curr); err != nil {
+			return err
 		}
+	}
+	return rows.Err()
+}
+
+// Query implements Ledger. One extra row is fetched to determine NextIndex.
+func (l *PostgresLedger) Query(ctx context.Context, f Filter) (*Page, error) {
+	f = f.normalize()
 
-		if curr.PrevHash != prev.Hash {
-			return fmt.Errorf("hash chain broken at index %d", curr.Index)
+	var since, until *time.Time
+	if !f.Since.IsZero() {
+		since = &f.Since
+	}
+	if !f.Until.IsZero() {
+		until = &f.Until
+	}
+
+	rows, err := l.pool.Query(ctx,
+		"SELECT "+entryColumns+` FROM trust_ledger
+		 WHERE idx >= $1
+		   AND ($2 = '' OR agent_uri = $2)
+		   AND ($3 = '' OR action = $3)
+		   AND ($4 = '' OR actor = $4)
+		   AND ($5::timestamptz IS NULL OR timestamp >= $5)
+		   AND ($6::timestamptz IS NULL OR timestamp < $6)
+		 ORDER BY idx ASC
+		 LIMIT $7`,
+		f.FromIndex, f.AgentURI, f.Action, f.Actor, since, until, f.Limit+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page{Entries: []*Entry{}}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan ledger row: %w", err)
 		}
-		if curr.Hash != hashEntry(curr) {
-			return fmt.Errorf("entry %d has invalid hash", curr.Index)
+		if len(page.Entries) == f.Limit {
+			page.NextIndex = e.Index
+			break
 		}
-		prev = curr
+		page.Entries = append(page.Entries, e)
 	}
-	return rows.Err()
+	return page, rows.Err()
 }
 
 // Root implements Ledger.
@@ -170,3 +214,62 @@ func (l *PostgresLedger) Root(ctx context.Context) (string, error) {
 	}
 	return hash, nil
 }
+
+// entryHashes loads the hashes of the first treeSize entries in index order.
+// Proof generation is O(n) in treeSize; the hashes are small enough that a
+// single sequential scan is cheaper than maintaining stored subtree hashes.
+func (l *PostgresLedger) entryHashes(ctx context.Context, treeSize int) ([]string, error) {
+	if treeSize < 1 {
+		return nil, fmt.Errorf("%w: tree size %d must be positive", ErrInvalidProofRange, treeSize)
+	}
+	rows, err := l.pool.Query(ctx,
+		"SELECT hash FROM trust_ledger WHERE idx < $1 ORDER BY idx ASC", treeSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query ledger hashes: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0, treeSize)
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, fmt.Errorf("scan ledger hash: %w", err)
+		}
+		hashes = append(hashes, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate ledger hashes: %w", err)
+	}
+	if len(hashes) != treeSize {
+		return nil, fmt.Errorf("%w: tree size %d exceeds ledger length %d", ErrInvalidProofRange, treeSize, len(hashes))
+	}
+	return hashes, nil
+}
+
+// MerkleRoot implements Ledger.
+func (l *PostgresLedger) MerkleRoot(ctx context.Context, treeSize int) (string, error) {
+	hashes, err := l.entryHashes(ctx, treeSize)
+	if err != nil {
+		return "", err
+	}
+	return merkleRootHex(hashes)
+}
+
+// InclusionProof implements Ledger.
+func (l *PostgresLedger) InclusionProof(ctx context.Context, index, treeSize int) (*InclusionProof, error) {
+	hashes, err := l.entryHashes(ctx, treeSize)
+	if err != nil {
+		return nil, err
+	}
+	return buildInclusionProof(hashes, index)
+}
+
+// ConsistencyProof implements Ledger.
+func (l *PostgresLedger) ConsistencyProof(ctx context.Context, oldSize, newSize int) (*ConsistencyProof, error) {
+	hashes, err := l.entryHashes(ctx, newSize)
+	if err != nil {
+		return nil, err
+	}
+	return buildConsistencyProof(hashes, oldSize)
+}
@@ -0,0 +1,297 @@
+package trustledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// ErrTreeHeadNotFound is returned when no tree head matches a lookup.
+var ErrTreeHeadNotFound = errors.New("tree head not found")
+
+// TreeHead is a signed commitment to the Merkle tree over the first TreeSize
+// ledger entries. Signature is a compact JWS over the same fields, signed with
+// the registry key published at /.well-known/jwks.json.
+type TreeHead struct {
+	TreeSize  int       `json:"tree_size"`
+	RootHash  string    `json:"root_hash"`
+	Timestamp time.Time `json:"timestamp"`
+	Signature string    `json:"signature"`
+}
+
+// TreeHeadSigner signs tree heads. identity.TokenIssuer implements it.
+type TreeHeadSigner interface {
+	SignTreeHead(treeSize int, rootHash string, timestamp time.Time) (string, error)
+}
+
+// TreeHeadStore persists signed tree heads. Heads are keyed by TreeSize, so
+// at most one head is kept per tree size.
+type TreeHeadStore interface {
+	// SaveTreeHead stores a signed head. Saving a head for a tree size that
+	// already has one is a no-op.
+	SaveTreeHead(ctx context.Context, head *TreeHead) error
+
+	// LatestTreeHead returns the head with the largest TreeSize.
+	LatestTreeHead(ctx context.Context) (*TreeHead, error)
+
+	// GetTreeHead returns the head for an exact tree size.
+	GetTreeHead(ctx context.Context, treeSize int) (*TreeHead, error)
+
+	// ListTreeHeads returns up to limit heads with TreeSize < before,
+	// newest first. before <= 0 means no upper bound.
+	ListTreeHeads(ctx context.Context, before, limit int) ([]*TreeHead, error)
+}
+
+// ── MemoryTreeHeadStore ─────────────────────────────────────────────────────
+
+// MemoryTreeHeadStore is an in-memory TreeHeadStore for tests and
+// single-process deployments.
+type MemoryTreeHeadStore struct {
+	mu    sync.RWMutex
+	heads []*TreeHead // sorted by TreeSize ascending
+}
+
+// NewMemoryTreeHeadStore creates an empty MemoryTreeHeadStore.
+func NewMemoryTreeHeadStore() *MemoryTreeHeadStore {
+	return &MemoryTreeHeadStore{}
+}
+
+// SaveTreeHead implements TreeHeadStore.
+func (s *MemoryTreeHeadStore) SaveTreeHead(_ context.Context, head *TreeHead) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.heads), func(i int) bool { return s.heads[i].TreeSize >= head.TreeSize })
+	if i < len(s.heads) && s.heads[i].TreeSize == head.TreeSize {
+		return nil
+	}
+	s.heads = append(s.heads, nil)
+	copy(s.heads[i+1:], s.heads[i:])
+	s.heads[i] = head
+	return nil
+}
+
+// LatestTreeHead implements TreeHeadStore.
+func (s *MemoryTreeHeadStore) LatestTreeHead(_ context.Context) (*TreeHead, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.heads) == 0 {
+		return nil, ErrTreeHeadNotFound
+	}
+	return s.heads[len(s.heads)-1], nil
+}
+
+// GetTreeHead implements TreeHeadStore.
+func (s *MemoryTreeHeadStore) GetTreeHead(_ context.Context, treeSize int) (*TreeHead, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.heads), func(i int) bool { return s.heads[i].TreeSize >= treeSize })
+	if i < len(s.heads) && s.heads[i].TreeSize == treeSize {
+		return s.heads[i], nil
+	}
+	return nil, ErrTreeHeadNotFound
+}
+
+// ListTreeHeads implements TreeHeadStore.
+func (s *MemoryTreeHeadStore) ListTreeHeads(_ context.Context, before, limit int) ([]*TreeHead, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*TreeHead, 0, limit)
+	for i := len(s.heads) - 1; i >= 0 && len(out) < limit; i-- {
+		if before > 0 && s.heads[i].TreeSize >= before {
+			continue
+		}
+		out = append(out, s.heads[i])
+	}
+	return out, nil
+}
+
+// ── PostgresTreeHeadStore ───────────────────────────────────────────────────
+
+// PostgresTreeHeadStore persists tree heads in the trust_ledger_tree_heads table.
+type PostgresTreeHeadStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTreeHeadStore creates a PostgresTreeHeadStore.
+func NewPostgresTreeHeadStore(pool *pgxpool.Pool) *PostgresTreeHeadStore {
+	return &PostgresTreeHeadStore{pool: pool}
+}
+
+// SaveTreeHead implements TreeHeadStore.
+func (s *PostgresTreeHeadStore) SaveTreeHead(ctx context.Context, head *TreeHead) error {
+	if _, err := s.pool.Exec(ctx,
+		`INSERT INTO trust_ledger_tree_heads (tree_size, root_hash, timestamp, signature)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (tree_size) DO NOTHING`,
+		head.TreeSize, head.RootHash, head.Timestamp, head.Signature,
+	); err != nil {
+		return fmt.Errorf("insert tree head: %w", err)
+	}
+	return nil
+}
+
+// LatestTreeHead implements TreeHeadStore.
+func (s *PostgresTreeHeadStore) LatestTreeHead(ctx context.Context) (*TreeHead, error) {
+	return s.queryOne(ctx,
+		`SELECT tree_size, root_hash, timestamp, signature
+		 FROM trust_ledger_tree_heads ORDER BY tree_size DESC LIMIT 1`)
+}
+
+// GetTreeHead implements TreeHeadStore.
+func (s *PostgresTreeHeadStore) GetTreeHead(ctx context.Context, treeSize int) (*TreeHead, error) {
+	return s.queryOne(ctx,
+		`SELECT tree_size, root_hash, timestamp, signature
+		 FROM trust_ledger_tree_heads WHERE tree_size = $1`, treeSize)
+}
+
+// ListTreeHeads implements TreeHeadStore.
+func (s *PostgresTreeHeadStore) ListTreeHeads(ctx context.Context, before, limit int) ([]*TreeHead, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT tree_size, root_hash, timestamp, signature
+		 FROM trust_ledger_tree_heads
+		 WHERE ($1 <= 0 OR tree_size < $1)
+		 ORDER BY tree_size DESC LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list tree heads: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*TreeHead
+	for rows.Next() {
+		h := &TreeHead{}
+		if err := rows.Scan(&h.TreeSize, &h.RootHash, &h.Timestamp, &h.Signature); err != nil {
+			return nil, fmt.Errorf("scan tree head: %w", err)
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresTreeHeadStore) queryOne(ctx context.Context, sql string, args ...any) (*TreeHead, error) {
+	h := &TreeHead{}
+	if err := s.pool.QueryRow(ctx, sql, args...).Scan(&h.TreeSize, &h.RootHash, &h.Timestamp, &h.Signature); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTreeHeadNotFound
+		}
+		return nil, fmt.Errorf("get tree head: %w", err)
+	}
+	return h, nil
+}
+
+// ── TreeHeadPublisher ───────────────────────────────────────────────────────
+
+// TreeHeadPublisher periodically signs and stores the ledger's current tree
+// head. Before publishing a new head it checks that the previous head is
+// consistent with the current tree, so a rewritten history is refused rather
+// than signed.
+type TreeHeadPublisher struct {
+	ledger   Ledger
+	store    TreeHeadStore
+	signer   TreeHeadSigner
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+// NewTreeHeadPublisher creates a TreeHeadPublisher. interval defaults to
+// one minute when zero.
+func NewTreeHeadPublisher(ledger Ledger, store TreeHeadStore, signer TreeHeadSigner, interval time.Duration, logger *zap.Logger) *TreeHeadPublisher {
+	if interval == 0 {
+		interval = time.Minute
+	}
+	return &TreeHeadPublisher{
+		ledger:   ledger,
+		store:    store,
+		signer:   signer,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Publish signs and stores a head for the current ledger size. If the latest
+// stored head already covers the current size it is returned unchanged.
+func (p *TreeHeadPublisher) Publish(ctx context.Context) (*TreeHead, error) {
+	size, err := p.ledger.Len(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ledger length: %w", err)
+	}
+
+	prev, err := p.store.LatestTreeHead(ctx)
+	if err != nil && !errors.Is(err, ErrTreeHeadNotFound) {
+		return nil, fmt.Errorf("latest tree head: %w", err)
+	}
+	if prev != nil && prev.TreeSize == size {
+		return prev, nil
+	}
+
+	root, err := p.ledger.MerkleRoot(ctx, size)
+	if err != nil {
+		return nil, fmt.Errorf("merkle root: %w", err)
+	}
+
+	if prev != nil {
+		if prev.TreeSize > size {
+			return nil, fmt.Errorf("ledger shrank from %d to %d entries", prev.TreeSize, size)
+		}
+		proof, err := p.ledger.ConsistencyProof(ctx, prev.TreeSize, size)
+		if err != nil {
+			return nil, fmt.Errorf("consistency proof: %w", err)
+		}
+		if proof.FirstRoot != prev.RootHash {
+			return nil, fmt.Errorf("ledger history rewritten: root for size %d is %s, signed head has %s",
+				prev.TreeSize, proof.FirstRoot, prev.RootHash)
+		}
+		if err := VerifyConsistency(proof); err != nil {
+			return nil, fmt.Errorf("consistency check: %w", err)
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	sig, err := p.signer.SignTreeHead(size, root, now)
+	if err != nil {
+		return nil, fmt.Errorf("sign tree head: %w", err)
+	}
+
+	head := &TreeHead{TreeSize: size, RootHash: root, Timestamp: now, Signature: sig}
+	if err := p.store.SaveTreeHead(ctx, head); err != nil {
+		return nil, err
+	}
+
+	p.logger.Info("published signed tree head",
+		zap.Int("tree_size", size),
+		zap.String("root_hash", root),
+	)
+	return head, nil
+}
+
+// Start publishes a head immediately and then on every interval until ctx
+// is done.
+func (p *TreeHeadPublisher) Start(ctx context.Context) {
+	p.publishOnce(ctx)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.publishOnce(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *TreeHeadPublisher) publishOnce(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := p.Publish(ctx); err != nil {
+		p.logger.Error("publish tree head", zap.Error(err))
+	}
+}
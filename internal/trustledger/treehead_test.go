@@ -0,0 +1,90 @@
+package trustledger_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
+	"go.uber.org/zap"
+)
+
+type stubSigner struct{ calls int }
+
+func (s *stubSigner) SignTreeHead(treeSize int, rootHash string, _ time.Time) (string, error) {
+	s.calls++
+	return fmt.Sprintf("sig-%d-%s", treeSize, rootHash[:8]), nil
+}
+
+func TestTreeHeadPublisher_publishesAndSkipsUnchanged(t *testing.T) {
+	l := newLedgerWithEntries(t, 3)
+	store := trustledger.NewMemoryTreeHeadStore()
+	signer := &stubSigner{}
+	p := trustledger.NewTreeHeadPublisher(l, store, signer, time.Minute, zap.NewNop())
+
+	head, err := p.Publish(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.TreeSize != 4 {
+		t.Errorf("TreeSize: got %d, want 4", head.TreeSize)
+	}
+	root, _ := l.MerkleRoot(ctx, 4)
+	if head.RootHash != root {
+		t.Errorf("RootHash: got %q, want %q", head.RootHash, root)
+	}
+
+	if _, err := p.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if signer.calls != 1 {
+		t.Errorf("expected unchanged ledger not to be re-signed, got %d signatures", signer.calls)
+	}
+
+	_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_9", "activate", "nexus-system", nil)
+	if _, err := p.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	heads, err := store.ListTreeHeads(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heads) != 2 || heads[0].TreeSize != 5 || heads[1].TreeSize != 4 {
+		t.Errorf("expected heads [5 4], got %+v", heads)
+	}
+}
+
+func TestTreeHeadPublisher_refusesRewrittenHistory(t *testing.T) {
+	l := newLedgerWithEntries(t, 3)
+	store := trustledger.NewMemoryTreeHeadStore()
+	p := trustledger.NewTreeHeadPublisher(l, store, &stubSigner{}, time.Minute, zap.NewNop())
+
+	if _, err := p.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rewrite a historical entry, then append so a new head is due.
+	e, _ := l.Get(ctx, 2)
+	e.Hash = e.PrevHash
+	_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_9", "activate", "nexus-system", nil)
+
+	if _, err := p.Publish(ctx); err == nil {
+		t.Fatal("expected Publish to refuse a rewritten history")
+	}
+	latest, _ := store.LatestTreeHead(ctx)
+	if latest.TreeSize != 4 {
+		t.Errorf("latest head should still be size 4, got %d", latest.TreeSize)
+	}
+}
+
+func TestMemoryTreeHeadStore_notFound(t *testing.T) {
+	store := trustledger.NewMemoryTreeHeadStore()
+	if _, err := store.LatestTreeHead(ctx); !errors.Is(err, trustledger.ErrTreeHeadNotFound) {
+		t.Errorf("LatestTreeHead on empty store: got %v, want ErrTreeHeadNotFound", err)
+	}
+	if _, err := store.GetTreeHead(ctx, 7); !errors.Is(err, trustledger.ErrTreeHeadNotFound) {
+		t.Errorf("GetTreeHead(7): got %v, want ErrTreeHeadNotFound", err)
+	}
+}
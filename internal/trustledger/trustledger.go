@@ -4,7 +4,14 @@
 // (64 hex zeros). Every subsequent entry records the SHA-256 of its predecessor,
 // making any tampering detectable via Verify.
 //
-// Two implementations of the Ledger interface are provided:
+// In addition to the linear chain, the entry hashes form the leaves of an
+// RFC 6962 Merkle tree. InclusionProof and ConsistencyProof let an auditor
+// check that a single entry is in the log, or that the log has only been
+// appended to, without downloading every entry. VerifyInclusion and
+// VerifyConsistency check those proofs client-side.
+//
+// Three implementations of the Ledger interface are provided:
 //   - MemoryLedger: in-process, for testing and development.
 //   - PostgresLedger: durable, for production use.
+//   - FileLedger: durable fsync'd segment files, for single-node deployments.
 package trustledger
@@ -2,99 +2,399 @@ package trustledger_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
+	"go.uber.org/zap"
 )
 
 var ctx = context.Background()
 
 func TestNew_genesisEntry(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		n, err := l.Len(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 genesis entry, got %d", n)
+		}
+
+		entry, err := l.Get(ctx, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Action != "genesis" {
+			t.Errorf("expected action 'genesis', got %q", entry.Action)
+		}
+		if entry.Hash != trustledger.GenesisHash {
+			t.Errorf("genesis hash: got %q, want GenesisHash", entry.Hash)
+		}
+	})
+}
+
+func TestAppend_chainsCorrectly(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		e1, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "register", "example.com", map[string]string{"key": "val"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e2, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "activate", "nexus-system", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if e2.PrevHash != e1.Hash {
+			t.Errorf("chain broken: e2.PrevHash=%q, want e1.Hash=%q", e2.PrevHash, e1.Hash)
+		}
+
+		n, err := l.Len(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 { // genesis + 2
+			t.Errorf("expected 3 entries, got %d", n)
+		}
+	})
+}
+
+func TestVerify_valid(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "register", "example.com", nil)
+		_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "activate", "nexus-system", nil)
+
+		if err := l.Verify(ctx); err != nil {
+			t.Errorf("Verify() failed on valid chain: %v", err)
+		}
+	})
+}
+
+func TestRoot_returnsLastHash(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		e, _ := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "register", "example.com", nil)
+
+		root, err := l.Root(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != e.Hash {
+			t.Errorf("Root(): got %q, want %q", root, e.Hash)
+		}
+	})
+}
+
+func TestVerify_genesisOnlyChain(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		if err := l.Verify(ctx); err != nil {
+			t.Errorf("Verify() on genesis-only chain should pass: %v", err)
+		}
+	})
+}
+
+func TestRoot_genesisOnly(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		root, err := l.Root(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != trustledger.GenesisHash {
+			t.Errorf("Root() on genesis-only: got %q, want GenesisHash", root)
+		}
+	})
+}
+
+// forEachLedger runs fn against every Ledger implementation. Tests that only
+// use the Ledger interface should go through it so all backends stay in step.
+func forEachLedger(t *testing.T, fn func(t *testing.T, l trustledger.Ledger)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, trustledger.New())
+	})
+	t.Run("file", func(t *testing.T) {
+		l, err := trustledger.NewFileLedger(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { l.Close() })
+		l.SetMaxSegmentEntries(4) // exercise segment rollover
+		fn(t, l)
+	})
+}
+
+func appendEntries(t *testing.T, l trustledger.Ledger, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		uri := fmt.Sprintf("agent://nexusagentprotocol.com/a/agent_%d", i)
+		if _, err := l.Append(ctx, uri, "register", "example.com", map[string]int{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newLedgerWithEntries(t *testing.T, n int) *trustledger.MemoryLedger {
+	t.Helper()
 	l := trustledger.New()
+	appendEntries(t, l, n)
+	return l
+}
+
+func TestInclusionProof_verifiesForEveryLeaf(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		appendEntries(t, l, 16)
+		for size := 1; size <= 17; size++ {
+			root, err := l.MerkleRoot(ctx, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for idx := 0; idx < size; idx++ {
+				proof, err := l.InclusionProof(ctx, idx, size)
+				if err != nil {
+					t.Fatalf("InclusionProof(%d, %d): %v", idx, size, err)
+				}
+				if proof.RootHash != root {
+					t.Fatalf("InclusionProof(%d, %d): root %q, want %q", idx, size, proof.RootHash, root)
+				}
+				if err := trustledger.VerifyInclusion(proof); err != nil {
+					t.Errorf("VerifyInclusion(%d, %d): %v", idx, size, err)
+				}
+			}
+		}
+	})
+}
+
+func TestInclusionProof_tamperedPathFails(t *testing.T) {
+	l := newLedgerWithEntries(t, 6)
 
-	n, err := l.Len(ctx)
+	proof, err := l.InclusionProof(ctx, 3, 7)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if n != 1 {
-		t.Errorf("expected 1 genesis entry, got %d", n)
+	e, _ := l.Get(ctx, 4)
+	proof.EntryHash = e.Hash // claim a different entry sits at index 3
+	if err := trustledger.VerifyInclusion(proof); err == nil {
+		t.Error("expected VerifyInclusion to fail for a substituted entry")
 	}
+}
+
+func TestInclusionProof_outOfRange(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		appendEntries(t, l, 2)
+		if _, err := l.InclusionProof(ctx, 3, 3); !errors.Is(err, trustledger.ErrInvalidProofRange) {
+			t.Errorf("index beyond tree size: got %v, want ErrInvalidProofRange", err)
+		}
+		if _, err := l.InclusionProof(ctx, 0, 10); !errors.Is(err, trustledger.ErrInvalidProofRange) {
+			t.Errorf("tree size beyond ledger: got %v, want ErrInvalidProofRange", err)
+		}
+	})
+}
 
-	entry, err := l.Get(ctx, 0)
+func TestConsistencyProof_verifiesForEveryPair(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		appendEntries(t, l, 12)
+		for second := 1; second <= 13; second++ {
+			for first := 1; first <= second; first++ {
+				proof, err := l.ConsistencyProof(ctx, first, second)
+				if err != nil {
+					t.Fatalf("ConsistencyProof(%d, %d): %v", first, second, err)
+				}
+				if err := trustledger.VerifyConsistency(proof); err != nil {
+					t.Errorf("VerifyConsistency(%d, %d): %v", first, second, err)
+				}
+			}
+		}
+	})
+}
+
+func TestConsistencyProof_wrongOldRootFails(t *testing.T) {
+	l := newLedgerWithEntries(t, 8)
+
+	proof, err := l.ConsistencyProof(ctx, 5, 9)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if entry.Action != "genesis" {
-		t.Errorf("expected action 'genesis', got %q", entry.Action)
-	}
-	if entry.Hash != trustledger.GenesisHash {
-		t.Errorf("genesis hash: got %q, want GenesisHash", entry.Hash)
+	other, _ := l.MerkleRoot(ctx, 4)
+	proof.FirstRoot = other
+	if err := trustledger.VerifyConsistency(proof); err == nil {
+		t.Error("expected VerifyConsistency to fail for a forged first root")
 	}
 }
 
-func TestAppend_chainsCorrectly(t *testing.T) {
+func TestMerkleRoot_stableAcrossAppends(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		appendEntries(t, l, 3)
+		before, err := l.MerkleRoot(ctx, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_x", "revoke", "example.com", nil)
+
+		after, err := l.MerkleRoot(ctx, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if before != after {
+			t.Errorf("historical tree head changed after append: %q != %q", before, after)
+		}
+	})
+}
+
+func TestAppend_storesPayload(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		e, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "revoke", "nexus-system", map[string]string{"reason": "key compromise"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(e.Payload) != `{"reason":"key compromise"}` {
+			t.Errorf("Payload: got %s", e.Payload)
+		}
+		if err := e.VerifyPayload(); err != nil {
+			t.Errorf("VerifyPayload: %v", err)
+		}
+	})
+}
+
+func TestVerify_detectsTamperedPayload(t *testing.T) {
 	l := trustledger.New()
+	e, _ := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "revoke", "nexus-system", map[string]string{"reason": "key compromise"})
 
-	e1, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "register", "example.com", map[string]string{"key": "val"})
-	if err != nil {
-		t.Fatal(err)
+	e.Payload = []byte(`{"reason":"routine"}`)
+	if err := l.Verify(ctx); err == nil {
+		t.Error("expected Verify to detect a payload that no longer matches data_hash")
 	}
+}
+
+func TestQuery_filtersAndPaginates(t *testing.T) {
+	forEachLedger(t, func(t *testing.T, l trustledger.Ledger) {
+		a := "agent://nexusagentprotocol.com/a/agent_a"
+		b := "agent://nexusagentprotocol.com/a/agent_b"
+		for _, step := range []struct{ uri, action string }{
+			{a, "register"}, {b, "register"}, {a, "activate"}, {b, "activate"}, {a, "deprecate"}, {a, "revoke"},
+		} {
+			if _, err := l.Append(ctx, step.uri, step.action, "nexus-system", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
 
-	e2, err := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "activate", "nexus-system", nil)
+		page, err := l.Query(ctx, trustledger.Filter{AgentURI: a, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Entries) != 2 || page.Entries[0].Action != "register" || page.Entries[1].Action != "activate" {
+			t.Fatalf("first page: got %+v", page.Entries)
+		}
+		if page.NextIndex != 5 {
+			t.Errorf("NextIndex: got %d, want 5", page.NextIndex)
+		}
+
+		page, err = l.Query(ctx, trustledger.Filter{AgentURI: a, Limit: 2, FromIndex: page.NextIndex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Entries) != 2 || page.Entries[1].Action != "revoke" || page.NextIndex != 0 {
+			t.Errorf("second page: got %d entries, NextIndex=%d", len(page.Entries), page.NextIndex)
+		}
+
+		page, _ = l.Query(ctx, trustledger.Filter{Action: "activate"})
+		if len(page.Entries) != 2 {
+			t.Errorf("action filter: got %d entries, want 2", len(page.Entries))
+		}
+
+		page, _ = l.Query(ctx, trustledger.Filter{Until: time.Now().Add(-time.Hour)})
+		if len(page.Entries) != 0 {
+			t.Errorf("until filter: got %d entries, want 0", len(page.Entries))
+		}
+	})
+}
+
+func TestVerifyIncremental_resumesFromCheckpoint(t *testing.T) {
+	l := newLedgerWithEntries(t, 3)
+
+	cp, err := l.VerifyIncremental(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	if e2.PrevHash != e1.Hash {
-		t.Errorf("chain broken: e2.PrevHash=%q, want e1.Hash=%q", e2.PrevHash, e1.Hash)
+	if cp.Index != 3 {
+		t.Errorf("checkpoint index: got %d, want 3", cp.Index)
 	}
 
-	n, err := l.Len(ctx)
+	// Corrupt an entry *before* the checkpoint without touching the
+	// checkpointed entry; incremental verification skips it by design.
+	e1, _ := l.Get(ctx, 1)
+	e1.Actor = "attacker.example"
+	_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_x", "revoke", "nexus-system", nil)
+
+	cp, err = l.VerifyIncremental(ctx)
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("incremental verify should only walk new entries: %v", err)
+	}
+	if cp.Index != 4 {
+		t.Errorf("checkpoint index: got %d, want 4", cp.Index)
 	}
-	if n != 3 { // genesis + 2
-		t.Errorf("expected 3 entries, got %d", n)
+	if err := l.Verify(ctx); err == nil {
+		t.Error("full Verify should still detect the corrupted entry")
 	}
 }
 
-func TestVerify_valid(t *testing.T) {
-	l := trustledger.New()
-	_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "register", "example.com", nil)
-	_, _ = l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "activate", "nexus-system", nil)
+func TestVerifyIncremental_detectsRewrittenCheckpoint(t *testing.T) {
+	l := newLedgerWithEntries(t, 2)
+	if _, err := l.VerifyIncremental(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tip, _ := l.Get(ctx, 2)
+	tip.Hash = trustledger.GenesisHash
 
-	if err := l.Verify(ctx); err != nil {
-		t.Errorf("Verify() failed on valid chain: %v", err)
+	_, err := l.VerifyIncremental(ctx)
+	var broken *trustledger.BrokenChainError
+	if !errors.As(err, &broken) || broken.Index != 2 {
+		t.Fatalf("expected break at checkpoint index 2, got %v", err)
 	}
 }
 
-func TestRoot_returnsLastHash(t *testing.T) {
-	l := trustledger.New()
-	e, _ := l.Append(ctx, "agent://nexusagentprotocol.com/a/agent_1", "register", "example.com", nil)
+func TestBackgroundVerifier_reportsBrokenIndex(t *testing.T) {
+	l := newLedgerWithEntries(t, 4)
+	e, _ := l.Get(ctx, 3)
+	e.Action = "tampered"
 
-	root, err := l.Root(ctx)
-	if err != nil {
-		t.Fatal(err)
+	var gotOK bool
+	gotBroken := -2
+	bv := trustledger.NewBackgroundVerifier(l, time.Minute, zap.NewNop())
+	bv.SetMetricsRecord(func(ok bool, _, brokenIndex int) {
+		gotOK, gotBroken = ok, brokenIndex
+	})
+
+	if err := bv.RunOnce(ctx); err == nil {
+		t.Fatal("expected RunOnce to fail")
 	}
-	if root != e.Hash {
-		t.Errorf("Root(): got %q, want %q", root, e.Hash)
+	if gotOK || gotBroken != 3 {
+		t.Errorf("metrics callback: ok=%v broken=%d, want ok=false broken=3", gotOK, gotBroken)
 	}
 }
 
-func TestVerify_genesisOnlyChain(t *testing.T) {
-	l := trustledger.New()
-	if err := l.Verify(ctx); err != nil {
-		t.Errorf("Verify() on genesis-only chain should pass: %v", err)
+func TestBackgroundVerifier_fullVerifyCatchesOldTampering(t *testing.T) {
+	l := newLedgerWithEntries(t, 4)
+	bv := trustledger.NewBackgroundVerifier(l, time.Minute, zap.NewNop())
+	bv.SetFullVerifyInterval(time.Hour)
+	if err := bv.RunOnce(ctx); err != nil {
+		t.Fatal(err)
 	}
-}
 
-func TestRoot_genesisOnly(t *testing.T) {
-	l := trustledger.New()
-	root, err := l.Root(ctx)
-	if err != nil {
-		t.Fatal(err)
+	// Tamper behind the checkpoint: the incremental pass cannot see it.
+	e, _ := l.Get(ctx, 1)
+	e.Actor = "attacker.example"
+	if err := bv.RunOnce(ctx); err != nil {
+		t.Fatalf("incremental run between full walks: %v", err)
 	}
-	if root != trustledger.GenesisHash {
-		t.Errorf("Root() on genesis-only: got %q, want GenesisHash", root)
+
+	// Once the full-verify interval has passed, the walk from genesis does.
+	bv.SetFullVerifyInterval(time.Nanosecond)
+	err := bv.RunOnce(ctx)
+	var broken *trustledger.BrokenChainError
+	if !errors.As(err, &broken) || broken.Index != 1 {
+		t.Fatalf("expected break at index 1, got %v", err)
 	}
 }
@@ -0,0 +1,98 @@
+package trustledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// BrokenChainError reports the first entry at which a chain fails to verify.
+type BrokenChainError struct {
+	Index  int
+	Reason string
+}
+
+func (e *BrokenChainError) Error() string { return e.Reason }
+
+// ChainVerifier checks a stream of entries, in index order, against the hash
+// chain rules used by every Ledger implementation. It holds only the previous
+// entry, so arbitrarily large ledgers can be verified in constant memory.
+type ChainVerifier struct {
+	prev  *Entry
+	count int
+}
+
+// NewChainVerifier returns a ChainVerifier expecting the genesis entry first.
+func NewChainVerifier() *ChainVerifier {
+	return &ChainVerifier{}
+}
+
+// Add verifies the next entry in the chain. It returns a *BrokenChainError
+// identifying the entry if verification fails.
+func (v *ChainVerifier) Add(e *Entry) error {
+	if v.prev == nil {
+		// Genesis: must be index 0 with the well-known constant hash.
+		if e.Index != 0 {
+			return &BrokenChainError{Index: e.Index, Reason: fmt.Sprintf("chain does not start at genesis: first index is %d", e.Index)}
+		}
+		if e.Hash != GenesisHash {
+			return &BrokenChainError{Index: 0, Reason: fmt.Sprintf("genesis entry has wrong hash: got %q", e.Hash)}
+		}
+		v.prev = e
+		v.count++
+		return nil
+	}
+
+	if e.Index != v.prev.Index+1 {
+		return &BrokenChainError{Index: v.prev.Index + 1, Reason: fmt.Sprintf("entry %d missing: next entry has index %d", v.prev.Index+1, e.Index)}
+	}
+	if e.PrevHash != v.prev.Hash {
+		return &BrokenChainError{Index: e.Index, Reason: fmt.Sprintf("hash chain broken at index %d", e.Index)}
+	}
+	if e.Hash != hashEntry(e) {
+		return &BrokenChainError{Index: e.Index, Reason: fmt.Sprintf("entry %d has invalid hash", e.Index)}
+	}
+	if err := e.VerifyPayload(); err != nil {
+		return &BrokenChainError{Index: e.Index, Reason: err.Error()}
+	}
+	v.prev = e
+	v.count++
+	return nil
+}
+
+// Count returns the number of entries verified so far.
+func (v *ChainVerifier) Count() int { return v.count }
+
+// Last returns the most recently verified entry, or nil if none.
+func (v *ChainVerifier) Last() *Entry { return v.prev }
+
+// VerifyJSONL reads a JSONL export (one Entry per line, as produced by
+// GET /ledger/export) and verifies it from genesis. It returns the verifier
+// so callers can report the number of entries and the chain tip.
+func VerifyJSONL(r io.Reader) (*ChainVerifier, error) {
+	v := NewChainVerifier()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return v, fmt.Errorf("line %d: decode entry: %w", line, err)
+		}
+		if err := v.Add(&e); err != nil {
+			return v, err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return v, fmt.Errorf("read export: %w", err)
+	}
+	if v.Count() == 0 {
+		return v, fmt.Errorf("export contains no entries")
+	}
+	return v, nil
+}
@@ -0,0 +1,75 @@
+package trustledger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/internal/trustledger"
+)
+
+func exportJSONL(t *testing.T, l *trustledger.MemoryLedger) *bytes.Buffer {
+	t.Helper()
+	page, err := l.Query(ctx, trustledger.Filter{Limit: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range page.Entries {
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func TestVerifyJSONL_valid(t *testing.T) {
+	l := newLedgerWithEntries(t, 5)
+
+	v, err := trustledger.VerifyJSONL(exportJSONL(t, l))
+	if err != nil {
+		t.Fatalf("VerifyJSONL: %v", err)
+	}
+	if v.Count() != 6 {
+		t.Errorf("Count: got %d, want 6", v.Count())
+	}
+	root, _ := l.Root(ctx)
+	if v.Last().Hash != root {
+		t.Errorf("Last().Hash: got %q, want %q", v.Last().Hash, root)
+	}
+}
+
+func TestVerifyJSONL_reportsFirstBrokenIndex(t *testing.T) {
+	l := newLedgerWithEntries(t, 5)
+	lines := strings.Split(strings.TrimSpace(exportJSONL(t, l).String()), "\n")
+
+	var e trustledger.Entry
+	_ = json.Unmarshal([]byte(lines[3]), &e)
+	e.Actor = "attacker.example"
+	tampered, _ := json.Marshal(e)
+	lines[3] = string(tampered)
+
+	_, err := trustledger.VerifyJSONL(strings.NewReader(strings.Join(lines, "\n")))
+	var broken *trustledger.BrokenChainError
+	if !errors.As(err, &broken) {
+		t.Fatalf("expected BrokenChainError, got %v", err)
+	}
+	if broken.Index != 3 {
+		t.Errorf("broken index: got %d, want 3", broken.Index)
+	}
+}
+
+func TestVerifyJSONL_missingEntry(t *testing.T) {
+	l := newLedgerWithEntries(t, 4)
+	lines := strings.Split(strings.TrimSpace(exportJSONL(t, l).String()), "\n")
+	lines = append(lines[:2], lines[3:]...)
+
+	_, err := trustledger.VerifyJSONL(strings.NewReader(strings.Join(lines, "\n")))
+	var broken *trustledger.BrokenChainError
+	if !errors.As(err, &broken) || broken.Index != 2 {
+		t.Fatalf("expected break at index 2, got %v", err)
+	}
+}
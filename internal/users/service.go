@@ -393,31 +393,32 @@ func (s *UserService) ForgotPassword(ctx context.Context, emailAddr string) erro
 	return nil
 }
 
-// ResetPassword validates a password-reset token and sets the new password.
-func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+// ResetPassword validates a password-reset token and sets the new password,
+// returning the user whose password changed.
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) (*User, error) {
 	if len(newPassword) < 8 {
-		return fmt.Errorf("password must be at least 8 characters")
+		return nil, fmt.Errorf("password must be at least 8 characters")
 	}
 
 	u, err := s.repo.UsePasswordResetToken(ctx, token)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
-			return fmt.Errorf("reset token not found or expired")
+			return nil, fmt.Errorf("reset token not found or expired")
 		}
-		return fmt.Errorf("reset password: %w", err)
+		return nil, fmt.Errorf("reset password: %w", err)
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
-		return fmt.Errorf("hash password: %w", err)
+		return nil, fmt.Errorf("hash password: %w", err)
 	}
 
 	if err := s.repo.SetPasswordHash(ctx, u.ID, string(hash)); err != nil {
-		return fmt.Errorf("set password: %w", err)
+		return nil, fmt.Errorf("set password: %w", err)
 	}
 
 	s.logger.Info("password reset", zap.String("user_id", u.ID.String()))
-	return nil
+	return u, nil
 }
 
 // generateSecureToken returns a hex-encoded random token of the given byte length.
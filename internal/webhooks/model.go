@@ -14,6 +14,9 @@ const (
 	EventAgentSuspended     = "agent.suspended"
 	EventAgentDeprecated    = "agent.deprecated"
 	EventAgentHealthDegraded = "agent.health_degraded"
+	EventAgentExpiring       = "agent.expiring"
+	EventAgentExpired        = "agent.expired"
+	EventAgentRenewed        = "agent.renewed"
 )
 
 // WebhookSubscription represents a user's subscription to webhook events.
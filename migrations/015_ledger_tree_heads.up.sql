@@ -0,0 +1,10 @@
+-- 015: Signed tree heads for the trust ledger
+-- Each row is a registry-signed commitment to the RFC 6962 Merkle root over
+-- the first tree_size ledger entries. Heads are append-only and never updated.
+
+CREATE TABLE IF NOT EXISTS trust_ledger_tree_heads (
+    tree_size  BIGINT      PRIMARY KEY,
+    root_hash  TEXT        NOT NULL,
+    timestamp  TIMESTAMPTZ NOT NULL,
+    signature  TEXT        NOT NULL
+);
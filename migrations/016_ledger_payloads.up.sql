@@ -0,0 +1,9 @@
+-- 016: Store Trust Ledger payloads
+-- payload holds the exact JSON bytes that data_hash was computed over, so a
+-- lifecycle history can be rebuilt and checked entry by entry. BYTEA (not
+-- JSONB) keeps the bytes verbatim; JSONB would reorder keys and break the hash.
+
+ALTER TABLE trust_ledger ADD COLUMN IF NOT EXISTS payload BYTEA;
+
+CREATE INDEX IF NOT EXISTS trust_ledger_action_idx ON trust_ledger (action);
+CREATE INDEX IF NOT EXISTS trust_ledger_actor_idx  ON trust_ledger (actor);
@@ -0,0 +1,10 @@
+-- 017: Verified Trust Ledger checkpoints
+-- Each row records that the chain was verified from genesis through idx, whose
+-- hash was hash at verified_at. Incremental verification resumes from the
+-- latest row instead of re-walking the whole chain.
+
+CREATE TABLE IF NOT EXISTS trust_ledger_checkpoints (
+    idx          BIGINT      PRIMARY KEY,
+    hash         TEXT        NOT NULL,
+    verified_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,19 @@
+-- 018: Multiple transport endpoints per agent
+-- agents.endpoint stays the primary HTTPS endpoint; rows here describe every
+-- endpoint (HTTPS, gRPC, WebSocket) with its region and SRV-style priority
+-- (lower is preferred) and weight (load share among equal priorities).
+
+CREATE TABLE IF NOT EXISTS agent_endpoints (
+    id         UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id   UUID        NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    url        TEXT        NOT NULL,
+    protocol   TEXT        NOT NULL
+        CHECK (protocol IN ('https', 'http', 'grpc', 'wss')),
+    region     TEXT        NOT NULL DEFAULT '',
+    priority   INT         NOT NULL DEFAULT 0 CHECK (priority BETWEEN 0 AND 65535),
+    weight     INT         NOT NULL DEFAULT 0 CHECK (weight BETWEEN 0 AND 65535),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (agent_id, url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_endpoints_agent_id ON agent_endpoints(agent_id);
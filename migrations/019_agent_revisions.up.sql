@@ -0,0 +1,43 @@
+-- 019: Agent revision history
+-- One row per registration, update and rollback, holding the full snapshot of
+-- the agent's user-editable fields (skills and MCP tools decoded out of
+-- metadata). ledger_index cross-links the trust ledger entry for the change.
+
+CREATE TABLE IF NOT EXISTS agent_revisions (
+    id           UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    agent_id     UUID        NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    revision     INT         NOT NULL CHECK (revision > 0),
+    action       TEXT        NOT NULL
+        CHECK (action IN ('baseline', 'register', 'update', 'rollback')),
+    actor        TEXT        NOT NULL DEFAULT '',
+    snapshot     JSONB       NOT NULL,
+    rollback_of  INT,
+    ledger_index BIGINT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (agent_id, revision)
+);
+
+-- Existing agents start their history with a baseline of their current state.
+INSERT INTO agent_revisions (agent_id, revision, action, actor, snapshot, created_at)
+SELECT a.id, 1, 'baseline', 'nexus-system',
+       jsonb_build_object(
+           'display_name',   a.display_name,
+           'description',    a.description,
+           'endpoint',       a.endpoint,
+           'endpoints',      COALESCE((
+               SELECT jsonb_agg(jsonb_build_object(
+                          'url', e.url, 'protocol', e.protocol, 'region', e.region,
+                          'priority', e.priority, 'weight', e.weight)
+                      ORDER BY e.priority, e.weight DESC)
+               FROM agent_endpoints e WHERE e.agent_id = a.id), '[]'::jsonb),
+           'public_key_pem', a.public_key_pem,
+           'version',        a.version,
+           'tags',           to_jsonb(a.tags),
+           'support_url',    a.support_url,
+           'metadata',       a.metadata - '_skills' - '_mcp_tools',
+           'skills',         COALESCE(NULLIF(a.metadata->>'_skills', '')::jsonb, '[]'::jsonb),
+           'mcp_tools',      COALESCE(NULLIF(a.metadata->>'_mcp_tools', '')::jsonb, '[]'::jsonb)
+       ),
+       a.updated_at
+FROM agents a
+ON CONFLICT (agent_id, revision) DO NOTHING;
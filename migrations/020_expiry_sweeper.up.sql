@@ -0,0 +1,22 @@
+-- 020: Certificate and registration expiry
+-- Every issued agent certificate is recorded in certificates (created in 001
+-- but unused until now); agents.cert_serial points at the current one.
+-- Certificates issued before this migration are backfilled by the expiry
+-- sweeper from the PEM stored in agents.public_key_pem.
+--
+-- expiry_notices records which expiry warnings have been sent. A renewal
+-- changes expires_at, so the next expiry gets a fresh warning.
+
+CREATE INDEX IF NOT EXISTS certificates_expires_at_idx
+    ON certificates (expires_at) WHERE revoked_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS agents_expires_at_idx
+    ON agents (expires_at) WHERE expires_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS expiry_notices (
+    agent_id    UUID        NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    kind        TEXT        NOT NULL CHECK (kind IN ('certificate', 'registration')),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    notified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (agent_id, kind, expires_at)
+);
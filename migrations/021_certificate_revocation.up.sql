@@ -0,0 +1,19 @@
+-- 021: Standard certificate revocation (X.509 CRL and OCSP)
+-- Revocation is tracked per certificate: revoked_at (from 001) is the actual
+-- revocation time and revocation_reason the RFC 5280 reason code. Revoking an
+-- agent revokes every certificate it still holds.
+
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS revocation_reason SMALLINT NOT NULL DEFAULT 0
+    CHECK (revocation_reason BETWEEN 0 AND 10 AND revocation_reason <> 7);
+
+-- Agents revoked before this migration: their certificates were revoked when
+-- the agent was (the best record we have is the agent's updated_at).
+UPDATE certificates c
+SET revoked_at = a.updated_at
+FROM agents a
+WHERE c.agent_id = a.id
+  AND a.status = 'revoked'
+  AND c.revoked_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS certificates_revoked_at_idx
+    ON certificates (revoked_at) WHERE revoked_at IS NOT NULL;
@@ -0,0 +1,22 @@
+-- 022: Token revocation denylist
+-- Task and user tokens are stateless JWTs; these tables list the ones revoked
+-- before they expire. revoked_tokens holds single tokens by jti (logout,
+-- RFC 7009 revocation) until they would have expired. revoked_token_subjects
+-- revokes every token of an agent URI or user ID issued at or before
+-- revoked_before; a NULL revoked_before covers all of its tokens until the
+-- subject is restored, and a NULL expires_at keeps the entry forever.
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti         TEXT        PRIMARY KEY,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    revoked_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS revoked_token_subjects (
+    subject         TEXT        PRIMARY KEY,
+    revoked_before  TIMESTAMPTZ,
+    expires_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
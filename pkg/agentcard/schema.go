@@ -47,7 +47,8 @@ type AgentEntry struct {
 	DisplayName    string            `json:"display_name"`
 	Description    string            `json:"description,omitempty"`
 	Endpoint       string            `json:"endpoint"`
-	Protocols      []string          `json:"protocols,omitempty"` // e.g. ["https", "grpc", "ws"]
+	Protocols      []string          `json:"protocols,omitempty"` // e.g. ["https", "grpc", "wss"]
+	Endpoints      []Endpoint        `json:"endpoints,omitempty"`
 	CapabilityNode string            `json:"capability_node"`
 	Status         string            `json:"status"`
 	Metadata       map[string]string `json:"metadata,omitempty"`
@@ -55,6 +56,17 @@ type AgentEntry struct {
 	NAPTrustTier string `json:"nap:trust_tier,omitempty"`
 }
 
+// Endpoint is one transport endpoint of an agent. Clients try endpoints in
+// ascending Priority order and spread load across endpoints of equal priority
+// in proportion to Weight, as with DNS SRV records.
+type Endpoint struct {
+	URL      string `json:"url"`
+	Protocol string `json:"protocol"` // "https", "http", "grpc" or "wss"
+	Region   string `json:"region,omitempty"`
+	Priority int    `json:"priority"`
+	Weight   int    `json:"weight"`
+}
+
 // A2ACapabilities describes the A2A protocol streaming / notification capabilities
 // declared by an agent. All fields default to false.
 type A2ACapabilities struct {
@@ -93,6 +105,8 @@ type A2ACard struct {
 	NAPRegistry    string `json:"nap:registry"`
 	NAPCertSerial  string `json:"nap:cert_serial,omitempty"`
 	NAPEndorsement string `json:"nap:endorsement,omitempty"`
+	// NAPEndpoints lists every transport endpoint; URL is the primary one.
+	NAPEndpoints []Endpoint `json:"nap:endpoints,omitempty"`
 }
 
 // Parse decodes an AgentCard from JSON bytes.
@@ -5,6 +5,7 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -12,6 +13,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,11 +31,18 @@ var ErrVerificationPending = errors.New("DNS TXT record not yet published or pro
 
 // ResolveResult contains the endpoint information returned by a resolve call.
 type ResolveResult struct {
-	ID         string `json:"id,omitempty"`
-	URI        string `json:"uri"`
-	Endpoint   string `json:"endpoint"`
-	Status     string `json:"status"`
-	CertSerial string `json:"cert_serial,omitempty"`
+	ID             string     `json:"id,omitempty"`
+	URI            string     `json:"uri"`
+	Endpoint       string     `json:"endpoint"`
+	Status         string     `json:"status"`
+	CertSerial     string     `json:"cert_serial,omitempty"`
+	TrustTier      string     `json:"trust_tier,omitempty"`
+	HealthStatus   string     `json:"health_status,omitempty"`
+	SunsetDate     *time.Time `json:"sunset_date,omitempty"`
+	ReplacementURI string     `json:"replacement_uri,omitempty"`
+	// Endpoints lists every transport endpoint the agent declares. Endpoint
+	// is the one preferred under the client's WithEndpointPreference.
+	Endpoints []agentcard.Endpoint `json:"endpoints,omitempty"`
 }
 
 // DNSChallengeResult holds the TXT record details returned by StartDNSChallenge.
@@ -62,11 +73,13 @@ type AgentResult struct {
 
 // ActivateResult holds the cert bundle returned by ActivateAgent.
 type ActivateResult struct {
-	URI             string `json:"uri"`
-	CertPEM         string `json:"cert_pem"`
-	PrivateKeyPEM   string `json:"private_key_pem"`
-	CAPEM           string `json:"ca_pem"`
-	MCPManifestJSON string `json:"mcp_manifest_json,omitempty"`
+	URI             string    `json:"uri"`
+	CertPEM         string    `json:"cert_pem"`
+	CertSerial      string    `json:"cert_serial"`
+	CertExpiresAt   time.Time `json:"cert_expires_at"`
+	PrivateKeyPEM   string    `json:"private_key_pem"`
+	CAPEM           string    `json:"ca_pem"`
+	MCPManifestJSON string    `json:"mcp_manifest_json,omitempty"`
 }
 
 // AgentDetail holds the full agent record returned by GET /api/v1/agents/:id.
@@ -90,10 +103,27 @@ type Client struct {
 	httpClient   *http.Client
 	cache        *resolverCache
 
+	// endpoint preference — see WithEndpointPreference
+	protocol string
+	region   string
+
+	// keyAlg is the type of key ActivateAgent and RenewCert generate.
+	keyAlg KeyAlgorithm
+
 	// token state — guarded by mu
 	mu          sync.Mutex
 	bearerToken string
 	tokenExpiry time.Time // zero = token was set manually (no auto-refresh)
+	// agentTokens caches audience-restricted tokens for CallAgent, by agent URI.
+	agentTokens map[string]cachedToken
+	// dpopKey, when set, binds agent tokens to this key (see WithDPoPKey).
+	dpopKey crypto.Signer
+}
+
+// cachedToken is a fetched token and the time to refresh it.
+type cachedToken struct {
+	token  string
+	expiry time.Time
 }
 
 // Option is a functional option for configuring a Client.
@@ -125,11 +155,26 @@ func WithBearerToken(token string) Option {
 	}
 }
 
+// WithKeyAlgorithm sets the type of agent key ActivateAgent and RenewCert
+// generate locally. The default is RSA; ECDSA P-256 and Ed25519 keys are
+// smaller and faster to handshake with.
+func WithKeyAlgorithm(alg KeyAlgorithm) Option {
+	return func(c *Client) error {
+		switch alg {
+		case KeyAlgorithmRSA, KeyAlgorithmECDSAP256, KeyAlgorithmEd25519:
+			c.keyAlg = alg
+			return nil
+		default:
+			return fmt.Errorf("unknown key algorithm %q (want rsa, ecdsa-p256 or ed25519)", alg)
+		}
+	}
+}
+
 // WithMTLS configures the client for mutual TLS authentication using the
 // provided PEM-encoded client certificate, private key, and CA certificate.
 //
 //	certPEM — the agent's X.509 certificate (from the activate response)
-//	keyPEM  — the agent's RSA private key (from the activate response)
+//	keyPEM  — the agent's private key (generated by ActivateAgent)
 //	caPEM   — the Nexus CA certificate (download from GET /api/v1/ca.crt)
 func WithMTLS(certPEM, keyPEM, caPEM string) Option {
 	return func(c *Client) error {
@@ -183,6 +228,7 @@ func New(registryBase string, opts ...Option) (*Client, error) {
 	c := &Client{
 		registryBase: registryBase,
 		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		protocol:     "https",
 	}
 	for _, o := range opts {
 		if err := o(c); err != nil {
@@ -202,7 +248,20 @@ func MustNew(registryBase string, opts ...Option) *Client {
 }
 
 // Resolve translates an agent:// URI string into its transport endpoint.
+// When the agent declares several endpoints, ResolveResult.Endpoint is the
+// one preferred under WithEndpointPreference and ResolveResult.Endpoints
+// holds all of them.
 func (c *Client) Resolve(ctx context.Context, agentURI string) (*ResolveResult, error) {
+	result, err := c.resolveCached(ctx, agentURI)
+	if err != nil {
+		return nil, err
+	}
+	return c.withPreferredEndpoint(result), nil
+}
+
+// resolveCached resolves agentURI through the cache. The result is the
+// registry's answer as is, shared with the cache: callers must not modify it.
+func (c *Client) resolveCached(ctx context.Context, agentURI string) (*ResolveResult, error) {
 	parsed, err := uri.Parse(agentURI)
 	if err != nil {
 		return nil, fmt.Errorf("parse URI: %w", err)
@@ -229,7 +288,7 @@ func (c *Client) Resolve(ctx context.Context, agentURI string) (*ResolveResult,
 // caches it, and returns it. Requires WithMTLS or WithCertDir.
 // Subsequent calls reuse the cached token until it approaches expiry.
 func (c *Client) FetchToken(ctx context.Context) (string, error) {
-	token, expiry, err := c.fetchTokenRaw(ctx)
+	token, expiry, err := c.fetchTokenRaw(ctx, nil, false)
 	if err != nil {
 		return "", err
 	}
@@ -240,17 +299,64 @@ func (c *Client) FetchToken(ctx context.Context) (string, error) {
 	return token, nil
 }
 
+// FetchTokenForAudience exchanges the client's mTLS certificate for a Task
+// Token that only audience accepts: the agent:// URI of the agent it will be
+// sent to. Unlike a token from FetchToken, it cannot be replayed against
+// other agents. The token is not cached; CallAgent fetches its own.
+//
+// The token is bound to the client certificate, or with WithDPoPKey to the
+// DPoP key; present it with the "DPoP" scheme and a proof from DPoPProof.
+func (c *Client) FetchTokenForAudience(ctx context.Context, audience string) (string, error) {
+	token, _, err := c.fetchTokenRaw(ctx, url.Values{"audience": {audience}}, true)
+	return token, err
+}
+
+// ExchangeToken performs an RFC 8693 token exchange. An agent that received
+// subjectToken from a caller gets a delegated token to call audience on that
+// caller's behalf. The registry records this agent as the actor ("act"
+// claim) and only allows scopes the subject token already has; an empty
+// scope keeps them all. subjectToken must have been issued for this agent.
+//
+//	upstream := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+//	tok, err := c.ExchangeToken(ctx, upstream, "agent://acme.com/finance/agent_b", "agent:call")
+func (c *Client) ExchangeToken(ctx context.Context, subjectToken, audience, scope string) (string, error) {
+	form := url.Values{
+		"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {"urn:ietf:params:oauth:token-type:access_token"},
+		"audience":           {audience},
+	}
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+	token, _, err := c.fetchTokenRaw(ctx, form, true)
+	return token, err
+}
+
 // fetchTokenRaw fetches a fresh token from the registry without touching
 // cached state. It uses the raw httpClient (not c.do) so it does not
-// attach any existing bearer token to the token-exchange request.
-func (c *Client) fetchTokenRaw(ctx context.Context) (token string, expiry time.Time, err error) {
-	url := c.registryBase + "/api/v1/token"
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+// attach any existing bearer token to the token-exchange request. form, if
+// non-nil, is sent as the request body. With dpop and a DPoP key configured
+// the token is requested bound to that key instead of the certificate.
+func (c *Client) fetchTokenRaw(ctx context.Context, form url.Values, dpop bool) (token string, expiry time.Time, err error) {
+	tokenURL := c.registryBase + "/api/v1/token"
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, body)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("build token request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Accept", "application/json")
+	if dpop && c.dpopKey != nil {
+		proof, err := c.dpopProof(http.MethodPost, tokenURL, "")
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("DPoP", proof)
+	}
 
 	// Use httpClient directly — the token endpoint authenticates via mTLS,
 	// not via an existing Bearer token.
@@ -260,12 +366,12 @@ func (c *Client) fetchTokenRaw(ctx context.Context) (token string, expiry time.T
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("read token response: %w", err)
 	}
 	if resp.StatusCode >= 300 {
-		return "", time.Time{}, fmt.Errorf("token endpoint error %d: %s", resp.StatusCode, string(body))
+		return "", time.Time{}, fmt.Errorf("token endpoint error %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	var payload struct {
@@ -273,7 +379,7 @@ func (c *Client) fetchTokenRaw(ctx context.Context) (token string, expiry time.T
 		ExpiresIn   int    `json:"expires_in"`
 		Error       string `json:"error"`
 	}
-	if err := json.Unmarshal(body, &payload); err != nil {
+	if err := json.Unmarshal(respBody, &payload); err != nil {
 		return "", time.Time{}, fmt.Errorf("decode token response: %w", err)
 	}
 	if payload.Error != "" {
@@ -286,29 +392,52 @@ func (c *Client) fetchTokenRaw(ctx context.Context) (token string, expiry time.T
 	return payload.AccessToken, exp, nil
 }
 
-// ensureToken returns a valid bearer token, fetching a new one if the cached
-// token is absent or approaching expiry. Thread-safe.
-func (c *Client) ensureToken(ctx context.Context) (string, error) {
+// ensureAgentToken returns a token for calling agentURI, and whether it is
+// DPoP-bound. A token set with WithBearerToken is used as is; otherwise a
+// token restricted to agentURI is fetched and cached until it approaches
+// expiry. Thread-safe.
+func (c *Client) ensureAgentToken(ctx context.Context, agentURI string) (token string, dpop bool, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// tokenExpiry.IsZero() means the token was set manually via WithBearerToken
-	// and should never be auto-refreshed.
-	if c.bearerToken != "" && (c.tokenExpiry.IsZero() || time.Now().Before(c.tokenExpiry)) {
-		return c.bearerToken, nil
+	if c.bearerToken != "" && c.tokenExpiry.IsZero() {
+		return c.bearerToken, false, nil
+	}
+	dpop = c.dpopKey != nil
+	if t, ok := c.agentTokens[agentURI]; ok && time.Now().Before(t.expiry) {
+		return t.token, dpop, nil
 	}
 
-	token, expiry, err := c.fetchTokenRaw(ctx)
+	token, expiry, err := c.fetchTokenRaw(ctx, url.Values{"audience": {agentURI}}, true)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	c.bearerToken = token
-	c.tokenExpiry = expiry
-	return token, nil
+	if c.agentTokens == nil {
+		c.agentTokens = make(map[string]cachedToken)
+	}
+	c.agentTokens[agentURI] = cachedToken{token: token, expiry: expiry}
+	return token, dpop, nil
 }
 
-// CallAgent resolves an agent:// URI, obtains a Task Token (auto-refreshed),
-// and makes an authenticated HTTP call to the agent's endpoint.
+// DPoPProof returns a DPoP proof for a method request to target presenting
+// accessToken, for callers that send DPoP-bound tokens themselves rather than
+// through CallAgent. Requires WithDPoPKey.
+//
+//	req.Header.Set("Authorization", "DPoP "+token)
+//	req.Header.Set("DPoP", proof)
+func (c *Client) DPoPProof(method, target, accessToken string) (string, error) {
+	if c.dpopKey == nil {
+		return "", errors.New("no DPoP key configured (see WithDPoPKey)")
+	}
+	return c.dpopProof(method, target, accessToken)
+}
+
+// CallAgent resolves an agent:// URI, obtains a Task Token restricted to that
+// agent (auto-refreshed), and makes an authenticated HTTP call to the agent's
+// endpoint. When the agent
+// declares several HTTPS endpoints they are tried in priority order (see
+// SelectEndpoints and WithEndpointPreference), failing over to the next one
+// when an endpoint is unreachable or answers 502, 503 or 504.
 //
 //	var reply InvoiceResponse
 //	err := c.CallAgent(ctx,
@@ -321,64 +450,152 @@ func (c *Client) ensureToken(ctx context.Context) (string, error) {
 // reqBody and respBody are JSON-encoded/decoded automatically. Pass nil for
 // either when not needed (e.g. GET requests or when the response is ignored).
 func (c *Client) CallAgent(ctx context.Context, agentURI, method, path string, reqBody, respBody any) error {
-	// 1. Resolve the agent URI to its current transport endpoint.
-	resolved, err := c.Resolve(ctx, agentURI)
+	var body []byte
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return fmt.Errorf("marshal request body: %w", err)
+		}
+		body = b
+	}
+
+	respBytes, err := c.callAgent(ctx, agentURI, method, path, body)
 	if err != nil {
-		return fmt.Errorf("resolve %q: %w", agentURI, err)
+		return err
 	}
 
-	// 2. Obtain a valid Task Token (fetched/refreshed automatically).
-	token, err := c.ensureToken(ctx)
+	if respBody != nil && len(respBytes) > 0 {
+		if err := json.Unmarshal(respBytes, respBody); err != nil {
+			return fmt.Errorf("decode agent response: %w", err)
+		}
+	}
+	return nil
+}
+
+// callAgent resolves agentURI and sends the request to its HTTP(S) endpoints
+// in preference order (see callTargets). It moves on to the next endpoint
+// when one cannot be connected to. Idempotent requests also move on after any
+// transport error or a 502, 503 or 504, which may come after the agent acted
+// on the request; a POST or PATCH then fails instead of being sent twice.
+// Any other response is final. body may be nil.
+func (c *Client) callAgent(ctx context.Context, agentURI, method, path string, body []byte) ([]byte, error) {
+	// 1. Resolve the agent URI to its transport endpoints.
+	resolved, err := c.resolveCached(ctx, agentURI)
 	if err != nil {
-		return fmt.Errorf("obtain task token: %w", err)
+		return nil, fmt.Errorf("resolve %q: %w", agentURI, err)
+	}
+	targets := c.callTargets(resolved)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("agent %q has no HTTP endpoint", agentURI)
 	}
 
-	// 3. Build the HTTP request to the agent endpoint.
-	target := strings.TrimRight(resolved.Endpoint, "/")
+	// 2. Obtain a Task Token for this agent (fetched/refreshed automatically).
+	token, dpop, err := c.ensureAgentToken(ctx, agentURI)
+	if err != nil {
+		return nil, fmt.Errorf("obtain task token: %w", err)
+	}
+
+	// 3. Try each endpoint until one answers.
+	var lastErr error
+	for _, base := range targets {
+		respBytes, retry, err := c.callEndpoint(ctx, base, method, path, body, token, dpop)
+		if err == nil {
+			return respBytes, nil
+		}
+		if retry == retryNever || (retry == retryIdempotent && !idempotent(method)) || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	if len(targets) == 1 {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("all %d endpoints failed, last error: %w", len(targets), lastErr)
+}
+
+// retryPolicy says whether a failed call may be sent to another endpoint.
+type retryPolicy int
+
+const (
+	retryNever      retryPolicy = iota // the agent gave a final answer
+	retryIdempotent                    // the agent may have acted on the request
+	retryAlways                        // the request never reached the agent
+)
+
+// idempotent reports whether sending a request with method twice has the
+// same effect as sending it once (RFC 9110 §9.2.2).
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// callEndpoint makes one authenticated call to the agent endpoint base,
+// presenting token with a fresh DPoP proof when dpop is set.
+// retry reports whether the failure is worth trying on another endpoint.
+func (c *Client) callEndpoint(ctx context.Context, base, method, path string, body []byte, token string, dpop bool) (respBytes []byte, retry retryPolicy, err error) {
+	target := strings.TrimRight(base, "/")
 	if path != "" {
 		target += "/" + strings.TrimLeft(path, "/")
 	}
 
 	var bodyReader io.Reader
-	if reqBody != nil {
-		b, err := json.Marshal(reqBody)
-		if err != nil {
-			return fmt.Errorf("marshal request body: %w", err)
-		}
-		bodyReader = bytes.NewReader(b)
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, target, bodyReader)
 	if err != nil {
-		return fmt.Errorf("build agent request: %w", err)
+		return nil, retryNever, fmt.Errorf("build agent request: %w", err)
 	}
-	if reqBody != nil {
+	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
+	if dpop {
+		proof, err := c.dpopProof(method, target, token)
+		if err != nil {
+			return nil, retryNever, err
+		}
+		req.Header.Set("Authorization", "DPoP "+token)
+		req.Header.Set("DPoP", proof)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// Until a connection is made, nothing has been sent to the agent.
+	var connected bool
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { connected = true },
+	}))
 
-	// 4. Execute against the agent (not the registry — use httpClient directly).
+	// Execute against the agent (not the registry — use httpClient directly).
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("call agent: %w", err)
+		retry = retryIdempotent
+		if !connected {
+			retry = retryAlways
+		}
+		return nil, retry, fmt.Errorf("call agent: %w", err)
 	}
 	defer resp.Body.Close()
 
-	respBytes, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1 MB limit
+	respBytes, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1 MB limit
 	if err != nil {
-		return fmt.Errorf("read agent response: %w", err)
+		return nil, retryIdempotent, fmt.Errorf("read agent response: %w", err)
 	}
 	if resp.StatusCode >= 300 {
-		return fmt.Errorf("agent returned HTTP %d: %s", resp.StatusCode, string(respBytes))
-	}
-
-	if respBody != nil && len(respBytes) > 0 {
-		if err := json.Unmarshal(respBytes, respBody); err != nil {
-			return fmt.Errorf("decode agent response: %w", err)
+		retry = retryNever
+		if resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusServiceUnavailable ||
+			resp.StatusCode == http.StatusGatewayTimeout {
+			retry = retryIdempotent
 		}
+		return nil, retry, fmt.Errorf("agent returned HTTP %d: %s", resp.StatusCode, string(respBytes))
 	}
-	return nil
+	return respBytes, retryNever, nil
 }
 
 // ResolveViaService translates an agent:// URI using the dedicated resolver
@@ -395,7 +612,7 @@ func (c *Client) ResolveViaService(ctx context.Context, resolverBase, agentURI s
 
 	if c.cache != nil {
 		if result, ok := c.cache.get(agentURI); ok {
-			return result, nil
+			return c.withPreferredEndpoint(result), nil
 		}
 	}
 
@@ -421,7 +638,7 @@ func (c *Client) ResolveViaService(ctx context.Context, resolverBase, agentURI s
 	if c.cache != nil {
 		c.cache.set(agentURI, &result)
 	}
-	return &result, nil
+	return c.withPreferredEndpoint(&result), nil
 }
 
 // resolve performs the actual HTTP resolve call to the registry.
@@ -563,21 +780,77 @@ func (c *Client) RegisterAgent(ctx context.Context, reg RegisterAgentRequest) (*
 	return &AgentResult{ID: resp.Agent.ID, URI: agentURI}, nil
 }
 
-// ActivateAgent posts to /api/v1/agents/:id/activate and returns the cert bundle.
-// The returned PrivateKeyPEM is delivered once — it is not persisted by the registry.
+// ActivateAgent activates the agent with the given UUID. A new private key is
+// generated locally and only its CSR is sent, so the key in the returned
+// PrivateKeyPEM never leaves this machine.
 func (c *Client) ActivateAgent(ctx context.Context, agentID string) (*ActivateResult, error) {
-	url := c.registryBase + "/api/v1/agents/" + agentID + "/activate"
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	return c.withLocalKey(agentID, func(csrPEM string) (*ActivateResult, error) {
+		return c.ActivateAgentWithCSR(ctx, agentID, csrPEM)
+	})
+}
+
+// ActivateAgentWithCSR posts to /api/v1/agents/:id/activate with a PEM-encoded
+// PKCS#10 CSR and returns the certificate issued for the CSR's key.
+// PrivateKeyPEM is empty in the result; the caller holds the key.
+func (c *Client) ActivateAgentWithCSR(ctx context.Context, agentID, csrPEM string) (*ActivateResult, error) {
+	return c.postCertRequest(ctx, agentID, "activate", csrPEM)
+}
+
+// RenewCert asks the registry to issue a new certificate for the agent with
+// the given UUID, for a new key generated locally. The agent URI is
+// unchanged. The client must carry the agent's Task Token or the owner's user
+// token.
+func (c *Client) RenewCert(ctx context.Context, agentID string) (*ActivateResult, error) {
+	return c.withLocalKey(agentID, func(csrPEM string) (*ActivateResult, error) {
+		return c.RenewCertWithCSR(ctx, agentID, csrPEM)
+	})
+}
+
+// RenewCertWithCSR is RenewCert for a CSR the caller generated.
+func (c *Client) RenewCertWithCSR(ctx context.Context, agentID, csrPEM string) (*ActivateResult, error) {
+	return c.postCertRequest(ctx, agentID, "renew", csrPEM)
+}
+
+// withLocalKey generates a key and CSR, runs issue with the CSR, and puts the
+// key in the result.
+func (c *Client) withLocalKey(agentID string, issue func(csrPEM string) (*ActivateResult, error)) (*ActivateResult, error) {
+	keyPEM, csrPEM, err := GenerateCSR(c.keyAlg, agentID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := issue(csrPEM)
+	if err != nil {
+		return nil, err
+	}
+	if result.CertPEM != "" {
+		result.PrivateKeyPEM = keyPEM
+	}
+	return result, nil
+}
+
+// postCertRequest posts csrPEM to /api/v1/agents/:id/<op>.
+func (c *Client) postCertRequest(ctx context.Context, agentID, op, csrPEM string) (*ActivateResult, error) {
+	payload, err := json.Marshal(map[string]string{"csr_pem": csrPEM})
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s request: %w", op, err)
+	}
+	url := c.registryBase + "/api/v1/agents/" + agentID + "/" + op
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
 	if err != nil {
 		return nil, fmt.Errorf("build request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
 	body, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
+	return decodeCertResponse(body, op)
+}
 
+// decodeCertResponse decodes an activate or renew response.
+func decodeCertResponse(body []byte, op string) (*ActivateResult, error) {
 	var resp struct {
 		Agent struct {
 			TrustRoot      string `json:"trust_root"`
@@ -585,20 +858,24 @@ func (c *Client) ActivateAgent(ctx context.Context, agentID string) (*ActivateRe
 			AgentID        string `json:"agent_id"`
 		} `json:"agent"`
 		Certificate struct {
-			PEM string `json:"pem"`
+			PEM       string    `json:"pem"`
+			Serial    string    `json:"serial"`
+			ExpiresAt time.Time `json:"expires_at"`
 		} `json:"certificate"`
 		PrivateKeyPEM   string `json:"private_key_pem"`
 		CAPEM           string `json:"ca_pem"`
 		MCPManifestJSON string `json:"mcp_manifest_json"`
 	}
 	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("decode activate response: %w", err)
+		return nil, fmt.Errorf("decode %s response: %w", op, err)
 	}
 
 	agentURI := "agent://" + resp.Agent.TrustRoot + "/" + resp.Agent.CapabilityNode + "/" + resp.Agent.AgentID
 	return &ActivateResult{
 		URI:             agentURI,
 		CertPEM:         resp.Certificate.PEM,
+		CertSerial:      resp.Certificate.Serial,
+		CertExpiresAt:   resp.Certificate.ExpiresAt,
 		PrivateKeyPEM:   resp.PrivateKeyPEM,
 		CAPEM:           resp.CAPEM,
 		MCPManifestJSON: resp.MCPManifestJSON,
@@ -696,53 +973,13 @@ func (c *Client) ListAgents(ctx context.Context, trustRoot, capabilityNode strin
 	return wrapper.Agents, nil
 }
 
-// CallAgentRaw is like CallAgent but accepts and returns raw JSON bytes.
-// Pass nil body for requests with no body (e.g. GET).
+// CallAgentRaw is like CallAgent, including endpoint failover, but accepts
+// and returns raw JSON bytes. Pass nil body for requests with no body (e.g. GET).
 func (c *Client) CallAgentRaw(ctx context.Context, agentURI, method, path string, body json.RawMessage) (json.RawMessage, error) {
-	resolved, err := c.Resolve(ctx, agentURI)
-	if err != nil {
-		return nil, fmt.Errorf("resolve %q: %w", agentURI, err)
-	}
-
-	token, err := c.ensureToken(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("obtain task token: %w", err)
-	}
-
-	target := strings.TrimRight(resolved.Endpoint, "/")
-	if path != "" {
-		target += "/" + strings.TrimLeft(path, "/")
+	if len(body) == 0 {
+		body = nil
 	}
-
-	var bodyReader io.Reader
-	if len(body) > 0 {
-		bodyReader = bytes.NewReader(body)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, target, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("build agent request: %w", err)
-	}
-	if bodyReader != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("call agent: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBytes, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-	if err != nil {
-		return nil, fmt.Errorf("read agent response: %w", err)
-	}
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("agent returned HTTP %d: %s", resp.StatusCode, string(respBytes))
-	}
-	return respBytes, nil
+	return c.callAgent(ctx, agentURI, method, path, body)
 }
 
 // RevokeAgent posts to POST /api/v1/agents/:id/revoke using the client's Bearer token.
@@ -796,6 +1033,7 @@ func (c *Client) RestoreAgent(ctx context.Context, id string) error {
 type CRLEntry struct {
 	CertSerial string `json:"cert_serial"`
 	Reason     string `json:"reason"`
+	ReasonCode string `json:"reason_code"` // RFC 5280 name, e.g. "keyCompromise"
 	RevokedAt  string `json:"revoked_at"`
 }
 
@@ -864,6 +1102,45 @@ func (c *Client) ResolveBatch(ctx context.Context, uris []string) ([]BatchResolv
 	return resp.Results, nil
 }
 
+// LedgerExport is a streaming trust ledger export from GET /api/v1/ledger/export.
+// Body yields one JSON-encoded ledger entry per line and must be closed.
+type LedgerExport struct {
+	Body io.ReadCloser
+	// Entries is the ledger length the registry advertised when the export
+	// started, or -1 if the header was missing.
+	Entries int
+}
+
+// ExportLedger starts a streaming download of the full trust ledger. The
+// response is not size-limited, unlike other calls on Client.
+func (c *Client) ExportLedger(ctx context.Context) (*LedgerExport, error) {
+	url := c.registryBase + "/api/v1/ledger/export"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		return nil, fmt.Errorf("server error %d: %s", resp.StatusCode, string(body))
+	}
+
+	entries := -1
+	if n, err := strconv.Atoi(resp.Header.Get("X-NAP-Ledger-Entries")); err == nil {
+		entries = n
+	}
+	return &LedgerExport{Body: resp.Body, Entries: entries}, nil
+}
+
 // do executes an HTTP request, attaching the Bearer token if present.
 func (c *Client) do(req *http.Request) ([]byte, error) {
 	if c.bearerToken != "" {
@@ -2,7 +2,9 @@ package client_test
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -272,6 +274,35 @@ func TestActivateAgent_success(t *testing.T) {
 	}
 }
 
+func TestActivateAgent_sendsCSRAndKeepsKey(t *testing.T) {
+	var gotCSR string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			CSRPEM string `json:"csr_pem"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotCSR = body.CSRPEM
+		json.NewEncoder(w).Encode(map[string]any{
+			"agent":       map[string]any{"trust_root": "nexusagentprotocol.com", "capability_node": "finance/taxes", "agent_id": "agent_test123"},
+			"certificate": map[string]any{"pem": "-----BEGIN CERTIFICATE-----\ntest\n-----END CERTIFICATE-----"},
+			"ca_pem":      "-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----",
+		})
+	}))
+	defer srv.Close()
+
+	c, _ := client.New(srv.URL)
+	result, err := c.ActivateAgent(context.Background(), "550e8400-e29b-41d4-a716-446655440000")
+	if err != nil {
+		t.Fatalf("ActivateAgent: %v", err)
+	}
+	if !strings.Contains(gotCSR, "BEGIN CERTIFICATE REQUEST") {
+		t.Errorf("registry received csr_pem %q, want a PEM CSR", gotCSR)
+	}
+	if !strings.Contains(result.PrivateKeyPEM, "PRIVATE KEY") {
+		t.Errorf("PrivateKeyPEM = %q, want the locally generated key", result.PrivateKeyPEM)
+	}
+}
+
 func TestRevokeAgent_success(t *testing.T) {
 	srv := stubRegistryServer(t)
 	defer srv.Close()
@@ -368,3 +399,84 @@ func TestCallAgent_resolveAndCall(t *testing.T) {
 		t.Errorf("unexpected reply: %v", reply)
 	}
 }
+
+func TestGenerateCSR_keyAlgorithms(t *testing.T) {
+	for _, alg := range []client.KeyAlgorithm{client.KeyAlgorithmRSA, client.KeyAlgorithmECDSAP256, client.KeyAlgorithmEd25519} {
+		keyPEM, csrPEM, err := client.GenerateCSR(alg, "agent_test123")
+		if err != nil {
+			t.Fatalf("%s: GenerateCSR: %v", alg, err)
+		}
+		block, _ := pem.Decode([]byte(csrPEM))
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			t.Fatalf("%s: parse CSR: %v", alg, err)
+		}
+		if err := csr.CheckSignature(); err != nil {
+			t.Errorf("%s: CSR signature: %v", alg, err)
+		}
+		if !strings.Contains(keyPEM, "PRIVATE KEY") {
+			t.Errorf("%s: key PEM = %q", alg, keyPEM)
+		}
+	}
+	if _, err := client.New("http://localhost", client.WithKeyAlgorithm("dsa")); err == nil {
+		t.Error("WithKeyAlgorithm accepted an unknown algorithm")
+	}
+}
+
+func TestCallAgent_audienceRestrictedTokens(t *testing.T) {
+	agentSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"` + strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") + `"}`))
+	}))
+	defer agentSrv.Close()
+
+	var fetches int
+	regSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/token" {
+			fetches++
+			r.ParseForm()
+			json.NewEncoder(w).Encode(map[string]any{
+				"access_token": "tok-for-" + r.PostForm.Get("audience"),
+				"expires_in":   3600,
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"endpoint": agentSrv.URL, "status": "active"})
+	}))
+	defer regSrv.Close()
+
+	c, _ := client.New(regSrv.URL)
+	for _, target := range []string{"agent://acme.com/x/agent_1", "agent://acme.com/x/agent_2", "agent://acme.com/x/agent_1"} {
+		var reply map[string]string
+		if err := c.CallAgent(context.Background(), target, http.MethodGet, "/", nil, &reply); err != nil {
+			t.Fatalf("CallAgent(%s): %v", target, err)
+		}
+		if reply["token"] != "tok-for-"+target {
+			t.Errorf("%s was sent %q", target, reply["token"])
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("fetched %d tokens, want one per agent (2)", fetches)
+	}
+}
+
+func TestExchangeToken_sendsRFC8693Request(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:token-exchange" ||
+			r.PostForm.Get("subject_token") != "upstream" ||
+			r.PostForm.Get("audience") != "agent://acme.com/x/agent_2" ||
+			r.PostForm.Get("scope") != "agent:call" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"error": "invalid_request"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "delegated", "expires_in": 600})
+	}))
+	defer srv.Close()
+
+	c, _ := client.New(srv.URL)
+	tok, err := c.ExchangeToken(context.Background(), "upstream", "agent://acme.com/x/agent_2", "agent:call")
+	if err != nil || tok != "delegated" {
+		t.Fatalf("ExchangeToken = %q, %v", tok, err)
+	}
+}
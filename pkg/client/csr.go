@@ -0,0 +1,79 @@
+package client
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+)
+
+const agentKeyBits = 2048
+
+// KeyAlgorithm selects the type of agent key GenerateCSR creates.
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA       KeyAlgorithm = "rsa"        // RSA 2048 (default)
+	KeyAlgorithmECDSAP256 KeyAlgorithm = "ecdsa-p256" // ECDSA on NIST P-256
+	KeyAlgorithmEd25519   KeyAlgorithm = "ed25519"    // Ed25519
+)
+
+// GenerateCSR creates a new agent private key of the given algorithm and a
+// PKCS#10 certificate signing request for it. Send the CSR to
+// ActivateAgentWithCSR or RenewCertWithCSR and keep the key: the registry
+// only ever sees the public half. The registry sets the certificate subject
+// and agent:// URI SAN itself, so commonName is informational. An empty alg
+// selects RSA.
+func GenerateCSR(alg KeyAlgorithm, commonName string) (keyPEM, csrPEM string, err error) {
+	var (
+		key   crypto.Signer
+		block *pem.Block
+	)
+	switch alg {
+	case "", KeyAlgorithmRSA:
+		k, err := rsa.GenerateKey(rand.Reader, agentKeyBits)
+		if err != nil {
+			return "", "", fmt.Errorf("generate agent key: %w", err)
+		}
+		key, block = k, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	case KeyAlgorithmECDSAP256:
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return "", "", fmt.Errorf("generate agent key: %w", err)
+		}
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", "", fmt.Errorf("marshal agent key: %w", err)
+		}
+		key, block = k, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	case KeyAlgorithmEd25519:
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", fmt.Errorf("generate agent key: %w", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return "", "", fmt.Errorf("marshal agent key: %w", err)
+		}
+		key, block = k, &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		return "", "", fmt.Errorf("unknown key algorithm %q (want rsa, ecdsa-p256 or ed25519)", alg)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, key)
+	if err != nil {
+		return "", "", fmt.Errorf("create CSR: %w", err)
+	}
+
+	keyPEM = string(pem.EncodeToMemory(block))
+	csrPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+	return keyPEM, csrPEM, nil
+}
@@ -60,6 +60,23 @@
 //
 //	token, err := c.FetchToken(ctx) // exchanges mTLS cert for JWT
 //
+// Tokens are bound to the client certificate. When the agents you call
+// cannot see it (TLS terminated by a proxy), bind them to a DPoP key instead:
+//
+//	c, _ := client.NewFromCertDir(registryURL, certDir, client.WithDPoPKey(key))
+//
+// # Verifying inbound calls
+//
+// An agent checks the Task Tokens it receives — signature, issuer, audience
+// and the certificate or DPoP binding — with a TokenVerifier:
+//
+//	v := client.NewTokenVerifier(registryURL, "agent://acme.com/finance/agent_b")
+//	http.Handle("/", v.Middleware(mux))
+//	// in mux: claims := client.TokenClaimsFromContext(r.Context())
+//
+// Signature checks alone accept a revoked token until it expires; add
+// client.WithIntrospection(c) to also ask the registry on every call.
+//
 // # Registering a new agent programmatically
 //
 // For scripted or server-side registration (the CLI 'nap claim' covers the
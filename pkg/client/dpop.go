@@ -0,0 +1,82 @@
+package client
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// WithDPoPKey makes the tokens CallAgent, FetchTokenForAudience and
+// ExchangeToken obtain DPoP-bound (RFC 9449) rather than bound to the client
+// certificate. Use it when the agents you call sit behind a proxy that
+// terminates TLS, so your certificate never reaches them: each call then
+// carries a fresh proof signed with key, and a stolen token is useless
+// without it. key is an in-memory *rsa.PrivateKey, P-256 *ecdsa.PrivateKey
+// or ed25519.PrivateKey: the agent's certificate key or a separate one.
+// Calls to the registry itself still use mTLS.
+func WithDPoPKey(key crypto.Signer) Option {
+	return func(c *Client) error {
+		switch key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		default:
+			return fmt.Errorf("DPoP key: unsupported private key type %T", key)
+		}
+		if _, err := signingMethod(key.Public()); err != nil {
+			return fmt.Errorf("DPoP key: %w", err)
+		}
+		c.dpopKey = key
+		return nil
+	}
+}
+
+// dpopProofClaims are the claims of a DPoP proof JWT (RFC 9449 §4.2).
+type dpopProofClaims struct {
+	jwt.RegisteredClaims
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+}
+
+// dpopProof signs a DPoP proof for a method request to target. accessToken,
+// if non-empty, is the token the request presents.
+func (c *Client) dpopProof(method, target, accessToken string) (string, error) {
+	pub, err := publicJWK(c.dpopKey.Public())
+	if err != nil {
+		return "", err
+	}
+	alg, _ := signingMethod(c.dpopKey.Public())
+	htu, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("DPoP proof: %w", err)
+	}
+	htu.RawQuery, htu.Fragment = "", ""
+
+	claims := dpopProofClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       uuid.New().String(),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+		HTM: method,
+		HTU: htu.String(),
+	}
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		claims.ATH = b64url.EncodeToString(sum[:])
+	}
+	tok := jwt.NewWithClaims(alg, claims)
+	tok.Header["typ"] = "dpop+jwt"
+	tok.Header["jwk"] = pub
+	signed, err := tok.SignedString(c.dpopKey)
+	if err != nil {
+		return "", fmt.Errorf("sign DPoP proof: %w", err)
+	}
+	return signed, nil
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/agentcard"
+)
+
+// WithEndpointPreference sets which of an agent's endpoints Resolve reports
+// as ResolveResult.Endpoint: endpoints speaking protocol ("https", "grpc",
+// "wss", ...) are preferred, and among those, endpoints in region. An empty
+// region has no regional preference. The default is ("https", "").
+//
+// CallAgent and CallAgentRaw always call over HTTP(S); a non-HTTP protocol
+// preference only affects Resolve.
+func WithEndpointPreference(protocol, region string) Option {
+	return func(c *Client) error {
+		c.protocol = protocol
+		c.region = region
+		return nil
+	}
+}
+
+// SelectEndpoints returns the endpoints speaking protocol (all endpoints when
+// protocol is empty) in the order a client should try them: endpoints in
+// region first, then by ascending Priority, with endpoints of equal priority
+// shuffled in proportion to their Weight as for DNS SRV records (RFC 2782).
+func SelectEndpoints(endpoints []agentcard.Endpoint, protocol, region string) []agentcard.Endpoint {
+	var out []agentcard.Endpoint
+	for _, ep := range endpoints {
+		if protocol == "" || ep.Protocol == protocol {
+			out = append(out, ep)
+		}
+	}
+	inRegion := func(ep agentcard.Endpoint) int {
+		if region != "" && ep.Region == region {
+			return 0
+		}
+		return 1
+	}
+	slices.SortStableFunc(out, func(a, b agentcard.Endpoint) int {
+		if r := inRegion(a) - inRegion(b); r != 0 {
+			return r
+		}
+		return a.Priority - b.Priority
+	})
+
+	// Weighted shuffle within each run of equal region preference and priority.
+	for start := 0; start < len(out); {
+		end := start + 1
+		for end < len(out) && inRegion(out[end]) == inRegion(out[start]) && out[end].Priority == out[start].Priority {
+			end++
+		}
+		weightedShuffle(out[start:end])
+		start = end
+	}
+	return out
+}
+
+// weightedShuffle orders eps by repeated weighted selection. Zero-weight
+// endpoints are only chosen once all weighted ones have been.
+func weightedShuffle(eps []agentcard.Endpoint) {
+	for i := range len(eps) - 1 {
+		total := 0
+		for _, ep := range eps[i:] {
+			total += max(ep.Weight, 0)
+		}
+		if total == 0 {
+			return
+		}
+		n := rand.IntN(total)
+		for j := i; j < len(eps); j++ {
+			n -= max(eps[j].Weight, 0)
+			if n < 0 {
+				eps[i], eps[j] = eps[j], eps[i]
+				break
+			}
+		}
+	}
+}
+
+// withPreferredEndpoint returns a copy of result whose Endpoint is the
+// preferred endpoint for c's protocol and region, chosen afresh on each call
+// so that equal-priority endpoints share the load. The primary endpoint is
+// kept when no declared endpoint speaks the preferred protocol. result itself,
+// which may be cached, is not modified.
+func (c *Client) withPreferredEndpoint(result *ResolveResult) *ResolveResult {
+	out := *result
+	if ordered := SelectEndpoints(result.Endpoints, c.protocol, c.region); len(ordered) > 0 {
+		out.Endpoint = ordered[0].URL
+	}
+	return &out
+}
+
+// callTargets returns the HTTP(S) base URLs to try, in order, when calling a
+// resolved agent: the declared endpoints in preference order, then the
+// primary endpoint if it is not among them.
+func (c *Client) callTargets(resolved *ResolveResult) []string {
+	protocol := c.protocol
+	if protocol != "https" && protocol != "http" {
+		protocol = "https"
+	}
+	ordered := SelectEndpoints(resolved.Endpoints, protocol, c.region)
+	if protocol == "https" {
+		// Plain-HTTP endpoints (development setups) are a last resort.
+		ordered = append(ordered, SelectEndpoints(resolved.Endpoints, "http", c.region)...)
+	}
+
+	targets := make([]string, 0, len(ordered)+1)
+	for _, ep := range ordered {
+		targets = append(targets, ep.URL)
+	}
+	primary := resolved.Endpoint
+	if (strings.HasPrefix(primary, "https://") || strings.HasPrefix(primary, "http://")) &&
+		!slices.Contains(targets, primary) {
+		targets = append(targets, primary)
+	}
+	return targets
+}
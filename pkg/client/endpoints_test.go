@@ -0,0 +1,205 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/agentcard"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/client"
+)
+
+func TestSelectEndpoints_order(t *testing.T) {
+	eps := []agentcard.Endpoint{
+		{URL: "https://us-backup", Protocol: "https", Region: "us", Priority: 20},
+		{URL: "grpc://us", Protocol: "grpc", Region: "us", Priority: 0},
+		{URL: "https://us", Protocol: "https", Region: "us", Priority: 10},
+		{URL: "https://eu", Protocol: "https", Region: "eu", Priority: 30},
+	}
+
+	got := urls(client.SelectEndpoints(eps, "https", ""))
+	if want := "https://us,https://us-backup,https://eu"; got != want {
+		t.Errorf("by priority: got %s, want %s", got, want)
+	}
+
+	got = urls(client.SelectEndpoints(eps, "https", "eu"))
+	if want := "https://eu,https://us,https://us-backup"; got != want {
+		t.Errorf("region first: got %s, want %s", got, want)
+	}
+
+	got = urls(client.SelectEndpoints(eps, "", ""))
+	if want := "grpc://us,https://us,https://us-backup,https://eu"; got != want {
+		t.Errorf("any protocol: got %s, want %s", got, want)
+	}
+}
+
+func TestSelectEndpoints_weight(t *testing.T) {
+	eps := []agentcard.Endpoint{
+		{URL: "https://a", Protocol: "https", Priority: 10, Weight: 0},
+		{URL: "https://b", Protocol: "https", Priority: 10, Weight: 100},
+	}
+	// A zero-weight endpoint is only picked after all weighted ones.
+	for range 20 {
+		if got := client.SelectEndpoints(eps, "https", "")[0].URL; got != "https://b" {
+			t.Fatalf("first endpoint = %s, want https://b", got)
+		}
+	}
+}
+
+func TestResolve_endpointPreference(t *testing.T) {
+	srv := endpointsRegistry(t, "https://primary.example.com", []agentcard.Endpoint{
+		{URL: "https://us.example.com", Protocol: "https", Region: "us", Priority: 10},
+		{URL: "grpc://eu.example.com:443", Protocol: "grpc", Region: "eu", Priority: 10},
+		{URL: "https://eu.example.com", Protocol: "https", Region: "eu", Priority: 20},
+	})
+
+	c, _ := client.New(srv.URL, client.WithEndpointPreference("https", "eu"))
+	res, err := c.Resolve(context.Background(), "agent://acme.com/finance/agent_1")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if res.Endpoint != "https://eu.example.com" {
+		t.Errorf("Endpoint = %s, want https://eu.example.com", res.Endpoint)
+	}
+	if len(res.Endpoints) != 3 {
+		t.Errorf("Endpoints has %d entries, want 3", len(res.Endpoints))
+	}
+
+	// The cached result keeps the registry's primary endpoint: the preferred
+	// one is chosen again on every Resolve.
+	c, _ = client.New(srv.URL, client.WithEndpointPreference("https", "eu"), client.WithCacheTTL(time.Minute))
+	for range 2 {
+		res, _ = c.Resolve(context.Background(), "agent://acme.com/finance/agent_1")
+		if res.Endpoint != "https://eu.example.com" {
+			t.Errorf("Endpoint = %s, want https://eu.example.com", res.Endpoint)
+		}
+		res.Endpoint = "https://tampered.example.com"
+	}
+
+	c, _ = client.New(srv.URL, client.WithEndpointPreference("grpc", ""))
+	res, _ = c.Resolve(context.Background(), "agent://acme.com/finance/agent_1")
+	if res.Endpoint != "grpc://eu.example.com:443" {
+		t.Errorf("Endpoint = %s, want grpc://eu.example.com:443", res.Endpoint)
+	}
+}
+
+func TestCallAgent_failover(t *testing.T) {
+	var downCalls atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downCalls.Add(1)
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"reply": "ok"})
+	}))
+	defer up.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	srv := endpointsRegistry(t, "", []agentcard.Endpoint{
+		{URL: unreachable.URL, Protocol: "http", Priority: 0},
+		{URL: down.URL, Protocol: "http", Priority: 10},
+		{URL: up.URL, Protocol: "http", Priority: 20},
+	})
+	c, _ := client.New(srv.URL, client.WithBearerToken("tok"), client.WithEndpointPreference("http", ""))
+
+	var reply map[string]string
+	err := c.CallAgent(context.Background(), "agent://acme.com/finance/agent_1", http.MethodGet, "/status", nil, &reply)
+	if err != nil {
+		t.Fatalf("CallAgent: %v", err)
+	}
+	if reply["reply"] != "ok" {
+		t.Errorf("unexpected reply: %v", reply)
+	}
+	if downCalls.Load() != 1 {
+		t.Errorf("draining endpoint called %d times, want 1", downCalls.Load())
+	}
+}
+
+// A POST is only sent to the next endpoint if the previous one could not be
+// connected to: after a 503 the agent may already have acted on it.
+func TestCallAgent_noFailoverForPOSTAfterResponse(t *testing.T) {
+	var downCalls, upCalls atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downCalls.Add(1)
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upCalls.Add(1)
+		json.NewEncoder(w).Encode(map[string]string{"reply": "ok"})
+	}))
+	defer up.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	srv := endpointsRegistry(t, "", []agentcard.Endpoint{
+		{URL: unreachable.URL, Protocol: "http", Priority: 0},
+		{URL: down.URL, Protocol: "http", Priority: 10},
+		{URL: up.URL, Protocol: "http", Priority: 20},
+	})
+	c, _ := client.New(srv.URL, client.WithBearerToken("tok"), client.WithEndpointPreference("http", ""))
+
+	err := c.CallAgent(context.Background(), "agent://acme.com/finance/agent_1", http.MethodPost, "/invoke", map[string]int{"n": 1}, nil)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 503") {
+		t.Fatalf("expected the HTTP 503 error, got %v", err)
+	}
+	if downCalls.Load() != 1 || upCalls.Load() != 0 {
+		t.Errorf("calls: draining %d, up %d; want 1 and 0", downCalls.Load(), upCalls.Load())
+	}
+}
+
+func TestCallAgent_noFailoverOnClientError(t *testing.T) {
+	var backupCalls atomic.Int32
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad input", http.StatusBadRequest)
+	}))
+	defer first.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupCalls.Add(1)
+	}))
+	defer backup.Close()
+
+	srv := endpointsRegistry(t, "", []agentcard.Endpoint{
+		{URL: first.URL, Protocol: "http", Priority: 0},
+		{URL: backup.URL, Protocol: "http", Priority: 10},
+	})
+	c, _ := client.New(srv.URL, client.WithBearerToken("tok"), client.WithEndpointPreference("http", ""))
+
+	_, err := c.CallAgentRaw(context.Background(), "agent://acme.com/finance/agent_1", http.MethodGet, "/", nil)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Fatalf("expected HTTP 400 error, got %v", err)
+	}
+	if backupCalls.Load() != 0 {
+		t.Errorf("backup endpoint called %d times, want 0", backupCalls.Load())
+	}
+}
+
+// endpointsRegistry serves /api/v1/resolve with the given endpoints.
+func endpointsRegistry(t *testing.T, primary string, eps []agentcard.Endpoint) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"uri":       "agent://acme.com/finance/agent_1",
+			"endpoint":  primary,
+			"endpoints": eps,
+			"status":    "active",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func urls(eps []agentcard.Endpoint) string {
+	out := make([]string, len(eps))
+	for i, ep := range eps {
+		out[i] = ep.URL
+	}
+	return strings.Join(out, ",")
+}
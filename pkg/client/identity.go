@@ -12,7 +12,7 @@ type CertBundle struct {
 	// CertPEM is the agent's X.509 certificate issued by the Nexus CA.
 	CertPEM string
 
-	// PrivateKeyPEM is the agent's RSA private key. Keep this secret.
+	// PrivateKeyPEM is the agent's RSA, ECDSA or Ed25519 private key. Keep this secret.
 	PrivateKeyPEM string
 
 	// CAPEM is the Nexus CA certificate used to verify the registry's TLS cert.
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Introspection is the registry's answer to a token introspection request
+// (RFC 7662). Only Active is set for a token that is invalid, expired,
+// revoked or not visible to the caller.
+type Introspection struct {
+	Active    bool               `json:"active"`
+	TokenType string             `json:"token_type,omitempty"` // "Bearer" or "DPoP"
+	TokenUse  string             `json:"token_use,omitempty"`  // "task", "user" or "admin"
+	Subject   string             `json:"sub,omitempty"`
+	ClientID  string             `json:"client_id,omitempty"` // the agent presenting the token
+	AgentURI  string             `json:"agent_uri,omitempty"`
+	Username  string             `json:"username,omitempty"`
+	Scope     string             `json:"scope,omitempty"`
+	Audience  []string           `json:"aud,omitempty"`
+	Issuer    string             `json:"iss,omitempty"`
+	JTI       string             `json:"jti,omitempty"`
+	ExpiresAt int64              `json:"exp,omitempty"`
+	IssuedAt  int64              `json:"iat,omitempty"`
+	Cnf       *TokenConfirmation `json:"cnf,omitempty"`
+	Act       *TokenActor        `json:"act,omitempty"`
+}
+
+// IntrospectToken asks the registry whether token is still active (RFC 7662,
+// POST /api/v1/token/introspect). The client authenticates with its mTLS
+// certificate, or with an admin token set by WithBearerToken. An agent sees
+// Task Tokens it holds or that were issued for it; admins see every token.
+func (c *Client) IntrospectToken(ctx context.Context, token string) (*Introspection, error) {
+	body, err := c.postTokenForm(ctx, "/api/v1/token/introspect", token)
+	if err != nil {
+		return nil, err
+	}
+	var result Introspection
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decode introspection response: %w", err)
+	}
+	return &result, nil
+}
+
+// RevokeToken revokes token at the registry before it expires (RFC 7009,
+// POST /api/v1/token/revoke), e.g. when an agent finishes a task early. An
+// agent may revoke the Task Tokens issued to it; an admin any token.
+// Revoking an invalid or already revoked token succeeds.
+func (c *Client) RevokeToken(ctx context.Context, token string) error {
+	_, err := c.postTokenForm(ctx, "/api/v1/token/revoke", token)
+	return err
+}
+
+// postTokenForm posts token as the "token" parameter to a registry endpoint.
+func (c *Client) postTokenForm(ctx context.Context, path, token string) ([]byte, error) {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.registryBase+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return c.do(req)
+}
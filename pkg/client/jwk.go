@@ -0,0 +1,126 @@
+package client
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwk is a public JSON Web Key as served by the registry's JWKS endpoint and
+// carried in DPoP proofs: RSA, EC P-256 or OKP Ed25519.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+var b64url = base64.RawURLEncoding
+
+// publicJWK encodes pub as a JWK.
+func publicJWK(pub crypto.PublicKey) (jwk, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		e := big.NewInt(int64(k.E)).Bytes()
+		return jwk{Kty: "RSA", N: b64url.EncodeToString(k.N.Bytes()), E: b64url.EncodeToString(e)}, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return jwk{}, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+		ek, err := k.ECDH()
+		if err != nil {
+			return jwk{}, fmt.Errorf("encode ECDSA key: %w", err)
+		}
+		raw := ek.Bytes() // 0x04 || X || Y
+		return jwk{Kty: "EC", Crv: "P-256", X: b64url.EncodeToString(raw[1:33]), Y: b64url.EncodeToString(raw[33:])}, nil
+	case ed25519.PublicKey:
+		return jwk{Kty: "OKP", Crv: "Ed25519", X: b64url.EncodeToString(k)}, nil
+	default:
+		return jwk{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// publicKey decodes the JWK.
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case j.Kty == "RSA":
+		n, errN := b64url.DecodeString(j.N)
+		e, errE := b64url.DecodeString(j.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("jwk %q: bad RSA key", j.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case j.Kty == "EC" && j.Crv == "P-256":
+		x, errX := b64url.DecodeString(j.X)
+		y, errY := b64url.DecodeString(j.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("jwk %q: bad P-256 coordinates", j.Kid)
+		}
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		x, err := b64url.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk %q: bad Ed25519 key", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("jwk %q: unsupported key type %s %s", j.Kid, j.Kty, j.Crv)
+	}
+}
+
+// thumbprint returns the RFC 7638 SHA-256 thumbprint of the JWK: its
+// required members in lexicographic order, without whitespace.
+func (j jwk) thumbprint() string {
+	var members string
+	switch j.Kty {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, j.E, j.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, j.Crv, j.X, j.Y)
+	default:
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, j.Crv, j.Kty, j.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return b64url.EncodeToString(sum[:])
+}
+
+// signingMethod returns the JWS algorithm used with keys of pub's type.
+func signingMethod(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return jwt.SigningMethodES256, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", pub)
+}
+
+// jwkFromHeader decodes the "jwk" member of a JWS header.
+func jwkFromHeader(v any) (jwk, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return jwk{}, err
+	}
+	var j jwk
+	if err := json.Unmarshal(raw, &j); err != nil || j.Kty == "" {
+		return jwk{}, fmt.Errorf("missing jwk header")
+	}
+	return j, nil
+}
@@ -0,0 +1,520 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// Errors returned by TokenVerifier.
+var (
+	// ErrInvalidToken is returned when the Task Token is missing, badly
+	// signed, expired, from another issuer or for another agent.
+	ErrInvalidToken = errors.New("invalid task token")
+	// ErrTokenBinding is returned when a sender-constrained token is not
+	// presented with the certificate or DPoP key it is bound to.
+	ErrTokenBinding = errors.New("task token is not presented by its holder")
+	// ErrTokenRevoked is returned, with WithIntrospection, when the registry
+	// reports that an otherwise valid token has been revoked.
+	ErrTokenRevoked = errors.New("task token has been revoked")
+)
+
+// DPoP proof freshness accepted by TokenVerifier.
+const (
+	dpopProofMaxAge = 5 * time.Minute
+	dpopClockSkew   = time.Minute
+)
+
+// DPoP replay cache: accepted proofs are kept in buckets by expiry, each
+// dropped whole once its proofs have expired. Once defaultMaxDPoPProofs are
+// remembered, the oldest bucket is dropped early (see WithMaxDPoPProofs).
+const (
+	defaultMaxDPoPProofs = 100_000
+	proofBucketWidth     = time.Minute
+)
+
+// TokenClaims are the claims of a verified Task Token.
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	AgentURI string             `json:"agent_uri"`
+	Scopes   []string           `json:"scopes"`
+	Act      *TokenActor        `json:"act,omitempty"`
+	Cnf      *TokenConfirmation `json:"cnf,omitempty"`
+}
+
+// TokenActor is the "act" claim of a delegated token: the agent acting for
+// the token's subject, and any actor before it.
+type TokenActor struct {
+	Subject string      `json:"sub"`
+	Act     *TokenActor `json:"act,omitempty"`
+}
+
+// TokenConfirmation is the "cnf" claim binding a token to the caller's
+// certificate ("x5t#S256") or DPoP key ("jkt").
+type TokenConfirmation struct {
+	X5TS256 string `json:"x5t#S256,omitempty"`
+	JKT     string `json:"jkt,omitempty"`
+}
+
+// HasScope reports whether the token grants scope.
+func (c *TokenClaims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// Actors returns the delegation chain of the token, the agent that called
+// you first. It is empty when the subject called you itself.
+func (c *TokenClaims) Actors() []string {
+	var actors []string
+	for a := c.Act; a != nil; a = a.Act {
+		actors = append(actors, a.Subject)
+	}
+	return actors
+}
+
+// VerifierOption configures a TokenVerifier.
+type VerifierOption func(*TokenVerifier)
+
+// WithVerifierHTTPClient sets the client used to fetch the registry's JWKS,
+// e.g. one trusting the Nexus CA.
+func WithVerifierHTTPClient(hc *http.Client) VerifierOption {
+	return func(v *TokenVerifier) { v.httpClient = hc }
+}
+
+// WithIssuer sets the expected "iss" claim when the registry's issuer URL
+// differs from the base URL the verifier fetches keys from.
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *TokenVerifier) { v.issuer = issuer }
+}
+
+// WithPublicURL sets the scheme and host callers use to reach the agent,
+// which DPoP proofs must name. Set it behind a TLS-terminating proxy; by
+// default it is taken from each request.
+func WithPublicURL(publicURL string) VerifierOption {
+	return func(v *TokenVerifier) { v.publicURL = strings.TrimRight(publicURL, "/") }
+}
+
+// WithRequireBinding rejects tokens that are not sender-constrained. Tokens
+// issued before the registry bound them are otherwise accepted as bearer
+// tokens.
+func WithRequireBinding() VerifierOption {
+	return func(v *TokenVerifier) { v.requireBinding = true }
+}
+
+// WithUnaudiencedTokens also accepts tokens without an "aud" claim, which
+// registries issued before tokens were restricted to one audience. Such a
+// token is valid at every agent, so only enable this while callers migrate.
+func WithUnaudiencedTokens() VerifierOption {
+	return func(v *TokenVerifier) { v.allowNoAudience = true }
+}
+
+// WithMaxDPoPProofs sets how many accepted DPoP proofs the verifier
+// remembers to detect replays (default 100000). Once that many unexpired
+// proofs are remembered, the ones closest to expiry are forgotten to make
+// room, so a flood of proofs shortens the replay window instead of locking
+// out every caller.
+func WithMaxDPoPProofs(n int) VerifierOption {
+	return func(v *TokenVerifier) { v.maxProofs = n }
+}
+
+// WithIntrospection makes the verifier ask the registry, through c, whether
+// each token that passes the local checks has been revoked (see
+// Client.IntrospectToken). It costs a registry round trip per call; without
+// it a revoked token is accepted until it expires.
+func WithIntrospection(c *Client) VerifierOption {
+	return func(v *TokenVerifier) { v.introspector = c }
+}
+
+// TokenVerifier checks the Task Tokens presented to an agent on inbound
+// calls: signed by the registry (keys from its JWKS, refreshed as they
+// rotate), unexpired, issued for this agent ("aud"), and presented by their
+// holder — over mTLS with the certificate they are bound to, or with a DPoP
+// proof from the bound key. Accepted DPoP proofs are remembered so each is
+// used once.
+//
+//	v := client.NewTokenVerifier("https://registry.nexusagentprotocol.com",
+//	    "agent://acme.com/finance/agent_b")
+//	http.ListenAndServeTLS(":443", certFile, keyFile, v.Middleware(mux))
+type TokenVerifier struct {
+	jwksURL         string
+	issuer          string
+	audience        string
+	publicURL       string
+	requireBinding  bool
+	allowNoAudience bool
+	httpClient      *http.Client
+	introspector    *Client // nil = no revocation check
+	maxProofs       int
+
+	flight      singleflight.Group // one JWKS fetch at a time
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey // by kid
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	buckets     map[int64]map[string]struct{} // DPoP proof expiry bucket → proofs
+	proofs      int
+}
+
+// JWKS refresh policy: keys are refetched after jwksRefresh, or sooner for an
+// unknown kid (a rotation), but not more often than jwksMinRefresh.
+const (
+	jwksRefresh    = 10 * time.Minute
+	jwksMinRefresh = 30 * time.Second
+)
+
+// firstSigningKeyID is the kid of the registry's key before any rotation.
+const firstSigningKeyID = "nexus-signing-key-1"
+
+// NewTokenVerifier creates a verifier for tokens issued by the registry at
+// registryBase to agentURI, the agent running it.
+func NewTokenVerifier(registryBase, agentURI string, opts ...VerifierOption) *TokenVerifier {
+	base := strings.TrimRight(registryBase, "/")
+	v := &TokenVerifier{
+		jwksURL:    base + "/.well-known/jwks.json",
+		issuer:     base,
+		audience:   agentURI,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		maxProofs:  defaultMaxDPoPProofs,
+		buckets:    make(map[int64]map[string]struct{}),
+	}
+	for _, o := range opts {
+		o(v)
+	}
+	return v
+}
+
+// Verify checks the Task Token on an inbound request, sent as
+// "Authorization: Bearer <token>" or, DPoP-bound, "Authorization: DPoP
+// <token>" with a DPoP proof header. It returns the token's claims; errors
+// wrap ErrInvalidToken, ErrTokenBinding or ErrTokenRevoked.
+func (v *TokenVerifier) Verify(r *http.Request) (*TokenClaims, error) {
+	var tokenStr string
+	auth := r.Header.Get("Authorization")
+	for _, scheme := range []string{"Bearer ", "DPoP "} {
+		if strings.HasPrefix(auth, scheme) {
+			tokenStr = strings.TrimPrefix(auth, scheme)
+		}
+	}
+	if tokenStr == "" {
+		return nil, fmt.Errorf("%w: no token presented", ErrInvalidToken)
+	}
+
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		pub, err := v.key(r.Context(), kid)
+		if err != nil {
+			return nil, err
+		}
+		if m, err := signingMethod(pub); err != nil || m.Alg() != t.Method.Alg() {
+			return nil, fmt.Errorf("alg %s does not match key %q", t.Method.Alg(), kid)
+		}
+		return pub, nil
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	switch {
+	case len(claims.Audience) == 0 && !v.allowNoAudience:
+		return nil, fmt.Errorf("%w: no audience (see WithUnaudiencedTokens)", ErrInvalidToken)
+	case len(claims.Audience) > 0 && !slices.Contains(claims.Audience, v.audience):
+		return nil, fmt.Errorf("%w: not issued for %s", ErrInvalidToken, v.audience)
+	}
+	if err := v.checkBinding(claims, r, tokenStr); err != nil {
+		return nil, err
+	}
+	if v.introspector != nil {
+		info, err := v.introspector.IntrospectToken(r.Context(), tokenStr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: introspection failed: %v", ErrInvalidToken, err)
+		}
+		if !info.Active {
+			return nil, ErrTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+// tokenClaimsKey is the context key under which Middleware stores claims.
+type tokenClaimsKey struct{}
+
+// Middleware wraps next so that it only sees requests with a valid Task
+// Token; others get 401. The claims are available to next through
+// TokenClaimsFromContext.
+func (v *TokenVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := v.Verify(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", DPoP error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenClaimsKey{}, claims)))
+	})
+}
+
+// TokenClaimsFromContext returns the claims Middleware verified, or nil.
+func TokenClaimsFromContext(ctx context.Context) *TokenClaims {
+	claims, _ := ctx.Value(tokenClaimsKey{}).(*TokenClaims)
+	return claims
+}
+
+// checkBinding verifies that a sender-constrained token is presented by its
+// holder.
+func (v *TokenVerifier) checkBinding(claims *TokenClaims, r *http.Request, tokenStr string) error {
+	cnf := claims.Cnf
+	switch {
+	case cnf == nil || (cnf.X5TS256 == "" && cnf.JKT == ""):
+		if v.requireBinding {
+			return fmt.Errorf("%w: token is not sender-constrained", ErrTokenBinding)
+		}
+		return nil
+	case cnf.X5TS256 != "":
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return fmt.Errorf("%w: no client certificate", ErrTokenBinding)
+		}
+		sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+		if subtle.ConstantTimeCompare([]byte(b64url.EncodeToString(sum[:])), []byte(cnf.X5TS256)) != 1 {
+			return fmt.Errorf("%w: client certificate does not match", ErrTokenBinding)
+		}
+		return nil
+	default:
+		jkt, err := v.verifyDPoP(r, tokenStr)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrTokenBinding, err)
+		}
+		if subtle.ConstantTimeCompare([]byte(jkt), []byte(cnf.JKT)) != 1 {
+			return fmt.Errorf("%w: DPoP key does not match", ErrTokenBinding)
+		}
+		return nil
+	}
+}
+
+// verifyDPoP validates the DPoP proof of r (RFC 9449 §4.3) for tokenStr and
+// returns the thumbprint of its key.
+func (v *TokenVerifier) verifyDPoP(r *http.Request, tokenStr string) (string, error) {
+	values := r.Header.Values("DPoP")
+	if len(values) != 1 {
+		return "", errors.New("exactly one DPoP proof header is required")
+	}
+
+	var jkt string
+	claims := &dpopProofClaims{}
+	_, err := jwt.ParseWithClaims(values[0], claims, func(t *jwt.Token) (any, error) {
+		if typ, _ := t.Header["typ"].(string); typ != "dpop+jwt" {
+			return nil, errors.New(`typ must be "dpop+jwt"`)
+		}
+		key, err := jwkFromHeader(t.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		pub, err := key.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		if m, err := signingMethod(pub); err != nil || m.Alg() != t.Method.Alg() {
+			return nil, fmt.Errorf("alg %s does not match the jwk", t.Method.Alg())
+		}
+		jkt = key.thumbprint()
+		return pub, nil
+	}, jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}))
+	if err != nil {
+		return "", fmt.Errorf("DPoP proof: %v", err)
+	}
+
+	now := time.Now()
+	sum := sha256.Sum256([]byte(tokenStr))
+	switch {
+	case claims.ID == "":
+		return "", errors.New("DPoP proof has no jti")
+	case claims.IssuedAt == nil || claims.IssuedAt.Before(now.Add(-dpopProofMaxAge)) || claims.IssuedAt.After(now.Add(dpopClockSkew)):
+		return "", errors.New("DPoP proof iat is missing or not recent")
+	case claims.HTM != r.Method:
+		return "", errors.New("DPoP proof htm does not match the request method")
+	case !sameRequestURL(claims.HTU, v.requestURL(r)):
+		return "", errors.New("DPoP proof htu does not match the request URL")
+	case subtle.ConstantTimeCompare([]byte(claims.ATH), []byte(b64url.EncodeToString(sum[:]))) != 1:
+		return "", errors.New("DPoP proof ath does not match the token")
+	}
+
+	if err := v.remember(jkt+":"+claims.ID, claims.IssuedAt.Add(dpopProofMaxAge+dpopClockSkew), now); err != nil {
+		return "", err
+	}
+	return jkt, nil
+}
+
+// remember records a DPoP proof until forgetAt, failing if it was already
+// recorded. When the cache is full the oldest bucket is evicted.
+func (v *TokenVerifier) remember(key string, forgetAt, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for start, bucket := range v.buckets {
+		if !now.Before(time.Unix(start, 0).Add(proofBucketWidth)) {
+			v.proofs -= len(bucket)
+			delete(v.buckets, start)
+		}
+	}
+
+	start := forgetAt.Truncate(proofBucketWidth).Unix()
+	if _, ok := v.buckets[start][key]; ok {
+		return errors.New("DPoP proof replayed")
+	}
+	for v.proofs >= v.maxProofs && len(v.buckets) > 0 {
+		oldest := start
+		for s := range v.buckets {
+			oldest = min(oldest, s)
+		}
+		if _, ok := v.buckets[oldest]; !ok {
+			break // the new proof expires first; every other bucket is newer
+		}
+		v.proofs -= len(v.buckets[oldest])
+		delete(v.buckets, oldest)
+	}
+	bucket := v.buckets[start]
+	if bucket == nil {
+		bucket = make(map[string]struct{})
+		v.buckets[start] = bucket
+	}
+	bucket[key] = struct{}{}
+	v.proofs++
+	return nil
+}
+
+// requestURL returns the URL a DPoP proof for r must name.
+func (v *TokenVerifier) requestURL(r *http.Request) string {
+	base := v.publicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + r.URL.Path
+}
+
+// sameRequestURL compares a proof's htu with the request URL, ignoring query,
+// fragment and the case of scheme and host.
+func sameRequestURL(htu, want string) bool {
+	a, errA := url.Parse(htu)
+	b, errB := url.Parse(want)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host) && a.Path == b.Path
+}
+
+// key returns the registry key published as kid, refetching the JWKS when it
+// is stale or the kid is unknown. A known key is returned at once while a
+// stale set is refreshed in the background; only an unknown kid waits for the
+// fetch. Tokens without a kid predate key rotation and were signed by the
+// registry's first key.
+func (v *TokenVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	now := time.Now()
+	pub, ok := v.lookup(kid)
+	stale := now.Sub(v.fetchedAt) > jwksRefresh
+	due := (!ok || stale) && now.Sub(v.attemptedAt) > jwksMinRefresh
+	v.mu.Unlock()
+
+	if due {
+		done := v.refetch()
+		if ok {
+			return pub, nil
+		}
+		select {
+		case res := <-done:
+			if res.Err != nil {
+				return nil, res.Err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		v.mu.Lock()
+		pub, ok = v.lookup(kid)
+		v.mu.Unlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return pub, nil
+}
+
+// refetch starts a JWKS fetch, or joins the one in flight, and swaps the keys
+// in if it succeeds. The fetch is shared, so it is not tied to any caller's
+// context. attemptedAt is set when it completes, so callers arriving while it
+// runs join it rather than being rate-limited.
+func (v *TokenVerifier) refetch() <-chan singleflight.Result {
+	return v.flight.DoChan("jwks", func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		keys, err := v.fetchKeys(ctx)
+
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.attemptedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		v.keys, v.fetchedAt = keys, v.attemptedAt
+		return nil, nil
+	})
+}
+
+func (v *TokenVerifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" {
+		kid = firstSigningKeyID
+	}
+	pub, ok := v.keys[kid]
+	return pub, ok
+}
+
+// fetchKeys downloads the registry's JWKS. It does not touch v's state.
+func (v *TokenVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build JWKS request: %w", err)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: HTTP %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			continue // a key type this SDK does not know
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
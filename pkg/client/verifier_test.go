@@ -0,0 +1,323 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmerrifield20/NexusAgentProtocol/pkg/client"
+)
+
+const verifierAgent = "agent://acme.com/x/agent_2"
+
+// tokenRegistry is a stub registry that publishes an Ed25519 JWKS and signs
+// Task Tokens bound to the caller's DPoP key. The resolve endpoint points at
+// *agentURL. sign's edit functions may change the claims before signing.
+func tokenRegistry(t *testing.T, agentURL *string) (*httptest.Server, func(cnf map[string]string, edit ...func(jwt.MapClaims)) string) {
+	t.Helper()
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	var srv *httptest.Server
+	sign := func(cnf map[string]string, edit ...func(jwt.MapClaims)) string {
+		claims := jwt.MapClaims{
+			"iss": srv.URL, "sub": "agent://acme.com/x/agent_1", "aud": verifierAgent,
+			"exp": time.Now().Add(time.Hour).Unix(), "scopes": []string{"agent:call"},
+		}
+		if cnf != nil {
+			claims["cnf"] = cnf
+		}
+		for _, e := range edit {
+			e(claims)
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		tok.Header["kid"] = "k1"
+		s, _ := tok.SignedString(key)
+		return s
+	}
+
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/jwks.json":
+			x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+			json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{"kty": "OKP", "crv": "Ed25519", "x": x, "kid": "k1"}}})
+		case "/api/v1/token":
+			// Bind to the proof's key: RFC 7638 thumbprint of an OKP JWK.
+			proof, _, err := jwt.NewParser().ParseUnverified(r.Header.Get("DPoP"), jwt.MapClaims{})
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			jwk, _ := proof.Header["jwk"].(map[string]any)
+			sum := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + jwk["x"].(string) + `"}`))
+			json.NewEncoder(w).Encode(map[string]any{
+				"access_token": sign(map[string]string{"jkt": base64.RawURLEncoding.EncodeToString(sum[:])}),
+				"token_type":   "DPoP",
+				"expires_in":   3600,
+			})
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"endpoint": *agentURL, "status": "active"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, sign
+}
+
+func TestTokenVerifier_DPoPBoundCall(t *testing.T) {
+	var agentURL string
+	registry, _ := tokenRegistry(t, &agentURL)
+
+	verifier := client.NewTokenVerifier(registry.URL, verifierAgent, client.WithRequireBinding())
+	agent := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"caller": client.TokenClaimsFromContext(r.Context()).Subject})
+	})))
+	defer agent.Close()
+	agentURL = agent.URL
+
+	_, dpopKey, _ := ed25519.GenerateKey(rand.Reader)
+	c, err := client.New(registry.URL, client.WithDPoPKey(dpopKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply map[string]string
+	if err := c.CallAgent(context.Background(), verifierAgent, http.MethodPost, "/v1/task", nil, &reply); err != nil {
+		t.Fatalf("CallAgent: %v", err)
+	}
+	if reply["caller"] != "agent://acme.com/x/agent_1" {
+		t.Errorf("reply = %v", reply)
+	}
+
+	// A stolen token is useless without the DPoP key, and proofs are single-use.
+	tok, _ := c.FetchTokenForAudience(context.Background(), verifierAgent)
+	proof, _ := c.DPoPProof(http.MethodPost, agent.URL+"/v1/task", tok)
+	for _, tc := range []struct {
+		name string
+		hdr  map[string]string
+		want int
+	}{
+		{"bearer", map[string]string{"Authorization": "Bearer " + tok}, http.StatusUnauthorized},
+		{"no proof", map[string]string{"Authorization": "DPoP " + tok}, http.StatusUnauthorized},
+		{"fresh proof", map[string]string{"Authorization": "DPoP " + tok, "DPoP": proof}, http.StatusOK},
+		{"reused proof", map[string]string{"Authorization": "DPoP " + tok, "DPoP": proof}, http.StatusUnauthorized},
+		{"other method", map[string]string{"Authorization": "DPoP " + tok, "DPoP": mustProof(t, c, http.MethodGet, agent.URL+"/v1/task", tok)}, http.StatusUnauthorized},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, agent.URL+"/v1/task", nil)
+		for k, v := range tc.hdr {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestTokenVerifier_WithMaxDPoPProofs(t *testing.T) {
+	var agentURL string
+	registry, _ := tokenRegistry(t, &agentURL)
+	verifier := client.NewTokenVerifier(registry.URL, verifierAgent, client.WithMaxDPoPProofs(1))
+
+	_, dpopKey, _ := ed25519.GenerateKey(rand.Reader)
+	c, err := client.New(registry.URL, client.WithDPoPKey(dpopKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := c.FetchTokenForAudience(context.Background(), verifierAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(proof string) error {
+		req := httptest.NewRequest(http.MethodPost, "http://agent.example/v1/task", nil)
+		req.Header.Set("Authorization", "DPoP "+tok)
+		req.Header.Set("DPoP", proof)
+		_, err := verifier.Verify(req)
+		return err
+	}
+	// A full cache makes room for new proofs rather than rejecting them.
+	var proof string
+	for i := range 2 {
+		proof = mustProof(t, c, http.MethodPost, "http://agent.example/v1/task", tok)
+		if err := verify(proof); err != nil {
+			t.Errorf("proof %d: %v", i, err)
+		}
+	}
+	if err := verify(proof); err == nil {
+		t.Error("replay of the last proof was accepted")
+	}
+}
+
+func mustProof(t *testing.T, c *client.Client, method, target, token string) string {
+	t.Helper()
+	proof, err := c.DPoPProof(method, target, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func TestTokenVerifier_certificateBinding(t *testing.T) {
+	var agentURL string
+	registry, sign := tokenRegistry(t, &agentURL)
+	verifier := client.NewTokenVerifier(registry.URL, verifierAgent)
+
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, key)
+	cert, _ := x509.ParseCertificate(der)
+	sum := sha256.Sum256(cert.Raw)
+	bound := sign(map[string]string{"x5t#S256": base64.RawURLEncoding.EncodeToString(sum[:])})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+bound)
+	if _, err := verifier.Verify(req); !errors.Is(err, client.ErrTokenBinding) {
+		t.Errorf("without the certificate: err = %v, want ErrTokenBinding", err)
+	}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	claims, err := verifier.Verify(req)
+	if err != nil || !claims.HasScope("agent:call") {
+		t.Fatalf("with the certificate: %+v, %v", claims, err)
+	}
+
+	// Unbound tokens pass unless binding is required.
+	req.Header.Set("Authorization", "Bearer "+sign(nil))
+	if _, err := verifier.Verify(req); err != nil {
+		t.Errorf("unbound token: %v", err)
+	}
+	strict := client.NewTokenVerifier(registry.URL, verifierAgent, client.WithRequireBinding())
+	if _, err := strict.Verify(req); !errors.Is(err, client.ErrTokenBinding) {
+		t.Errorf("unbound token with WithRequireBinding: err = %v", err)
+	}
+}
+
+func TestTokenVerifier_audience(t *testing.T) {
+	var agentURL string
+	registry, sign := tokenRegistry(t, &agentURL)
+	noAudience := sign(nil, func(c jwt.MapClaims) { delete(c, "aud") })
+	otherAudience := sign(nil, func(c jwt.MapClaims) { c["aud"] = "agent://acme.com/x/agent_3" })
+
+	verifier := client.NewTokenVerifier(registry.URL, verifierAgent)
+	legacy := client.NewTokenVerifier(registry.URL, verifierAgent, client.WithUnaudiencedTokens())
+	for _, tc := range []struct {
+		name    string
+		v       *client.TokenVerifier
+		token   string
+		wantErr bool
+	}{
+		{"for this agent", verifier, sign(nil), false},
+		{"for another agent", verifier, otherAudience, true},
+		{"no audience", verifier, noAudience, true},
+		{"no audience, legacy tokens allowed", legacy, noAudience, false},
+		{"for another agent, legacy tokens allowed", legacy, otherAudience, true},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		_, err := tc.v.Verify(req)
+		if tc.wantErr != (err != nil) || (err != nil && !errors.Is(err, client.ErrInvalidToken)) {
+			t.Errorf("%s: err = %v, want error: %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestTokenVerifier_WithIntrospection(t *testing.T) {
+	var agentURL string
+	registry, sign := tokenRegistry(t, &agentURL)
+	tok := sign(nil)
+
+	revoked := false
+	introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/token/introspect" || r.FormValue("token") != tok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"active": !revoked})
+	}))
+	defer introspection.Close()
+	c, err := client.New(introspection.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := client.NewTokenVerifier(registry.URL, verifierAgent, client.WithIntrospection(c))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	if _, err := verifier.Verify(req); err != nil {
+		t.Fatalf("active token: %v", err)
+	}
+	revoked = true
+	if _, err := verifier.Verify(req); !errors.Is(err, client.ErrTokenRevoked) {
+		t.Errorf("revoked token: err = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestTokenVerifier_sharesJWKSFetch(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		x := base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{"kty": "OKP", "crv": "Ed25519", "x": x, "kid": "k1"}}})
+	}))
+	defer registry.Close()
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"iss": registry.URL, "sub": "agent://acme.com/x/agent_1", "aud": verifierAgent,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	tok.Header["kid"] = "k1"
+	signed, _ := tok.SignedString(key)
+	request := func(ctx context.Context) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/task", nil).WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		return req
+	}
+
+	verifier := client.NewTokenVerifier(registry.URL, verifierAgent)
+	errs := make(chan error, 5)
+	for range 5 {
+		go func() {
+			_, err := verifier.Verify(request(context.Background()))
+			errs <- err
+		}()
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A caller that gives up is not stuck behind the fetch in flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := verifier.Verify(request(ctx)); err == nil {
+		t.Error("Verify with an expired context succeeded before the keys arrived")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Verify with an expired context took %v", d)
+	}
+
+	close(release)
+	for range 5 {
+		if err := <-errs; err != nil {
+			t.Errorf("Verify: %v", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+}